@@ -0,0 +1,22 @@
+package dnsserver
+
+import "net"
+
+// SetQueryACL sets the networks allowed to query the server; other clients are
+// refused before any lookup is made. An empty list, the default, allows
+// everyone. Dynamic updates and zone transfers additionally need their own
+// ACLs.
+func (ds *Server) SetQueryACL(allowed []net.IPNet) {
+	ds.aclMutex.Lock()
+	defer ds.aclMutex.Unlock()
+
+	ds.queryACL = append([]net.IPNet{}, allowed...)
+}
+
+// queryAllowed reports whether the client at addr may query the server.
+func (ds *Server) queryAllowed(addr net.Addr) bool {
+	ds.aclMutex.RLock()
+	defer ds.aclMutex.RUnlock()
+
+	return len(ds.queryACL) == 0 || aclAllows(ds.queryACL, addr)
+}
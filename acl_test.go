@@ -0,0 +1,54 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestQueryACL(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func() *dns.Msg {
+		t.Helper()
+
+		msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return msg
+	}
+
+	if msg := query(); msg.Rcode != dns.RcodeSuccess || len(msg.Answer) != 1 {
+		t.Fatalf("query without an ACL was not answered: %v", msg)
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	_, private, _ := net.ParseCIDR("10.0.0.0/8")
+
+	ds.SetQueryACL([]net.IPNet{*private, *loopback})
+
+	if msg := query(); msg.Rcode != dns.RcodeSuccess || len(msg.Answer) != 1 {
+		t.Fatalf("allowed client was not answered: %v", msg)
+	}
+
+	ds.SetQueryACL([]net.IPNet{*private})
+
+	if msg := query(); msg.Rcode != dns.RcodeRefused || len(msg.Answer) != 0 {
+		t.Fatalf("denied client was not refused: %v", msg)
+	}
+
+	ds.SetQueryACL(nil)
+
+	if msg := query(); msg.Rcode != dns.RcodeSuccess {
+		t.Fatalf("clearing the ACL did not allow everyone: %v", msg)
+	}
+}
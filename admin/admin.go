@@ -0,0 +1,294 @@
+// Package admin provides an HTTP API for managing a dnsserver's A and SRV
+// records. It lives in its own package so that programs embedding dnsserver do
+// not carry an admin API they do not use.
+//
+// The handler serves these endpoints, taking and returning JSON:
+//
+//	GET    /a                       list all A records
+//	GET    /a/{host}                the addresses of a host
+//	PUT    /a/{host}                replace a host's addresses: {"ips": [...]}
+//	DELETE /a/{host}                delete a host
+//	GET    /srv                     list all SRV records
+//	GET    /srv/{service}/{proto}   the targets of a service
+//	PUT    /srv/{service}/{proto}   replace a service's targets: {"targets": [...]}
+//	DELETE /srv/{service}/{proto}   delete a service
+//
+// Mount it under a prefix with http.StripPrefix, and behind whatever
+// authentication the deployment requires; the handler performs none.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/erikh/dnsserver"
+	"github.com/erikh/dnsserver/db"
+)
+
+// maxBodySize bounds the size of a request body.
+const maxBodySize = 1 << 20
+
+// errBadRequest marks errors caused by the request rather than the server.
+var errBadRequest = errors.New("bad request")
+
+// ARecord is the JSON form of a host's A records.
+type ARecord struct {
+	IPs []net.IP `json:"ips"`
+}
+
+// SRVTarget is the JSON form of a single SRV target.
+type SRVTarget struct {
+	Priority uint16 `json:"priority"`
+	Weight   uint16 `json:"weight"`
+	Port     uint16 `json:"port"`
+	Host     string `json:"host"`
+	TTL      uint32 `json:"ttl,omitempty"`
+}
+
+// SRVRecord is the JSON form of a service's SRV records.
+type SRVRecord struct {
+	Targets []SRVTarget `json:"targets"`
+}
+
+type handler struct {
+	ds *dnsserver.Server
+}
+
+// Handler returns an http.Handler managing the records of ds.
+func Handler(ds *dnsserver.Server) http.Handler {
+	return &handler{ds: ds}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "a":
+		h.listA(w, req)
+	case len(parts) == 2 && parts[0] == "a" && parts[1] != "":
+		h.serveA(w, req, parts[1])
+	case len(parts) == 1 && parts[0] == "srv":
+		h.listSRV(w, req)
+	case len(parts) == 3 && parts[0] == "srv" && parts[1] != "" && parts[2] != "":
+		h.serveSRV(w, req, parts[1], parts[2])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (h *handler) listA(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+
+	records, err := h.ds.ListA()
+	if err != nil {
+		writeResult(w, err)
+		return
+	}
+
+	list := map[string]ARecord{}
+	for host, ips := range records {
+		list[host] = ARecord{IPs: ips}
+	}
+
+	writeJSON(w, list)
+}
+
+func (h *handler) serveA(w http.ResponseWriter, req *http.Request, host string) {
+	switch req.Method {
+	case http.MethodGet:
+		ips, err := h.ds.HostA(host)
+		if err != nil {
+			writeResult(w, err)
+			return
+		}
+
+		writeJSON(w, ARecord{IPs: ips})
+	case http.MethodPut:
+		var record ARecord
+		if err := readJSON(req, &record); err != nil {
+			writeResult(w, err)
+			return
+		}
+
+		writeResult(w, h.setA(host, record.IPs))
+	case http.MethodDelete:
+		if _, err := h.ds.HostA(host); err != nil {
+			writeResult(w, err)
+			return
+		}
+
+		writeResult(w, h.ds.DeleteA(host))
+	default:
+		methodNotAllowed(w, "GET, PUT, DELETE")
+	}
+}
+
+// setA replaces the addresses of host with ips. All of them are checked first,
+// so that an invalid address leaves the host untouched.
+func (h *handler) setA(host string, ips []net.IP) error {
+	if len(ips) == 0 {
+		return fmt.Errorf("%w: no addresses", errBadRequest)
+	}
+
+	for _, ip := range ips {
+		if ip.To4() == nil || ip.IsUnspecified() {
+			return fmt.Errorf("%w: %v", dnsserver.ErrInvalidIP, ip)
+		}
+	}
+
+	if err := h.ds.SetA(host, ips[0]); err != nil {
+		return err
+	}
+
+	for _, ip := range ips[1:] {
+		if err := h.ds.AddA(host, ip); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *handler) listSRV(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		methodNotAllowed(w, "GET")
+		return
+	}
+
+	records, err := h.ds.ListSRVSpecs()
+	if err != nil {
+		writeResult(w, err)
+		return
+	}
+
+	list := map[string]SRVRecord{}
+	for spec, srvs := range records {
+		list[spec.String()] = toSRVRecord(srvs)
+	}
+
+	writeJSON(w, list)
+}
+
+func (h *handler) serveSRV(w http.ResponseWriter, req *http.Request, service, protocol string) {
+	switch req.Method {
+	case http.MethodGet:
+		srvs, err := h.ds.ServiceSRV(service, protocol)
+		if err != nil {
+			writeResult(w, err)
+			return
+		}
+
+		writeJSON(w, toSRVRecord(srvs))
+	case http.MethodPut:
+		var record SRVRecord
+		if err := readJSON(req, &record); err != nil {
+			writeResult(w, err)
+			return
+		}
+
+		writeResult(w, h.setSRV(service, protocol, record.Targets))
+	case http.MethodDelete:
+		if _, err := h.ds.ServiceSRV(service, protocol); err != nil {
+			writeResult(w, err)
+			return
+		}
+
+		writeResult(w, h.ds.DeleteSRV(service, protocol))
+	default:
+		methodNotAllowed(w, "GET, PUT, DELETE")
+	}
+}
+
+// setSRV replaces the targets of a service.
+func (h *handler) setSRV(service, protocol string, targets []SRVTarget) error {
+	if len(targets) == 0 {
+		return fmt.Errorf("%w: no targets", errBadRequest)
+	}
+
+	// SetSRV replaces the live targets, so a bad one must be caught before
+	// the first write rather than leave the service half replaced.
+	srvs := make([]*db.SRVRecord, len(targets))
+	for i, target := range targets {
+		srvs[i] = &db.SRVRecord{
+			Priority: target.Priority,
+			Weight:   target.Weight,
+			Port:     target.Port,
+			Host:     target.Host,
+			TTL:      target.TTL,
+		}
+
+		if err := h.ds.ValidateSRV(service, protocol, srvs[i]); err != nil {
+			return err
+		}
+	}
+
+	for i, srv := range srvs {
+		set := h.ds.AddSRV
+		if i == 0 {
+			set = h.ds.SetSRV
+		}
+
+		if err := set(service, protocol, srv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toSRVRecord(srvs []*db.SRVRecord) SRVRecord {
+	record := SRVRecord{Targets: []SRVTarget{}}
+
+	for _, srv := range srvs {
+		record.Targets = append(record.Targets, SRVTarget{
+			Priority: srv.Priority,
+			Weight:   srv.Weight,
+			Port:     srv.Port,
+			Host:     srv.Host,
+			TTL:      srv.TTL,
+		})
+	}
+
+	return record
+}
+
+// readJSON decodes the request body into v.
+func readJSON(req *http.Request, v interface{}) error {
+	if err := json.NewDecoder(http.MaxBytesReader(nil, req.Body, maxBodySize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeResult replies to a request with the status matching err; a nil err is
+// a success with no content.
+func writeResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.Is(err, db.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errBadRequest), errors.Is(err, dnsserver.ErrInvalidName), errors.Is(err, dnsserver.ErrInvalidIP), errors.Is(err, dnsserver.ErrNotFQDN):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow string) {
+	w.Header().Set("Allow", allow)
+	http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,159 @@
+package admin
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/erikh/dnsserver"
+)
+
+func do(t *testing.T, h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestA(t *testing.T) {
+	ds := dnsserver.New("docker")
+	h := Handler(ds)
+
+	if w := do(t, h, http.MethodGet, "/a/test", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("missing host was %d", w.Code)
+	}
+
+	if w := do(t, h, http.MethodPut, "/a/test", `{"ips": ["127.0.0.2", "127.0.0.3"]}`); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT was %d: %s", w.Code, w.Body)
+	}
+
+	if records := ds.GetA("test.docker."); len(records) != 2 {
+		t.Fatalf("PUT did not set both addresses: %v", records)
+	}
+
+	w := do(t, h, http.MethodGet, "/a/test", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET was %d", w.Code)
+	}
+
+	var record ARecord
+	if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(record.IPs) != 2 || !record.IPs[0].Equal(net.ParseIP("127.0.0.2")) || !record.IPs[1].Equal(net.ParseIP("127.0.0.3")) {
+		t.Fatalf("GET returned %v", record.IPs)
+	}
+
+	w = do(t, h, http.MethodGet, "/a", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list was %d", w.Code)
+	}
+
+	list := map[string]ARecord{}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 || len(list["test"].IPs) != 2 {
+		t.Fatalf("list returned %v", list)
+	}
+
+	for _, body := range []string{`{"ips": ["::1"]}`, `{"ips": []}`, `{"ips": ["bogus"]}`, `not json`} {
+		if w := do(t, h, http.MethodPut, "/a/test", body); w.Code != http.StatusBadRequest {
+			t.Fatalf("PUT %s was %d", body, w.Code)
+		}
+	}
+
+	if records := ds.GetA("test.docker."); len(records) != 2 {
+		t.Fatalf("rejected PUT changed the host: %v", records)
+	}
+
+	if w := do(t, h, http.MethodDelete, "/a/test", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE was %d", w.Code)
+	}
+
+	if w := do(t, h, http.MethodDelete, "/a/test", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("second DELETE was %d", w.Code)
+	}
+
+	if w := do(t, h, http.MethodPost, "/a/test", ""); w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") == "" {
+		t.Fatalf("POST was %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	if w := do(t, h, http.MethodGet, "/aaaa/test", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown path was %d", w.Code)
+	}
+}
+
+func TestSRV(t *testing.T) {
+	ds := dnsserver.New("docker")
+	h := Handler(ds)
+
+	if w := do(t, h, http.MethodGet, "/srv/http/tcp", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("missing service was %d", w.Code)
+	}
+
+	body := `{"targets": [{"priority": 10, "weight": 5, "port": 80, "host": "web"}, {"port": 8080, "host": "web2", "ttl": 30}]}`
+	if w := do(t, h, http.MethodPut, "/srv/http/tcp", body); w.Code != http.StatusNoContent {
+		t.Fatalf("PUT was %d: %s", w.Code, w.Body)
+	}
+
+	if records := ds.GetSRV("_http._tcp.docker."); len(records) != 2 {
+		t.Fatalf("PUT did not set both targets: %v", records)
+	}
+
+	w := do(t, h, http.MethodGet, "/srv/http/tcp", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET was %d", w.Code)
+	}
+
+	var record SRVRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SRVTarget{
+		{Priority: 10, Weight: 5, Port: 80, Host: "web.docker."},
+		{Port: 8080, Host: "web2.docker.", TTL: 30},
+	}
+
+	if len(record.Targets) != len(want) || record.Targets[0] != want[0] || record.Targets[1] != want[1] {
+		t.Fatalf("GET returned %v", record.Targets)
+	}
+
+	w = do(t, h, http.MethodGet, "/srv", "")
+
+	list := map[string]SRVRecord{}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 1 || len(list["_http._tcp"].Targets) != 2 {
+		t.Fatalf("list returned %v", list)
+	}
+
+	if w := do(t, h, http.MethodPut, "/srv/http/tcp", `{"targets": []}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("PUT without targets was %d", w.Code)
+	}
+
+	if w := do(t, h, http.MethodPut, "/srv/http/tcp", `{"targets": [{"port": 81, "host": "web3"}, {"port": 82, "host": ""}]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("PUT with an empty target was %d", w.Code)
+	}
+
+	if records := ds.GetSRV("_http._tcp.docker."); len(records) != 2 || records[0].Port != 80 || records[1].Port != 8080 {
+		t.Fatalf("rejected PUT changed the targets: %v", records)
+	}
+
+	if w := do(t, h, http.MethodDelete, "/srv/http/tcp", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE was %d", w.Code)
+	}
+
+	if w := do(t, h, http.MethodDelete, "/srv/http/tcp", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("second DELETE was %d", w.Code)
+	}
+}
@@ -0,0 +1,69 @@
+package dnsserver
+
+import (
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ptrIndex maps addresses to the hosts whose A records hold them, for
+// answering reverse lookups without PTR records; see SetAutoPTR. It is built
+// from the DB's A records, and built again once the zone's serial moves on.
+type ptrIndex struct {
+	mutex   sync.Mutex
+	enabled bool
+	built   bool
+	serial  uint32              // ds.serial when built
+	hosts   map[string][]string // address -> sorted host FQDNs
+}
+
+// SetAutoPTR sets whether reverse lookups for the addresses of A records are
+// answered with PTR records to the hosts holding them, beside any set with
+// SetPTR. They are derived from the A records as they stand, so records set
+// with SetA, removed with DeleteA or changed in any other way are reflected in
+// the next reverse lookup, and an address held by several hosts is answered
+// with a PTR record for each. The wildcard is left out.
+func (ds *Server) SetAutoPTR(enabled bool) {
+	ds.reverse.mutex.Lock()
+	defer ds.reverse.mutex.Unlock()
+
+	ds.reverse.enabled = enabled
+	ds.reverse.built = false
+	ds.reverse.hosts = nil
+}
+
+// autoPTRs returns the FQDNs of the hosts holding ip in their A records, or
+// nil if SetAutoPTR is off.
+func (ds *Server) autoPTRs(ip net.IP) ([]string, error) {
+	ds.reverse.mutex.Lock()
+	defer ds.reverse.mutex.Unlock()
+
+	if !ds.reverse.enabled {
+		return nil, nil
+	}
+
+	// the serial is read first, so a change made while building is picked up
+	// by the next lookup
+	if serial := atomic.LoadUint32(&ds.serial); !ds.reverse.built || serial != ds.reverse.serial {
+		hosts := map[string][]string{}
+
+		err := ds.db.ForEachA(func(host string, addr net.IP) bool {
+			if host != wildcardHost {
+				hosts[addr.String()] = append(hosts[addr.String()], ds.qualifyHost(host))
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, names := range hosts {
+			sort.Strings(names)
+		}
+
+		ds.reverse.built, ds.reverse.serial, ds.reverse.hosts = true, serial, hosts
+	}
+
+	return ds.reverse.hosts[ip.String()], nil
+}
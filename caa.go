@@ -0,0 +1,60 @@
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// ErrInvalidCAATag is returned by SetCAA for a tag other than issue,
+// issuewild or iodef.
+var ErrInvalidCAATag = errors.New("invalid CAA tag")
+
+// GetCAA receives a FQDN; looks up and supplies the CAA records.
+func (ds *Server) GetCAA(name string) []*dns.CAA {
+	recs, err := ds.db.GetCAA(ds.subdomain(name))
+	if err != nil {
+		ds.logLookup(name, dns.TypeCAA, err)
+		return nil
+	}
+
+	records := []*dns.CAA{}
+
+	for _, rec := range recs {
+		records = append(records, &dns.CAA{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypeCAA,
+				Class:  dns.ClassINET,
+				Ttl:    ds.ttlFor(0),
+			},
+			Flag:  rec.Flag,
+			Tag:   rec.Tag,
+			Value: rec.Value,
+		})
+	}
+
+	return records
+}
+
+// SetCAA adds a CAA record for a host, naming the certificate authorities
+// allowed to issue for it. Note that this is not the FQDN, but a hostname.
+// The tag must be issue, issuewild or iodef; ErrInvalidCAATag is returned
+// otherwise. Setting an already registered tag and value updates its flag.
+func (ds *Server) SetCAA(host string, flag uint8, tag, value string) error {
+	switch tag {
+	case "issue", "issuewild", "iodef":
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidCAATag, tag)
+	}
+
+	return ds.changed(ds.db.SetCAA(host, &db.CAARecord{Flag: flag, Tag: tag, Value: value}))
+}
+
+// DeleteCAA deletes all of a host's CAA records. Note that this is not the
+// FQDN, but a hostname.
+func (ds *Server) DeleteCAA(host string) error {
+	return ds.changed(ds.db.DeleteCAA(host))
+}
@@ -0,0 +1,182 @@
+package dnsserver
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// CacheStats reports how the forwarding cache has performed.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64 // entries dropped for expiry or to make room
+}
+
+type cacheKey struct {
+	name  string
+	qtype uint16
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+// cache holds forwarded responses until their TTL runs out, dropping the
+// least recently used entry when full.
+type cache struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[cacheKey]*list.Element
+	lru     *list.List
+	stats   CacheStats
+	now     func() time.Time
+}
+
+func newCache() *cache {
+	return &cache{
+		entries: map[cacheKey]*list.Element{},
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// SetCacheSize sets how many forwarded responses are cached. A size of 0, the
+// default, disables the cache. Shrinking the cache evicts the least recently
+// used entries.
+func (ds *Server) SetCacheSize(n int) {
+	ds.cache.resize(n)
+}
+
+// CacheStats returns the forwarding cache's counters.
+func (ds *Server) CacheStats() CacheStats {
+	ds.cache.mutex.Lock()
+	defer ds.cache.mutex.Unlock()
+
+	return ds.cache.stats
+}
+
+func (c *cache) resize(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.size = n
+	for c.lru.Len() > c.size {
+		c.evict(c.lru.Back())
+	}
+}
+
+// get returns a copy of the cached response for question with its TTLs
+// reduced by the time spent in the cache, or nil.
+func (c *cache) get(question dns.Question) *dns.Msg {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.size == 0 {
+		return nil
+	}
+
+	elem, ok := c.entries[keyFor(question)]
+	if !ok {
+		c.stats.Misses++
+		return nil
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	now := c.now()
+
+	if !now.Before(entry.expires) {
+		c.evict(elem)
+		c.stats.Misses++
+		return nil
+	}
+
+	c.lru.MoveToFront(elem)
+	c.stats.Hits++
+
+	msg := entry.msg.Copy()
+	elapsed := uint32(now.Sub(entry.stored) / time.Second)
+
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				rr.Header().Ttl -= elapsed
+			}
+		}
+	}
+
+	return msg
+}
+
+// put caches msg for its minimum TTL. Server failures and responses with
+// nothing to take a TTL from are not cached.
+func (c *cache) put(question dns.Question, msg *dns.Msg) {
+	if msg.Rcode == dns.RcodeServerFailure {
+		return
+	}
+
+	ttl, ok := minTTL(msg)
+	if !ok || ttl == 0 {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.size == 0 {
+		return
+	}
+
+	key := keyFor(question)
+	if elem, ok := c.entries[key]; ok {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+
+	for c.lru.Len() >= c.size {
+		c.evict(c.lru.Back())
+	}
+
+	now := c.now()
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		msg:     msg.Copy(),
+		stored:  now,
+		expires: now.Add(time.Duration(ttl) * time.Second),
+	})
+}
+
+// evict removes elem; the mutex must be held.
+func (c *cache) evict(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+	c.stats.Evictions++
+}
+
+func keyFor(question dns.Question) cacheKey {
+	return cacheKey{name: strings.ToLower(question.Name), qtype: question.Qtype}
+}
+
+// minTTL returns the lowest TTL of the records in msg, ignoring EDNS0 OPT
+// records. ok is false if msg carries no records.
+func minTTL(msg *dns.Msg) (ttl uint32, ok bool) {
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+
+			if !ok || rr.Header().Ttl < ttl {
+				ttl, ok = rr.Header().Ttl, true
+			}
+		}
+	}
+
+	return ttl, ok
+}
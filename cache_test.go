@@ -0,0 +1,123 @@
+package dnsserver
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestForwardCache(t *testing.T) {
+	var queries int32
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		atomic.AddInt32(&queries, 1)
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+
+		if r.Question[0].Name == "fail.example.com." {
+			m.SetRcode(r, dns.RcodeServerFailure)
+		} else {
+			rr, _ := dns.NewRR(r.Question[0].Name + " 30 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		}
+
+		w.WriteMsg(m)
+	})}
+	go upstream.ActivateAndServe()
+	defer upstream.Shutdown()
+
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	ds.SetForwarders([]string{conn.LocalAddr().String()})
+	ds.SetCacheSize(10)
+
+	now := time.Now()
+	setClock := func(offset time.Duration) {
+		ds.cache.mutex.Lock()
+		ds.cache.now = func() time.Time { return now.Add(offset) }
+		ds.cache.mutex.Unlock()
+	}
+	setClock(0)
+
+	query := func(name string) *dns.Msg {
+		t.Helper()
+
+		msg, err := msgClientAddr(addr, name, dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return msg
+	}
+
+	query("example.com.")
+	setClock(10 * time.Second)
+	msg := query("example.com.")
+
+	if n := atomic.LoadInt32(&queries); n != 1 {
+		t.Fatalf("expected one upstream query, got %d", n)
+	}
+
+	if len(msg.Answer) != 1 || msg.Answer[0].Header().Ttl != 20 {
+		t.Fatalf("cached answer did not count down its TTL: %v", msg.Answer)
+	}
+
+	if stats := ds.CacheStats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+
+	setClock(31 * time.Second)
+	query("example.com.")
+
+	if n := atomic.LoadInt32(&queries); n != 2 {
+		t.Fatalf("expired entry was served from the cache; %d upstream queries", n)
+	}
+
+	if stats := ds.CacheStats(); stats.Evictions != 1 {
+		t.Fatalf("expired entry was not evicted: %+v", stats)
+	}
+
+	query("fail.example.com.")
+	query("fail.example.com.")
+
+	if n := atomic.LoadInt32(&queries); n != 4 {
+		t.Fatalf("SERVFAIL was cached; %d upstream queries", n)
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	c := newCache()
+	c.resize(2)
+
+	for _, name := range []string{"one.", "two.", "three."} {
+		msg := new(dns.Msg)
+		rr, _ := dns.NewRR(name + " 30 IN A 192.0.2.1")
+		msg.Answer = append(msg.Answer, rr)
+		c.put(dns.Question{Name: name, Qtype: dns.TypeA}, msg)
+	}
+
+	if c.get(dns.Question{Name: "one.", Qtype: dns.TypeA}) != nil {
+		t.Fatal("least recently used entry was not evicted")
+	}
+
+	if c.get(dns.Question{Name: "THREE.", Qtype: dns.TypeA}) == nil {
+		t.Fatal("cache lookups are not case-insensitive")
+	}
+
+	c.resize(0)
+
+	if c.get(dns.Question{Name: "three.", Qtype: dns.TypeA}) != nil {
+		t.Fatal("disabled cache returned an entry")
+	}
+}
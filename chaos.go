@@ -0,0 +1,75 @@
+package dnsserver
+
+import (
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// SetVersionString sets the version served to CHAOS class TXT queries for
+// "version.bind.", which monitoring tools use to identify servers. The empty
+// string, the default, refuses them, so as not to tell clients what is
+// running.
+func (ds *Server) SetVersionString(version string) {
+	ds.chaosMutex.Lock()
+	ds.version = version
+	ds.chaosMutex.Unlock()
+}
+
+// SetHostnameBind sets whether CHAOS class TXT queries for "hostname.bind."
+// are answered with the name of the host the server runs on, as os.Hostname
+// reports it. They are refused by default.
+func (ds *Server) SetHostnameBind(enabled bool) {
+	ds.chaosMutex.Lock()
+	ds.chaosHost = enabled
+	ds.chaosMutex.Unlock()
+}
+
+// serveChaos answers a CHAOS class query. Only TXT and ANY queries for the
+// names enabled with SetVersionString and SetHostnameBind are answered; any
+// other is refused.
+func (ds *Server) serveChaos(w dns.ResponseWriter, r *dns.Msg) {
+	m := &dns.Msg{}
+
+	question := r.Question[0]
+	if question.Qtype != dns.TypeTXT && question.Qtype != dns.TypeANY {
+		m.SetRcode(r, dns.RcodeRefused)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	ds.chaosMutex.RLock()
+	version, hostname := ds.version, ds.chaosHost
+	ds.chaosMutex.RUnlock()
+
+	var value string
+
+	switch strings.ToLower(question.Name) {
+	case "version.bind.":
+		value = version
+	case "hostname.bind.":
+		if hostname {
+			value, _ = os.Hostname()
+		}
+	}
+
+	if value == "" {
+		m.SetRcode(r, dns.RcodeRefused)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	m.SetReply(r)
+	m.Authoritative = true
+	m.Answer = []dns.RR{&dns.TXT{
+		Hdr: dns.RR_Header{
+			Name:   question.Name,
+			Rrtype: dns.TypeTXT,
+			Class:  dns.ClassCHAOS,
+		},
+		Txt: splitTXT(value),
+	}}
+
+	ds.writeMsg(w, r, m)
+}
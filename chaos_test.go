@@ -0,0 +1,77 @@
+package dnsserver
+
+import (
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestChaos(t *testing.T) {
+	ds := New("docker")
+
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	chaos := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+		m.Question[0].Qclass = dns.ClassCHAOS
+
+		reply, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return reply
+	}
+
+	for _, name := range []string{"version.bind.", "hostname.bind."} {
+		if m := chaos(name, dns.TypeTXT); m.Rcode != dns.RcodeRefused || len(m.Answer) != 0 {
+			t.Fatalf("%s was answered by default: %v", name, m)
+		}
+	}
+
+	ds.SetVersionString("dnsserver test")
+
+	m := chaos("version.bind.", dns.TypeTXT)
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+		t.Fatalf("version.bind reply was %v", m)
+	}
+
+	if txt, ok := m.Answer[0].(*dns.TXT); !ok || txt.Hdr.Class != dns.ClassCHAOS || len(txt.Txt) != 1 || txt.Txt[0] != "dnsserver test" {
+		t.Fatalf("version.bind answer was %v", m.Answer[0])
+	}
+
+	if m := chaos("hostname.bind.", dns.TypeTXT); m.Rcode != dns.RcodeRefused {
+		t.Fatalf("hostname.bind was answered without being enabled: %v", m)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("no hostname to serve: %v", err)
+	}
+
+	ds.SetHostnameBind(true)
+
+	m = chaos("hostname.bind.", dns.TypeTXT)
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 || m.Answer[0].(*dns.TXT).Txt[0] != hostname {
+		t.Fatalf("hostname.bind reply was %v, expected %q", m, hostname)
+	}
+
+	if m := chaos("version.bind.", dns.TypeA); m.Rcode != dns.RcodeRefused {
+		t.Fatalf("CHAOS A query was answered with %v", m)
+	}
+
+	if m := chaos("other.bind.", dns.TypeTXT); m.Rcode != dns.RcodeRefused {
+		t.Fatalf("unknown CHAOS name was answered with %v", m)
+	}
+
+	ds.SetVersionString("")
+
+	if m := chaos("version.bind.", dns.TypeTXT); m.Rcode != dns.RcodeRefused {
+		t.Fatalf("version.bind was answered after being disabled: %v", m)
+	}
+}
@@ -0,0 +1,256 @@
+// Package config builds a dnsserver from a JSON file holding its domain,
+// listen address, TTL, forwarders and the records it starts out serving:
+//
+//	{
+//	  "domain": "docker",
+//	  "listen": "127.0.0.1:5300",
+//	  "network": "both",
+//	  "ttl": 60,
+//	  "forwarders": ["8.8.8.8"],
+//	  "records": {
+//	    "a": {"web": ["10.0.0.2", "10.0.0.3"]},
+//	    "cname": {"www": "web"},
+//	    "srv": [{"service": "http", "protocol": "tcp", "host": "web", "port": 80}]
+//	  }
+//	}
+//
+// Only the domain is required. It lives in its own package so that programs
+// embedding dnsserver do not carry a configuration format they do not use.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/erikh/dnsserver"
+	"github.com/erikh/dnsserver/db"
+)
+
+// ErrInvalidConfig is returned, wrapped with the field at fault, for a
+// configuration which cannot be read or does not describe a valid server.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Config is the JSON form of a server's configuration.
+type Config struct {
+	// Domain is the unqualified domain served, as New takes it.
+	Domain string `json:"domain"`
+	// Listen is the address to serve on, e.g. "127.0.0.1:53". NewServer does
+	// not bind it; pass it to the server's Listen, or see ListenAddr.
+	Listen string `json:"listen,omitempty"`
+	// Network is "udp", the default, "tcp" or "both"; see WithListenNet.
+	Network string `json:"network,omitempty"`
+	// TTL is the default TTL of served records; 0 keeps DefaultTTL.
+	TTL uint32 `json:"ttl,omitempty"`
+	// Forwarders are the upstream servers; see SetForwarders.
+	Forwarders []string `json:"forwarders,omitempty"`
+	// Records are the records the server starts out serving.
+	Records Records `json:"records"`
+}
+
+// Records holds the initial records of a server, keyed by hostname.
+type Records struct {
+	A     map[string][]string `json:"a,omitempty"`
+	AAAA  map[string]string   `json:"aaaa,omitempty"`
+	CNAME map[string]string   `json:"cname,omitempty"`
+	TXT   map[string][]string `json:"txt,omitempty"`
+	MX    map[string][]MX     `json:"mx,omitempty"`
+	SRV   []SRV               `json:"srv,omitempty"`
+}
+
+// MX is the JSON form of a mail exchange.
+type MX struct {
+	Preference uint16 `json:"preference"`
+	Mail       string `json:"mail"`
+}
+
+// SRV is the JSON form of a SRV record and the service it is for.
+type SRV struct {
+	Service  string `json:"service"`
+	Protocol string `json:"protocol"`
+	Host     string `json:"host"`
+	Port     uint16 `json:"port"`
+	Priority uint16 `json:"priority,omitempty"`
+	Weight   uint16 `json:"weight,omitempty"`
+	TTL      uint32 `json:"ttl,omitempty"`
+}
+
+// LoadConfig reads the configuration at path and returns the server it
+// describes, with its records seeded, along with the configuration itself so
+// that the caller can bind its Listen address; see Read and NewServer.
+func LoadConfig(path string) (*dnsserver.Server, *Config, error) {
+	c, err := Read(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ds, err := c.NewServer()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ds, c, nil
+}
+
+// ListenAddr returns the address the server should be bound to: Listen, or
+// def if the configuration leaves it out.
+func (c *Config) ListenAddr(def string) string {
+	if c.Listen == "" {
+		return def
+	}
+
+	return c.Listen
+}
+
+// Read reads and validates the configuration at path. Fields it does not know
+// are rejected, so that typos do not go unnoticed.
+func Read(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+
+	c := &Config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidConfig, path, err)
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Validate checks the fields of c which the server does not check itself
+// when NewServer applies them.
+func (c *Config) Validate() error {
+	if c.Listen != "" {
+		if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+			return fmt.Errorf("%w: listen: %v", ErrInvalidConfig, err)
+		}
+	}
+
+	switch c.Network {
+	case "", "udp", "tcp", "both":
+	default:
+		return fmt.Errorf("%w: network: %q is not udp, tcp or both", ErrInvalidConfig, c.Network)
+	}
+
+	for i, forwarder := range c.Forwarders {
+		host, _, err := net.SplitHostPort(forwarder)
+		if err != nil {
+			host = forwarder
+		}
+
+		if net.ParseIP(host) == nil {
+			return fmt.Errorf("%w: forwarders[%d]: %q is not an address", ErrInvalidConfig, i, forwarder)
+		}
+	}
+
+	for host, addrs := range c.Records.A {
+		if len(addrs) == 0 {
+			return fmt.Errorf("%w: records.a[%q]: no addresses", ErrInvalidConfig, host)
+		}
+
+		for _, addr := range addrs {
+			if net.ParseIP(addr) == nil {
+				return fmt.Errorf("%w: records.a[%q]: %q is not an address", ErrInvalidConfig, host, addr)
+			}
+		}
+	}
+
+	for host, addr := range c.Records.AAAA {
+		if net.ParseIP(addr) == nil {
+			return fmt.Errorf("%w: records.aaaa[%q]: %q is not an address", ErrInvalidConfig, host, addr)
+		}
+	}
+
+	return nil
+}
+
+// NewServer returns the server c describes, with its records seeded. Records
+// the server rejects, such as hosts which do not form valid names, are
+// reported with the field they came from.
+func (c *Config) NewServer() (*dnsserver.Server, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	opts := []dnsserver.Option{}
+
+	if c.Network != "" {
+		opts = append(opts, dnsserver.WithListenNet(c.Network))
+	}
+
+	if c.TTL != 0 {
+		opts = append(opts, dnsserver.WithTTL(c.TTL))
+	}
+
+	if len(c.Forwarders) != 0 {
+		opts = append(opts, dnsserver.WithForwarders(c.Forwarders))
+	}
+
+	ds, err := dnsserver.NewWithError(c.Domain, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: domain: %v", ErrInvalidConfig, err)
+	}
+
+	if err := c.seed(ds); err != nil {
+		return nil, err
+	}
+
+	return ds, nil
+}
+
+// seed stores c's records in ds.
+func (c *Config) seed(ds *dnsserver.Server) error {
+	for host, addrs := range c.Records.A {
+		for _, addr := range addrs {
+			if err := ds.AddA(host, net.ParseIP(addr)); err != nil {
+				return fmt.Errorf("%w: records.a[%q]: %v", ErrInvalidConfig, host, err)
+			}
+		}
+	}
+
+	for host, addr := range c.Records.AAAA {
+		if err := ds.SetAAAA(host, net.ParseIP(addr)); err != nil {
+			return fmt.Errorf("%w: records.aaaa[%q]: %v", ErrInvalidConfig, host, err)
+		}
+	}
+
+	for alias, target := range c.Records.CNAME {
+		if err := ds.SetCNAME(alias, target); err != nil {
+			return fmt.Errorf("%w: records.cname[%q]: %v", ErrInvalidConfig, alias, err)
+		}
+	}
+
+	for host, values := range c.Records.TXT {
+		if err := ds.SetTXT(host, values); err != nil {
+			return fmt.Errorf("%w: records.txt[%q]: %v", ErrInvalidConfig, host, err)
+		}
+	}
+
+	for host, mxs := range c.Records.MX {
+		for _, mx := range mxs {
+			if err := ds.SetMX(host, mx.Preference, mx.Mail); err != nil {
+				return fmt.Errorf("%w: records.mx[%q]: %v", ErrInvalidConfig, host, err)
+			}
+		}
+	}
+
+	for i, srv := range c.Records.SRV {
+		rec := &db.SRVRecord{Priority: srv.Priority, Weight: srv.Weight, Port: srv.Port, Host: srv.Host, TTL: srv.TTL}
+		if err := ds.AddSRV(srv.Service, srv.Protocol, rec); err != nil {
+			return fmt.Errorf("%w: records.srv[%d]: %v", ErrInvalidConfig, i, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestLoadConfig(t *testing.T) {
+	ds, c, err := LoadConfig("testdata/server.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+
+		m := ds.Resolve(r, nil)
+		if m == nil || m.Rcode != dns.RcodeSuccess {
+			t.Fatalf("reply to %s %s was %v", name, dns.TypeToString[qtype], m)
+		}
+
+		return m
+	}
+
+	m := resolve("web.docker.", dns.TypeA)
+	if len(m.Answer) != 2 {
+		t.Fatalf("web had %d A records, expected 2: %v", len(m.Answer), m)
+	}
+
+	if ttl := m.Answer[0].Header().Ttl; ttl != 60 {
+		t.Fatalf("A record TTL was %d, expected the configured 60", ttl)
+	}
+
+	if m := resolve("web.docker.", dns.TypeAAAA); len(m.Answer) != 1 {
+		t.Fatalf("AAAA reply was %v", m)
+	}
+
+	if m := resolve("www.docker.", dns.TypeCNAME); len(m.Answer) != 1 || m.Answer[0].(*dns.CNAME).Target != "web.docker." {
+		t.Fatalf("CNAME reply was %v", m)
+	}
+
+	if m := resolve("web.docker.", dns.TypeTXT); len(m.Answer) != 1 {
+		t.Fatalf("TXT reply was %v", m)
+	}
+
+	if m := resolve("docker.", dns.TypeMX); len(m.Answer) != 1 || m.Answer[0].(*dns.MX).Mx != "mail.docker." {
+		t.Fatalf("MX reply was %v", m)
+	}
+
+	if m := resolve("_http._tcp.docker.", dns.TypeSRV); len(m.Answer) != 2 {
+		t.Fatalf("SRV reply was %v", m)
+	}
+
+	if c.Listen != "127.0.0.1:0" || c.Network != "both" {
+		t.Fatalf("listen settings were %q %q", c.Listen, c.Network)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	for i, tc := range []struct {
+		config string
+		field  string
+	}{
+		{`{"domain": "docker", "ttl": "sixty"}`, "ttl"},
+		{`{"domain": "docker", "tll": 60}`, "tll"},
+		{`{"domain": ""}`, "domain"},
+		{`{"domain": "docker."}`, "domain"},
+		{`{"domain": "a..b"}`, "domain"},
+		{`{"domain": "docker", "listen": "127.0.0.1"}`, "listen"},
+		{`{"domain": "docker", "network": "sctp"}`, "network"},
+		{`{"domain": "docker", "forwarders": ["upstream"]}`, "forwarders[0]"},
+		{`{"domain": "docker", "records": {"a": {"web": ["not an address"]}}}`, `records.a["web"]`},
+		{`{"domain": "docker", "records": {"a": {"web": ["::1"]}}}`, `records.a["web"]`},
+		{`{"domain": "docker", "records": {"a": {"a..b": ["127.0.0.2"]}}}`, `records.a["a..b"]`},
+		{`{"domain": "docker", "records": {"aaaa": {"web": "127.0.0.2"}}}`, `records.aaaa["web"]`},
+		{`{"domain": "docker", "records": {"srv": [{"service": "http", "protocol": "tcp", "host": "", "port": 80}]}}`, "records.srv[0]"},
+	} {
+		path := filepath.Join(dir, "config.json")
+		if err := os.WriteFile(path, []byte(tc.config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		ds, c, err := LoadConfig(path)
+		if !errors.Is(err, ErrInvalidConfig) || ds != nil || c != nil {
+			t.Fatalf("%d: %s loaded with %v", i, tc.config, err)
+		}
+
+		if !strings.Contains(err.Error(), tc.field) {
+			t.Fatalf("%d: error %q does not name %s", i, err, tc.field)
+		}
+	}
+
+	if _, _, err := LoadConfig(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Fatalf("missing config yielded %v", err)
+	}
+}
+
+func TestLoadConfigListen(t *testing.T) {
+	ds, c, err := LoadConfig("testdata/server.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop, err := ds.ListenAndReady(c.ListenAddr("127.0.0.1:53"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+
+	// the configured network is both, so the records are served over each
+	udpIP, udpPort := ds.Listening()
+	tcpIP, tcpPort := ds.ListeningTCP()
+
+	for network, addr := range map[string]string{
+		"udp": net.JoinHostPort(udpIP.String(), strconv.Itoa(int(udpPort))),
+		"tcp": net.JoinHostPort(tcpIP.String(), strconv.Itoa(int(tcpPort))),
+	} {
+		r := new(dns.Msg)
+		r.SetQuestion("web.docker.", dns.TypeA)
+
+		client := &dns.Client{Net: network}
+		m, _, err := client.Exchange(r, addr)
+		if err != nil {
+			t.Fatalf("%s query to the configured address %s failed: %v", network, addr, err)
+		}
+
+		if len(m.Answer) != 2 {
+			t.Fatalf("%s reply was %v", network, m)
+		}
+	}
+
+	if addr := (&Config{}).ListenAddr("127.0.0.1:53"); addr != "127.0.0.1:53" {
+		t.Fatalf("unset listen address defaulted to %q", addr)
+	}
+}
@@ -0,0 +1,833 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltA     = []byte("a")
+	boltATTL  = []byte("attl")
+	boltAMeta = []byte("ameta")
+	boltAAAA  = []byte("aaaa")
+	boltCNAME = []byte("cname")
+	boltDNAME = []byte("dname")
+	boltTXT   = []byte("txt")
+	boltMX    = []byte("mx")
+	boltCAA   = []byte("caa")
+	boltNS    = []byte("ns")
+	boltPTR   = []byte("ptr")
+	boltSRV   = []byte("srv")
+	boltRaw   = []byte("raw")
+
+	boltBuckets = [][]byte{boltA, boltATTL, boltAMeta, boltAAAA, boltCNAME, boltDNAME, boltTXT, boltMX, boltCAA, boltNS, boltPTR, boltSRV, boltRaw}
+)
+
+// Bolt is a DB persisted to a single file with bbolt. Each record type is kept
+// in its own bucket; addresses are stored in their 16-byte form and the
+// remaining record data as JSON.
+type Bolt struct {
+	db *bolt.DB
+}
+
+// NewBolt opens (creating if necessary) the database file at path.
+func NewBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range boltBuckets {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Bolt{db: db}, nil
+}
+
+// Close flushes and closes the database file.
+func (b *Bolt) Close() error {
+	if err := b.db.Sync(); err != nil {
+		b.db.Close()
+		return err
+	}
+
+	return b.db.Close()
+}
+
+func encodeIPs(ips []net.IP) []byte {
+	content := make([]byte, 0, len(ips)*net.IPv6len)
+	for _, ip := range ips {
+		content = append(content, ip.To16()...)
+	}
+
+	return content
+}
+
+func decodeIPs(content []byte) []net.IP {
+	ips := []net.IP{}
+	for i := 0; i+net.IPv6len <= len(content); i += net.IPv6len {
+		ips = append(ips, append(net.IP(nil), content[i:i+net.IPv6len]...))
+	}
+
+	return ips
+}
+
+func (b *Bolt) get(bucket []byte, name string) ([]byte, error) {
+	var content []byte
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		val := tx.Bucket(bucket).Get([]byte(canonical(name)))
+		if val == nil {
+			return ErrNotFound
+		}
+
+		// values are only valid for the life of the transaction.
+		content = append([]byte(nil), val...)
+		return nil
+	})
+
+	return content, err
+}
+
+func (b *Bolt) put(bucket []byte, name string, content []byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(canonical(name)), content)
+	})
+}
+
+func (b *Bolt) delete(name string, buckets ...[]byte) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range buckets {
+			if err := tx.Bucket(bucket).Delete([]byte(canonical(name))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *Bolt) getJSON(bucket []byte, name string, v interface{}) error {
+	content, err := b.get(bucket, name)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+func (b *Bolt) putJSON(bucket []byte, name string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return b.put(bucket, name, content)
+}
+
+// updateJSON performs a read-modify-write of the JSON stored for name in a
+// single transaction; fn receives a pointer to the current value.
+func (b *Bolt) updateJSON(bucket []byte, name string, v interface{}, fn func() error) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		key := []byte(canonical(name))
+
+		if content := bk.Get(key); content != nil {
+			if err := json.Unmarshal(content, v); err != nil {
+				return err
+			}
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+
+		content, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		return bk.Put(key, content)
+	})
+}
+
+// SetA overwrites or sets the A record for the entry, replacing any addresses
+// already registered.
+func (b *Bolt) SetA(host string, ip net.IP) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(canonical(host))
+
+		if err := forgetA(tx, key); err != nil {
+			return err
+		}
+
+		return tx.Bucket(boltA).Put(key, encodeIPs([]net.IP{ip}))
+	})
+}
+
+// ImportA sets the A record for each entry, as SetA does, in one transaction.
+func (b *Bolt) ImportA(records map[string]net.IP) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for host, ip := range records {
+			key := []byte(canonical(host))
+
+			if err := forgetA(tx, key); err != nil {
+				return err
+			}
+
+			if err := tx.Bucket(boltA).Put(key, encodeIPs([]net.IP{ip})); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// AddA appends an address to the A records for the entry. Adding an address
+// that is already registered is a no-op.
+func (b *Bolt) AddA(host string, ip net.IP) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(boltA)
+		key := []byte(canonical(host))
+
+		ips := decodeIPs(bk.Get(key))
+		if containsIP(ips, ip) {
+			return nil
+		}
+
+		return bk.Put(key, encodeIPs(append(ips, ip)))
+	})
+}
+
+// DeleteA deletes an A record for a host. If any ips are provided, only those
+// addresses are removed; otherwise the whole entry is.
+func (b *Bolt) DeleteA(host string, ips ...net.IP) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(boltA)
+		key := []byte(canonical(host))
+
+		kept := []net.IP{}
+		if len(ips) != 0 {
+			for _, existing := range decodeIPs(bk.Get(key)) {
+				if !containsIP(ips, existing) {
+					kept = append(kept, existing)
+				}
+			}
+		}
+
+		if len(kept) != 0 {
+			return bk.Put(key, encodeIPs(kept))
+		}
+
+		if err := forgetA(tx, key); err != nil {
+			return err
+		}
+
+		return bk.Delete(key)
+	})
+}
+
+// SetATTL sets the TTL override for a host's A records. A TTL of 0 removes the
+// override.
+func (b *Bolt) SetATTL(host string, ttl uint32) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(canonical(host))
+
+		if tx.Bucket(boltA).Get(key) == nil {
+			return ErrNotFound
+		}
+
+		if ttl == 0 {
+			return tx.Bucket(boltATTL).Delete(key)
+		}
+
+		content := make([]byte, 4)
+		binary.BigEndian.PutUint32(content, ttl)
+		return tx.Bucket(boltATTL).Put(key, content)
+	})
+}
+
+// GetATTL retrieves the TTL override for a host's A records, or 0 if there is
+// none.
+func (b *Bolt) GetATTL(fqdn string) (uint32, error) {
+	var ttl uint32
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		key := []byte(canonical(fqdn))
+
+		if tx.Bucket(boltA).Get(key) == nil {
+			return ErrNotFound
+		}
+
+		if content := tx.Bucket(boltATTL).Get(key); len(content) == 4 {
+			ttl = binary.BigEndian.Uint32(content)
+		}
+
+		return nil
+	})
+
+	return ttl, err
+}
+
+// SetAMeta replaces the metadata of a host's A records. A nil or empty meta
+// removes it.
+func (b *Bolt) SetAMeta(host string, meta map[string]string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(canonical(host))
+
+		if tx.Bucket(boltA).Get(key) == nil {
+			return ErrNotFound
+		}
+
+		if len(meta) == 0 {
+			return tx.Bucket(boltAMeta).Delete(key)
+		}
+
+		content, err := json.Marshal(meta)
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(boltAMeta).Put(key, content)
+	})
+}
+
+// GetAMeta retrieves the metadata of a host's A records, or nil if there is
+// none.
+func (b *Bolt) GetAMeta(fqdn string) (map[string]string, error) {
+	var meta map[string]string
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		key := []byte(canonical(fqdn))
+
+		if tx.Bucket(boltA).Get(key) == nil {
+			return ErrNotFound
+		}
+
+		if content := tx.Bucket(boltAMeta).Get(key); content != nil {
+			return json.Unmarshal(content, &meta)
+		}
+
+		return nil
+	})
+
+	return meta, err
+}
+
+// forgetA removes the TTL override and metadata of a host whose addresses are
+// being replaced or removed.
+func forgetA(tx *bolt.Tx, key []byte) error {
+	if err := tx.Bucket(boltATTL).Delete(key); err != nil {
+		return err
+	}
+
+	return tx.Bucket(boltAMeta).Delete(key)
+}
+
+// GetA retrieves the A records by FQDN.
+func (b *Bolt) GetA(fqdn string) ([]net.IP, error) {
+	content, err := b.get(boltA, fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeIPs(content), nil
+}
+
+// ListA lists all the A records in the database.
+func (b *Bolt) ListA() (ARecords, error) {
+	tmp := ARecords{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltA).ForEach(func(k, v []byte) error {
+			tmp[string(k)] = decodeIPs(v)
+			return nil
+		})
+	})
+
+	return tmp, err
+}
+
+// ForEachA calls fn for each address of each host within one read
+// transaction, until fn returns false. fn must not change the database.
+func (b *Bolt) ForEachA(fn func(string, net.IP) bool) error {
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltA).ForEach(func(k, v []byte) error {
+			for _, ip := range decodeIPs(v) {
+				if !fn(string(k), ip) {
+					return errStop
+				}
+			}
+			return nil
+		})
+	})
+
+	if err == errStop {
+		return nil
+	}
+
+	return err
+}
+
+// CountA returns the number of hosts with A records.
+func (b *Bolt) CountA() (int, error) {
+	return b.count(boltA)
+}
+
+// SetAAAA overwrites or sets the AAAA record for the entry.
+func (b *Bolt) SetAAAA(host string, ip net.IP) error {
+	return b.put(boltAAAA, host, encodeIPs([]net.IP{ip}))
+}
+
+// GetAAAA retrieves an AAAA record by FQDN.
+func (b *Bolt) GetAAAA(fqdn string) (net.IP, error) {
+	content, err := b.get(boltAAAA, fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := decodeIPs(content)
+	if len(ips) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return ips[0], nil
+}
+
+// DeleteAAAA deletes an AAAA record for a host.
+func (b *Bolt) DeleteAAAA(host string) error {
+	return b.delete(host, boltAAAA)
+}
+
+// ListAAAA lists all the AAAA records in the database.
+func (b *Bolt) ListAAAA() (AAAARecords, error) {
+	tmp := AAAARecords{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltAAAA).ForEach(func(k, v []byte) error {
+			if ips := decodeIPs(v); len(ips) != 0 {
+				tmp[string(k)] = ips[0]
+			}
+			return nil
+		})
+	})
+
+	return tmp, err
+}
+
+// DeleteByIP removes ip from every host's A and AAAA records in one
+// transaction, returning the number of records removed. Hosts left with no A
+// records are deleted.
+func (b *Bolt) DeleteByIP(ip net.IP) (int, error) {
+	var n int
+
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		n = 0
+
+		for _, bucket := range [][]byte{boltA, boltAAAA} {
+			bk := tx.Bucket(bucket)
+			changed := map[string][]net.IP{}
+
+			err := bk.ForEach(func(k, v []byte) error {
+				ips := decodeIPs(v)
+				if !containsIP(ips, ip) {
+					return nil
+				}
+
+				kept := []net.IP{}
+				for _, existing := range ips {
+					if !existing.Equal(ip) {
+						kept = append(kept, existing)
+					}
+				}
+
+				changed[string(k)] = kept
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			// the bucket cannot be changed while it is being iterated.
+			for host, kept := range changed {
+				n++
+
+				if len(kept) != 0 {
+					if err := bk.Put([]byte(host), encodeIPs(kept)); err != nil {
+						return err
+					}
+					continue
+				}
+
+				if err := bk.Delete([]byte(host)); err != nil {
+					return err
+				}
+
+				if err := forgetA(tx, []byte(host)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+
+	return n, err
+}
+
+// SetCNAME overwrites or sets the CNAME record for the alias.
+func (b *Bolt) SetCNAME(alias, target string) error {
+	return b.put(boltCNAME, alias, []byte(target))
+}
+
+// GetCNAME retrieves the target of a CNAME record by alias.
+func (b *Bolt) GetCNAME(alias string) (string, error) {
+	content, err := b.get(boltCNAME, alias)
+	return string(content), err
+}
+
+// DeleteCNAME deletes a CNAME record for an alias.
+func (b *Bolt) DeleteCNAME(alias string) error {
+	return b.delete(alias, boltCNAME)
+}
+
+// SetDNAME overwrites or sets the DNAME record for the owner.
+func (b *Bolt) SetDNAME(owner, target string) error {
+	return b.put(boltDNAME, owner, []byte(target))
+}
+
+// GetDNAME retrieves the target of a DNAME record by owner.
+func (b *Bolt) GetDNAME(owner string) (string, error) {
+	content, err := b.get(boltDNAME, owner)
+	return string(content), err
+}
+
+// DeleteDNAME deletes a DNAME record for an owner.
+func (b *Bolt) DeleteDNAME(owner string) error {
+	return b.delete(owner, boltDNAME)
+}
+
+// SetTXT overwrites or sets the TXT records for the entry.
+func (b *Bolt) SetTXT(host string, values []string) error {
+	return b.putJSON(boltTXT, host, values)
+}
+
+// GetTXT retrieves the TXT records by FQDN.
+func (b *Bolt) GetTXT(fqdn string) ([]string, error) {
+	values := []string{}
+	err := b.getJSON(boltTXT, fqdn, &values)
+	return values, err
+}
+
+// DeleteTXT deletes the TXT records for a host.
+func (b *Bolt) DeleteTXT(host string) error {
+	return b.delete(host, boltTXT)
+}
+
+// SetMX adds a MX record for the entry. If the mail exchange is already
+// registered for the entry, its preference is updated instead.
+func (b *Bolt) SetMX(host string, mx *MXRecord) error {
+	recs := []*MXRecord{}
+
+	return b.updateJSON(boltMX, host, &recs, func() error {
+		for i, existing := range recs {
+			if existing.Mail == mx.Mail {
+				recs[i] = mx
+				return nil
+			}
+		}
+
+		recs = append(recs, mx)
+		return nil
+	})
+}
+
+// GetMX retrieves the MX records by FQDN.
+func (b *Bolt) GetMX(fqdn string) ([]*MXRecord, error) {
+	recs := []*MXRecord{}
+	err := b.getJSON(boltMX, fqdn, &recs)
+	return recs, err
+}
+
+// DeleteMX deletes the MX records for a host.
+func (b *Bolt) DeleteMX(host string) error {
+	return b.delete(host, boltMX)
+}
+
+// SetCAA adds a CAA record for the entry. If the tag and value are already
+// registered for the entry, its flag is updated instead.
+func (b *Bolt) SetCAA(host string, caa *CAARecord) error {
+	recs := []*CAARecord{}
+
+	return b.updateJSON(boltCAA, host, &recs, func() error {
+		for i, existing := range recs {
+			if existing.Tag == caa.Tag && existing.Value == caa.Value {
+				recs[i] = caa
+				return nil
+			}
+		}
+
+		recs = append(recs, caa)
+		return nil
+	})
+}
+
+// GetCAA retrieves the CAA records by FQDN.
+func (b *Bolt) GetCAA(fqdn string) ([]*CAARecord, error) {
+	recs := []*CAARecord{}
+	err := b.getJSON(boltCAA, fqdn, &recs)
+	return recs, err
+}
+
+// DeleteCAA deletes the CAA records for a host.
+func (b *Bolt) DeleteCAA(host string) error {
+	return b.delete(host, boltCAA)
+}
+
+// SetNS overwrites or sets the NS records for the entry.
+func (b *Bolt) SetNS(host string, nameservers []string) error {
+	return b.putJSON(boltNS, host, nameservers)
+}
+
+// GetNS retrieves the NS records by FQDN.
+func (b *Bolt) GetNS(fqdn string) ([]string, error) {
+	var nameservers []string
+	err := b.getJSON(boltNS, fqdn, &nameservers)
+	return nameservers, err
+}
+
+// DeleteNS deletes the NS records for a host.
+func (b *Bolt) DeleteNS(host string) error {
+	return b.delete(host, boltNS)
+}
+
+// SetPTR overwrites or sets the PTR record for a reverse (arpa) name.
+func (b *Bolt) SetPTR(arpa, host string) error {
+	return b.put(boltPTR, arpa, []byte(host))
+}
+
+// GetPTR retrieves the host a reverse (arpa) name points at.
+func (b *Bolt) GetPTR(arpa string) (string, error) {
+	content, err := b.get(boltPTR, arpa)
+	return string(content), err
+}
+
+// DeletePTR deletes the PTR record for a reverse (arpa) name.
+func (b *Bolt) DeletePTR(arpa string) error {
+	return b.delete(arpa, boltPTR)
+}
+
+// SetSRV sets a srv record with service and protocol pointing at a name and
+// port, replacing any targets already registered.
+func (b *Bolt) SetSRV(spec string, srv *SRVRecord) error {
+	return b.putJSON(boltSRV, spec, []*SRVRecord{srv})
+}
+
+// ImportSRV sets the SRV record for each spec, as SetSRV does, in one
+// transaction.
+func (b *Bolt) ImportSRV(records map[string]*SRVRecord) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for spec, srv := range records {
+			content, err := json.Marshal([]*SRVRecord{srv})
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Bucket(boltSRV).Put([]byte(canonical(spec)), content); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Replace swaps in the given A and SRV records, as described by Replacer, by
+// recreating their buckets in one transaction.
+func (b *Bolt) Replace(a ARecords, ttls map[string]uint32, srv SRVRecords) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{boltA, boltATTL, boltAMeta, boltSRV} {
+			if err := tx.DeleteBucket(bucket); err != nil {
+				return err
+			}
+
+			if _, err := tx.CreateBucket(bucket); err != nil {
+				return err
+			}
+		}
+
+		for host, ips := range a {
+			key := []byte(canonical(host))
+
+			if err := tx.Bucket(boltA).Put(key, encodeIPs(ips)); err != nil {
+				return err
+			}
+
+			if ttl := ttls[host]; ttl != 0 {
+				content := make([]byte, 4)
+				binary.BigEndian.PutUint32(content, ttl)
+
+				if err := tx.Bucket(boltATTL).Put(key, content); err != nil {
+					return err
+				}
+			}
+		}
+
+		for spec, targets := range srv {
+			content, err := json.Marshal(targets)
+			if err != nil {
+				return err
+			}
+
+			if err := tx.Bucket(boltSRV).Put([]byte(canonical(spec)), content); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// AddSRV adds a target to a srv record. If a target with the same host and
+// port is already registered, it is replaced.
+func (b *Bolt) AddSRV(spec string, srv *SRVRecord) error {
+	recs := []*SRVRecord{}
+
+	return b.updateJSON(boltSRV, spec, &recs, func() error {
+		for i, existing := range recs {
+			if existing.Host == srv.Host && existing.Port == srv.Port {
+				recs[i] = srv
+				return nil
+			}
+		}
+
+		recs = append(recs, srv)
+		return nil
+	})
+}
+
+// GetSRV gets a service's targets based on a name.
+func (b *Bolt) GetSRV(spec string) ([]*SRVRecord, error) {
+	recs := []*SRVRecord{}
+	err := b.getJSON(boltSRV, spec, &recs)
+	return recs, err
+}
+
+// ListSRV lists all SRV records in the database.
+func (b *Bolt) ListSRV() (SRVRecords, error) {
+	tmp := SRVRecords{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSRV).ForEach(func(k, v []byte) error {
+			recs := []*SRVRecord{}
+			if err := json.Unmarshal(v, &recs); err != nil {
+				return err
+			}
+
+			tmp[string(k)] = recs
+			return nil
+		})
+	})
+
+	return tmp, err
+}
+
+// CountSRV returns the number of services with SRV records.
+func (b *Bolt) CountSRV() (int, error) {
+	return b.count(boltSRV)
+}
+
+// count returns the number of keys in bucket.
+func (b *Bolt) count(bucket []byte) (int, error) {
+	var n int
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		n = tx.Bucket(bucket).Stats().KeyN
+		return nil
+	})
+
+	return n, err
+}
+
+// DeleteSRV deletes a SRV record based on the service and protocol.
+func (b *Bolt) DeleteSRV(spec string) error {
+	return b.delete(spec, boltSRV)
+}
+
+// SetRaw adds a raw record for the name. A record already held which differs
+// only in its TTL is replaced instead.
+func (b *Bolt) SetRaw(name string, rr dns.RR) error {
+	lines := []string{}
+
+	return b.updateJSON(boltRaw, name, &lines, func() error {
+		rrs, err := decodeRaw(lines)
+		if err != nil {
+			return err
+		}
+
+		lines = encodeRaw(addRaw(rrs, rr))
+		return nil
+	})
+}
+
+// GetRaw retrieves the raw records of a type by name; dns.TypeANY retrieves
+// those of every type.
+func (b *Bolt) GetRaw(name string, qtype uint16) ([]dns.RR, error) {
+	lines := []string{}
+	if err := b.getJSON(boltRaw, name, &lines); err != nil {
+		return nil, err
+	}
+
+	rrs, err := decodeRaw(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	if rrs = rawOfType(rrs, qtype); len(rrs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return rrs, nil
+}
+
+// DeleteRaw deletes the raw records of a type for a name; dns.TypeANY deletes
+// those of every type.
+func (b *Bolt) DeleteRaw(name string, qtype uint16) error {
+	if qtype == dns.TypeANY {
+		return b.delete(name, boltRaw)
+	}
+
+	lines := []string{}
+
+	return b.updateJSON(boltRaw, name, &lines, func() error {
+		rrs, err := decodeRaw(lines)
+		if err != nil {
+			return err
+		}
+
+		kept := []dns.RR{}
+		for _, rr := range rrs {
+			if rr.Header().Rrtype != qtype {
+				kept = append(kept, rr)
+			}
+		}
+
+		lines = encodeRaw(kept)
+		return nil
+	})
+}
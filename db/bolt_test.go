@@ -0,0 +1,93 @@
+package db
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempBoltPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "dnsserver-bolt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(dir, "records.db"), func() { os.RemoveAll(dir) }
+}
+
+func TestBolt(t *testing.T) {
+	path, cleanup := tempBoltPath(t)
+	defer cleanup()
+
+	b, err := NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	testDB(t, b)
+}
+
+func TestBoltReplace(t *testing.T) {
+	path, cleanup := tempBoltPath(t)
+	defer cleanup()
+
+	b, err := NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	testReplace(t, b)
+}
+
+func TestBoltPersistence(t *testing.T) {
+	path, cleanup := tempBoltPath(t)
+	defer cleanup()
+
+	b, err := NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ip := net.ParseIP("127.0.0.2")
+	srv := &SRVRecord{Port: 80, Host: "test.docker."}
+
+	if err := b.SetA("test", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.SetSRV("_test._tcp", srv); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err = NewBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	ips, err := b.GetA("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ips) != 1 || !ips[0].Equal(ip) {
+		t.Fatalf("A records after reopening were %v", ips)
+	}
+
+	srvs, err := b.GetSRV("_test._tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srvs) != 1 || !srvs[0].Equal(srv) {
+		t.Fatalf("SRV records after reopening were %v", srvs)
+	}
+}
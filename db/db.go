@@ -3,21 +3,111 @@ package db
 import (
 	"errors"
 	"net"
+	"strings"
+
+	"github.com/miekg/dns"
 )
 
 // DB is for pluggable backends. To swap out the DB implementation, implement
 // this interface and provide it at construction time to the DNS server struct.
 type DB interface {
 	SetA(string, net.IP) error
-	GetA(string) (net.IP, error)
-	DeleteA(string) error
+	AddA(string, net.IP) error
+	GetA(string) ([]net.IP, error)
+	DeleteA(string, ...net.IP) error
+	SetATTL(string, uint32) error
+	GetATTL(string) (uint32, error)
+	SetAMeta(string, map[string]string) error
+	GetAMeta(string) (map[string]string, error)
 	ListA() (ARecords, error)
+	ForEachA(func(string, net.IP) bool) error
+	CountA() (int, error)
+	SetAAAA(string, net.IP) error
+	GetAAAA(string) (net.IP, error)
+	DeleteAAAA(string) error
+	ListAAAA() (AAAARecords, error)
+	DeleteByIP(net.IP) (int, error)
+	SetCNAME(string, string) error
+	GetCNAME(string) (string, error)
+	DeleteCNAME(string) error
+	SetDNAME(string, string) error
+	GetDNAME(string) (string, error)
+	DeleteDNAME(string) error
+	SetTXT(string, []string) error
+	GetTXT(string) ([]string, error)
+	DeleteTXT(string) error
+	SetMX(string, *MXRecord) error
+	GetMX(string) ([]*MXRecord, error)
+	DeleteMX(string) error
+	SetCAA(string, *CAARecord) error
+	GetCAA(string) ([]*CAARecord, error)
+	DeleteCAA(string) error
+	SetNS(string, []string) error
+	GetNS(string) ([]string, error)
+	DeleteNS(string) error
+	SetPTR(string, string) error
+	GetPTR(string) (string, error)
+	DeletePTR(string) error
 	SetSRV(string, *SRVRecord) error
-	GetSRV(string) (*SRVRecord, error)
+	AddSRV(string, *SRVRecord) error
+	GetSRV(string) ([]*SRVRecord, error)
 	DeleteSRV(string) error
 	ListSRV() (SRVRecords, error)
+	CountSRV() (int, error)
+	SetRaw(string, dns.RR) error
+	GetRaw(string, uint16) ([]dns.RR, error)
+	DeleteRaw(string, uint16) error
 	Close() error
 }
 
+// Importer is implemented by DBs which can store many records at once more
+// cheaply than one at a time. Each entry replaces any records already held for
+// its name, as SetA and SetSRV do.
+type Importer interface {
+	ImportA(map[string]net.IP) error
+	ImportSRV(map[string]*SRVRecord) error
+}
+
+// Flusher is implemented by DBs which can remove every record of a type at
+// once, rather than by listing and deleting them one at a time.
+type Flusher interface {
+	FlushA() error
+	FlushSRV() error
+	FlushAll() error
+}
+
+// Mover is implemented by DBs which can rename a host's A records, or replace
+// its addresses, in one step, so that lookups never find the host missing
+// partway through. Both return ErrNotFound when the host has no A records.
+type Mover interface {
+	RenameA(oldHost, newHost string) error
+	MoveA(host string, ip net.IP) error
+}
+
+// Replacer is implemented by DBs which can replace every A and SRV record in
+// one step, so that lookups find either the old records or the new ones and
+// never a mix. ttls holds the TTL overrides of the A hosts which have one; all
+// other TTL overrides and A metadata are removed.
+type Replacer interface {
+	Replace(a ARecords, ttls map[string]uint32, srv SRVRecords) error
+}
+
+// PrefixLister is implemented by DBs which can list the records at or below a
+// name, such as "svc" for "svc" and "web.svc", without listing every record.
+// The name is relative to the domain, as the DB's keys are; "" lists all.
+type PrefixLister interface {
+	ListAByPrefix(string) (ARecords, error)
+	ListSRVByPrefix(string) (SRVRecords, error)
+}
+
+// Under reports whether name is parent or below it, as PrefixLister matches
+// them. Every name is under "".
+func Under(name, parent string) bool {
+	return parent == "" || name == parent || strings.HasSuffix(name, "."+parent)
+}
+
+// errStop is used within ForEachA implementations to end an iteration early.
+var errStop = errors.New("stop iteration")
+
 // ErrNotFound is for when the record cannot be located
 var ErrNotFound = errors.New("not found")
@@ -0,0 +1,602 @@
+package db
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// testDB exercises the DB interface against a backend. The backend must start
+// out empty.
+func testDB(t *testing.T, d DB) {
+	ip := net.ParseIP("127.0.0.2")
+	ip2 := net.ParseIP("127.0.0.3")
+
+	if _, err := d.GetA("test"); err != ErrNotFound {
+		t.Fatalf("missing A record did not yield ErrNotFound: %v", err)
+	}
+
+	if err := d.SetA("test", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.AddA("Test", ip2); err != nil {
+		t.Fatal(err)
+	}
+
+	ips, err := d.GetA("TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ips) != 2 || !ips[0].Equal(ip) || !ips[1].Equal(ip2) {
+		t.Fatalf("A records were %v", ips)
+	}
+
+	if err := d.SetATTL("test", 30); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl, err := d.GetATTL("test"); err != nil || ttl != 30 {
+		t.Fatalf("A TTL was %d (%v)", ttl, err)
+	}
+
+	if err := d.SetATTL("missing", 30); err != ErrNotFound {
+		t.Fatalf("setting TTL for a missing host did not yield ErrNotFound: %v", err)
+	}
+
+	if meta, err := d.GetAMeta("test"); err != nil || meta != nil {
+		t.Fatalf("untagged host had metadata %v (%v)", meta, err)
+	}
+
+	if err := d.SetAMeta("test", map[string]string{"owner": "abc", "role": "web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if meta, err := d.GetAMeta("TEST"); err != nil || len(meta) != 2 || meta["owner"] != "abc" || meta["role"] != "web" {
+		t.Fatalf("A metadata was %v (%v)", meta, err)
+	}
+
+	if err := d.SetAMeta("missing", map[string]string{"owner": "abc"}); err != ErrNotFound {
+		t.Fatalf("setting metadata for a missing host did not yield ErrNotFound: %v", err)
+	}
+
+	if _, err := d.GetAMeta("missing"); err != ErrNotFound {
+		t.Fatalf("metadata for a missing host did not yield ErrNotFound: %v", err)
+	}
+
+	as, err := d.ListA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(as) != 1 || len(as["test"]) != 2 {
+		t.Fatalf("A listing was %v", as)
+	}
+
+	if n, err := d.CountA(); err != nil || n != 1 {
+		t.Fatalf("A count was %d (%v)", n, err)
+	}
+
+	var visited []net.IP
+
+	if err := d.ForEachA(func(name string, ip net.IP) bool {
+		if name != "test" {
+			t.Fatalf("ForEachA visited %q", name)
+		}
+		visited = append(visited, ip)
+		return true
+	}); err != nil || len(visited) != 2 {
+		t.Fatalf("ForEachA visited %v (%v)", visited, err)
+	}
+
+	visited = nil
+
+	if err := d.ForEachA(func(name string, ip net.IP) bool {
+		visited = append(visited, ip)
+		return false
+	}); err != nil || len(visited) != 1 {
+		t.Fatalf("ForEachA did not stop early: %v (%v)", visited, err)
+	}
+
+	if err := d.DeleteA("test", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if ips, err := d.GetA("test"); err != nil || len(ips) != 1 || !ips[0].Equal(ip2) {
+		t.Fatalf("A records after removing one address were %v (%v)", ips, err)
+	}
+
+	if meta, err := d.GetAMeta("test"); err != nil || meta["owner"] != "abc" {
+		t.Fatalf("metadata after removing one address was %v (%v)", meta, err)
+	}
+
+	if err := d.DeleteA("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetA("test"); err != ErrNotFound {
+		t.Fatalf("deleted A record did not yield ErrNotFound: %v", err)
+	}
+
+	if _, err := d.GetATTL("test"); err != ErrNotFound {
+		t.Fatalf("TTL for deleted A record did not yield ErrNotFound: %v", err)
+	}
+
+	if n, err := d.CountA(); err != nil || n != 0 {
+		t.Fatalf("A count after delete was %d (%v)", n, err)
+	}
+
+	ip6 := net.ParseIP("fe80::1")
+
+	if err := d.SetAAAA("test", ip6); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := d.GetAAAA("test"); err != nil || !got.Equal(ip6) {
+		t.Fatalf("AAAA record was %v (%v)", got, err)
+	}
+
+	if aaaas, err := d.ListAAAA(); err != nil || len(aaaas) != 1 || !aaaas["test"].Equal(ip6) {
+		t.Fatalf("AAAA listing was %v (%v)", aaaas, err)
+	}
+
+	if err := d.DeleteAAAA("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetAAAA("test"); err != ErrNotFound {
+		t.Fatalf("deleted AAAA record did not yield ErrNotFound: %v", err)
+	}
+
+	if err := d.SetCNAME("www", "web.docker."); err != nil {
+		t.Fatal(err)
+	}
+
+	if target, err := d.GetCNAME("WWW"); err != nil || target != "web.docker." {
+		t.Fatalf("CNAME target was %q (%v)", target, err)
+	}
+
+	if err := d.DeleteCNAME("www"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetCNAME("www"); err != ErrNotFound {
+		t.Fatalf("deleted CNAME record did not yield ErrNotFound: %v", err)
+	}
+
+	if err := d.SetDNAME("old", "new.docker."); err != nil {
+		t.Fatal(err)
+	}
+
+	if target, err := d.GetDNAME("OLD"); err != nil || target != "new.docker." {
+		t.Fatalf("DNAME target was %q (%v)", target, err)
+	}
+
+	if err := d.DeleteDNAME("old"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetDNAME("old"); err != ErrNotFound {
+		t.Fatalf("deleted DNAME record did not yield ErrNotFound: %v", err)
+	}
+
+	txt := []string{"one", "two"}
+
+	if err := d.SetTXT("test", txt); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := d.GetTXT("test"); err != nil || !reflect.DeepEqual(got, txt) {
+		t.Fatalf("TXT records were %v (%v)", got, err)
+	}
+
+	if err := d.DeleteTXT("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetTXT("test"); err != ErrNotFound {
+		t.Fatalf("deleted TXT record did not yield ErrNotFound: %v", err)
+	}
+
+	for _, mx := range []*MXRecord{
+		&MXRecord{Preference: 10, Mail: "mx1.docker."},
+		&MXRecord{Preference: 20, Mail: "mx2.docker."},
+		&MXRecord{Preference: 30, Mail: "mx1.docker."},
+	} {
+		if err := d.SetMX("test", mx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mxs, err := d.GetMX("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(mxs) != 2 || !mxs[0].Equal(&MXRecord{Preference: 30, Mail: "mx1.docker."}) {
+		t.Fatalf("MX records were %v", mxs)
+	}
+
+	if err := d.DeleteMX("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetMX("test"); err != ErrNotFound {
+		t.Fatalf("deleted MX record did not yield ErrNotFound: %v", err)
+	}
+
+	issue := &CAARecord{Tag: "issue", Value: "ca.example.net"}
+	iodef := &CAARecord{Tag: "iodef", Value: "mailto:security@example.net"}
+
+	if err := d.SetCAA("test", &CAARecord{Flag: 128, Tag: "issue", Value: "ca.example.net"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, caa := range []*CAARecord{issue, iodef} {
+		if err := d.SetCAA("Test", caa); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	caas, err := d.GetCAA("TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(caas) != 2 || !caas[0].Equal(issue) || !caas[1].Equal(iodef) {
+		t.Fatalf("CAA records were %v", caas)
+	}
+
+	if err := d.DeleteCAA("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetCAA("test"); err != ErrNotFound {
+		t.Fatalf("deleted CAA record did not yield ErrNotFound: %v", err)
+	}
+
+	nameservers := []string{"ns1.docker.", "ns2.docker."}
+
+	if err := d.SetNS("@", nameservers); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := d.GetNS("@"); err != nil || !reflect.DeepEqual(got, nameservers) {
+		t.Fatalf("NS records were %v (%v)", got, err)
+	}
+
+	if err := d.DeleteNS("@"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetNS("@"); err != ErrNotFound {
+		t.Fatalf("deleted NS record did not yield ErrNotFound: %v", err)
+	}
+
+	if err := d.SetPTR("2.0.0.127.in-addr.arpa.", "test.docker."); err != nil {
+		t.Fatal(err)
+	}
+
+	if host, err := d.GetPTR("2.0.0.127.in-addr.arpa."); err != nil || host != "test.docker." {
+		t.Fatalf("PTR record was %q (%v)", host, err)
+	}
+
+	if err := d.DeletePTR("2.0.0.127.in-addr.arpa."); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetPTR("2.0.0.127.in-addr.arpa."); err != ErrNotFound {
+		t.Fatalf("deleted PTR record did not yield ErrNotFound: %v", err)
+	}
+
+	srv := &SRVRecord{Priority: 1, Weight: 2, Port: 80, Host: "test.docker.", TTL: 30}
+	srv2 := &SRVRecord{Port: 81, Host: "test2.docker."}
+
+	if err := d.SetSRV("_test._tcp", srv); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.AddSRV("_test._tcp", srv2); err != nil {
+		t.Fatal(err)
+	}
+
+	srvs, err := d.GetSRV("_test._tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srvs) != 2 || !srvs[0].Equal(srv) || !srvs[1].Equal(srv2) {
+		t.Fatalf("SRV records were %v", srvs)
+	}
+
+	srvList, err := d.ListSRV()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srvList) != 1 || len(srvList["_test._tcp"]) != 2 {
+		t.Fatalf("SRV listing was %v", srvList)
+	}
+
+	if n, err := d.CountSRV(); err != nil || n != 1 {
+		t.Fatalf("SRV count was %d (%v)", n, err)
+	}
+
+	if err := d.DeleteSRV("_test._tcp"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetSRV("_test._tcp"); err != ErrNotFound {
+		t.Fatalf("deleted SRV record did not yield ErrNotFound: %v", err)
+	}
+
+	if n, err := d.CountSRV(); err != nil || n != 0 {
+		t.Fatalf("SRV count after delete was %d (%v)", n, err)
+	}
+
+	imp, ok := d.(Importer)
+	if !ok {
+		t.Fatalf("%T does not implement Importer", d)
+	}
+
+	if err := d.AddA("bulk1", ip2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := imp.ImportA(map[string]net.IP{"Bulk1": ip, "bulk2": ip2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ips, err := d.GetA("bulk1"); err != nil || len(ips) != 1 || !ips[0].Equal(ip) {
+		t.Fatalf("imported A record was %v (%v)", ips, err)
+	}
+
+	if ips, err := d.GetA("bulk2"); err != nil || len(ips) != 1 || !ips[0].Equal(ip2) {
+		t.Fatalf("imported A record was %v (%v)", ips, err)
+	}
+
+	if err := imp.ImportSRV(map[string]*SRVRecord{"_bulk._tcp": srv, "_bulk._udp": srv2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if srvs, err := d.GetSRV("_BULK._tcp"); err != nil || len(srvs) != 1 || !srvs[0].Equal(srv) {
+		t.Fatalf("imported SRV record was %v (%v)", srvs, err)
+	}
+
+	for _, host := range []string{"bulk1", "bulk2"} {
+		if err := d.DeleteA(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, spec := range []string{"_bulk._tcp", "_bulk._udp"} {
+		if err := d.DeleteSRV(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shared := net.ParseIP("127.0.0.9")
+
+	for _, host := range []string{"gone1", "gone2", "kept"} {
+		if err := d.SetA(host, shared); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := d.AddA("kept", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SetATTL("gone1", 30); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SetAMeta("gone1", map[string]string{"owner": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := d.DeleteByIP(shared); err != nil || n != 3 {
+		t.Fatalf("DeleteByIP removed %d records (%v)", n, err)
+	}
+
+	for _, host := range []string{"gone1", "gone2"} {
+		if _, err := d.GetA(host); err != ErrNotFound {
+			t.Fatalf("host %q survived DeleteByIP: %v", host, err)
+		}
+	}
+
+	if _, err := d.GetATTL("gone1"); err != ErrNotFound {
+		t.Fatalf("TTL survived DeleteByIP: %v", err)
+	}
+
+	if _, err := d.GetAMeta("gone1"); err != ErrNotFound {
+		t.Fatalf("metadata survived DeleteByIP: %v", err)
+	}
+
+	if ips, err := d.GetA("kept"); err != nil || len(ips) != 1 || !ips[0].Equal(ip) {
+		t.Fatalf("DeleteByIP left %v (%v)", ips, err)
+	}
+
+	if err := d.SetAAAA("gone6", ip6); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := d.DeleteByIP(ip6); err != nil || n != 1 {
+		t.Fatalf("DeleteByIP removed %d AAAA records (%v)", n, err)
+	}
+
+	if _, err := d.GetAAAA("gone6"); err != ErrNotFound {
+		t.Fatalf("AAAA record survived DeleteByIP: %v", err)
+	}
+
+	if err := d.DeleteA("kept"); err != nil {
+		t.Fatal(err)
+	}
+
+	hinfo, err := dns.NewRR("box.docker. 60 IN HINFO \"amd64\" \"linux\"")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loc, err := dns.NewRR("box.docker. 60 IN LOC 52 22 23.000 N 4 53 32.000 E -2.00m 0.00m 10000m 10m")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rr := range []dns.RR{hinfo, loc, hinfo} {
+		if err := d.SetRaw("box", rr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rrs, err := d.GetRaw("BOX", dns.TypeHINFO); err != nil || len(rrs) != 1 || rrs[0].String() != hinfo.String() {
+		t.Fatalf("raw HINFO records were %v (%v)", rrs, err)
+	}
+
+	if rrs, err := d.GetRaw("box", dns.TypeANY); err != nil || len(rrs) != 2 {
+		t.Fatalf("raw records were %v (%v)", rrs, err)
+	}
+
+	if _, err := d.GetRaw("box", dns.TypeNAPTR); err != ErrNotFound {
+		t.Fatalf("missing raw type did not yield ErrNotFound: %v", err)
+	}
+
+	if err := d.DeleteRaw("box", dns.TypeHINFO); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetRaw("box", dns.TypeHINFO); err != ErrNotFound {
+		t.Fatalf("deleted raw record did not yield ErrNotFound: %v", err)
+	}
+
+	if rrs, err := d.GetRaw("box", dns.TypeLOC); err != nil || len(rrs) != 1 {
+		t.Fatalf("deleting HINFO left raw records %v (%v)", rrs, err)
+	}
+
+	if err := d.DeleteRaw("box", dns.TypeANY); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.GetRaw("box", dns.TypeANY); err != ErrNotFound {
+		t.Fatalf("deleted raw records did not yield ErrNotFound: %v", err)
+	}
+}
+
+// testListByPrefix tests the PrefixLister of d, which must have no records.
+func testListByPrefix(t *testing.T, d DB) {
+	pl, ok := d.(PrefixLister)
+	if !ok {
+		t.Fatalf("%T does not implement PrefixLister", d)
+	}
+
+	ip := net.ParseIP("127.0.0.2")
+
+	for _, host := range []string{"svc", "web.svc", "db.web.svc", "notsvc", "svc.other", "x.abc", "x.a_c"} {
+		if err := d.SetA(host, ip); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	as, err := pl.ListAByPrefix("SVC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(as) != 3 || as["svc"] == nil || as["web.svc"] == nil || as["db.web.svc"] == nil {
+		t.Fatalf("A records under svc were %v", as)
+	}
+
+	// the _ of a_c must not match any character
+	if as, err := pl.ListAByPrefix("a_c"); err != nil || len(as) != 1 || as["x.a_c"] == nil {
+		t.Fatalf("A records under a_c were %v (%v)", as, err)
+	}
+
+	if as, err := pl.ListAByPrefix(""); err != nil || len(as) != 7 {
+		t.Fatalf("A records under the domain were %v (%v)", as, err)
+	}
+
+	for _, spec := range []string{"_http._tcp", "_dns._udp", "_http._tcp.svc"} {
+		if err := d.SetSRV(spec, &SRVRecord{Port: 80, Host: "web.svc."}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srvs, err := pl.ListSRVByPrefix("_tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srvs) != 1 || len(srvs["_http._tcp"]) != 1 {
+		t.Fatalf("SRV records under _tcp were %v", srvs)
+	}
+
+	srvs["_http._tcp"][0].Port = 8080
+	if srvs, _ := pl.ListSRVByPrefix("_tcp"); srvs["_http._tcp"][0].Port != 80 {
+		t.Fatal("modifying a listed record modified the stored record")
+	}
+
+	if srvs, err := pl.ListSRVByPrefix("svc"); err != nil || len(srvs) != 1 || srvs["_http._tcp.svc"] == nil {
+		t.Fatalf("SRV records under svc were %v (%v)", srvs, err)
+	}
+}
+
+// testReplace tests the Replacer of d, which must have no records.
+func testReplace(t *testing.T, d DB) {
+	r, ok := d.(Replacer)
+	if !ok {
+		t.Fatalf("%T does not implement Replacer", d)
+	}
+
+	ip, ip2 := net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")
+	srv := &SRVRecord{Host: "test.docker.", Port: 80, Priority: 1, Weight: 2}
+
+	if err := d.SetA("old", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SetA("kept", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SetATTL("kept", 30); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SetAMeta("kept", map[string]string{"owner": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.AddSRV("_old._tcp", srv); err != nil {
+		t.Fatal(err)
+	}
+
+	a := ARecords{"kept": {ip2}, "new": {ip, ip2}}
+	srvs := SRVRecords{"_new._tcp": {srv}}
+
+	if err := r.Replace(a, map[string]uint32{"new": 60}, srvs); err != nil {
+		t.Fatal(err)
+	}
+
+	if as, err := d.ListA(); err != nil || !reflect.DeepEqual(as, a) {
+		t.Fatalf("A records after Replace were %v (%v)", as, err)
+	}
+
+	if ttl, err := d.GetATTL("new"); err != nil || ttl != 60 {
+		t.Fatalf("replaced TTL was %d (%v)", ttl, err)
+	}
+
+	if ttl, err := d.GetATTL("kept"); err != nil || ttl != 0 {
+		t.Fatalf("TTL survived Replace: %d (%v)", ttl, err)
+	}
+
+	if meta, err := d.GetAMeta("kept"); err != nil || len(meta) != 0 {
+		t.Fatalf("metadata survived Replace: %v (%v)", meta, err)
+	}
+
+	if got, err := d.ListSRV(); err != nil || len(got) != 1 || len(got["_new._tcp"]) != 1 || !got["_new._tcp"][0].Equal(srv) {
+		t.Fatalf("SRV records after Replace were %v (%v)", got, err)
+	}
+}
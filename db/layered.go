@@ -0,0 +1,720 @@
+package db
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// Layered is a DB keeping a fast primary, such as a Map, in front of a slower
+// secondary which holds the authoritative records, such as SQLite:
+//
+//	ds := dnsserver.NewWithDB("docker", db.NewLayered(db.NewMap(), sqlite))
+//
+// Lookups try the primary first. Names it does not hold are looked up in the
+// secondary, and any records found there are copied into the primary for next
+// time. Writes go to the secondary and then the primary; writes which only
+// change part of a name's records, such as AddA, bring the primary's copy of
+// the name up to date from the secondary rather than changing a copy it may
+// not hold in full. Listing and counting, which must see every record, are
+// answered by the secondary alone.
+//
+// The primary should start out empty, or hold only records the secondary
+// also has. Misses are not remembered, so names which do not exist are always
+// looked up in the secondary.
+type Layered struct {
+	primary, secondary DB
+}
+
+// NewLayered returns a Layered DB reading through primary to secondary.
+func NewLayered(primary, secondary DB) *Layered {
+	return &Layered{primary: primary, secondary: secondary}
+}
+
+// ignoreNotFound returns err, unless it is ErrNotFound. Deleting from the
+// primary what it does not hold is not an error.
+func ignoreNotFound(err error) error {
+	if err == ErrNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// SetA sets a host to an IP in both DBs.
+func (l *Layered) SetA(host string, ip net.IP) error {
+	if err := l.secondary.SetA(host, ip); err != nil {
+		return err
+	}
+
+	return l.primary.SetA(host, ip)
+}
+
+// AddA adds an IP to a host in the secondary, and refreshes the primary's copy
+// of the host.
+func (l *Layered) AddA(host string, ip net.IP) error {
+	if err := l.secondary.AddA(host, ip); err != nil {
+		return err
+	}
+
+	_, err := l.cacheA(host)
+	return err
+}
+
+// GetA retrieves the A records by FQDN, from the primary if it holds them.
+func (l *Layered) GetA(fqdn string) ([]net.IP, error) {
+	if ips, err := l.primary.GetA(fqdn); err != ErrNotFound {
+		return ips, err
+	}
+
+	return l.cacheA(fqdn)
+}
+
+// cacheA copies a host's A records, TTL and metadata from the secondary into
+// the primary, replacing any it held.
+func (l *Layered) cacheA(host string) ([]net.IP, error) {
+	ips, err := l.secondary.GetA(host)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, err := l.secondary.GetATTL(host)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+
+	meta, err := l.secondary.GetAMeta(host)
+	if err != nil && err != ErrNotFound {
+		return nil, err
+	}
+
+	for i, ip := range ips {
+		set := l.primary.AddA
+		if i == 0 {
+			set = l.primary.SetA
+		}
+
+		if err := set(host, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	if ttl != 0 {
+		if err := l.primary.SetATTL(host, ttl); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(meta) != 0 {
+		if err := l.primary.SetAMeta(host, meta); err != nil {
+			return nil, err
+		}
+	}
+
+	return ips, nil
+}
+
+// DeleteA deletes a host, or only the given addresses of it, from both DBs.
+func (l *Layered) DeleteA(host string, ips ...net.IP) error {
+	if err := l.secondary.DeleteA(host, ips...); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteA(host, ips...))
+}
+
+// SetATTL sets the TTL of a host's A records in the secondary, and refreshes
+// the primary's copy of the host.
+func (l *Layered) SetATTL(host string, ttl uint32) error {
+	if err := l.secondary.SetATTL(host, ttl); err != nil {
+		return err
+	}
+
+	_, err := l.cacheA(host)
+	return err
+}
+
+// GetATTL retrieves the TTL of a host's A records, from the primary if it
+// holds the host.
+func (l *Layered) GetATTL(fqdn string) (uint32, error) {
+	if _, err := l.GetA(fqdn); err != nil {
+		return 0, err
+	}
+
+	return l.primary.GetATTL(fqdn)
+}
+
+// SetAMeta sets the metadata of a host's A records in the secondary, and
+// refreshes the primary's copy of the host.
+func (l *Layered) SetAMeta(host string, meta map[string]string) error {
+	if err := l.secondary.SetAMeta(host, meta); err != nil {
+		return err
+	}
+
+	_, err := l.cacheA(host)
+	return err
+}
+
+// GetAMeta retrieves the metadata of a host's A records, from the primary if
+// it holds the host.
+func (l *Layered) GetAMeta(fqdn string) (map[string]string, error) {
+	if _, err := l.GetA(fqdn); err != nil {
+		return nil, err
+	}
+
+	return l.primary.GetAMeta(fqdn)
+}
+
+// ListA lists all A records held by the secondary.
+func (l *Layered) ListA() (ARecords, error) {
+	return l.secondary.ListA()
+}
+
+// ListAByPrefix lists the A records held by the secondary at or below prefix.
+func (l *Layered) ListAByPrefix(prefix string) (ARecords, error) {
+	if pl, ok := l.secondary.(PrefixLister); ok {
+		return pl.ListAByPrefix(prefix)
+	}
+
+	records, err := l.secondary.ListA()
+	if err != nil {
+		return nil, err
+	}
+
+	for host := range records {
+		if !Under(host, prefix) {
+			delete(records, host)
+		}
+	}
+
+	return records, nil
+}
+
+// ForEachA calls fn for each address of each host held by the secondary.
+func (l *Layered) ForEachA(fn func(string, net.IP) bool) error {
+	return l.secondary.ForEachA(fn)
+}
+
+// CountA returns the number of hosts with A records held by the secondary.
+func (l *Layered) CountA() (int, error) {
+	return l.secondary.CountA()
+}
+
+// SetAAAA sets a host to an IPv6 address in both DBs.
+func (l *Layered) SetAAAA(host string, ip net.IP) error {
+	if err := l.secondary.SetAAAA(host, ip); err != nil {
+		return err
+	}
+
+	return l.primary.SetAAAA(host, ip)
+}
+
+// GetAAAA retrieves the AAAA record by FQDN, from the primary if it holds it.
+func (l *Layered) GetAAAA(fqdn string) (net.IP, error) {
+	if ip, err := l.primary.GetAAAA(fqdn); err != ErrNotFound {
+		return ip, err
+	}
+
+	ip, err := l.secondary.GetAAAA(fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	return ip, l.primary.SetAAAA(fqdn, ip)
+}
+
+// DeleteAAAA deletes a host's AAAA record from both DBs.
+func (l *Layered) DeleteAAAA(host string) error {
+	if err := l.secondary.DeleteAAAA(host); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteAAAA(host))
+}
+
+// ListAAAA lists all AAAA records held by the secondary.
+func (l *Layered) ListAAAA() (AAAARecords, error) {
+	return l.secondary.ListAAAA()
+}
+
+// DeleteByIP removes ip from every host's A and AAAA records in both DBs,
+// returning the number of records the secondary removed.
+func (l *Layered) DeleteByIP(ip net.IP) (int, error) {
+	n, err := l.secondary.DeleteByIP(ip)
+	if err != nil {
+		return n, err
+	}
+
+	_, err = l.primary.DeleteByIP(ip)
+	return n, err
+}
+
+// SetCNAME points an alias at a target in both DBs.
+func (l *Layered) SetCNAME(alias, target string) error {
+	if err := l.secondary.SetCNAME(alias, target); err != nil {
+		return err
+	}
+
+	return l.primary.SetCNAME(alias, target)
+}
+
+// GetCNAME retrieves the target of an alias, from the primary if it holds it.
+func (l *Layered) GetCNAME(alias string) (string, error) {
+	if target, err := l.primary.GetCNAME(alias); err != ErrNotFound {
+		return target, err
+	}
+
+	target, err := l.secondary.GetCNAME(alias)
+	if err != nil {
+		return "", err
+	}
+
+	return target, l.primary.SetCNAME(alias, target)
+}
+
+// DeleteCNAME deletes an alias from both DBs.
+func (l *Layered) DeleteCNAME(alias string) error {
+	if err := l.secondary.DeleteCNAME(alias); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteCNAME(alias))
+}
+
+// SetDNAME points an owner's subtree at a target in both DBs.
+func (l *Layered) SetDNAME(owner, target string) error {
+	if err := l.secondary.SetDNAME(owner, target); err != nil {
+		return err
+	}
+
+	return l.primary.SetDNAME(owner, target)
+}
+
+// GetDNAME retrieves the target of an owner, from the primary if it holds it.
+func (l *Layered) GetDNAME(owner string) (string, error) {
+	if target, err := l.primary.GetDNAME(owner); err != ErrNotFound {
+		return target, err
+	}
+
+	target, err := l.secondary.GetDNAME(owner)
+	if err != nil {
+		return "", err
+	}
+
+	return target, l.primary.SetDNAME(owner, target)
+}
+
+// DeleteDNAME deletes an owner's DNAME from both DBs.
+func (l *Layered) DeleteDNAME(owner string) error {
+	if err := l.secondary.DeleteDNAME(owner); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteDNAME(owner))
+}
+
+// SetTXT sets a host's TXT records in both DBs.
+func (l *Layered) SetTXT(host string, values []string) error {
+	if err := l.secondary.SetTXT(host, values); err != nil {
+		return err
+	}
+
+	return l.primary.SetTXT(host, values)
+}
+
+// GetTXT retrieves the TXT records by FQDN, from the primary if it holds them.
+func (l *Layered) GetTXT(fqdn string) ([]string, error) {
+	if values, err := l.primary.GetTXT(fqdn); err != ErrNotFound {
+		return values, err
+	}
+
+	values, err := l.secondary.GetTXT(fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	return values, l.primary.SetTXT(fqdn, values)
+}
+
+// DeleteTXT deletes a host's TXT records from both DBs.
+func (l *Layered) DeleteTXT(host string) error {
+	if err := l.secondary.DeleteTXT(host); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteTXT(host))
+}
+
+// SetMX adds or replaces a mail exchanger of a host in the secondary, and
+// refreshes the primary's copy of the host's MX records.
+func (l *Layered) SetMX(host string, mx *MXRecord) error {
+	if err := l.secondary.SetMX(host, mx); err != nil {
+		return err
+	}
+
+	// SetMX adds to what the primary holds, so start it afresh
+	if err := ignoreNotFound(l.primary.DeleteMX(host)); err != nil {
+		return err
+	}
+
+	_, err := l.cacheMX(host)
+	return err
+}
+
+// GetMX retrieves the MX records by FQDN, from the primary if it holds them.
+func (l *Layered) GetMX(fqdn string) ([]*MXRecord, error) {
+	if mxs, err := l.primary.GetMX(fqdn); err != ErrNotFound {
+		return mxs, err
+	}
+
+	return l.cacheMX(fqdn)
+}
+
+// cacheMX copies a host's MX records from the secondary into the primary,
+// adding to any it held.
+func (l *Layered) cacheMX(host string) ([]*MXRecord, error) {
+	mxs, err := l.secondary.GetMX(host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mx := range mxs {
+		if err := l.primary.SetMX(host, mx); err != nil {
+			return nil, err
+		}
+	}
+
+	return mxs, nil
+}
+
+// DeleteMX deletes a host's MX records from both DBs.
+func (l *Layered) DeleteMX(host string) error {
+	if err := l.secondary.DeleteMX(host); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteMX(host))
+}
+
+// SetCAA adds or replaces a CAA record of a host in the secondary, and
+// refreshes the primary's copy of the host's CAA records.
+func (l *Layered) SetCAA(host string, caa *CAARecord) error {
+	if err := l.secondary.SetCAA(host, caa); err != nil {
+		return err
+	}
+
+	// SetCAA adds to what the primary holds, so start it afresh
+	if err := ignoreNotFound(l.primary.DeleteCAA(host)); err != nil {
+		return err
+	}
+
+	_, err := l.cacheCAA(host)
+	return err
+}
+
+// GetCAA retrieves the CAA records by FQDN, from the primary if it holds them.
+func (l *Layered) GetCAA(fqdn string) ([]*CAARecord, error) {
+	if caas, err := l.primary.GetCAA(fqdn); err != ErrNotFound {
+		return caas, err
+	}
+
+	return l.cacheCAA(fqdn)
+}
+
+// cacheCAA copies a host's CAA records from the secondary into the primary,
+// adding to any it held.
+func (l *Layered) cacheCAA(host string) ([]*CAARecord, error) {
+	caas, err := l.secondary.GetCAA(host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, caa := range caas {
+		if err := l.primary.SetCAA(host, caa); err != nil {
+			return nil, err
+		}
+	}
+
+	return caas, nil
+}
+
+// DeleteCAA deletes a host's CAA records from both DBs.
+func (l *Layered) DeleteCAA(host string) error {
+	if err := l.secondary.DeleteCAA(host); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteCAA(host))
+}
+
+// SetNS sets the nameservers of a host in both DBs.
+func (l *Layered) SetNS(host string, nameservers []string) error {
+	if err := l.secondary.SetNS(host, nameservers); err != nil {
+		return err
+	}
+
+	return l.primary.SetNS(host, nameservers)
+}
+
+// GetNS retrieves the NS records by FQDN, from the primary if it holds them.
+func (l *Layered) GetNS(fqdn string) ([]string, error) {
+	if nameservers, err := l.primary.GetNS(fqdn); err != ErrNotFound {
+		return nameservers, err
+	}
+
+	nameservers, err := l.secondary.GetNS(fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	return nameservers, l.primary.SetNS(fqdn, nameservers)
+}
+
+// DeleteNS deletes a host's NS records from both DBs.
+func (l *Layered) DeleteNS(host string) error {
+	if err := l.secondary.DeleteNS(host); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteNS(host))
+}
+
+// SetPTR points a reverse name at a host in both DBs.
+func (l *Layered) SetPTR(arpa, host string) error {
+	if err := l.secondary.SetPTR(arpa, host); err != nil {
+		return err
+	}
+
+	return l.primary.SetPTR(arpa, host)
+}
+
+// GetPTR retrieves the host a reverse name points at, from the primary if it
+// holds it.
+func (l *Layered) GetPTR(arpa string) (string, error) {
+	if host, err := l.primary.GetPTR(arpa); err != ErrNotFound {
+		return host, err
+	}
+
+	host, err := l.secondary.GetPTR(arpa)
+	if err != nil {
+		return "", err
+	}
+
+	return host, l.primary.SetPTR(arpa, host)
+}
+
+// DeletePTR deletes a reverse name from both DBs.
+func (l *Layered) DeletePTR(arpa string) error {
+	if err := l.secondary.DeletePTR(arpa); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeletePTR(arpa))
+}
+
+// SetSRV sets the target of a service in both DBs.
+func (l *Layered) SetSRV(spec string, srv *SRVRecord) error {
+	if err := l.secondary.SetSRV(spec, srv); err != nil {
+		return err
+	}
+
+	return l.primary.SetSRV(spec, srv)
+}
+
+// AddSRV adds a target to a service in the secondary, and refreshes the
+// primary's copy of the service.
+func (l *Layered) AddSRV(spec string, srv *SRVRecord) error {
+	if err := l.secondary.AddSRV(spec, srv); err != nil {
+		return err
+	}
+
+	_, err := l.cacheSRV(spec)
+	return err
+}
+
+// GetSRV retrieves the targets of a service, from the primary if it holds
+// them.
+func (l *Layered) GetSRV(spec string) ([]*SRVRecord, error) {
+	if srvs, err := l.primary.GetSRV(spec); err != ErrNotFound {
+		return srvs, err
+	}
+
+	return l.cacheSRV(spec)
+}
+
+// cacheSRV copies the targets of a service from the secondary into the
+// primary, replacing any it held.
+func (l *Layered) cacheSRV(spec string) ([]*SRVRecord, error) {
+	srvs, err := l.secondary.GetSRV(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, srv := range srvs {
+		set := l.primary.AddSRV
+		if i == 0 {
+			set = l.primary.SetSRV
+		}
+
+		if err := set(spec, srv); err != nil {
+			return nil, err
+		}
+	}
+
+	return srvs, nil
+}
+
+// DeleteSRV deletes a service from both DBs.
+func (l *Layered) DeleteSRV(spec string) error {
+	if err := l.secondary.DeleteSRV(spec); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteSRV(spec))
+}
+
+// ListSRV lists all SRV records held by the secondary.
+func (l *Layered) ListSRV() (SRVRecords, error) {
+	return l.secondary.ListSRV()
+}
+
+// ListSRVByPrefix lists the SRV records held by the secondary at or below
+// prefix.
+func (l *Layered) ListSRVByPrefix(prefix string) (SRVRecords, error) {
+	if pl, ok := l.secondary.(PrefixLister); ok {
+		return pl.ListSRVByPrefix(prefix)
+	}
+
+	records, err := l.secondary.ListSRV()
+	if err != nil {
+		return nil, err
+	}
+
+	for spec := range records {
+		if !Under(spec, prefix) {
+			delete(records, spec)
+		}
+	}
+
+	return records, nil
+}
+
+// CountSRV returns the number of services with SRV records held by the
+// secondary.
+func (l *Layered) CountSRV() (int, error) {
+	return l.secondary.CountSRV()
+}
+
+// SetRaw adds or replaces a raw record of a name in the secondary, and
+// refreshes the primary's copy of the name's raw records.
+func (l *Layered) SetRaw(name string, rr dns.RR) error {
+	if err := l.secondary.SetRaw(name, rr); err != nil {
+		return err
+	}
+
+	// SetRaw adds to what the primary holds, so start it afresh
+	if err := ignoreNotFound(l.primary.DeleteRaw(name, dns.TypeANY)); err != nil {
+		return err
+	}
+
+	_, err := l.cacheRaw(name, dns.TypeANY)
+	return err
+}
+
+// GetRaw retrieves the raw records of a type by name, from the primary if it
+// holds them.
+func (l *Layered) GetRaw(name string, qtype uint16) ([]dns.RR, error) {
+	if rrs, err := l.primary.GetRaw(name, qtype); err != ErrNotFound {
+		return rrs, err
+	}
+
+	return l.cacheRaw(name, qtype)
+}
+
+// cacheRaw copies a name's raw records of every type from the secondary into
+// the primary, adding to any it held, and returns those of type qtype.
+func (l *Layered) cacheRaw(name string, qtype uint16) ([]dns.RR, error) {
+	rrs, err := l.secondary.GetRaw(name, dns.TypeANY)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rr := range rrs {
+		if err := l.primary.SetRaw(name, rr); err != nil {
+			return nil, err
+		}
+	}
+
+	if rrs = rawOfType(rrs, qtype); len(rrs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return rrs, nil
+}
+
+// DeleteRaw deletes a name's raw records of a type from both DBs.
+func (l *Layered) DeleteRaw(name string, qtype uint16) error {
+	if err := l.secondary.DeleteRaw(name, qtype); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(l.primary.DeleteRaw(name, qtype))
+}
+
+// Close closes both DBs, returning the first error.
+func (l *Layered) Close() error {
+	err := l.secondary.Close()
+	if err2 := l.primary.Close(); err == nil {
+		err = err2
+	}
+
+	return err
+}
+
+// ImportA stores many A records at once in both DBs, as SetA would.
+func (l *Layered) ImportA(records map[string]net.IP) error {
+	if err := importA(l.secondary, records); err != nil {
+		return err
+	}
+
+	return importA(l.primary, records)
+}
+
+// ImportSRV stores many SRV records at once in both DBs, as SetSRV would.
+func (l *Layered) ImportSRV(records map[string]*SRVRecord) error {
+	if err := importSRV(l.secondary, records); err != nil {
+		return err
+	}
+
+	return importSRV(l.primary, records)
+}
+
+// importA imports records into d, one at a time if it is not an Importer.
+func importA(d DB, records map[string]net.IP) error {
+	if imp, ok := d.(Importer); ok {
+		return imp.ImportA(records)
+	}
+
+	for host, ip := range records {
+		if err := d.SetA(host, ip); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// importSRV imports records into d, one at a time if it is not an Importer.
+func importSRV(d DB, records map[string]*SRVRecord) error {
+	if imp, ok := d.(Importer); ok {
+		return imp.ImportSRV(records)
+	}
+
+	for spec, srv := range records {
+		if err := d.SetSRV(spec, srv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
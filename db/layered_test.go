@@ -0,0 +1,167 @@
+package db
+
+import (
+	"net"
+	"testing"
+)
+
+func TestLayered(t *testing.T) {
+	testDB(t, NewLayered(NewMap(), NewMap()))
+}
+
+func TestLayeredListByPrefix(t *testing.T) {
+	testListByPrefix(t, NewLayered(NewMap(), NewMap()))
+}
+
+func TestLayeredReadThrough(t *testing.T) {
+	primary, secondary := NewMap(), NewMap()
+	l := NewLayered(primary, secondary)
+
+	secondary.SetA("one", net.ParseIP("127.0.0.2"))
+	secondary.SetATTL("one", 60)
+	secondary.SetSRV("_test._tcp", &SRVRecord{Port: 1, Host: "one."})
+	secondary.SetMX("one", &MXRecord{Preference: 10, Mail: "mail."})
+
+	if _, err := primary.GetA("one"); err != ErrNotFound {
+		t.Fatalf("primary held A record before it was read: %v", err)
+	}
+
+	ips, err := l.GetA("one")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("wrong IPs read through: %v", ips)
+	}
+
+	if ips, err := primary.GetA("one"); err != nil || len(ips) != 1 {
+		t.Fatalf("A record was not copied to the primary: %v %v", ips, err)
+	}
+
+	if ttl, err := primary.GetATTL("one"); err != nil || ttl != 60 {
+		t.Fatalf("A TTL was not copied to the primary: %v %v", ttl, err)
+	}
+
+	if _, err := l.GetSRV("_test._tcp"); err != nil {
+		t.Fatal(err)
+	}
+
+	if srvs, err := primary.GetSRV("_test._tcp"); err != nil || len(srvs) != 1 || srvs[0].Port != 1 {
+		t.Fatalf("SRV record was not copied to the primary: %v %v", srvs, err)
+	}
+
+	if _, err := l.GetMX("one"); err != nil {
+		t.Fatal(err)
+	}
+
+	if mxs, err := primary.GetMX("one"); err != nil || len(mxs) != 1 || mxs[0].Mail != "mail." {
+		t.Fatalf("MX record was not copied to the primary: %v %v", mxs, err)
+	}
+
+	// once copied, the primary answers even if the secondary has lost the record
+	secondary.DeleteA("one")
+
+	if _, err := l.GetA("one"); err != nil {
+		t.Fatalf("primary did not answer: %v", err)
+	}
+
+	if _, err := l.GetA("missing"); err != ErrNotFound {
+		t.Fatalf("miss was not ErrNotFound: %v", err)
+	}
+
+	if n, _ := primary.CountA(); n != 1 {
+		t.Fatalf("miss changed the primary: %d hosts", n)
+	}
+}
+
+func TestLayeredWriteThrough(t *testing.T) {
+	primary, secondary := NewMap(), NewMap()
+	l := NewLayered(primary, secondary)
+
+	both := func(host string, want int) {
+		t.Helper()
+
+		for name, d := range map[string]DB{"primary": primary, "secondary": secondary} {
+			ips, err := d.GetA(host)
+			if want == 0 {
+				if err != ErrNotFound {
+					t.Fatalf("%s still holds %q: %v %v", name, host, ips, err)
+				}
+				continue
+			}
+
+			if err != nil || len(ips) != want {
+				t.Fatalf("%s holds %v for %q, expected %d IPs: %v", name, ips, host, want, err)
+			}
+		}
+	}
+
+	if err := l.SetA("one", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	both("one", 1)
+
+	if err := l.AddA("one", net.ParseIP("127.0.0.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	both("one", 2)
+
+	// the primary only holds what it has read, and is brought up to date in full
+	secondary.SetA("two", net.ParseIP("127.0.0.4"))
+
+	if err := l.AddA("two", net.ParseIP("127.0.0.5")); err != nil {
+		t.Fatal(err)
+	}
+
+	both("two", 2)
+
+	if err := l.SetATTL("one", 30); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, d := range map[string]DB{"primary": primary, "secondary": secondary} {
+		if ttl, err := d.GetATTL("one"); err != nil || ttl != 30 {
+			t.Fatalf("%s TTL is %d, expected 30: %v", name, ttl, err)
+		}
+	}
+
+	if err := l.DeleteA("one", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	both("one", 1)
+
+	if err := l.DeleteA("two"); err != nil {
+		t.Fatal(err)
+	}
+
+	both("two", 0)
+
+	if err := l.AddSRV("_test._tcp", &SRVRecord{Port: 1, Host: "one."}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.AddSRV("_test._tcp", &SRVRecord{Port: 2, Host: "one."}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, d := range map[string]DB{"primary": primary, "secondary": secondary} {
+		if srvs, err := d.GetSRV("_test._tcp"); err != nil || len(srvs) != 2 {
+			t.Fatalf("%s holds %v, expected 2 SRV records: %v", name, srvs, err)
+		}
+	}
+
+	// listing and counting see the secondary's records, read or not
+	secondary.SetA("three", net.ParseIP("127.0.0.6"))
+
+	if records, err := l.ListA(); err != nil || len(records) != 2 {
+		t.Fatalf("ListA returned %v, expected 2 hosts: %v", records, err)
+	}
+
+	if n, err := l.CountA(); err != nil || n != 2 {
+		t.Fatalf("CountA returned %d, expected 2: %v", n, err)
+	}
+}
@@ -2,24 +2,102 @@ package db
 
 import (
 	"net"
+	"strings"
 	"sync"
+
+	"github.com/miekg/dns"
 )
 
-// Map is a simple in-memory map of DNS entries. These are 1:1 entries, no
-// multiple address forms are permitted.
+// Map is a simple in-memory map of DNS entries. A, TXT, MX, CAA, NS, SRV and raw records
+// may hold several values per name; all other records are 1:1 entries. Names are
+// case-insensitive and are stored lowercased.
 type Map struct {
-	aRecords   ARecords     // FQDN -> IP
-	srvRecords SRVRecords   // service (e.g., _test._tcp) -> SRV
-	aMutex     sync.RWMutex // mutex for A record operations
-	srvMutex   sync.RWMutex // mutex for SRV record operations
+	aShards      [aShardCount]aShard     // A records, sharded by FQDN
+	aaaaRecords  AAAARecords             // FQDN -> IPv6
+	cnameRecords map[string]string       // alias -> target FQDN
+	dnameRecords map[string]string       // owner -> target FQDN
+	txtRecords   map[string][]string     // FQDN -> TXT values
+	mxRecords    map[string][]*MXRecord  // FQDN -> MX
+	caaRecords   map[string][]*CAARecord // FQDN -> CAA
+	nsRecords    map[string][]string     // FQDN -> nameserver FQDNs
+	ptrRecords   map[string]string       // arpa name -> FQDN
+	srvRecords   SRVRecords              // service (e.g., _test._tcp) -> []SRV
+	rawRecords   map[string][]dns.RR     // FQDN -> raw records of any type
+	aaaaMutex    sync.RWMutex            // mutex for AAAA record operations
+	cnameMutex   sync.RWMutex            // mutex for CNAME record operations
+	dnameMutex   sync.RWMutex            // mutex for DNAME record operations
+	txtMutex     sync.RWMutex            // mutex for TXT record operations
+	mxMutex      sync.RWMutex            // mutex for MX record operations
+	caaMutex     sync.RWMutex            // mutex for CAA record operations
+	nsMutex      sync.RWMutex            // mutex for NS record operations
+	ptrMutex     sync.RWMutex            // mutex for PTR record operations
+	srvMutex     sync.RWMutex            // mutex for SRV record operations
+	rawMutex     sync.RWMutex            // mutex for raw record operations
 }
 
 // NewMap makes a new *Map.
 func NewMap() *Map {
-	return &Map{
-		aRecords:   ARecords{},
-		srvRecords: SRVRecords{},
+	m := &Map{
+		aaaaRecords:  AAAARecords{},
+		cnameRecords: map[string]string{},
+		dnameRecords: map[string]string{},
+		txtRecords:   map[string][]string{},
+		mxRecords:    map[string][]*MXRecord{},
+		caaRecords:   map[string][]*CAARecord{},
+		nsRecords:    map[string][]string{},
+		ptrRecords:   map[string]string{},
+		srvRecords:   SRVRecords{},
+		rawRecords:   map[string][]dns.RR{},
+	}
+
+	for i := range m.aShards {
+		m.aShards[i].reset()
 	}
+
+	return m
+}
+
+// aShardCount is the number of shards A records are spread across, so that
+// lookups of different names rarely contend for a lock.
+const aShardCount = 256
+
+// aShard holds the A records for the names which hash to it.
+type aShard struct {
+	sync.RWMutex
+	records ARecords                     // FQDN -> []IP
+	ttls    map[string]uint32            // FQDN -> TTL override for A records
+	meta    map[string]map[string]string // FQDN -> metadata of A records
+}
+
+// reset empties the shard; the caller must hold its lock, or be its only user.
+func (s *aShard) reset() {
+	s.records = ARecords{}
+	s.ttls = map[string]uint32{}
+	s.meta = map[string]map[string]string{}
+}
+
+// forget removes the TTL override and metadata of a host; the caller must hold
+// the shard's lock.
+func (s *aShard) forget(host string) {
+	delete(s.ttls, host)
+	delete(s.meta, host)
+}
+
+// shardA returns the shard for a canonical FQDN.
+func (m *Map) shardA(fqdn string) *aShard {
+	return &m.aShards[aShardIndex(fqdn)]
+}
+
+// aShardIndex returns the index of the shard for a canonical FQDN, chosen by
+// its FNV-1a hash.
+func aShardIndex(fqdn string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(fqdn); i++ {
+		h ^= uint32(fqdn[i])
+		h *= 16777619
+	}
+
+	return h % aShardCount
 }
 
 // Close does nothing.
@@ -27,68 +105,737 @@ func (m *Map) Close() error {
 	return nil
 }
 
-// SetA overwrites or sets the A record for the entry.
+// SetA overwrites or sets the A record for the entry, replacing any addresses
+// already registered.
 func (m *Map) SetA(host string, ip net.IP) error {
-	m.aMutex.Lock()
-	m.aRecords[host] = ip
-	m.aMutex.Unlock()
+	host = canonical(host)
+	sh := m.shardA(host)
+	sh.Lock()
+	sh.records[host] = []net.IP{ip}
+	sh.forget(host)
+	sh.Unlock()
+	return nil
+}
+
+// ImportA sets the A record for each entry, as SetA does. The entries are
+// grouped by shard first, so that each shard is locked once.
+func (m *Map) ImportA(records map[string]net.IP) error {
+	byShard := map[uint32]map[string]net.IP{}
+
+	for host, ip := range records {
+		host = canonical(host)
+		i := aShardIndex(host)
+		if byShard[i] == nil {
+			byShard[i] = map[string]net.IP{}
+		}
+		byShard[i][host] = ip
+	}
+
+	for i, entries := range byShard {
+		sh := &m.aShards[i]
+		sh.Lock()
+		for host, ip := range entries {
+			sh.records[host] = []net.IP{ip}
+			sh.forget(host)
+		}
+		sh.Unlock()
+	}
+
+	return nil
+}
+
+// AddA appends an address to the A records for the entry. Adding an address
+// that is already registered is a no-op.
+func (m *Map) AddA(host string, ip net.IP) error {
+	host = canonical(host)
+	sh := m.shardA(host)
+	sh.Lock()
+	defer sh.Unlock()
+
+	for _, existing := range sh.records[host] {
+		if existing.Equal(ip) {
+			return nil
+		}
+	}
+
+	sh.records[host] = append(sh.records[host], ip)
 	return nil
 }
 
-// DeleteA deletes an A record for a host. Note that this is not the FQDN, but a hostname.
-func (m *Map) DeleteA(host string) error {
-	m.aMutex.Lock()
-	delete(m.aRecords, host)
-	m.aMutex.Unlock()
+// DeleteA deletes an A record for a host. Note that this is not the FQDN, but
+// a hostname. If any ips are provided, only those addresses are removed;
+// otherwise the whole entry is.
+func (m *Map) DeleteA(host string, ips ...net.IP) error {
+	host = canonical(host)
+	sh := m.shardA(host)
+	sh.Lock()
+	defer sh.Unlock()
+
+	if len(ips) == 0 {
+		delete(sh.records, host)
+		sh.forget(host)
+		return nil
+	}
+
+	kept := []net.IP{}
+
+	for _, existing := range sh.records[host] {
+		if !containsIP(ips, existing) {
+			kept = append(kept, existing)
+		}
+	}
+
+	if len(kept) == 0 {
+		delete(sh.records, host)
+		sh.forget(host)
+	} else {
+		sh.records[host] = kept
+	}
+
+	return nil
+}
+
+// SetATTL sets the TTL override for a host's A records. A TTL of 0 removes the
+// override.
+func (m *Map) SetATTL(host string, ttl uint32) error {
+	host = canonical(host)
+	sh := m.shardA(host)
+	sh.Lock()
+	defer sh.Unlock()
+
+	if _, ok := sh.records[host]; !ok {
+		return ErrNotFound
+	}
+
+	if ttl == 0 {
+		delete(sh.ttls, host)
+	} else {
+		sh.ttls[host] = ttl
+	}
 
 	return nil
 }
 
-// GetA retrieves an A record by FQDN.
-func (m *Map) GetA(fqdn string) (net.IP, error) {
-	m.aMutex.RLock()
-	defer m.aMutex.RUnlock()
-	val, ok := m.aRecords[fqdn]
+// GetATTL retrieves the TTL override for a host's A records, or 0 if there is
+// none.
+func (m *Map) GetATTL(fqdn string) (uint32, error) {
+	fqdn = canonical(fqdn)
+	sh := m.shardA(fqdn)
+	sh.RLock()
+	defer sh.RUnlock()
+
+	if _, ok := sh.records[fqdn]; !ok {
+		return 0, ErrNotFound
+	}
+
+	return sh.ttls[fqdn], nil
+}
+
+// SetAMeta replaces the metadata of a host's A records. A nil or empty meta
+// removes it.
+func (m *Map) SetAMeta(host string, meta map[string]string) error {
+	host = canonical(host)
+	sh := m.shardA(host)
+	sh.Lock()
+	defer sh.Unlock()
+
+	if _, ok := sh.records[host]; !ok {
+		return ErrNotFound
+	}
+
+	if len(meta) == 0 {
+		delete(sh.meta, host)
+	} else {
+		sh.meta[host] = copyMeta(meta)
+	}
+
+	return nil
+}
+
+// GetAMeta retrieves the metadata of a host's A records, or nil if there is
+// none.
+func (m *Map) GetAMeta(fqdn string) (map[string]string, error) {
+	fqdn = canonical(fqdn)
+	sh := m.shardA(fqdn)
+	sh.RLock()
+	defer sh.RUnlock()
+
+	if _, ok := sh.records[fqdn]; !ok {
+		return nil, ErrNotFound
+	}
+
+	return copyMeta(sh.meta[fqdn]), nil
+}
+
+// GetA retrieves the A records by FQDN.
+func (m *Map) GetA(fqdn string) ([]net.IP, error) {
+	fqdn = canonical(fqdn)
+	sh := m.shardA(fqdn)
+	sh.RLock()
+	defer sh.RUnlock()
+	val, ok := sh.records[fqdn]
 	if !ok {
 		return nil, ErrNotFound
 	}
 
-	return val, nil
+	return copyIPs(val), nil
 }
 
-// ListA lists all the A records in the database.
+// ListA lists all the A records in the database. Each shard is copied in
+// turn, so changes made during the listing may be partly reflected.
 func (m *Map) ListA() (ARecords, error) {
-	m.aMutex.RLock()
-	defer m.aMutex.RUnlock()
+	tmp := ARecords{}
 
+	for i := range m.aShards {
+		sh := &m.aShards[i]
+		sh.RLock()
+		for name, rec := range sh.records {
+			tmp[name] = copyIPs(rec)
+		}
+		sh.RUnlock()
+	}
+
+	return tmp, nil
+}
+
+// ListAByPrefix is like ListA, but only copies the hosts at or below prefix.
+func (m *Map) ListAByPrefix(prefix string) (ARecords, error) {
+	prefix = canonical(prefix)
 	tmp := ARecords{}
 
-	for name, rec := range m.aRecords {
-		tmp[name] = rec[:]
+	for i := range m.aShards {
+		sh := &m.aShards[i]
+		sh.RLock()
+		for name, rec := range sh.records {
+			if Under(name, prefix) {
+				tmp[name] = copyIPs(rec)
+			}
+		}
+		sh.RUnlock()
 	}
 
 	return tmp, nil
 }
 
-// SetSRV sets a srv record with service and protocol pointing at a name and port.
+// ForEachA calls fn for each address of each host, without copying the
+// records, until fn returns false. fn is called with a shard's read lock held
+// and must not change the Map.
+func (m *Map) ForEachA(fn func(string, net.IP) bool) error {
+	for i := range m.aShards {
+		sh := &m.aShards[i]
+		sh.RLock()
+		for name, ips := range sh.records {
+			for _, ip := range ips {
+				if !fn(name, ip) {
+					sh.RUnlock()
+					return nil
+				}
+			}
+		}
+		sh.RUnlock()
+	}
+
+	return nil
+}
+
+// RenameA moves the A records and TTL override of oldHost to newHost,
+// replacing any newHost already has. Both shards are locked for the whole
+// move, so no lookup sees oldHost gone before newHost exists.
+func (m *Map) RenameA(oldHost, newHost string) error {
+	oldHost, newHost = canonical(oldHost), canonical(newHost)
+	i, j := aShardIndex(oldHost), aShardIndex(newHost)
+	from, to := &m.aShards[i], &m.aShards[j]
+
+	// lock in index order, so that two renames between the same shards
+	// cannot deadlock
+	if i > j {
+		i, j = j, i
+	}
+
+	m.aShards[i].Lock()
+	defer m.aShards[i].Unlock()
+
+	if i != j {
+		m.aShards[j].Lock()
+		defer m.aShards[j].Unlock()
+	}
+
+	ips, ok := from.records[oldHost]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if oldHost == newHost {
+		return nil
+	}
+
+	ttl, hasTTL := from.ttls[oldHost]
+	meta, hasMeta := from.meta[oldHost]
+
+	delete(from.records, oldHost)
+	from.forget(oldHost)
+
+	to.records[newHost] = ips
+	to.forget(newHost)
+	if hasTTL {
+		to.ttls[newHost] = ttl
+	}
+	if hasMeta {
+		to.meta[newHost] = meta
+	}
+
+	return nil
+}
+
+// MoveA replaces the addresses of an existing host with ip, keeping its TTL
+// override.
+func (m *Map) MoveA(host string, ip net.IP) error {
+	host = canonical(host)
+	sh := m.shardA(host)
+	sh.Lock()
+	defer sh.Unlock()
+
+	if _, ok := sh.records[host]; !ok {
+		return ErrNotFound
+	}
+
+	sh.records[host] = []net.IP{ip}
+	return nil
+}
+
+// CountA returns the number of hosts with A records.
+func (m *Map) CountA() (int, error) {
+	var n int
+
+	for i := range m.aShards {
+		sh := &m.aShards[i]
+		sh.RLock()
+		n += len(sh.records)
+		sh.RUnlock()
+	}
+
+	return n, nil
+}
+
+// SetAAAA overwrites or sets the AAAA record for the entry.
+func (m *Map) SetAAAA(host string, ip net.IP) error {
+	host = canonical(host)
+	m.aaaaMutex.Lock()
+	m.aaaaRecords[host] = append(net.IP(nil), ip...)
+	m.aaaaMutex.Unlock()
+	return nil
+}
+
+// DeleteAAAA deletes an AAAA record for a host. Note that this is not the FQDN, but a hostname.
+func (m *Map) DeleteAAAA(host string) error {
+	host = canonical(host)
+	m.aaaaMutex.Lock()
+	delete(m.aaaaRecords, host)
+	m.aaaaMutex.Unlock()
+
+	return nil
+}
+
+// GetAAAA retrieves an AAAA record by FQDN.
+func (m *Map) GetAAAA(fqdn string) (net.IP, error) {
+	fqdn = canonical(fqdn)
+	m.aaaaMutex.RLock()
+	defer m.aaaaMutex.RUnlock()
+	val, ok := m.aaaaRecords[fqdn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append(net.IP(nil), val...), nil
+}
+
+// ListAAAA lists all the AAAA records in the database.
+func (m *Map) ListAAAA() (AAAARecords, error) {
+	m.aaaaMutex.RLock()
+	defer m.aaaaMutex.RUnlock()
+
+	tmp := AAAARecords{}
+
+	for name, rec := range m.aaaaRecords {
+		tmp[name] = append(net.IP(nil), rec...)
+	}
+
+	return tmp, nil
+}
+
+// DeleteByIP removes ip from every host's A and AAAA records, returning the
+// number of records removed. Hosts left with no A records are deleted.
+func (m *Map) DeleteByIP(ip net.IP) (int, error) {
+	var n int
+
+	for i := range m.aShards {
+		sh := &m.aShards[i]
+		sh.Lock()
+		for host, ips := range sh.records {
+			if !containsIP(ips, ip) {
+				continue
+			}
+
+			kept := []net.IP{}
+			for _, existing := range ips {
+				if !existing.Equal(ip) {
+					kept = append(kept, existing)
+				}
+			}
+			n++
+
+			if len(kept) == 0 {
+				delete(sh.records, host)
+				sh.forget(host)
+			} else {
+				sh.records[host] = kept
+			}
+		}
+		sh.Unlock()
+	}
+
+	m.aaaaMutex.Lock()
+	for host, existing := range m.aaaaRecords {
+		if existing.Equal(ip) {
+			delete(m.aaaaRecords, host)
+			n++
+		}
+	}
+	m.aaaaMutex.Unlock()
+
+	return n, nil
+}
+
+// SetCNAME overwrites or sets the CNAME record for the alias.
+func (m *Map) SetCNAME(alias, target string) error {
+	alias = canonical(alias)
+	m.cnameMutex.Lock()
+	m.cnameRecords[alias] = target
+	m.cnameMutex.Unlock()
+	return nil
+}
+
+// GetCNAME retrieves the target of a CNAME record by alias.
+func (m *Map) GetCNAME(alias string) (string, error) {
+	alias = canonical(alias)
+	m.cnameMutex.RLock()
+	defer m.cnameMutex.RUnlock()
+
+	target, ok := m.cnameRecords[alias]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return target, nil
+}
+
+// DeleteCNAME deletes a CNAME record for an alias.
+func (m *Map) DeleteCNAME(alias string) error {
+	alias = canonical(alias)
+	m.cnameMutex.Lock()
+	delete(m.cnameRecords, alias)
+	m.cnameMutex.Unlock()
+
+	return nil
+}
+
+// SetDNAME overwrites or sets the DNAME record for the owner.
+func (m *Map) SetDNAME(owner, target string) error {
+	owner = canonical(owner)
+	m.dnameMutex.Lock()
+	m.dnameRecords[owner] = target
+	m.dnameMutex.Unlock()
+	return nil
+}
+
+// GetDNAME retrieves the target of a DNAME record by owner.
+func (m *Map) GetDNAME(owner string) (string, error) {
+	owner = canonical(owner)
+	m.dnameMutex.RLock()
+	defer m.dnameMutex.RUnlock()
+
+	target, ok := m.dnameRecords[owner]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return target, nil
+}
+
+// DeleteDNAME deletes a DNAME record for an owner.
+func (m *Map) DeleteDNAME(owner string) error {
+	owner = canonical(owner)
+	m.dnameMutex.Lock()
+	delete(m.dnameRecords, owner)
+	m.dnameMutex.Unlock()
+
+	return nil
+}
+
+// SetTXT overwrites or sets the TXT records for the entry.
+func (m *Map) SetTXT(host string, values []string) error {
+	host = canonical(host)
+	m.txtMutex.Lock()
+	m.txtRecords[host] = append([]string(nil), values...)
+	m.txtMutex.Unlock()
+	return nil
+}
+
+// GetTXT retrieves the TXT records by FQDN.
+func (m *Map) GetTXT(fqdn string) ([]string, error) {
+	fqdn = canonical(fqdn)
+	m.txtMutex.RLock()
+	defer m.txtMutex.RUnlock()
+
+	values, ok := m.txtRecords[fqdn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]string(nil), values...), nil
+}
+
+// DeleteTXT deletes the TXT records for a host.
+func (m *Map) DeleteTXT(host string) error {
+	host = canonical(host)
+	m.txtMutex.Lock()
+	delete(m.txtRecords, host)
+	m.txtMutex.Unlock()
+
+	return nil
+}
+
+// SetMX adds a MX record for the entry. If the mail exchange is already
+// registered for the entry, its preference is updated instead.
+func (m *Map) SetMX(host string, mx *MXRecord) error {
+	host = canonical(host)
+	m.mxMutex.Lock()
+	defer m.mxMutex.Unlock()
+
+	t := *mx
+
+	for i, existing := range m.mxRecords[host] {
+		if existing.Mail == mx.Mail {
+			m.mxRecords[host][i] = &t
+			return nil
+		}
+	}
+
+	m.mxRecords[host] = append(m.mxRecords[host], &t)
+	return nil
+}
+
+// GetMX retrieves the MX records by FQDN.
+func (m *Map) GetMX(fqdn string) ([]*MXRecord, error) {
+	fqdn = canonical(fqdn)
+	m.mxMutex.RLock()
+	defer m.mxMutex.RUnlock()
+
+	recs, ok := m.mxRecords[fqdn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	tmp := []*MXRecord{}
+	for _, rec := range recs {
+		t := *rec
+		tmp = append(tmp, &t)
+	}
+
+	return tmp, nil
+}
+
+// DeleteMX deletes the MX records for a host.
+func (m *Map) DeleteMX(host string) error {
+	host = canonical(host)
+	m.mxMutex.Lock()
+	delete(m.mxRecords, host)
+	m.mxMutex.Unlock()
+
+	return nil
+}
+
+// SetCAA adds a CAA record for the entry. If the tag and value are already
+// registered for the entry, its flag is updated instead.
+func (m *Map) SetCAA(host string, caa *CAARecord) error {
+	host = canonical(host)
+	m.caaMutex.Lock()
+	defer m.caaMutex.Unlock()
+
+	t := *caa
+
+	for i, existing := range m.caaRecords[host] {
+		if existing.Tag == caa.Tag && existing.Value == caa.Value {
+			m.caaRecords[host][i] = &t
+			return nil
+		}
+	}
+
+	m.caaRecords[host] = append(m.caaRecords[host], &t)
+	return nil
+}
+
+// GetCAA retrieves the CAA records by FQDN.
+func (m *Map) GetCAA(fqdn string) ([]*CAARecord, error) {
+	fqdn = canonical(fqdn)
+	m.caaMutex.RLock()
+	defer m.caaMutex.RUnlock()
+
+	recs, ok := m.caaRecords[fqdn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	tmp := []*CAARecord{}
+	for _, rec := range recs {
+		t := *rec
+		tmp = append(tmp, &t)
+	}
+
+	return tmp, nil
+}
+
+// DeleteCAA deletes the CAA records for a host.
+func (m *Map) DeleteCAA(host string) error {
+	host = canonical(host)
+	m.caaMutex.Lock()
+	delete(m.caaRecords, host)
+	m.caaMutex.Unlock()
+
+	return nil
+}
+
+// SetNS overwrites or sets the NS records for the entry.
+func (m *Map) SetNS(host string, nameservers []string) error {
+	host = canonical(host)
+	m.nsMutex.Lock()
+	m.nsRecords[host] = append([]string(nil), nameservers...)
+	m.nsMutex.Unlock()
+	return nil
+}
+
+// GetNS retrieves the NS records by FQDN.
+func (m *Map) GetNS(fqdn string) ([]string, error) {
+	fqdn = canonical(fqdn)
+	m.nsMutex.RLock()
+	defer m.nsMutex.RUnlock()
+
+	nameservers, ok := m.nsRecords[fqdn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]string(nil), nameservers...), nil
+}
+
+// DeleteNS deletes the NS records for a host.
+func (m *Map) DeleteNS(host string) error {
+	host = canonical(host)
+	m.nsMutex.Lock()
+	delete(m.nsRecords, host)
+	m.nsMutex.Unlock()
+
+	return nil
+}
+
+// SetPTR overwrites or sets the PTR record for a reverse (arpa) name.
+func (m *Map) SetPTR(arpa, host string) error {
+	arpa = canonical(arpa)
+	m.ptrMutex.Lock()
+	m.ptrRecords[arpa] = host
+	m.ptrMutex.Unlock()
+	return nil
+}
+
+// GetPTR retrieves the host a reverse (arpa) name points at.
+func (m *Map) GetPTR(arpa string) (string, error) {
+	arpa = canonical(arpa)
+	m.ptrMutex.RLock()
+	defer m.ptrMutex.RUnlock()
+
+	host, ok := m.ptrRecords[arpa]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return host, nil
+}
+
+// DeletePTR deletes the PTR record for a reverse (arpa) name.
+func (m *Map) DeletePTR(arpa string) error {
+	arpa = canonical(arpa)
+	m.ptrMutex.Lock()
+	delete(m.ptrRecords, arpa)
+	m.ptrMutex.Unlock()
+
+	return nil
+}
+
+// SetSRV sets a srv record with service and protocol pointing at a name and
+// port, replacing any targets already registered.
 func (m *Map) SetSRV(spec string, srv *SRVRecord) error {
+	spec = canonical(spec)
+	t := *srv
+
 	m.srvMutex.Lock()
-	m.srvRecords[spec] = srv
+	m.srvRecords[spec] = []*SRVRecord{&t}
 	m.srvMutex.Unlock()
 	return nil
 }
 
-// GetSRV gets a service based on a name
-func (m *Map) GetSRV(spec string) (*SRVRecord, error) {
+// ImportSRV sets the SRV record for each spec, as SetSRV does, under a single
+// lock.
+func (m *Map) ImportSRV(records map[string]*SRVRecord) error {
+	m.srvMutex.Lock()
+	defer m.srvMutex.Unlock()
+
+	for spec, srv := range records {
+		copied := *srv
+		m.srvRecords[canonical(spec)] = []*SRVRecord{&copied}
+	}
+
+	return nil
+}
+
+// AddSRV adds a target to a srv record. If a target with the same host and
+// port is already registered, it is replaced.
+func (m *Map) AddSRV(spec string, srv *SRVRecord) error {
+	spec = canonical(spec)
+	m.srvMutex.Lock()
+	defer m.srvMutex.Unlock()
+
+	t := *srv
+
+	for i, existing := range m.srvRecords[spec] {
+		if existing.Host == srv.Host && existing.Port == srv.Port {
+			m.srvRecords[spec][i] = &t
+			return nil
+		}
+	}
+
+	m.srvRecords[spec] = append(m.srvRecords[spec], &t)
+	return nil
+}
+
+// GetSRV gets a service's targets based on a name. The records returned are
+// copies and may be modified freely.
+func (m *Map) GetSRV(spec string) ([]*SRVRecord, error) {
+	spec = canonical(spec)
 	m.srvMutex.RLock()
 	defer m.srvMutex.RUnlock()
 
-	srv, ok := m.srvRecords[spec]
+	srvs, ok := m.srvRecords[spec]
 	if !ok {
 		return nil, ErrNotFound
 	}
 
-	return srv, nil
+	tmp := []*SRVRecord{}
+	for _, srv := range srvs {
+		t := *srv
+		tmp = append(tmp, &t)
+	}
+
+	return tmp, nil
 }
 
 // ListSRV lists all SRV records in the database.
@@ -98,19 +845,267 @@ func (m *Map) ListSRV() (SRVRecords, error) {
 	m.srvMutex.RLock()
 	defer m.srvMutex.RUnlock()
 
-	for name, rec := range m.srvRecords {
-		t := *rec
-		tmp[name] = &t
+	for name, recs := range m.srvRecords {
+		for _, rec := range recs {
+			t := *rec
+			tmp[name] = append(tmp[name], &t)
+		}
+	}
+
+	return tmp, nil
+}
+
+// ListSRVByPrefix is like ListSRV, but only copies the services at or below
+// prefix.
+func (m *Map) ListSRVByPrefix(prefix string) (SRVRecords, error) {
+	prefix = canonical(prefix)
+	tmp := SRVRecords{}
+
+	m.srvMutex.RLock()
+	defer m.srvMutex.RUnlock()
+
+	for name, recs := range m.srvRecords {
+		if !Under(name, prefix) {
+			continue
+		}
+
+		for _, rec := range recs {
+			t := *rec
+			tmp[name] = append(tmp[name], &t)
+		}
 	}
 
 	return tmp, nil
 }
 
+// CountSRV returns the number of services with SRV records.
+func (m *Map) CountSRV() (int, error) {
+	m.srvMutex.RLock()
+	defer m.srvMutex.RUnlock()
+	return len(m.srvRecords), nil
+}
+
 // DeleteSRV deletes a SRV record based on the service and protocol.
 func (m *Map) DeleteSRV(spec string) error {
+	spec = canonical(spec)
 	m.srvMutex.Lock()
 	delete(m.srvRecords, spec)
 	m.srvMutex.Unlock()
 
 	return nil
 }
+
+// SetRaw adds a raw record for the name. A record already held which differs
+// only in its TTL is replaced instead.
+func (m *Map) SetRaw(name string, rr dns.RR) error {
+	name = canonical(name)
+	m.rawMutex.Lock()
+	m.rawRecords[name] = addRaw(m.rawRecords[name], dns.Copy(rr))
+	m.rawMutex.Unlock()
+
+	return nil
+}
+
+// GetRaw retrieves the raw records of a type by name; dns.TypeANY retrieves
+// those of every type.
+func (m *Map) GetRaw(name string, qtype uint16) ([]dns.RR, error) {
+	name = canonical(name)
+	m.rawMutex.RLock()
+	defer m.rawMutex.RUnlock()
+
+	rrs := rawOfType(m.rawRecords[name], qtype)
+	if len(rrs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	for i, rr := range rrs {
+		rrs[i] = dns.Copy(rr)
+	}
+
+	return rrs, nil
+}
+
+// DeleteRaw deletes the raw records of a type for a name; dns.TypeANY deletes
+// those of every type.
+func (m *Map) DeleteRaw(name string, qtype uint16) error {
+	name = canonical(name)
+	m.rawMutex.Lock()
+	defer m.rawMutex.Unlock()
+
+	kept := []dns.RR{}
+	if qtype != dns.TypeANY {
+		for _, rr := range m.rawRecords[name] {
+			if rr.Header().Rrtype != qtype {
+				kept = append(kept, rr)
+			}
+		}
+	}
+
+	if len(kept) == 0 {
+		delete(m.rawRecords, name)
+	} else {
+		m.rawRecords[name] = kept
+	}
+
+	return nil
+}
+
+// FlushA removes all A records and their TTLs.
+func (m *Map) FlushA() error {
+	for i := range m.aShards {
+		sh := &m.aShards[i]
+		sh.Lock()
+		sh.reset()
+		sh.Unlock()
+	}
+	return nil
+}
+
+// Replace swaps in the given A and SRV records, as described by Replacer. The
+// new shards are built first, then every shard and the SRV records are locked
+// together for the swap itself.
+func (m *Map) Replace(a ARecords, ttls map[string]uint32, srv SRVRecords) error {
+	var shards [aShardCount]aShard
+	for i := range shards {
+		shards[i].reset()
+	}
+
+	for host, ips := range a {
+		host = canonical(host)
+		sh := &shards[aShardIndex(host)]
+
+		for _, ip := range ips {
+			sh.records[host] = append(sh.records[host], append(net.IP(nil), ip...))
+		}
+	}
+
+	for host, ttl := range ttls {
+		host = canonical(host)
+		sh := &shards[aShardIndex(host)]
+
+		if _, ok := sh.records[host]; ok && ttl != 0 {
+			sh.ttls[host] = ttl
+		}
+	}
+
+	srvRecords := SRVRecords{}
+	for spec, targets := range srv {
+		spec = canonical(spec)
+
+		for _, target := range targets {
+			copied := *target
+			srvRecords[spec] = append(srvRecords[spec], &copied)
+		}
+	}
+
+	for i := range m.aShards {
+		m.aShards[i].Lock()
+	}
+	m.srvMutex.Lock()
+
+	for i := range m.aShards {
+		sh := &m.aShards[i]
+		sh.records, sh.ttls, sh.meta = shards[i].records, shards[i].ttls, shards[i].meta
+	}
+	m.srvRecords = srvRecords
+
+	m.srvMutex.Unlock()
+	for i := range m.aShards {
+		m.aShards[i].Unlock()
+	}
+
+	return nil
+}
+
+// FlushSRV removes all SRV records.
+func (m *Map) FlushSRV() error {
+	m.srvMutex.Lock()
+	m.srvRecords = SRVRecords{}
+	m.srvMutex.Unlock()
+	return nil
+}
+
+// FlushAll removes every record of every type.
+func (m *Map) FlushAll() error {
+	m.FlushA()
+	m.FlushSRV()
+
+	m.aaaaMutex.Lock()
+	m.aaaaRecords = AAAARecords{}
+	m.aaaaMutex.Unlock()
+
+	m.cnameMutex.Lock()
+	m.cnameRecords = map[string]string{}
+	m.cnameMutex.Unlock()
+
+	m.dnameMutex.Lock()
+	m.dnameRecords = map[string]string{}
+	m.dnameMutex.Unlock()
+
+	m.txtMutex.Lock()
+	m.txtRecords = map[string][]string{}
+	m.txtMutex.Unlock()
+
+	m.mxMutex.Lock()
+	m.mxRecords = map[string][]*MXRecord{}
+	m.mxMutex.Unlock()
+
+	m.caaMutex.Lock()
+	m.caaRecords = map[string][]*CAARecord{}
+	m.caaMutex.Unlock()
+
+	m.nsMutex.Lock()
+	m.nsRecords = map[string][]string{}
+	m.nsMutex.Unlock()
+
+	m.ptrMutex.Lock()
+	m.ptrRecords = map[string]string{}
+	m.ptrMutex.Unlock()
+
+	m.rawMutex.Lock()
+	m.rawRecords = map[string][]dns.RR{}
+	m.rawMutex.Unlock()
+
+	return nil
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, i := range ips {
+		if i.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// copyIPs deep-copies a list of addresses so callers cannot modify the stored
+// values.
+func copyIPs(ips []net.IP) []net.IP {
+	tmp := make([]net.IP, len(ips))
+	for i, ip := range ips {
+		tmp[i] = append(net.IP(nil), ip...)
+	}
+
+	return tmp
+}
+
+// copyMeta copies a metadata map, returning nil for an empty one.
+func copyMeta(meta map[string]string) map[string]string {
+	if len(meta) == 0 {
+		return nil
+	}
+
+	tmp := make(map[string]string, len(meta))
+	for k, v := range meta {
+		tmp[k] = v
+	}
+
+	return tmp
+}
+
+// canonical normalizes a name for storage and lookup; DNS names are
+// case-insensitive (RFC 4343).
+func canonical(name string) string {
+	return strings.ToLower(name)
+}
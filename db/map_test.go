@@ -0,0 +1,249 @@
+package db
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+)
+
+func TestMapGetSRVConcurrent(t *testing.T) {
+	m := NewMap()
+
+	records := []*SRVRecord{
+		&SRVRecord{Port: 1, Host: "one."},
+		&SRVRecord{Port: 2, Host: "two."},
+	}
+
+	if err := m.SetSRV("_test._tcp", records[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan string, 100)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				m.SetSRV("_test._tcp", records[j%2])
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				srvs, err := m.GetSRV("_test._tcp")
+				if err != nil {
+					errs <- err.Error()
+					return
+				}
+
+				srv := srvs[0]
+				if !srv.Equal(records[0]) && !srv.Equal(records[1]) {
+					errs <- "torn read: " + srv.Host
+					return
+				}
+
+				// modifying the copy must not race with or affect the stored record.
+				srv.Port = 5150
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	srvs, err := m.GetSRV("_test._tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if srvs[0].Port == 5150 {
+		t.Fatal("modifying a record returned by GetSRV modified the stored record")
+	}
+
+	m.SetSRV("_test._tcp", records[0])
+	records[0].Port = 5150
+	if srvs, _ := m.GetSRV("_test._tcp"); srvs[0].Port == 5150 {
+		t.Fatal("modifying a record passed to SetSRV modified the stored record")
+	}
+}
+
+func TestMap(t *testing.T) {
+	testDB(t, NewMap())
+}
+
+func TestMapAAAACopies(t *testing.T) {
+	m := NewMap()
+
+	ip := net.ParseIP("fe80::1")
+	if err := m.SetAAAA("test", ip); err != nil {
+		t.Fatal(err)
+	}
+	ip[15] = 2
+
+	got, err := m.GetAAAA("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got[15] = 3
+
+	list, err := m.ListAAAA()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list["test"][15] = 4
+
+	if got, _ := m.GetAAAA("test"); !got.Equal(net.ParseIP("fe80::1")) {
+		t.Fatalf("stored AAAA record was changed through a caller's slice: %v", got)
+	}
+}
+
+func TestMapListByPrefix(t *testing.T) {
+	testListByPrefix(t, NewMap())
+}
+
+func TestMapReplace(t *testing.T) {
+	testReplace(t, NewMap())
+}
+
+func TestMapFlush(t *testing.T) {
+	m := NewMap()
+
+	m.SetA("one", net.ParseIP("127.0.0.2"))
+	m.SetATTL("one", 60)
+	m.SetSRV("_test._tcp", &SRVRecord{Port: 1, Host: "one."})
+	m.SetTXT("one", []string{"text"})
+	m.SetNS("@", []string{"ns1."})
+
+	if err := m.FlushA(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.GetATTL("one"); err != ErrNotFound {
+		t.Fatalf("A TTL survived FlushA: %v", err)
+	}
+
+	if err := m.FlushSRV(); err != nil {
+		t.Fatal(err)
+	}
+
+	if srvs, _ := m.ListSRV(); len(srvs) != 0 {
+		t.Fatalf("SRV records survived FlushSRV: %v", srvs)
+	}
+
+	if _, err := m.GetTXT("one"); err != nil {
+		t.Fatalf("TXT record did not survive FlushSRV: %v", err)
+	}
+
+	if err := m.FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.GetTXT("one"); err != ErrNotFound {
+		t.Fatalf("TXT record survived FlushAll: %v", err)
+	}
+
+	if _, err := m.GetNS("@"); err != ErrNotFound {
+		t.Fatalf("NS record survived FlushAll: %v", err)
+	}
+}
+
+// singleLockA is the unsharded A record store Map used to have, kept as a
+// baseline for BenchmarkMapGetAParallel.
+type singleLockA struct {
+	sync.RWMutex
+	records ARecords
+}
+
+func (s *singleLockA) GetA(fqdn string) ([]net.IP, error) {
+	fqdn = canonical(fqdn)
+	s.RLock()
+	defer s.RUnlock()
+	val, ok := s.records[fqdn]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return copyIPs(val), nil
+}
+
+func benchmarkGetAParallel(b *testing.B, getA func(string) ([]net.IP, error), setA func(string, net.IP) error) {
+	names := make([]string, 1024)
+	for i := range names {
+		names[i] = fmt.Sprintf("host%d.docker.", i)
+		setA(names[i], net.ParseIP("127.0.0.2"))
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			// a writer now and then makes the readers contend for the lock
+			if i%16 == 0 {
+				setA(names[i%len(names)], net.ParseIP("127.0.0.3"))
+				continue
+			}
+
+			if _, err := getA(names[i%len(names)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkMapGetAParallel(b *testing.B) {
+	m := NewMap()
+	benchmarkGetAParallel(b, m.GetA, m.SetA)
+}
+
+func BenchmarkSingleLockGetAParallel(b *testing.B) {
+	s := &singleLockA{records: ARecords{}}
+	benchmarkGetAParallel(b, s.GetA, func(host string, ip net.IP) error {
+		host = canonical(host)
+		s.Lock()
+		s.records[host] = []net.IP{ip}
+		s.Unlock()
+		return nil
+	})
+}
+
+func benchmarkZone(b *testing.B) *Map {
+	m := NewMap()
+
+	records := map[string]net.IP{}
+	for i := 0; i < 50000; i++ {
+		records[fmt.Sprintf("host%d", i)] = net.IPv4(10, byte(i>>16), byte(i>>8), byte(i))
+	}
+	m.ImportA(records)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	return m
+}
+
+func BenchmarkMapListA(b *testing.B) {
+	m := benchmarkZone(b)
+
+	for i := 0; i < b.N; i++ {
+		as, _ := m.ListA()
+		for range as {
+		}
+	}
+}
+
+func BenchmarkMapForEachA(b *testing.B) {
+	m := benchmarkZone(b)
+
+	for i := 0; i < b.N; i++ {
+		m.ForEachA(func(string, net.IP) bool { return true })
+	}
+}
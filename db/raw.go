@@ -0,0 +1,68 @@
+package db
+
+import (
+	"github.com/miekg/dns"
+)
+
+// Raw records are resource records of types the DB does not otherwise model,
+// stored whole. A name may hold several of each type; SetRaw replaces a record
+// which differs from the one set only in its TTL, rather than adding another.
+// Backends which cannot hold a dns.RR store its presentation form, and parse
+// it again when read.
+
+// addRaw adds rr to rrs, replacing any duplicate of it.
+func addRaw(rrs []dns.RR, rr dns.RR) []dns.RR {
+	for i, existing := range rrs {
+		if dns.IsDuplicate(existing, rr) {
+			rrs[i] = rr
+			return rrs
+		}
+	}
+
+	return append(rrs, rr)
+}
+
+// rawOfType returns those of rrs of type qtype, or all of them for
+// dns.TypeANY.
+func rawOfType(rrs []dns.RR, qtype uint16) []dns.RR {
+	matched := []dns.RR{}
+
+	for _, rr := range rrs {
+		if qtype == dns.TypeANY || rr.Header().Rrtype == qtype {
+			matched = append(matched, rr)
+		}
+	}
+
+	return matched
+}
+
+// rawKey identifies rr among the raw records of its name, as addRaw does: its
+// presentation form with the TTL left out.
+func rawKey(rr dns.RR) string {
+	c := dns.Copy(rr)
+	c.Header().Ttl = 0
+	return c.String()
+}
+
+func encodeRaw(rrs []dns.RR) []string {
+	lines := make([]string, 0, len(rrs))
+	for _, rr := range rrs {
+		lines = append(lines, rr.String())
+	}
+
+	return lines
+}
+
+func decodeRaw(lines []string) ([]dns.RR, error) {
+	rrs := make([]dns.RR, 0, len(lines))
+	for _, line := range lines {
+		rr, err := dns.NewRR(line)
+		if err != nil {
+			return nil, err
+		}
+
+		rrs = append(rrs, rr)
+	}
+
+	return rrs, nil
+}
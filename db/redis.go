@@ -0,0 +1,771 @@
+package db
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis"
+	"github.com/miekg/dns"
+)
+
+// DefaultRedisPrefix is the prefix applied to every key the Redis backend
+// manages, unless changed with WithRedisPrefix.
+const DefaultRedisPrefix = "dnsserver:"
+
+const (
+	redisA     = "a:"
+	redisATTL  = "attl:"
+	redisAMeta = "ameta:"
+	redisAAAA  = "aaaa:"
+	redisCNAME = "cname:"
+	redisDNAME = "dname:"
+	redisTXT   = "txt:"
+	redisMX    = "mx:"
+	redisCAA   = "caa:"
+	redisNS    = "ns:"
+	redisPTR   = "ptr:"
+	redisSRV   = "srv:"
+	redisRaw   = "raw:"
+)
+
+// Redis is a DB backed by a Redis server. A records are stored as sets of
+// address strings keyed by FQDN, and SRV records as JSON keyed by spec; the
+// remaining record types are stored likewise as strings or JSON.
+type Redis struct {
+	client  *redis.Client
+	options *redis.Options
+	prefix  string
+}
+
+// RedisOption configures a Redis DB at construction.
+type RedisOption func(*Redis)
+
+// WithRedisPassword sets the password used to authenticate to the Redis
+// server.
+func WithRedisPassword(password string) RedisOption {
+	return func(r *Redis) {
+		r.options.Password = password
+	}
+}
+
+// WithRedisDB selects the Redis database to use.
+func WithRedisDB(db int) RedisOption {
+	return func(r *Redis) {
+		r.options.DB = db
+	}
+}
+
+// WithRedisPrefix sets the prefix applied to all keys, so that several servers
+// may share one Redis database.
+func WithRedisPrefix(prefix string) RedisOption {
+	return func(r *Redis) {
+		r.prefix = prefix
+	}
+}
+
+// NewRedis makes a new *Redis connected to the server at addr. The connection
+// is verified before returning.
+func NewRedis(addr string, opts ...RedisOption) (*Redis, error) {
+	r := &Redis{
+		options: &redis.Options{Addr: addr},
+		prefix:  DefaultRedisPrefix,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.client = redis.NewClient(r.options)
+
+	if err := r.client.Ping().Err(); err != nil {
+		r.client.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Close closes the client pool.
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
+
+func (r *Redis) key(kind, name string) string {
+	return r.prefix + kind + canonical(name)
+}
+
+// scan iterates over all keys of a kind, calling fn with the name each key
+// was stored under.
+func (r *Redis) scan(kind string, fn func(key, name string) error) error {
+	prefix := r.prefix + kind
+	iter := r.client.Scan(0, prefix+"*", 0).Iterator()
+
+	for iter.Next() {
+		if err := fn(iter.Val(), strings.TrimPrefix(iter.Val(), prefix)); err != nil {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// count returns the number of keys of kind.
+func (r *Redis) count(kind string) (int, error) {
+	var n int
+
+	err := r.scan(kind, func(string, string) error {
+		n++
+		return nil
+	})
+
+	return n, err
+}
+
+// getJSON unmarshals the JSON stored at key into v.
+func (r *Redis) getJSON(key string, v interface{}) error {
+	content, err := r.client.Get(key).Bytes()
+	if err == redis.Nil {
+		return ErrNotFound
+	} else if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, v)
+}
+
+// updateJSON performs an optimistic read-modify-write of the JSON stored at
+// key; fn receives a pointer to the current value.
+func (r *Redis) updateJSON(key string, v interface{}, fn func() error) error {
+	return r.client.Watch(func(tx *redis.Tx) error {
+		content, err := tx.Get(key).Bytes()
+		if err == nil {
+			if err := json.Unmarshal(content, v); err != nil {
+				return err
+			}
+		} else if err != redis.Nil {
+			return err
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+
+		content, err = json.Marshal(v)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(func(pipe redis.Pipeliner) error {
+			return pipe.Set(key, content, 0).Err()
+		})
+
+		return err
+	}, key)
+}
+
+func (r *Redis) setJSON(key string, v interface{}) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return r.client.Set(key, content, 0).Err()
+}
+
+func (r *Redis) getString(key string) (string, error) {
+	val, err := r.client.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+
+	return val, err
+}
+
+func parseIPs(vals []string) []net.IP {
+	sort.Strings(vals)
+
+	ips := []net.IP{}
+	for _, val := range vals {
+		if ip := net.ParseIP(val); ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+// SetA overwrites or sets the A record for the entry, replacing any addresses
+// already registered.
+func (r *Redis) SetA(host string, ip net.IP) error {
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(r.key(redisA, host), r.key(redisATTL, host), r.key(redisAMeta, host))
+		pipe.SAdd(r.key(redisA, host), ip.String())
+		return nil
+	})
+
+	return err
+}
+
+// ImportA sets the A record for each entry, as SetA does, in one MULTI/EXEC
+// round trip.
+func (r *Redis) ImportA(records map[string]net.IP) error {
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		for host, ip := range records {
+			pipe.Del(r.key(redisA, host), r.key(redisATTL, host), r.key(redisAMeta, host))
+			pipe.SAdd(r.key(redisA, host), ip.String())
+		}
+		return nil
+	})
+
+	return err
+}
+
+// AddA appends an address to the A records for the entry.
+func (r *Redis) AddA(host string, ip net.IP) error {
+	return r.client.SAdd(r.key(redisA, host), ip.String()).Err()
+}
+
+// DeleteA deletes an A record for a host. If any ips are provided, only those
+// addresses are removed; otherwise the whole entry is.
+func (r *Redis) DeleteA(host string, ips ...net.IP) error {
+	if len(ips) == 0 {
+		return r.client.Del(r.key(redisA, host), r.key(redisATTL, host), r.key(redisAMeta, host)).Err()
+	}
+
+	members := []interface{}{}
+	for _, ip := range ips {
+		members = append(members, ip.String())
+	}
+
+	if err := r.client.SRem(r.key(redisA, host), members...).Err(); err != nil {
+		return err
+	}
+
+	// removing the last member removes the set; take the TTL and metadata
+	// with it.
+	n, err := r.client.Exists(r.key(redisA, host)).Result()
+	if err != nil || n != 0 {
+		return err
+	}
+
+	return r.client.Del(r.key(redisATTL, host), r.key(redisAMeta, host)).Err()
+}
+
+// SetATTL sets the TTL override for a host's A records. A TTL of 0 removes the
+// override.
+func (r *Redis) SetATTL(host string, ttl uint32) error {
+	n, err := r.client.Exists(r.key(redisA, host)).Result()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	if ttl == 0 {
+		return r.client.Del(r.key(redisATTL, host)).Err()
+	}
+
+	return r.client.Set(r.key(redisATTL, host), ttl, 0).Err()
+}
+
+// GetATTL retrieves the TTL override for a host's A records, or 0 if there is
+// none.
+func (r *Redis) GetATTL(fqdn string) (uint32, error) {
+	n, err := r.client.Exists(r.key(redisA, fqdn)).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+
+	val, err := r.getString(r.key(redisATTL, fqdn))
+	if err == ErrNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	ttl, err := strconv.ParseUint(val, 10, 32)
+	return uint32(ttl), err
+}
+
+// SetAMeta replaces the metadata of a host's A records, which is stored as a
+// hash. A nil or empty meta removes it.
+func (r *Redis) SetAMeta(host string, meta map[string]string) error {
+	n, err := r.client.Exists(r.key(redisA, host)).Result()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	fields := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		fields[k] = v
+	}
+
+	_, err = r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(r.key(redisAMeta, host))
+		if len(fields) != 0 {
+			pipe.HMSet(r.key(redisAMeta, host), fields)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// GetAMeta retrieves the metadata of a host's A records, or nil if there is
+// none.
+func (r *Redis) GetAMeta(fqdn string) (map[string]string, error) {
+	n, err := r.client.Exists(r.key(redisA, fqdn)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if n == 0 {
+		return nil, ErrNotFound
+	}
+
+	meta, err := r.client.HGetAll(r.key(redisAMeta, fqdn)).Result()
+	if err != nil || len(meta) == 0 {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// GetA retrieves the A records by FQDN.
+func (r *Redis) GetA(fqdn string) ([]net.IP, error) {
+	vals, err := r.client.SMembers(r.key(redisA, fqdn)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(vals) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return parseIPs(vals), nil
+}
+
+// ListA lists all the A records in the database.
+func (r *Redis) ListA() (ARecords, error) {
+	tmp := ARecords{}
+
+	err := r.scan(redisA, func(key, name string) error {
+		vals, err := r.client.SMembers(key).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(vals) != 0 {
+			tmp[name] = parseIPs(vals)
+		}
+
+		return nil
+	})
+
+	return tmp, err
+}
+
+// ForEachA calls fn for each address of each host until fn returns false.
+// Hosts are fetched one at a time as the keyspace is scanned.
+func (r *Redis) ForEachA(fn func(string, net.IP) bool) error {
+	err := r.scan(redisA, func(key, name string) error {
+		vals, err := r.client.SMembers(key).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, ip := range parseIPs(vals) {
+			if !fn(name, ip) {
+				return errStop
+			}
+		}
+
+		return nil
+	})
+
+	if err == errStop {
+		return nil
+	}
+
+	return err
+}
+
+// CountA returns the number of hosts with A records. The keyspace is scanned,
+// but no records are fetched.
+func (r *Redis) CountA() (int, error) {
+	return r.count(redisA)
+}
+
+// SetAAAA overwrites or sets the AAAA record for the entry.
+func (r *Redis) SetAAAA(host string, ip net.IP) error {
+	return r.client.Set(r.key(redisAAAA, host), ip.String(), 0).Err()
+}
+
+// GetAAAA retrieves an AAAA record by FQDN.
+func (r *Redis) GetAAAA(fqdn string) (net.IP, error) {
+	val, err := r.getString(r.key(redisAAAA, fqdn))
+	if err != nil {
+		return nil, err
+	}
+
+	return net.ParseIP(val), nil
+}
+
+// DeleteAAAA deletes an AAAA record for a host.
+func (r *Redis) DeleteAAAA(host string) error {
+	return r.client.Del(r.key(redisAAAA, host)).Err()
+}
+
+// ListAAAA lists all the AAAA records in the database.
+func (r *Redis) ListAAAA() (AAAARecords, error) {
+	tmp := AAAARecords{}
+
+	err := r.scan(redisAAAA, func(key, name string) error {
+		val, err := r.getString(key)
+		if err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		tmp[name] = net.ParseIP(val)
+		return nil
+	})
+
+	return tmp, err
+}
+
+// DeleteByIP removes ip from every host's A and AAAA records, returning the
+// number of records removed. The keyspace is scanned, so this is not atomic.
+func (r *Redis) DeleteByIP(ip net.IP) (int, error) {
+	var n int
+
+	err := r.scan(redisA, func(key, name string) error {
+		removed, err := r.client.SRem(key, ip.String()).Result()
+		if err != nil || removed == 0 {
+			return err
+		}
+		n++
+
+		// removing the last member removes the set; take the TTL and
+		// metadata with it.
+		exists, err := r.client.Exists(key).Result()
+		if err != nil || exists != 0 {
+			return err
+		}
+
+		return r.client.Del(r.key(redisATTL, name), r.key(redisAMeta, name)).Err()
+	})
+	if err != nil {
+		return n, err
+	}
+
+	err = r.scan(redisAAAA, func(key, name string) error {
+		val, err := r.getString(key)
+		if err == ErrNotFound || (err == nil && !net.ParseIP(val).Equal(ip)) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		n++
+
+		return r.client.Del(key).Err()
+	})
+
+	return n, err
+}
+
+// SetCNAME overwrites or sets the CNAME record for the alias.
+func (r *Redis) SetCNAME(alias, target string) error {
+	return r.client.Set(r.key(redisCNAME, alias), target, 0).Err()
+}
+
+// GetCNAME retrieves the target of a CNAME record by alias.
+func (r *Redis) GetCNAME(alias string) (string, error) {
+	return r.getString(r.key(redisCNAME, alias))
+}
+
+// DeleteCNAME deletes a CNAME record for an alias.
+func (r *Redis) DeleteCNAME(alias string) error {
+	return r.client.Del(r.key(redisCNAME, alias)).Err()
+}
+
+// SetDNAME overwrites or sets the DNAME record for the owner.
+func (r *Redis) SetDNAME(owner, target string) error {
+	return r.client.Set(r.key(redisDNAME, owner), target, 0).Err()
+}
+
+// GetDNAME retrieves the target of a DNAME record by owner.
+func (r *Redis) GetDNAME(owner string) (string, error) {
+	return r.getString(r.key(redisDNAME, owner))
+}
+
+// DeleteDNAME deletes a DNAME record for an owner.
+func (r *Redis) DeleteDNAME(owner string) error {
+	return r.client.Del(r.key(redisDNAME, owner)).Err()
+}
+
+// SetTXT overwrites or sets the TXT records for the entry.
+func (r *Redis) SetTXT(host string, values []string) error {
+	return r.setJSON(r.key(redisTXT, host), values)
+}
+
+// GetTXT retrieves the TXT records by FQDN.
+func (r *Redis) GetTXT(fqdn string) ([]string, error) {
+	values := []string{}
+	err := r.getJSON(r.key(redisTXT, fqdn), &values)
+	return values, err
+}
+
+// DeleteTXT deletes the TXT records for a host.
+func (r *Redis) DeleteTXT(host string) error {
+	return r.client.Del(r.key(redisTXT, host)).Err()
+}
+
+// SetMX adds a MX record for the entry. If the mail exchange is already
+// registered for the entry, its preference is updated instead.
+func (r *Redis) SetMX(host string, mx *MXRecord) error {
+	recs := []*MXRecord{}
+
+	return r.updateJSON(r.key(redisMX, host), &recs, func() error {
+		for i, existing := range recs {
+			if existing.Mail == mx.Mail {
+				recs[i] = mx
+				return nil
+			}
+		}
+
+		recs = append(recs, mx)
+		return nil
+	})
+}
+
+// GetMX retrieves the MX records by FQDN.
+func (r *Redis) GetMX(fqdn string) ([]*MXRecord, error) {
+	recs := []*MXRecord{}
+	err := r.getJSON(r.key(redisMX, fqdn), &recs)
+	return recs, err
+}
+
+// DeleteMX deletes the MX records for a host.
+func (r *Redis) DeleteMX(host string) error {
+	return r.client.Del(r.key(redisMX, host)).Err()
+}
+
+// SetCAA adds a CAA record for the entry. If the tag and value are already
+// registered for the entry, its flag is updated instead.
+func (r *Redis) SetCAA(host string, caa *CAARecord) error {
+	recs := []*CAARecord{}
+
+	return r.updateJSON(r.key(redisCAA, host), &recs, func() error {
+		for i, existing := range recs {
+			if existing.Tag == caa.Tag && existing.Value == caa.Value {
+				recs[i] = caa
+				return nil
+			}
+		}
+
+		recs = append(recs, caa)
+		return nil
+	})
+}
+
+// GetCAA retrieves the CAA records by FQDN.
+func (r *Redis) GetCAA(fqdn string) ([]*CAARecord, error) {
+	recs := []*CAARecord{}
+	err := r.getJSON(r.key(redisCAA, fqdn), &recs)
+	return recs, err
+}
+
+// DeleteCAA deletes the CAA records for a host.
+func (r *Redis) DeleteCAA(host string) error {
+	return r.client.Del(r.key(redisCAA, host)).Err()
+}
+
+// SetNS overwrites or sets the NS records for the entry.
+func (r *Redis) SetNS(host string, nameservers []string) error {
+	return r.setJSON(r.key(redisNS, host), nameservers)
+}
+
+// GetNS retrieves the NS records by FQDN.
+func (r *Redis) GetNS(fqdn string) ([]string, error) {
+	var nameservers []string
+	err := r.getJSON(r.key(redisNS, fqdn), &nameservers)
+	return nameservers, err
+}
+
+// DeleteNS deletes the NS records for a host.
+func (r *Redis) DeleteNS(host string) error {
+	return r.client.Del(r.key(redisNS, host)).Err()
+}
+
+// SetPTR overwrites or sets the PTR record for a reverse (arpa) name.
+func (r *Redis) SetPTR(arpa, host string) error {
+	return r.client.Set(r.key(redisPTR, arpa), host, 0).Err()
+}
+
+// GetPTR retrieves the host a reverse (arpa) name points at.
+func (r *Redis) GetPTR(arpa string) (string, error) {
+	return r.getString(r.key(redisPTR, arpa))
+}
+
+// DeletePTR deletes the PTR record for a reverse (arpa) name.
+func (r *Redis) DeletePTR(arpa string) error {
+	return r.client.Del(r.key(redisPTR, arpa)).Err()
+}
+
+// SetSRV sets a srv record with service and protocol pointing at a name and
+// port, replacing any targets already registered.
+func (r *Redis) SetSRV(spec string, srv *SRVRecord) error {
+	return r.setJSON(r.key(redisSRV, spec), []*SRVRecord{srv})
+}
+
+// ImportSRV sets the SRV record for each spec, as SetSRV does, in one
+// MULTI/EXEC round trip.
+func (r *Redis) ImportSRV(records map[string]*SRVRecord) error {
+	_, err := r.client.TxPipelined(func(pipe redis.Pipeliner) error {
+		for spec, srv := range records {
+			content, err := json.Marshal([]*SRVRecord{srv})
+			if err != nil {
+				return err
+			}
+
+			pipe.Set(r.key(redisSRV, spec), content, 0)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// AddSRV adds a target to a srv record. If a target with the same host and
+// port is already registered, it is replaced.
+func (r *Redis) AddSRV(spec string, srv *SRVRecord) error {
+	recs := []*SRVRecord{}
+
+	return r.updateJSON(r.key(redisSRV, spec), &recs, func() error {
+		for i, existing := range recs {
+			if existing.Host == srv.Host && existing.Port == srv.Port {
+				recs[i] = srv
+				return nil
+			}
+		}
+
+		recs = append(recs, srv)
+		return nil
+	})
+}
+
+// GetSRV gets a service's targets based on a name.
+func (r *Redis) GetSRV(spec string) ([]*SRVRecord, error) {
+	recs := []*SRVRecord{}
+	err := r.getJSON(r.key(redisSRV, spec), &recs)
+	return recs, err
+}
+
+// ListSRV lists all SRV records in the database.
+func (r *Redis) ListSRV() (SRVRecords, error) {
+	tmp := SRVRecords{}
+
+	err := r.scan(redisSRV, func(key, name string) error {
+		recs := []*SRVRecord{}
+
+		if err := r.getJSON(key, &recs); err == ErrNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		tmp[name] = recs
+		return nil
+	})
+
+	return tmp, err
+}
+
+// CountSRV returns the number of services with SRV records, as CountA does
+// for hosts.
+func (r *Redis) CountSRV() (int, error) {
+	return r.count(redisSRV)
+}
+
+// DeleteSRV deletes a SRV record based on the service and protocol.
+func (r *Redis) DeleteSRV(spec string) error {
+	return r.client.Del(r.key(redisSRV, spec)).Err()
+}
+
+// SetRaw adds a raw record for the name. A record already held which differs
+// only in its TTL is replaced instead.
+func (r *Redis) SetRaw(name string, rr dns.RR) error {
+	lines := []string{}
+
+	return r.updateJSON(r.key(redisRaw, name), &lines, func() error {
+		rrs, err := decodeRaw(lines)
+		if err != nil {
+			return err
+		}
+
+		lines = encodeRaw(addRaw(rrs, rr))
+		return nil
+	})
+}
+
+// GetRaw retrieves the raw records of a type by name; dns.TypeANY retrieves
+// those of every type.
+func (r *Redis) GetRaw(name string, qtype uint16) ([]dns.RR, error) {
+	lines := []string{}
+	if err := r.getJSON(r.key(redisRaw, name), &lines); err != nil {
+		return nil, err
+	}
+
+	rrs, err := decodeRaw(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	if rrs = rawOfType(rrs, qtype); len(rrs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return rrs, nil
+}
+
+// DeleteRaw deletes the raw records of a type for a name; dns.TypeANY deletes
+// those of every type.
+func (r *Redis) DeleteRaw(name string, qtype uint16) error {
+	if qtype == dns.TypeANY {
+		return r.client.Del(r.key(redisRaw, name)).Err()
+	}
+
+	lines := []string{}
+
+	return r.updateJSON(r.key(redisRaw, name), &lines, func() error {
+		rrs, err := decodeRaw(lines)
+		if err != nil {
+			return err
+		}
+
+		kept := []dns.RR{}
+		for _, rr := range rrs {
+			if rr.Header().Rrtype != qtype {
+				kept = append(kept, rr)
+			}
+		}
+
+		lines = encodeRaw(kept)
+		return nil
+	})
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// The Redis tests only run when REDIS_ADDR points at a server. Keys are
+// created under a unique prefix and removed afterwards.
+func TestRedis(t *testing.T) {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+
+	prefix := fmt.Sprintf("dnsserver-test-%d:", time.Now().UnixNano())
+
+	r, err := NewRedis(addr, WithRedisPrefix(prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		keys, _ := r.client.Keys(prefix + "*").Result()
+		if len(keys) > 0 {
+			r.client.Del(keys...)
+		}
+		r.Close()
+	}()
+
+	testDB(t, r)
+}
@@ -0,0 +1,1089 @@
+package db
+
+import (
+	"database/sql"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	// register the sqlite3 driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var sqliteSchema = []string{
+	`create table if not exists a_records (fqdn text not null, ip blob not null, primary key (fqdn, ip))`,
+	`create table if not exists a_ttls (fqdn text primary key, ttl integer not null)`,
+	`create table if not exists a_meta (fqdn text not null, key text not null, value text not null, primary key (fqdn, key))`,
+	`create table if not exists aaaa_records (fqdn text primary key, ip blob not null)`,
+	`create table if not exists cname_records (alias text primary key, target text not null)`,
+	`create table if not exists dname_records (owner text primary key, target text not null)`,
+	`create table if not exists txt_records (fqdn text not null, position integer not null, value text not null, primary key (fqdn, position))`,
+	`create table if not exists mx_records (fqdn text not null, mail text not null, preference integer not null, primary key (fqdn, mail))`,
+	`create table if not exists caa_records (fqdn text not null, tag text not null, value text not null, flag integer not null, primary key (fqdn, tag, value))`,
+	`create table if not exists ns_records (fqdn text not null, position integer not null, host text not null, primary key (fqdn, position))`,
+	`create table if not exists ptr_records (arpa text primary key, host text not null)`,
+	`create table if not exists srv_records (spec text not null, port integer not null, host text not null, priority integer not null, weight integer not null, ttl integer not null, primary key (spec, host, port))`,
+	`create table if not exists raw_records (fqdn text not null, type integer not null, rdata text not null, record text not null, primary key (fqdn, rdata))`,
+}
+
+// statement names, see sqliteStatements.
+const (
+	sqlInsertA = iota
+	sqlDeleteA
+	sqlDeleteAIP
+	sqlExistsA
+	sqlGetA
+	sqlListA
+	sqlPrefixA
+	sqlCountA
+	sqlSetATTL
+	sqlDeleteATTL
+	sqlGetATTL
+	sqlInsertAMeta
+	sqlDeleteAMeta
+	sqlGetAMeta
+	sqlSetAAAA
+	sqlGetAAAA
+	sqlDeleteAAAA
+	sqlListAAAA
+	sqlPurgeA
+	sqlPurgeATTL
+	sqlPurgeAMeta
+	sqlPurgeAAAA
+	sqlClearA
+	sqlClearATTL
+	sqlClearAMeta
+	sqlClearSRV
+	sqlSetCNAME
+	sqlGetCNAME
+	sqlDeleteCNAME
+	sqlSetDNAME
+	sqlGetDNAME
+	sqlDeleteDNAME
+	sqlInsertTXT
+	sqlGetTXT
+	sqlDeleteTXT
+	sqlSetMX
+	sqlGetMX
+	sqlDeleteMX
+	sqlSetCAA
+	sqlGetCAA
+	sqlDeleteCAA
+	sqlInsertNS
+	sqlGetNS
+	sqlDeleteNS
+	sqlSetPTR
+	sqlGetPTR
+	sqlDeletePTR
+	sqlSetSRV
+	sqlGetSRV
+	sqlListSRV
+	sqlPrefixSRV
+	sqlCountSRV
+	sqlDeleteSRV
+	sqlSetRaw
+	sqlGetRaw
+	sqlDeleteRaw
+)
+
+var sqliteStatements = map[int]string{
+	sqlInsertA:     `insert or ignore into a_records (fqdn, ip) values (?, ?)`,
+	sqlDeleteA:     `delete from a_records where fqdn = ?`,
+	sqlDeleteAIP:   `delete from a_records where fqdn = ? and ip = ?`,
+	sqlExistsA:     `select count(*) from a_records where fqdn = ?`,
+	sqlGetA:        `select ip from a_records where fqdn = ? order by rowid`,
+	sqlListA:       `select fqdn, ip from a_records order by rowid`,
+	sqlPrefixA:     `select fqdn, ip from a_records where fqdn = ? or fqdn like ? escape '\' order by rowid`,
+	sqlCountA:      `select count(distinct fqdn) from a_records`,
+	sqlSetATTL:     `insert or replace into a_ttls (fqdn, ttl) values (?, ?)`,
+	sqlDeleteATTL:  `delete from a_ttls where fqdn = ?`,
+	sqlGetATTL:     `select ttl from a_ttls where fqdn = ?`,
+	sqlInsertAMeta: `insert into a_meta (fqdn, key, value) values (?, ?, ?)`,
+	sqlDeleteAMeta: `delete from a_meta where fqdn = ?`,
+	sqlGetAMeta:    `select key, value from a_meta where fqdn = ?`,
+	sqlSetAAAA:     `insert or replace into aaaa_records (fqdn, ip) values (?, ?)`,
+	sqlGetAAAA:     `select ip from aaaa_records where fqdn = ?`,
+	sqlDeleteAAAA:  `delete from aaaa_records where fqdn = ?`,
+	sqlListAAAA:    `select fqdn, ip from aaaa_records`,
+	sqlPurgeA:      `delete from a_records where ip = ?`,
+	sqlPurgeATTL:   `delete from a_ttls where fqdn not in (select fqdn from a_records)`,
+	sqlPurgeAMeta:  `delete from a_meta where fqdn not in (select fqdn from a_records)`,
+	sqlPurgeAAAA:   `delete from aaaa_records where ip = ?`,
+	sqlClearA:      `delete from a_records`,
+	sqlClearATTL:   `delete from a_ttls`,
+	sqlClearAMeta:  `delete from a_meta`,
+	sqlClearSRV:    `delete from srv_records`,
+	sqlSetCNAME:    `insert or replace into cname_records (alias, target) values (?, ?)`,
+	sqlGetCNAME:    `select target from cname_records where alias = ?`,
+	sqlDeleteCNAME: `delete from cname_records where alias = ?`,
+	sqlSetDNAME:    `insert or replace into dname_records (owner, target) values (?, ?)`,
+	sqlGetDNAME:    `select target from dname_records where owner = ?`,
+	sqlDeleteDNAME: `delete from dname_records where owner = ?`,
+	sqlInsertTXT:   `insert into txt_records (fqdn, position, value) values (?, ?, ?)`,
+	sqlGetTXT:      `select value from txt_records where fqdn = ? order by position`,
+	sqlDeleteTXT:   `delete from txt_records where fqdn = ?`,
+	sqlSetMX:       `insert into mx_records (fqdn, mail, preference) values (?, ?, ?) on conflict (fqdn, mail) do update set preference = excluded.preference`,
+	sqlGetMX:       `select preference, mail from mx_records where fqdn = ? order by rowid`,
+	sqlDeleteMX:    `delete from mx_records where fqdn = ?`,
+	sqlSetCAA:      `insert into caa_records (fqdn, tag, value, flag) values (?, ?, ?, ?) on conflict (fqdn, tag, value) do update set flag = excluded.flag`,
+	sqlGetCAA:      `select flag, tag, value from caa_records where fqdn = ? order by rowid`,
+	sqlDeleteCAA:   `delete from caa_records where fqdn = ?`,
+	sqlInsertNS:    `insert into ns_records (fqdn, position, host) values (?, ?, ?)`,
+	sqlGetNS:       `select host from ns_records where fqdn = ? order by position`,
+	sqlDeleteNS:    `delete from ns_records where fqdn = ?`,
+	sqlSetPTR:      `insert or replace into ptr_records (arpa, host) values (?, ?)`,
+	sqlGetPTR:      `select host from ptr_records where arpa = ?`,
+	sqlDeletePTR:   `delete from ptr_records where arpa = ?`,
+	sqlSetSRV:      `insert into srv_records (spec, port, host, priority, weight, ttl) values (?, ?, ?, ?, ?, ?) on conflict (spec, host, port) do update set priority = excluded.priority, weight = excluded.weight, ttl = excluded.ttl`,
+	sqlGetSRV:      `select priority, weight, port, host, ttl from srv_records where spec = ? order by rowid`,
+	sqlListSRV:     `select spec, priority, weight, port, host, ttl from srv_records order by rowid`,
+	sqlPrefixSRV:   `select spec, priority, weight, port, host, ttl from srv_records where spec = ? or spec like ? escape '\' order by rowid`,
+	sqlCountSRV:    `select count(distinct spec) from srv_records`,
+	sqlDeleteSRV:   `delete from srv_records where spec = ?`,
+	sqlSetRaw:      `insert into raw_records (fqdn, type, rdata, record) values (?, ?, ?, ?) on conflict (fqdn, rdata) do update set record = excluded.record`,
+	sqlGetRaw:      `select record from raw_records where fqdn = ? and (type = ? or ? = 255) order by rowid`,
+	sqlDeleteRaw:   `delete from raw_records where fqdn = ? and (type = ? or ? = 255)`,
+}
+
+// SQLite is a DB stored in a SQLite database. Every table is created on open
+// if it does not already exist, and all queries are prepared up front.
+//
+// Multiple addresses per host and targets per service mean a_records and
+// srv_records are keyed by the name and value together, rather than the name
+// alone.
+type SQLite struct {
+	db    *sql.DB
+	stmts map[int]*sql.Stmt
+}
+
+// NewSQLite opens the SQLite database at dsn, e.g. a file path or ":memory:".
+func NewSQLite(dsn string) (*SQLite, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite serializes writers anyway, and each connection to ":memory:" would
+	// otherwise see its own empty database.
+	db.SetMaxOpenConns(1)
+
+	s := &SQLite{db: db, stmts: map[int]*sql.Stmt{}}
+
+	for _, schema := range sqliteSchema {
+		if _, err := db.Exec(schema); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	for name, query := range sqliteStatements {
+		stmt, err := db.Prepare(query)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+
+		s.stmts[name] = stmt
+	}
+
+	return s, nil
+}
+
+// Close closes the database.
+func (s *SQLite) Close() error {
+	for _, stmt := range s.stmts {
+		stmt.Close()
+	}
+
+	return s.db.Close()
+}
+
+// tx runs fn in a transaction, committing if it returns nil.
+func (s *SQLite) tx(fn func(stmt func(int) *sql.Stmt) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(func(name int) *sql.Stmt { return tx.Stmt(s.stmts[name]) }); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLite) exec(name int, args ...interface{}) error {
+	_, err := s.stmts[name].Exec(args...)
+	return err
+}
+
+// queryRow scans a single row into dest, returning ErrNotFound if there is
+// none.
+func (s *SQLite) queryRow(name int, args []interface{}, dest ...interface{}) error {
+	err := s.stmts[name].QueryRow(args...).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func scanIPs(rows *sql.Rows) ([]net.IP, error) {
+	defer rows.Close()
+
+	ips := []net.IP{}
+
+	for rows.Next() {
+		var ip []byte
+		if err := rows.Scan(&ip); err != nil {
+			return nil, err
+		}
+
+		ips = append(ips, net.IP(ip))
+	}
+
+	return ips, rows.Err()
+}
+
+// SetA overwrites or sets the A record for the entry, replacing any addresses
+// already registered.
+func (s *SQLite) SetA(host string, ip net.IP) error {
+	host = canonical(host)
+
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		if _, err := stmt(sqlDeleteA).Exec(host); err != nil {
+			return err
+		}
+
+		if _, err := stmt(sqlDeleteATTL).Exec(host); err != nil {
+			return err
+		}
+
+		if _, err := stmt(sqlDeleteAMeta).Exec(host); err != nil {
+			return err
+		}
+
+		_, err := stmt(sqlInsertA).Exec(host, []byte(ip.To16()))
+		return err
+	})
+}
+
+// ImportA sets the A record for each entry, as SetA does, in one transaction.
+func (s *SQLite) ImportA(records map[string]net.IP) error {
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		deleteA, deleteTTL, deleteMeta, insert := stmt(sqlDeleteA), stmt(sqlDeleteATTL), stmt(sqlDeleteAMeta), stmt(sqlInsertA)
+
+		for host, ip := range records {
+			host = canonical(host)
+
+			if _, err := deleteA.Exec(host); err != nil {
+				return err
+			}
+
+			if _, err := deleteTTL.Exec(host); err != nil {
+				return err
+			}
+
+			if _, err := deleteMeta.Exec(host); err != nil {
+				return err
+			}
+
+			if _, err := insert.Exec(host, []byte(ip.To16())); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// AddA appends an address to the A records for the entry. Adding an address
+// that is already registered is a no-op.
+func (s *SQLite) AddA(host string, ip net.IP) error {
+	return s.exec(sqlInsertA, canonical(host), []byte(ip.To16()))
+}
+
+// DeleteA deletes an A record for a host. If any ips are provided, only those
+// addresses are removed; otherwise the whole entry is.
+func (s *SQLite) DeleteA(host string, ips ...net.IP) error {
+	host = canonical(host)
+
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		if len(ips) == 0 {
+			if _, err := stmt(sqlDeleteA).Exec(host); err != nil {
+				return err
+			}
+		}
+
+		for _, ip := range ips {
+			if _, err := stmt(sqlDeleteAIP).Exec(host, []byte(ip.To16())); err != nil {
+				return err
+			}
+		}
+
+		var count int
+		if err := stmt(sqlExistsA).QueryRow(host).Scan(&count); err != nil {
+			return err
+		}
+
+		if count == 0 {
+			if _, err := stmt(sqlDeleteATTL).Exec(host); err != nil {
+				return err
+			}
+
+			_, err := stmt(sqlDeleteAMeta).Exec(host)
+			return err
+		}
+
+		return nil
+	})
+}
+
+// SetATTL sets the TTL override for a host's A records. A TTL of 0 removes the
+// override.
+func (s *SQLite) SetATTL(host string, ttl uint32) error {
+	host = canonical(host)
+
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		var count int
+		if err := stmt(sqlExistsA).QueryRow(host).Scan(&count); err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		if ttl == 0 {
+			_, err := stmt(sqlDeleteATTL).Exec(host)
+			return err
+		}
+
+		_, err := stmt(sqlSetATTL).Exec(host, ttl)
+		return err
+	})
+}
+
+// GetATTL retrieves the TTL override for a host's A records, or 0 if there is
+// none.
+func (s *SQLite) GetATTL(fqdn string) (uint32, error) {
+	fqdn = canonical(fqdn)
+
+	var count int
+	if err := s.queryRow(sqlExistsA, []interface{}{fqdn}, &count); err != nil {
+		return 0, err
+	}
+
+	if count == 0 {
+		return 0, ErrNotFound
+	}
+
+	var ttl uint32
+	if err := s.queryRow(sqlGetATTL, []interface{}{fqdn}, &ttl); err != nil && err != ErrNotFound {
+		return 0, err
+	}
+
+	return ttl, nil
+}
+
+// SetAMeta replaces the metadata of a host's A records. A nil or empty meta
+// removes it.
+func (s *SQLite) SetAMeta(host string, meta map[string]string) error {
+	host = canonical(host)
+
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		var count int
+		if err := stmt(sqlExistsA).QueryRow(host).Scan(&count); err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		if _, err := stmt(sqlDeleteAMeta).Exec(host); err != nil {
+			return err
+		}
+
+		insert := stmt(sqlInsertAMeta)
+		for k, v := range meta {
+			if _, err := insert.Exec(host, k, v); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetAMeta retrieves the metadata of a host's A records, or nil if there is
+// none.
+func (s *SQLite) GetAMeta(fqdn string) (map[string]string, error) {
+	fqdn = canonical(fqdn)
+
+	var count int
+	if err := s.queryRow(sqlExistsA, []interface{}{fqdn}, &count); err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.stmts[sqlGetAMeta].Query(fqdn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var meta map[string]string
+
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		meta[k] = v
+	}
+
+	return meta, rows.Err()
+}
+
+// GetA retrieves the A records by FQDN.
+func (s *SQLite) GetA(fqdn string) ([]net.IP, error) {
+	rows, err := s.stmts[sqlGetA].Query(canonical(fqdn))
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := scanIPs(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ips) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return ips, nil
+}
+
+// ListA lists all the A records in the database.
+func (s *SQLite) ListA() (ARecords, error) {
+	return s.listA(sqlListA)
+}
+
+// ListAByPrefix is like ListA, but only lists the hosts at or below prefix.
+func (s *SQLite) ListAByPrefix(prefix string) (ARecords, error) {
+	if prefix == "" {
+		return s.ListA()
+	}
+
+	prefix = canonical(prefix)
+	return s.listA(sqlPrefixA, prefix, likeBelow(prefix))
+}
+
+func (s *SQLite) listA(stmt int, args ...interface{}) (ARecords, error) {
+	rows, err := s.stmts[stmt].Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tmp := ARecords{}
+
+	for rows.Next() {
+		var (
+			fqdn string
+			ip   []byte
+		)
+
+		if err := rows.Scan(&fqdn, &ip); err != nil {
+			return nil, err
+		}
+
+		tmp[fqdn] = append(tmp[fqdn], net.IP(ip))
+	}
+
+	return tmp, rows.Err()
+}
+
+// ForEachA calls fn for each address of each host as the rows are read, until
+// fn returns false. fn must not change the database.
+func (s *SQLite) ForEachA(fn func(string, net.IP) bool) error {
+	rows, err := s.stmts[sqlListA].Query()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			fqdn string
+			ip   []byte
+		)
+
+		if err := rows.Scan(&fqdn, &ip); err != nil {
+			return err
+		}
+
+		if !fn(fqdn, net.IP(ip)) {
+			return nil
+		}
+	}
+
+	return rows.Err()
+}
+
+// CountA returns the number of hosts with A records.
+func (s *SQLite) CountA() (int, error) {
+	var n int
+	if err := s.queryRow(sqlCountA, nil, &n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// SetAAAA overwrites or sets the AAAA record for the entry.
+func (s *SQLite) SetAAAA(host string, ip net.IP) error {
+	return s.exec(sqlSetAAAA, canonical(host), []byte(ip.To16()))
+}
+
+// GetAAAA retrieves an AAAA record by FQDN.
+func (s *SQLite) GetAAAA(fqdn string) (net.IP, error) {
+	var ip []byte
+	if err := s.queryRow(sqlGetAAAA, []interface{}{canonical(fqdn)}, &ip); err != nil {
+		return nil, err
+	}
+
+	return net.IP(ip), nil
+}
+
+// DeleteAAAA deletes an AAAA record for a host.
+func (s *SQLite) DeleteAAAA(host string) error {
+	return s.exec(sqlDeleteAAAA, canonical(host))
+}
+
+// ListAAAA lists all the AAAA records in the database.
+func (s *SQLite) ListAAAA() (AAAARecords, error) {
+	rows, err := s.stmts[sqlListAAAA].Query()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tmp := AAAARecords{}
+
+	for rows.Next() {
+		var (
+			fqdn string
+			ip   []byte
+		)
+
+		if err := rows.Scan(&fqdn, &ip); err != nil {
+			return nil, err
+		}
+
+		tmp[fqdn] = net.IP(ip)
+	}
+
+	return tmp, rows.Err()
+}
+
+// DeleteByIP removes ip from every host's A and AAAA records in one
+// transaction, returning the number of records removed.
+func (s *SQLite) DeleteByIP(ip net.IP) (int, error) {
+	var n int
+
+	err := s.tx(func(stmt func(int) *sql.Stmt) error {
+		n = 0
+
+		for _, name := range []int{sqlPurgeA, sqlPurgeAAAA} {
+			res, err := stmt(name).Exec([]byte(ip.To16()))
+			if err != nil {
+				return err
+			}
+
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			n += int(affected)
+		}
+
+		if _, err := stmt(sqlPurgeATTL).Exec(); err != nil {
+			return err
+		}
+
+		_, err := stmt(sqlPurgeAMeta).Exec()
+		return err
+	})
+
+	return n, err
+}
+
+// SetCNAME overwrites or sets the CNAME record for the alias.
+func (s *SQLite) SetCNAME(alias, target string) error {
+	return s.exec(sqlSetCNAME, canonical(alias), target)
+}
+
+// GetCNAME retrieves the target of a CNAME record by alias.
+func (s *SQLite) GetCNAME(alias string) (string, error) {
+	var target string
+	err := s.queryRow(sqlGetCNAME, []interface{}{canonical(alias)}, &target)
+	return target, err
+}
+
+// DeleteCNAME deletes a CNAME record for an alias.
+func (s *SQLite) DeleteCNAME(alias string) error {
+	return s.exec(sqlDeleteCNAME, canonical(alias))
+}
+
+// SetDNAME overwrites or sets the DNAME record for the owner.
+func (s *SQLite) SetDNAME(owner, target string) error {
+	return s.exec(sqlSetDNAME, canonical(owner), target)
+}
+
+// GetDNAME retrieves the target of a DNAME record by owner.
+func (s *SQLite) GetDNAME(owner string) (string, error) {
+	var target string
+	err := s.queryRow(sqlGetDNAME, []interface{}{canonical(owner)}, &target)
+	return target, err
+}
+
+// DeleteDNAME deletes a DNAME record for an owner.
+func (s *SQLite) DeleteDNAME(owner string) error {
+	return s.exec(sqlDeleteDNAME, canonical(owner))
+}
+
+// SetTXT overwrites or sets the TXT records for the entry.
+func (s *SQLite) SetTXT(host string, values []string) error {
+	host = canonical(host)
+
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		if _, err := stmt(sqlDeleteTXT).Exec(host); err != nil {
+			return err
+		}
+
+		for i, value := range values {
+			if _, err := stmt(sqlInsertTXT).Exec(host, i, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetTXT retrieves the TXT records by FQDN.
+func (s *SQLite) GetTXT(fqdn string) ([]string, error) {
+	rows, err := s.stmts[sqlGetTXT].Query(canonical(fqdn))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []string{}
+
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(values) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return values, nil
+}
+
+// DeleteTXT deletes the TXT records for a host.
+func (s *SQLite) DeleteTXT(host string) error {
+	return s.exec(sqlDeleteTXT, canonical(host))
+}
+
+// SetMX adds a MX record for the entry. If the mail exchange is already
+// registered for the entry, its preference is updated instead.
+func (s *SQLite) SetMX(host string, mx *MXRecord) error {
+	return s.exec(sqlSetMX, canonical(host), mx.Mail, mx.Preference)
+}
+
+// GetMX retrieves the MX records by FQDN.
+func (s *SQLite) GetMX(fqdn string) ([]*MXRecord, error) {
+	rows, err := s.stmts[sqlGetMX].Query(canonical(fqdn))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recs := []*MXRecord{}
+
+	for rows.Next() {
+		mx := &MXRecord{}
+		if err := rows.Scan(&mx.Preference, &mx.Mail); err != nil {
+			return nil, err
+		}
+
+		recs = append(recs, mx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(recs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return recs, nil
+}
+
+// DeleteMX deletes the MX records for a host.
+func (s *SQLite) DeleteMX(host string) error {
+	return s.exec(sqlDeleteMX, canonical(host))
+}
+
+// SetCAA adds a CAA record for the entry. If the tag and value are already
+// registered for the entry, its flag is updated instead.
+func (s *SQLite) SetCAA(host string, caa *CAARecord) error {
+	return s.exec(sqlSetCAA, canonical(host), caa.Tag, caa.Value, caa.Flag)
+}
+
+// GetCAA retrieves the CAA records by FQDN.
+func (s *SQLite) GetCAA(fqdn string) ([]*CAARecord, error) {
+	rows, err := s.stmts[sqlGetCAA].Query(canonical(fqdn))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recs := []*CAARecord{}
+
+	for rows.Next() {
+		caa := &CAARecord{}
+		if err := rows.Scan(&caa.Flag, &caa.Tag, &caa.Value); err != nil {
+			return nil, err
+		}
+
+		recs = append(recs, caa)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(recs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return recs, nil
+}
+
+// DeleteCAA deletes the CAA records for a host.
+func (s *SQLite) DeleteCAA(host string) error {
+	return s.exec(sqlDeleteCAA, canonical(host))
+}
+
+// SetNS overwrites or sets the NS records for the entry.
+func (s *SQLite) SetNS(host string, nameservers []string) error {
+	host = canonical(host)
+
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		if _, err := stmt(sqlDeleteNS).Exec(host); err != nil {
+			return err
+		}
+
+		for i, nameserver := range nameservers {
+			if _, err := stmt(sqlInsertNS).Exec(host, i, nameserver); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetNS retrieves the NS records by FQDN.
+func (s *SQLite) GetNS(fqdn string) ([]string, error) {
+	rows, err := s.stmts[sqlGetNS].Query(canonical(fqdn))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	nameservers := []string{}
+
+	for rows.Next() {
+		var nameserver string
+		if err := rows.Scan(&nameserver); err != nil {
+			return nil, err
+		}
+
+		nameservers = append(nameservers, nameserver)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(nameservers) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return nameservers, nil
+}
+
+// DeleteNS deletes the NS records for a host.
+func (s *SQLite) DeleteNS(host string) error {
+	return s.exec(sqlDeleteNS, canonical(host))
+}
+
+// SetPTR overwrites or sets the PTR record for a reverse (arpa) name.
+func (s *SQLite) SetPTR(arpa, host string) error {
+	return s.exec(sqlSetPTR, canonical(arpa), host)
+}
+
+// GetPTR retrieves the host a reverse (arpa) name points at.
+func (s *SQLite) GetPTR(arpa string) (string, error) {
+	var host string
+	err := s.queryRow(sqlGetPTR, []interface{}{canonical(arpa)}, &host)
+	return host, err
+}
+
+// DeletePTR deletes the PTR record for a reverse (arpa) name.
+func (s *SQLite) DeletePTR(arpa string) error {
+	return s.exec(sqlDeletePTR, canonical(arpa))
+}
+
+// SetSRV sets a srv record with service and protocol pointing at a name and
+// port, replacing any targets already registered.
+func (s *SQLite) SetSRV(spec string, srv *SRVRecord) error {
+	spec = canonical(spec)
+
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		if _, err := stmt(sqlDeleteSRV).Exec(spec); err != nil {
+			return err
+		}
+
+		_, err := stmt(sqlSetSRV).Exec(spec, srv.Port, srv.Host, srv.Priority, srv.Weight, srv.TTL)
+		return err
+	})
+}
+
+// ImportSRV sets the SRV record for each spec, as SetSRV does, in one
+// transaction.
+func (s *SQLite) ImportSRV(records map[string]*SRVRecord) error {
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		deleteSRV, insert := stmt(sqlDeleteSRV), stmt(sqlSetSRV)
+
+		for spec, srv := range records {
+			spec = canonical(spec)
+
+			if _, err := deleteSRV.Exec(spec); err != nil {
+				return err
+			}
+
+			if _, err := insert.Exec(spec, srv.Port, srv.Host, srv.Priority, srv.Weight, srv.TTL); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Replace swaps in the given A and SRV records, as described by Replacer, in
+// one transaction.
+func (s *SQLite) Replace(a ARecords, ttls map[string]uint32, srv SRVRecords) error {
+	return s.tx(func(stmt func(int) *sql.Stmt) error {
+		for _, name := range []int{sqlClearA, sqlClearATTL, sqlClearAMeta, sqlClearSRV} {
+			if _, err := stmt(name).Exec(); err != nil {
+				return err
+			}
+		}
+
+		insertA, setTTL, insertSRV := stmt(sqlInsertA), stmt(sqlSetATTL), stmt(sqlSetSRV)
+
+		for host, ips := range a {
+			fqdn := canonical(host)
+
+			for _, ip := range ips {
+				if _, err := insertA.Exec(fqdn, []byte(ip.To16())); err != nil {
+					return err
+				}
+			}
+
+			if ttl := ttls[host]; ttl != 0 {
+				if _, err := setTTL.Exec(fqdn, ttl); err != nil {
+					return err
+				}
+			}
+		}
+
+		for spec, targets := range srv {
+			spec = canonical(spec)
+
+			for _, target := range targets {
+				if _, err := insertSRV.Exec(spec, target.Port, target.Host, target.Priority, target.Weight, target.TTL); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// AddSRV adds a target to a srv record. If a target with the same host and
+// port is already registered, it is replaced.
+func (s *SQLite) AddSRV(spec string, srv *SRVRecord) error {
+	return s.exec(sqlSetSRV, canonical(spec), srv.Port, srv.Host, srv.Priority, srv.Weight, srv.TTL)
+}
+
+// GetSRV gets a service's targets based on a name.
+func (s *SQLite) GetSRV(spec string) ([]*SRVRecord, error) {
+	rows, err := s.stmts[sqlGetSRV].Query(canonical(spec))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	recs := []*SRVRecord{}
+
+	for rows.Next() {
+		srv := &SRVRecord{}
+		if err := rows.Scan(&srv.Priority, &srv.Weight, &srv.Port, &srv.Host, &srv.TTL); err != nil {
+			return nil, err
+		}
+
+		recs = append(recs, srv)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(recs) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return recs, nil
+}
+
+// ListSRV lists all SRV records in the database.
+func (s *SQLite) ListSRV() (SRVRecords, error) {
+	return s.listSRV(sqlListSRV)
+}
+
+// ListSRVByPrefix is like ListSRV, but only lists the services at or below
+// prefix.
+func (s *SQLite) ListSRVByPrefix(prefix string) (SRVRecords, error) {
+	if prefix == "" {
+		return s.ListSRV()
+	}
+
+	prefix = canonical(prefix)
+	return s.listSRV(sqlPrefixSRV, prefix, likeBelow(prefix))
+}
+
+func (s *SQLite) listSRV(stmt int, args ...interface{}) (SRVRecords, error) {
+	rows, err := s.stmts[stmt].Query(args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tmp := SRVRecords{}
+
+	for rows.Next() {
+		var spec string
+		srv := &SRVRecord{}
+
+		if err := rows.Scan(&spec, &srv.Priority, &srv.Weight, &srv.Port, &srv.Host, &srv.TTL); err != nil {
+			return nil, err
+		}
+
+		tmp[spec] = append(tmp[spec], srv)
+	}
+
+	return tmp, rows.Err()
+}
+
+// CountSRV returns the number of services with SRV records.
+func (s *SQLite) CountSRV() (int, error) {
+	var n int
+	if err := s.queryRow(sqlCountSRV, nil, &n); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+// DeleteSRV deletes a SRV record based on the service and protocol.
+func (s *SQLite) DeleteSRV(spec string) error {
+	return s.exec(sqlDeleteSRV, canonical(spec))
+}
+
+// SetRaw adds a raw record for the name. A record already held which differs
+// only in its TTL is replaced instead.
+func (s *SQLite) SetRaw(name string, rr dns.RR) error {
+	return s.exec(sqlSetRaw, canonical(name), rr.Header().Rrtype, rawKey(rr), rr.String())
+}
+
+// GetRaw retrieves the raw records of a type by name; dns.TypeANY retrieves
+// those of every type.
+func (s *SQLite) GetRaw(name string, qtype uint16) ([]dns.RR, error) {
+	rows, err := s.stmts[sqlGetRaw].Query(canonical(name), qtype, qtype)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []string{}
+
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return decodeRaw(lines)
+}
+
+// DeleteRaw deletes the raw records of a type for a name; dns.TypeANY deletes
+// those of every type.
+func (s *SQLite) DeleteRaw(name string, qtype uint16) error {
+	return s.exec(sqlDeleteRaw, canonical(name), qtype, qtype)
+}
+
+// likeBelow returns a LIKE pattern matching the names below name. The
+// wildcards LIKE gives meaning to, including the _ of SRV specs, are escaped.
+func likeBelow(name string) string {
+	return "%." + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(name)
+}
@@ -0,0 +1,33 @@
+package db
+
+import "testing"
+
+func TestSQLite(t *testing.T) {
+	s, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	testDB(t, s)
+}
+
+func TestSQLiteListByPrefix(t *testing.T) {
+	s, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	testListByPrefix(t, s)
+}
+
+func TestSQLiteReplace(t *testing.T) {
+	s, err := NewSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	testReplace(t, s)
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"errors"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// ErrReadOnly is returned by every method of a Static DB which would change
+// its records.
+var ErrReadOnly = errors.New("read-only DB")
+
+// Static is a read-only DB of A and SRV records, built once from a snapshot.
+// Since its records never change, lookups take no locks, which makes it
+// cheaper than a Map for immutable deployments. Every method which would
+// change a record returns ErrReadOnly; lookups of other types yield
+// ErrNotFound.
+type Static struct {
+	aRecords   ARecords
+	srvRecords SRVRecords
+}
+
+// NewStatic returns a Static DB serving a copy of the given A records, keyed
+// by host, and SRV records, keyed by spec, e.g. "_http._tcp".
+func NewStatic(a map[string]net.IP, srv map[string]*SRVRecord) *Static {
+	s := &Static{aRecords: ARecords{}, srvRecords: SRVRecords{}}
+
+	for host, ip := range a {
+		s.aRecords[canonical(host)] = []net.IP{append(net.IP(nil), ip...)}
+	}
+
+	for spec, rec := range srv {
+		t := *rec
+		s.srvRecords[canonical(spec)] = []*SRVRecord{&t}
+	}
+
+	return s
+}
+
+// SetA returns ErrReadOnly.
+func (s *Static) SetA(string, net.IP) error { return ErrReadOnly }
+
+// AddA returns ErrReadOnly.
+func (s *Static) AddA(string, net.IP) error { return ErrReadOnly }
+
+// GetA retrieves the IPs by FQDN.
+func (s *Static) GetA(fqdn string) ([]net.IP, error) {
+	ips, ok := s.aRecords[canonical(fqdn)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	return copyIPs(ips), nil
+}
+
+// DeleteA returns ErrReadOnly.
+func (s *Static) DeleteA(string, ...net.IP) error { return ErrReadOnly }
+
+// SetATTL returns ErrReadOnly.
+func (s *Static) SetATTL(string, uint32) error { return ErrReadOnly }
+
+// GetATTL returns 0 for hosts with A records, as a Static DB sets no TTLs.
+func (s *Static) GetATTL(fqdn string) (uint32, error) {
+	if _, ok := s.aRecords[canonical(fqdn)]; !ok {
+		return 0, ErrNotFound
+	}
+
+	return 0, nil
+}
+
+// SetAMeta returns ErrReadOnly.
+func (s *Static) SetAMeta(string, map[string]string) error { return ErrReadOnly }
+
+// GetAMeta returns no metadata for hosts with A records.
+func (s *Static) GetAMeta(fqdn string) (map[string]string, error) {
+	if _, ok := s.aRecords[canonical(fqdn)]; !ok {
+		return nil, ErrNotFound
+	}
+
+	return map[string]string{}, nil
+}
+
+// ListA lists all A records.
+func (s *Static) ListA() (ARecords, error) {
+	tmp := make(ARecords, len(s.aRecords))
+	for host, ips := range s.aRecords {
+		tmp[host] = copyIPs(ips)
+	}
+
+	return tmp, nil
+}
+
+// ForEachA calls fn for each address of each host, without copying the
+// records, until fn returns false. fn must not modify the addresses.
+func (s *Static) ForEachA(fn func(string, net.IP) bool) error {
+	for host, ips := range s.aRecords {
+		for _, ip := range ips {
+			if !fn(host, ip) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// CountA returns the number of hosts with A records.
+func (s *Static) CountA() (int, error) {
+	return len(s.aRecords), nil
+}
+
+// SetAAAA returns ErrReadOnly.
+func (s *Static) SetAAAA(string, net.IP) error { return ErrReadOnly }
+
+// GetAAAA returns ErrNotFound; a Static DB holds no AAAA records.
+func (s *Static) GetAAAA(string) (net.IP, error) { return nil, ErrNotFound }
+
+// DeleteAAAA returns ErrReadOnly.
+func (s *Static) DeleteAAAA(string) error { return ErrReadOnly }
+
+// ListAAAA lists no records; a Static DB holds no AAAA records.
+func (s *Static) ListAAAA() (AAAARecords, error) { return AAAARecords{}, nil }
+
+// DeleteByIP returns ErrReadOnly.
+func (s *Static) DeleteByIP(net.IP) (int, error) { return 0, ErrReadOnly }
+
+// SetCNAME returns ErrReadOnly.
+func (s *Static) SetCNAME(string, string) error { return ErrReadOnly }
+
+// GetCNAME returns ErrNotFound; a Static DB holds no CNAME records.
+func (s *Static) GetCNAME(string) (string, error) { return "", ErrNotFound }
+
+// DeleteCNAME returns ErrReadOnly.
+func (s *Static) DeleteCNAME(string) error { return ErrReadOnly }
+
+// SetDNAME returns ErrReadOnly.
+func (s *Static) SetDNAME(string, string) error { return ErrReadOnly }
+
+// GetDNAME returns ErrNotFound; a Static DB holds no DNAME records.
+func (s *Static) GetDNAME(string) (string, error) { return "", ErrNotFound }
+
+// DeleteDNAME returns ErrReadOnly.
+func (s *Static) DeleteDNAME(string) error { return ErrReadOnly }
+
+// SetTXT returns ErrReadOnly.
+func (s *Static) SetTXT(string, []string) error { return ErrReadOnly }
+
+// GetTXT returns ErrNotFound; a Static DB holds no TXT records.
+func (s *Static) GetTXT(string) ([]string, error) { return nil, ErrNotFound }
+
+// DeleteTXT returns ErrReadOnly.
+func (s *Static) DeleteTXT(string) error { return ErrReadOnly }
+
+// SetMX returns ErrReadOnly.
+func (s *Static) SetMX(string, *MXRecord) error { return ErrReadOnly }
+
+// GetMX returns ErrNotFound; a Static DB holds no MX records.
+func (s *Static) GetMX(string) ([]*MXRecord, error) { return nil, ErrNotFound }
+
+// DeleteMX returns ErrReadOnly.
+func (s *Static) DeleteMX(string) error { return ErrReadOnly }
+
+// SetCAA returns ErrReadOnly.
+func (s *Static) SetCAA(string, *CAARecord) error { return ErrReadOnly }
+
+// GetCAA returns ErrNotFound; a Static DB holds no CAA records.
+func (s *Static) GetCAA(string) ([]*CAARecord, error) { return nil, ErrNotFound }
+
+// DeleteCAA returns ErrReadOnly.
+func (s *Static) DeleteCAA(string) error { return ErrReadOnly }
+
+// SetNS returns ErrReadOnly.
+func (s *Static) SetNS(string, []string) error { return ErrReadOnly }
+
+// GetNS returns ErrNotFound; a Static DB holds no NS records.
+func (s *Static) GetNS(string) ([]string, error) { return nil, ErrNotFound }
+
+// DeleteNS returns ErrReadOnly.
+func (s *Static) DeleteNS(string) error { return ErrReadOnly }
+
+// SetPTR returns ErrReadOnly.
+func (s *Static) SetPTR(string, string) error { return ErrReadOnly }
+
+// GetPTR returns ErrNotFound; a Static DB holds no PTR records.
+func (s *Static) GetPTR(string) (string, error) { return "", ErrNotFound }
+
+// DeletePTR returns ErrReadOnly.
+func (s *Static) DeletePTR(string) error { return ErrReadOnly }
+
+// SetSRV returns ErrReadOnly.
+func (s *Static) SetSRV(string, *SRVRecord) error { return ErrReadOnly }
+
+// AddSRV returns ErrReadOnly.
+func (s *Static) AddSRV(string, *SRVRecord) error { return ErrReadOnly }
+
+// GetSRV retrieves the SRV records by spec.
+func (s *Static) GetSRV(spec string) ([]*SRVRecord, error) {
+	recs, ok := s.srvRecords[canonical(spec)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	tmp := make([]*SRVRecord, 0, len(recs))
+	for _, rec := range recs {
+		t := *rec
+		tmp = append(tmp, &t)
+	}
+
+	return tmp, nil
+}
+
+// DeleteSRV returns ErrReadOnly.
+func (s *Static) DeleteSRV(string) error { return ErrReadOnly }
+
+// ListSRV lists all SRV records.
+func (s *Static) ListSRV() (SRVRecords, error) {
+	tmp := make(SRVRecords, len(s.srvRecords))
+	for spec := range s.srvRecords {
+		tmp[spec], _ = s.GetSRV(spec)
+	}
+
+	return tmp, nil
+}
+
+// CountSRV returns the number of services with SRV records.
+func (s *Static) CountSRV() (int, error) {
+	return len(s.srvRecords), nil
+}
+
+// SetRaw returns ErrReadOnly.
+func (s *Static) SetRaw(string, dns.RR) error { return ErrReadOnly }
+
+// GetRaw returns ErrNotFound; a Static DB holds no raw records.
+func (s *Static) GetRaw(string, uint16) ([]dns.RR, error) { return nil, ErrNotFound }
+
+// DeleteRaw returns ErrReadOnly.
+func (s *Static) DeleteRaw(string, uint16) error { return ErrReadOnly }
+
+// Close does nothing; a Static DB holds no resources.
+func (s *Static) Close() error {
+	return nil
+}
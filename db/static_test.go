@@ -0,0 +1,113 @@
+package db
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestStatic(t *testing.T) {
+	a := map[string]net.IP{"Test": net.ParseIP("127.0.0.2")}
+	srv := map[string]*SRVRecord{"_http._tcp": {Port: 80, Host: "test.docker."}}
+
+	var d DB = NewStatic(a, srv)
+
+	// the snapshot is copied
+	a["Test"][15] = 9
+	srv["_http._tcp"].Port = 81
+
+	ips, err := d.GetA("TEST")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("GetA returned %v (%v)", ips, err)
+	}
+
+	ips[0][15] = 9
+
+	if ips, _ := d.GetA("test"); !ips[0].Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatal("modifying returned addresses changed the DB")
+	}
+
+	if srvs, err := d.GetSRV("_http._tcp"); err != nil || len(srvs) != 1 || srvs[0].Port != 80 {
+		t.Fatalf("GetSRV returned %v (%v)", srvs, err)
+	}
+
+	if _, err := d.GetA("missing"); err != ErrNotFound {
+		t.Fatalf("missing host yielded %v", err)
+	}
+
+	if _, err := d.GetTXT("test"); err != ErrNotFound {
+		t.Fatalf("TXT lookup yielded %v", err)
+	}
+
+	if n, _ := d.CountA(); n != 1 {
+		t.Fatalf("CountA returned %d", n)
+	}
+
+	if records, _ := d.ListSRV(); len(records) != 1 {
+		t.Fatalf("ListSRV returned %v", records)
+	}
+
+	for name, err := range map[string]error{
+		"SetA":        d.SetA("new", net.ParseIP("127.0.0.3")),
+		"AddA":        d.AddA("test", net.ParseIP("127.0.0.3")),
+		"DeleteA":     d.DeleteA("test"),
+		"SetATTL":     d.SetATTL("test", 60),
+		"SetCNAME":    d.SetCNAME("www", "test.docker."),
+		"SetTXT":      d.SetTXT("test", []string{"text"}),
+		"SetSRV":      d.SetSRV("_http._tcp", &SRVRecord{Port: 82, Host: "test.docker."}),
+		"DeleteSRV":   d.DeleteSRV("_http._tcp"),
+		"DeletePTR":   d.DeletePTR("2.0.0.127.in-addr.arpa."),
+		"DeleteDNAME": d.DeleteDNAME("old"),
+	} {
+		if err != ErrReadOnly {
+			t.Fatalf("%s returned %v, expected ErrReadOnly", name, err)
+		}
+	}
+
+	if _, err := d.DeleteByIP(net.ParseIP("127.0.0.2")); err != ErrReadOnly {
+		t.Fatalf("DeleteByIP returned %v", err)
+	}
+
+	if ips, err := d.GetA("test"); err != nil || len(ips) != 1 {
+		t.Fatalf("writes changed the records: %v (%v)", ips, err)
+	}
+}
+
+func benchmarkGetA(b *testing.B, getA func(string) ([]net.IP, error), names []string) {
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			if _, err := getA(names[i%len(names)]); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func staticBenchmarkRecords() (map[string]net.IP, []string) {
+	records := map[string]net.IP{}
+	names := make([]string, 1024)
+
+	for i := range names {
+		names[i] = fmt.Sprintf("host%d", i)
+		records[names[i]] = net.IPv4(10, 0, byte(i>>8), byte(i))
+	}
+
+	return records, names
+}
+
+func BenchmarkStaticGetA(b *testing.B) {
+	records, names := staticBenchmarkRecords()
+	benchmarkGetA(b, NewStatic(records, nil).GetA, names)
+}
+
+func BenchmarkMapGetA(b *testing.B) {
+	records, names := staticBenchmarkRecords()
+
+	m := NewMap()
+	m.ImportA(records)
+
+	benchmarkGetA(b, m.GetA, names)
+}
@@ -1,21 +1,116 @@
 package db
 
-import "net"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
 
-// ARecords is a typed mapping of A records.
-type ARecords map[string]net.IP
+// ARecords is a typed mapping of A records. Each host may carry several
+// addresses for round-robin purposes.
+type ARecords map[string][]net.IP
 
-// SRVRecords is likewise a collection of SRV records.
-type SRVRecords map[string]*SRVRecord
+// AHost is a host's A records along with the metadata set for them, if any.
+type AHost struct {
+	IPs  []net.IP
+	Meta map[string]string
+}
+
+// AAAARecords is a typed mapping of AAAA records.
+type AAAARecords map[string]net.IP
+
+// SRVRecords is likewise a collection of SRV records. Each service may carry
+// several targets.
+type SRVRecords map[string][]*SRVRecord
+
+// ErrInvalidSRVSpec is returned by ParseSRVSpec for names which are not of the
+// form _service._proto.
+var ErrInvalidSRVSpec = errors.New("invalid SRV spec")
+
+// SRVSpec names a service's SRV records by service and protocol, e.g. http and
+// tcp. The keys of SRVRecords are SRVSpecs in their String form.
+type SRVSpec struct {
+	Service  string
+	Protocol string
+}
+
+// String returns the spec as it is stored and queried, relative to the
+// domain: _service._proto.
+func (s SRVSpec) String() string {
+	return fmt.Sprintf("_%s._%s", s.Service, s.Protocol)
+}
+
+// ParseSRVSpec extracts the service and protocol from name, which is either a
+// query name within domain, such as _http._tcp.docker., or a spec relative to
+// it, such as _http._tcp. Trailing dots are ignored, and domain is compared
+// case-insensitively; it may be empty for a relative name. What remains must
+// be exactly two labels, each an underscore followed by at least one
+// character, or ErrInvalidSRVSpec is returned.
+func ParseSRVSpec(name, domain string) (SRVSpec, error) {
+	rel := strings.TrimSuffix(name, ".")
+
+	if domain = strings.TrimSuffix(domain, "."); domain != "" {
+		suffix := "." + domain
+		if len(rel) <= len(suffix) || !strings.EqualFold(rel[len(rel)-len(suffix):], suffix) {
+			return SRVSpec{}, fmt.Errorf("%w: %q is not within %q", ErrInvalidSRVSpec, name, domain)
+		}
+
+		rel = rel[:len(rel)-len(suffix)]
+	}
+
+	parts := strings.Split(rel, ".")
+	if len(parts) != 2 {
+		return SRVSpec{}, fmt.Errorf("%w: %q", ErrInvalidSRVSpec, name)
+	}
+
+	for _, part := range parts {
+		if len(part) < 2 || part[0] != '_' {
+			return SRVSpec{}, fmt.Errorf("%w: %q", ErrInvalidSRVSpec, name)
+		}
+	}
+
+	return SRVSpec{Service: parts[0][1:], Protocol: parts[1][1:]}, nil
+}
 
 // SRVRecord encapsulates the data segment of a SRV record. Priority and Weight
-// are always 0 in our SRV records.
+// default to 0. A zero TTL means the server default is used.
 type SRVRecord struct {
-	Port uint16
-	Host string
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Host     string
+	TTL      uint32
 }
 
 // Equal tests if the srvrecords are equal.
 func (s *SRVRecord) Equal(s2 *SRVRecord) bool {
-	return s.Port == s2.Port && s.Host == s2.Host
+	return s.Priority == s2.Priority &&
+		s.Weight == s2.Weight &&
+		s.Port == s2.Port &&
+		s.Host == s2.Host &&
+		s.TTL == s2.TTL
+}
+
+// MXRecord encapsulates the data segment of a MX record.
+type MXRecord struct {
+	Preference uint16
+	Mail       string
+}
+
+// Equal tests if the mxrecords are equal.
+func (m *MXRecord) Equal(m2 *MXRecord) bool {
+	return m.Preference == m2.Preference && m.Mail == m2.Mail
+}
+
+// CAARecord encapsulates the data segment of a CAA record (RFC 8659).
+type CAARecord struct {
+	Flag  uint8
+	Tag   string
+	Value string
+}
+
+// Equal tests if the caarecords are equal.
+func (c *CAARecord) Equal(c2 *CAARecord) bool {
+	return c.Flag == c2.Flag && c.Tag == c2.Tag && c.Value == c2.Value
 }
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSRVSpec(t *testing.T) {
+	for _, tc := range []struct {
+		name, domain string
+		spec         SRVSpec
+	}{
+		{"_http._tcp.docker.", "docker.", SRVSpec{"http", "tcp"}},
+		{"_http._tcp.docker", "docker", SRVSpec{"http", "tcp"}},
+		{"_HTTP._tcp.Docker.", "docker.", SRVSpec{"HTTP", "tcp"}},
+		{"_sip._udp.example.com.", "example.com.", SRVSpec{"sip", "udp"}},
+		{"_http._tcp", "", SRVSpec{"http", "tcp"}},
+		{"_http._tcp.", "", SRVSpec{"http", "tcp"}},
+	} {
+		spec, err := ParseSRVSpec(tc.name, tc.domain)
+		if err != nil {
+			t.Fatalf("%q in %q: %v", tc.name, tc.domain, err)
+		}
+
+		if spec != tc.spec {
+			t.Fatalf("%q in %q parsed as %v", tc.name, tc.domain, spec)
+		}
+	}
+
+	for _, tc := range []struct{ name, domain string }{
+		{"http._tcp.docker.", "docker."},
+		{"_http.tcp.docker.", "docker."},
+		{"_http._tcp._extra.docker.", "docker."},
+		{"www._http._tcp.docker.", "docker."},
+		{"_http.docker.", "docker."},
+		{"_._tcp.docker.", "docker."},
+		{"_http._.docker.", "docker."},
+		{"_http._tcp.example.com.", "docker."},
+		{"_http._tcpdocker.", "docker."},
+		{"docker.", "docker."},
+		{"_http._tcp.docker.", ""},
+		{"", ""},
+	} {
+		if spec, err := ParseSRVSpec(tc.name, tc.domain); !errors.Is(err, ErrInvalidSRVSpec) {
+			t.Fatalf("%q in %q parsed as %v, %v", tc.name, tc.domain, spec, err)
+		}
+	}
+}
+
+func TestSRVSpecString(t *testing.T) {
+	spec := SRVSpec{Service: "http", Protocol: "tcp"}
+	if spec.String() != "_http._tcp" {
+		t.Fatalf("spec formatted as %q", spec)
+	}
+
+	parsed, err := ParseSRVSpec(spec.String()+".docker.", "docker.")
+	if err != nil || parsed != spec {
+		t.Fatalf("spec did not round trip: %v, %v", parsed, err)
+	}
+}
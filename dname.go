@@ -0,0 +1,123 @@
+package dnsserver
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// errDNAMETooLong is returned internally when substituting a DNAME target
+// into a query name yields a name too long to be valid.
+var errDNAMETooLong = errors.New("DNAME substitution yields an invalid name")
+
+// maxDNAMEChase is the number of DNAME and CNAME records followed from a query
+// name before the chain is taken for a loop.
+const maxDNAMEChase = 8
+
+// GetDNAME receives a FQDN; looks up and supplies the DNAME record owned by
+// it. Names below the owner are answered through it; see SetDNAME.
+func (ds *Server) GetDNAME(name string) []*dns.DNAME {
+	target, err := ds.db.GetDNAME(ds.subdomain(name))
+	if err != nil {
+		ds.logLookup(name, dns.TypeDNAME, err)
+		return nil
+	}
+
+	return []*dns.DNAME{&dns.DNAME{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeDNAME,
+			Class:  dns.ClassINET,
+			Ttl:    ds.ttlFor(0),
+		},
+		Target: target,
+	}}
+}
+
+// SetDNAME redirects the subtree below a host to a target (RFC 6672): with
+// SetDNAME("old", "new"), A queries for "host.old.<domain>" are answered with a
+// CNAME to "host.new.<domain>" and the records found there. The owner itself
+// is not redirected. The owner is a hostname, not the FQDN; the target is
+// qualified with the server's domain unless it is already a FQDN.
+func (ds *Server) SetDNAME(owner, target string) error {
+	return ds.changed(ds.db.SetDNAME(owner, ds.qualifyTarget(target)))
+}
+
+// DeleteDNAME deletes a host's DNAME record. Note that this is not the FQDN,
+// but a hostname.
+func (ds *Server) DeleteDNAME(owner string) error {
+	return ds.changed(ds.db.DeleteDNAME(owner))
+}
+
+// dnameFor returns the DNAME record redirecting name: the one owned by its
+// closest enclosing name in the domain, if any.
+func (ds *Server) dnameFor(name string) *dns.DNAME {
+	labels := dns.SplitDomainName(name)
+
+	for i := 1; i < len(labels); i++ {
+		owner := strings.Join(labels[i:], ".") + "."
+		if !strings.EqualFold(owner, ds.domain) && !ds.inDomain(owner) {
+			break
+		}
+
+		if dnames := ds.GetDNAME(owner); len(dnames) != 0 {
+			return dnames[0]
+		}
+	}
+
+	return nil
+}
+
+// chaseDNAME resolves name through the DNAME redirecting it, if any,
+// returning the DNAME and a CNAME synthesized from it to the substituted name,
+// followed by whatever lookup yields for that name. Further DNAME and CNAME
+// records met on the way are followed too. A chain which comes back to a name
+// already visited, or is longer than maxDNAMEChase, yields errCNAMELoop. A nil
+// result means no DNAME redirects name.
+func (ds *Server) chaseDNAME(name string, lookup func(string) []dns.RR) ([]dns.RR, error) {
+	if ds.dnameFor(name) == nil {
+		return nil, nil
+	}
+
+	answers := []dns.RR{}
+	seen := map[string]bool{}
+
+	for ds.inDomain(name) || strings.EqualFold(name, ds.domain) {
+		key := strings.ToLower(name)
+		if seen[key] || len(seen) == maxDNAMEChase {
+			return nil, errCNAMELoop
+		}
+		seen[key] = true
+
+		if dname := ds.dnameFor(name); dname != nil {
+			target := name[:len(name)-len(dname.Hdr.Name)] + dname.Target
+			if checkName(target) != nil {
+				return nil, errDNAMETooLong
+			}
+
+			answers = append(answers, dname, &dns.CNAME{
+				Hdr: dns.RR_Header{
+					Name:   name,
+					Rrtype: dns.TypeCNAME,
+					Class:  dns.ClassINET,
+					Ttl:    dname.Hdr.Ttl,
+				},
+				Target: target,
+			})
+			name = target
+			continue
+		}
+
+		if cnames := ds.GetCNAME(name); len(cnames) != 0 {
+			answers = append(answers, cnames[0])
+			name = cnames[0].Target
+			continue
+		}
+
+		return append(answers, lookup(name)...), nil
+	}
+
+	// the chain has left the domain, and is not ours to follow
+	return answers, nil
+}
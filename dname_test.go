@@ -0,0 +1,88 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestDNAME(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetA("host.new", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetDNAME("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+
+		m := ds.Resolve(r, nil)
+		if m == nil {
+			t.Fatalf("no reply to %s %s", name, dns.TypeToString[qtype])
+		}
+
+		return m
+	}
+
+	m := resolve("host.old.docker.", dns.TypeA)
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 3 {
+		t.Fatalf("A reply under the DNAME was %v", m)
+	}
+
+	if dname, ok := m.Answer[0].(*dns.DNAME); !ok || dname.Hdr.Name != "old.docker." || dname.Target != "new.docker." {
+		t.Fatalf("first answer was not the DNAME: %v", m.Answer[0])
+	}
+
+	if cname, ok := m.Answer[1].(*dns.CNAME); !ok || cname.Hdr.Name != "host.old.docker." || cname.Target != "host.new.docker." {
+		t.Fatalf("second answer was not the synthesized CNAME: %v", m.Answer[1])
+	}
+
+	if a, ok := m.Answer[2].(*dns.A); !ok || a.Hdr.Name != "host.new.docker." || !a.A.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("third answer was not the target's A record: %v", m.Answer[2])
+	}
+
+	// the owner itself is not redirected
+	if m := resolve("old.docker.", dns.TypeA); m.Rcode != dns.RcodeSuccess || len(m.Answer) != 0 {
+		t.Fatalf("A reply at the DNAME owner was %v", m)
+	}
+
+	if m := resolve("old.docker.", dns.TypeDNAME); len(m.Answer) != 1 {
+		t.Fatalf("DNAME reply was %v", m)
+	}
+
+	if m := resolve("host.old.docker.", dns.TypeANY); len(m.Answer) != 3 {
+		t.Fatalf("ANY reply under the DNAME was %v", m)
+	}
+
+	// a DNAME leading back to itself through another DNAME is a loop
+	ds.SetDNAME("loop1", "loop2")
+	ds.SetDNAME("loop2", "loop1")
+
+	if m := resolve("host.loop1.docker.", dns.TypeA); m.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("DNAME loop was answered with %v", m)
+	}
+
+	// as is one leading back through a CNAME
+	ds.SetDNAME("a", "b")
+	ds.SetCNAME("host.b", "host.a")
+
+	if m := resolve("host.a.docker.", dns.TypeA); m.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("DNAME and CNAME loop was answered with %v", m)
+	}
+
+	if err := ds.DeleteDNAME("old"); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := resolve("host.old.docker.", dns.TypeA); m.Rcode != dns.RcodeNameError {
+		t.Fatalf("A reply under a deleted DNAME was %v", m)
+	}
+}
@@ -0,0 +1,374 @@
+package dnsserver
+
+import (
+	"crypto"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// sigInception backdates signatures, to allow for clock skew between us
+	// and validators.
+	sigInception = time.Hour
+	// sigValidity is how long signatures are valid for after they are made.
+	sigValidity = 7 * 24 * time.Hour
+)
+
+// ErrInvalidKey is returned by EnableDNSSEC when a key or signer is missing.
+var ErrInvalidKey = errors.New("invalid DNSSEC key")
+
+// dnssec holds the keys set with EnableDNSSEC.
+type dnssec struct {
+	zsk, ksk             *dns.DNSKEY
+	zskSigner, kskSigner crypto.Signer
+}
+
+// EnableDNSSEC turns on online signing (RFC 4035). Replies to queries with the
+// DO bit set carry RRSIGs, made with zsk, over the records of the zone they
+// hold. Names which do not exist, and types a name does not have, are denied
+// with NSEC records covering only the name queried, so that the zone cannot be
+// walked. The apex answers DNSKEY queries with both keys, signed with ksk, and
+// DS queries with the SHA-256 digest of ksk for the parent zone.
+//
+// The signers hold the private halves of the keys; zsk and ksk may be the same
+// key to sign with a single key. The owner names of the keys are set to the
+// domain.
+func (ds *Server) EnableDNSSEC(zsk, ksk *dns.DNSKEY, zskSigner, kskSigner crypto.Signer) error {
+	if zsk == nil || ksk == nil || zskSigner == nil || kskSigner == nil {
+		return ErrInvalidKey
+	}
+
+	keys := &dnssec{zsk: ds.apexKey(zsk), ksk: ds.apexKey(ksk), zskSigner: zskSigner, kskSigner: kskSigner}
+	if zsk == ksk {
+		keys.ksk = keys.zsk
+	}
+
+	ds.dnssecMutex.Lock()
+	ds.dnssec = keys
+	ds.dnssecMutex.Unlock()
+
+	return nil
+}
+
+// apexKey returns a copy of key owned by the apex.
+func (ds *Server) apexKey(key *dns.DNSKEY) *dns.DNSKEY {
+	k := *key
+	k.Hdr = dns.RR_Header{Name: ds.domain, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET}
+	return &k
+}
+
+// keys returns the DNSSEC keys, or nil if signing is not enabled.
+func (ds *Server) keys() *dnssec {
+	ds.dnssecMutex.RLock()
+	defer ds.dnssecMutex.RUnlock()
+
+	return ds.dnssec
+}
+
+// dnskeys returns the DNSKEY records for the apex.
+func (ds *Server) dnskeys(keys *dnssec) []dns.RR {
+	rrs := []dns.RR{}
+
+	for _, key := range []*dns.DNSKEY{keys.zsk, keys.ksk} {
+		k := *key
+		k.Hdr.Ttl = ds.ttlFor(0)
+		rrs = append(rrs, &k)
+
+		if keys.ksk == keys.zsk {
+			break
+		}
+	}
+
+	return rrs
+}
+
+// lookupDNSSEC answers DNSKEY and DS queries, which are only held at the
+// apex, and only when signing is enabled.
+func (ds *Server) lookupDNSSEC(name string, qtype uint16) []dns.RR {
+	keys := ds.keys()
+	if keys == nil || !strings.EqualFold(name, ds.domain) {
+		return nil
+	}
+
+	if qtype == dns.TypeDNSKEY {
+		return ds.dnskeys(keys)
+	}
+
+	digest := keys.ksk.ToDS(dns.SHA256)
+	if digest == nil {
+		return nil
+	}
+
+	digest.Hdr.Ttl = ds.ttlFor(0)
+	return []dns.RR{digest}
+}
+
+// secure signs the reply m to r, if signing is enabled and r set the DO bit.
+// Denials of existence for names in the zone gain NSEC records first, so that
+// they are signed along with the rest.
+func (ds *Server) secure(r, m *dns.Msg) {
+	keys := ds.keys()
+	if keys == nil || len(r.Question) != 1 {
+		return
+	}
+
+	if opt := r.IsEdns0(); opt == nil || !opt.Do() {
+		return
+	}
+
+	if len(m.Answer) == 0 && hasSOA(m.Ns) {
+		name := strings.ToLower(r.Question[0].Name)
+
+		switch m.Rcode {
+		case dns.RcodeSuccess:
+			m.Ns = append(m.Ns, ds.nsec(name, successor(name), ds.typesAt(name)))
+		case dns.RcodeNameError:
+			m.Ns = append(m.Ns, ds.nsec(predecessor(name, ds.domain), successor(name), nil))
+
+			wildcard := "*." + ds.closestEncloser(name)
+			if wildcard != name {
+				m.Ns = append(m.Ns, ds.nsec(predecessor(wildcard, ds.domain), successor(wildcard), nil))
+			}
+		}
+	}
+
+	now := time.Now()
+
+	m.Answer = ds.signSection(keys, m.Answer, now)
+	m.Ns = ds.signSection(keys, m.Ns, now)
+	m.Extra = ds.signSection(keys, m.Extra, now)
+}
+
+// signSection appends RRSIGs for each RRset in rrs which belongs to the zone.
+// The DNSKEY RRset is signed with the KSK; everything else with the ZSK.
+func (ds *Server) signSection(keys *dnssec, rrs []dns.RR, now time.Time) []dns.RR {
+	type rrsetKey struct {
+		name  string
+		rtype uint16
+	}
+
+	var order []rrsetKey
+
+	sets := map[rrsetKey][]dns.RR{}
+
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Rrtype == dns.TypeOPT || h.Rrtype == dns.TypeRRSIG || h.Rrtype == dns.TypeTSIG {
+			continue
+		}
+
+		if !strings.EqualFold(h.Name, ds.domain) && !ds.inDomain(h.Name) {
+			continue
+		}
+
+		k := rrsetKey{strings.ToLower(h.Name), h.Rrtype}
+		if _, ok := sets[k]; !ok {
+			order = append(order, k)
+		}
+
+		sets[k] = append(sets[k], rr)
+	}
+
+	for _, k := range order {
+		key, signer := keys.zsk, keys.zskSigner
+		if k.rtype == dns.TypeDNSKEY {
+			key, signer = keys.ksk, keys.kskSigner
+		}
+
+		set := sets[k]
+
+		sig := &dns.RRSIG{
+			Hdr:        dns.RR_Header{Name: set[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: set[0].Header().Ttl},
+			KeyTag:     key.KeyTag(),
+			SignerName: ds.domain,
+			Algorithm:  key.Algorithm,
+			Inception:  uint32(now.Add(-sigInception).Unix()),
+			Expiration: uint32(now.Add(sigValidity).Unix()),
+		}
+
+		if err := sig.Sign(signer, set); err != nil {
+			ds.log().Error("signing records failed", "name", k.name, "qtype", dns.TypeToString[k.rtype], "err", err)
+			continue
+		}
+
+		rrs = append(rrs, sig)
+	}
+
+	return rrs
+}
+
+// nsec returns an NSEC record at name pointing to next, listing types along
+// with RRSIG and NSEC themselves.
+func (ds *Server) nsec(name, next string, types []uint16) *dns.NSEC {
+	types = append(types, dns.TypeRRSIG, dns.TypeNSEC)
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return &dns.NSEC{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeNSEC,
+			Class:  dns.ClassINET,
+			Ttl:    ds.soa().Minttl,
+		},
+		NextDomain: next,
+		TypeBitMap: types,
+	}
+}
+
+// typesAt returns the types held at name, raw records included, in order.
+func (ds *Server) typesAt(name string) []uint16 {
+	var types []uint16
+
+	apex := strings.EqualFold(name, ds.domain)
+
+	for _, rrtype := range []uint16{dns.TypeA, dns.TypeNS, dns.TypeCNAME, dns.TypeSOA, dns.TypePTR, dns.TypeMX, dns.TypeTXT, dns.TypeAAAA, dns.TypeSRV, dns.TypeDNAME, dns.TypeDNSKEY, dns.TypeCAA} {
+		var present bool
+
+		switch rrtype {
+		case dns.TypeNS, dns.TypeSOA, dns.TypeDNSKEY:
+			present = apex
+		default:
+			present = len(ds.rrset(name, rrtype)) != 0
+		}
+
+		if present {
+			types = append(types, rrtype)
+		}
+	}
+
+	seen := map[uint16]bool{}
+	for _, rr := range ds.GetRaw(name, dns.TypeANY) {
+		if rrtype := rr.Header().Rrtype; !seen[rrtype] {
+			seen[rrtype] = true
+			types = append(types, rrtype)
+		}
+	}
+
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	return types
+}
+
+// closestEncloser returns the longest ancestor of name which exists, which is
+// at least the apex.
+func (ds *Server) closestEncloser(name string) string {
+	for {
+		i := strings.IndexByte(name, '.')
+		if i < 0 || i == len(name)-1 {
+			return ds.domain
+		}
+
+		name = name[i+1:]
+		if strings.EqualFold(name, ds.domain) || !ds.inDomain(name) || ds.nameInUse(name) {
+			return strings.ToLower(name)
+		}
+	}
+}
+
+// hasSOA reports whether rrs holds a SOA record.
+func hasSOA(rrs []dns.RR) bool {
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			return true
+		}
+	}
+
+	return false
+}
+
+// successor returns the name immediately following name in canonical order
+// (RFC 4034 section 6.1): its first child.
+func successor(name string) string {
+	return `\000.` + name
+}
+
+// predecessor returns a name sorting just before name in canonical order, and
+// after any name that could exist between the two, without leaving the zone.
+// The last octet of the first label is decremented and the label padded to
+// full length with the highest octet (RFC 4471 section 3.1.2).
+func predecessor(name, zone string) string {
+	if strings.EqualFold(name, zone) {
+		return zone
+	}
+
+	labels := dns.SplitDomainName(name)
+	first := []byte(unescapeLabel(labels[0]))
+	rest := dns.Fqdn(strings.Join(labels[1:], "."))
+
+	last := first[len(first)-1]
+	first = first[:len(first)-1]
+
+	if last == 0 {
+		if len(first) == 0 {
+			return rest
+		}
+
+		return escapeLabel(first) + "." + rest
+	}
+
+	last--
+	if last >= 'A' && last <= 'Z' {
+		// canonical order compares letters as lower case
+		last = 'A' - 1
+	}
+
+	first = append(first, last)
+
+	for len(first) < maxLabelLen && len(first)+len(rest)+2 <= maxNameLen {
+		first = append(first, 0xff)
+	}
+
+	return escapeLabel(first) + "." + rest
+}
+
+// unescapeLabel decodes the \DDD and \X escapes of a presentation format
+// label.
+func unescapeLabel(label string) string {
+	var b []byte
+
+	for i := 0; i < len(label); i++ {
+		if label[i] != '\\' || i+1 >= len(label) {
+			b = append(b, label[i])
+			continue
+		}
+
+		if i+3 < len(label) && isDigit(label[i+1]) && isDigit(label[i+2]) && isDigit(label[i+3]) {
+			b = append(b, (label[i+1]-'0')*100+(label[i+2]-'0')*10+(label[i+3]-'0'))
+			i += 3
+			continue
+		}
+
+		b = append(b, label[i+1])
+		i++
+	}
+
+	return string(b)
+}
+
+// escapeLabel encodes a label in presentation format, escaping any octet
+// which is not a letter, digit, hyphen or underscore.
+func escapeLabel(label []byte) string {
+	var b strings.Builder
+
+	for _, c := range label {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', isDigit(c), c == '-', c == '_', c == '*':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('\\')
+			b.WriteByte('0' + c/100)
+			b.WriteByte('0' + c/10%10)
+			b.WriteByte('0' + c%10)
+		}
+	}
+
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
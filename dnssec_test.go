@@ -0,0 +1,260 @@
+package dnsserver
+
+import (
+	"crypto"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func generateKey(t *testing.T, flags uint16) (*dns.DNSKEY, crypto.Signer) {
+	t.Helper()
+
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "docker.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, priv.(crypto.Signer)
+}
+
+// verifySigned checks that every RRset in rrs is covered by an RRSIG made
+// with key, and returns the RRSIGs.
+func verifySigned(t *testing.T, key *dns.DNSKEY, rrs []dns.RR) []*dns.RRSIG {
+	t.Helper()
+
+	type rrsetKey struct {
+		name  string
+		rtype uint16
+	}
+
+	sets := map[rrsetKey][]dns.RR{}
+	sigs := []*dns.RRSIG{}
+
+	for _, rr := range rrs {
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			sigs = append(sigs, sig)
+			continue
+		}
+
+		k := rrsetKey{rr.Header().Name, rr.Header().Rrtype}
+		sets[k] = append(sets[k], rr)
+	}
+
+	for k, set := range sets {
+		var verified bool
+
+		rrtype := k.rtype
+
+		for _, sig := range sigs {
+			if sig.TypeCovered != rrtype || sig.Header().Name != k.name {
+				continue
+			}
+
+			if err := sig.Verify(key, set); err != nil {
+				t.Fatalf("RRSIG over %s did not verify: %v", dns.TypeToString[rrtype], err)
+			}
+
+			if !sig.ValidityPeriod(time.Now()) {
+				t.Fatalf("RRSIG over %s is not currently valid", dns.TypeToString[rrtype])
+			}
+
+			verified = true
+		}
+
+		if !verified {
+			t.Fatalf("%s RRset was not signed: %v", dns.TypeToString[rrtype], rrs)
+		}
+	}
+
+	return sigs
+}
+
+func TestDNSSEC(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.AddA("test", net.ParseIP("127.0.0.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		m := new(dns.Msg)
+		m.SetQuestion(name, qtype)
+		m.SetEdns0(EDNS0Size, true)
+
+		msg, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return msg
+	}
+
+	if msg := query("test.docker.", dns.TypeA); len(msg.Answer) != 2 {
+		t.Fatalf("unsigned zone answered %v", msg.Answer)
+	}
+
+	zsk, zskSigner := generateKey(t, dns.ZONE)
+	ksk, kskSigner := generateKey(t, dns.ZONE|dns.SEP)
+
+	if err := ds.EnableDNSSEC(zsk, nil, zskSigner, nil); err != ErrInvalidKey {
+		t.Fatalf("missing key was %v", err)
+	}
+
+	if err := ds.EnableDNSSEC(zsk, ksk, zskSigner, kskSigner); err != nil {
+		t.Fatal(err)
+	}
+
+	// without the DO bit nothing is signed
+	if msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA); err != nil || len(msg.Answer) != 2 {
+		t.Fatalf("query without DO was answered with %v (%v)", msg, err)
+	}
+
+	msg := query("test.docker.", dns.TypeA)
+	if len(msg.Answer) != 3 {
+		t.Fatalf("expected two A records and an RRSIG, got %v", msg.Answer)
+	}
+
+	for _, sig := range verifySigned(t, zsk, msg.Answer) {
+		if sig.SignerName != "docker." || sig.KeyTag != zsk.KeyTag() {
+			t.Fatalf("answer was signed by %s/%d", sig.SignerName, sig.KeyTag)
+		}
+	}
+
+	verifySigned(t, zsk, msg.Ns)
+
+	msg = query("docker.", dns.TypeDNSKEY)
+	if len(msg.Answer) != 3 {
+		t.Fatalf("expected two DNSKEYs and an RRSIG, got %v", msg.Answer)
+	}
+
+	verifySigned(t, ksk, msg.Answer)
+
+	msg = query("docker.", dns.TypeDS)
+	if len(msg.Answer) != 2 {
+		t.Fatalf("expected a DS and an RRSIG, got %v", msg.Answer)
+	}
+
+	if digest := msg.Answer[0].(*dns.DS); digest.KeyTag != ksk.KeyTag() || digest.Digest != ksk.ToDS(dns.SHA256).Digest {
+		t.Fatalf("DS did not match the KSK: %v", digest)
+	}
+
+	msg = query("missing.docker.", dns.TypeA)
+	if msg.Rcode != dns.RcodeNameError {
+		t.Fatalf("missing name was %s", dns.RcodeToString[msg.Rcode])
+	}
+
+	verifySigned(t, zsk, msg.Ns)
+
+	var nsecs []*dns.NSEC
+	for _, rr := range msg.Ns {
+		if nsec, ok := rr.(*dns.NSEC); ok {
+			nsecs = append(nsecs, nsec)
+		}
+	}
+
+	if len(nsecs) != 2 {
+		t.Fatalf("expected NSECs covering the name and the wildcard, got %v", msg.Ns)
+	}
+
+	if owner := nsecs[0].Hdr.Name; !strings.HasPrefix(owner, "missinf") || nsecs[0].NextDomain != `\000.missing.docker.` {
+		t.Fatalf("NSEC did not cover only the name: %v", nsecs[0])
+	}
+
+	if owner := nsecs[1].Hdr.Name; !strings.HasPrefix(owner, `\)`) || nsecs[1].NextDomain != `\000.*.docker.` {
+		t.Fatalf("NSEC did not cover only the wildcard: %v", nsecs[1])
+	}
+
+	msg = query("test.docker.", dns.TypeTXT)
+	if msg.Rcode != dns.RcodeSuccess || len(msg.Answer) != 0 {
+		t.Fatalf("NODATA query was answered with %v", msg)
+	}
+
+	verifySigned(t, zsk, msg.Ns)
+
+	for _, rr := range msg.Ns {
+		if nsec, ok := rr.(*dns.NSEC); ok {
+			if nsec.Hdr.Name != "test.docker." || len(nsec.TypeBitMap) != 3 || nsec.TypeBitMap[0] != dns.TypeA {
+				t.Fatalf("NODATA NSEC was %v", nsec)
+			}
+
+			return
+		}
+	}
+
+	t.Fatalf("NODATA reply had no NSEC: %v", msg.Ns)
+}
+
+func TestDNSSECMaxAnswers(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	for i := 2; i < 6; i++ {
+		if err := ds.AddA("test", net.IPv4(127, 0, 0, byte(i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	zsk, zskSigner := generateKey(t, dns.ZONE)
+	ksk, kskSigner := generateKey(t, dns.ZONE|dns.SEP)
+
+	if err := ds.EnableDNSSEC(zsk, ksk, zskSigner, kskSigner); err != nil {
+		t.Fatal(err)
+	}
+
+	ds.SetMaxAnswers(2)
+
+	m := new(dns.Msg)
+	m.SetQuestion("test.docker.", dns.TypeA)
+	m.SetEdns0(EDNS0Size, true)
+
+	msg, err := dns.Exchange(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the capped answers are signed as they were sent
+	if len(msg.Answer) != 3 || !msg.Truncated {
+		t.Fatalf("expected two A records and an RRSIG, got %v", msg)
+	}
+
+	verifySigned(t, zsk, msg.Answer)
+}
+
+func TestPredecessor(t *testing.T) {
+	table := map[string]string{
+		"docker.":          "docker.",
+		`\000.docker.`:     "docker.",
+		`a\000.docker.`:    "a.docker.",
+		"b.docker.":        "a" + strings.Repeat(`\255`, 62) + ".docker.",
+		"x.b.docker.":      "w" + strings.Repeat(`\255`, 62) + ".b.docker.",
+		"\\[.docker.":      `\064` + strings.Repeat(`\255`, 62) + ".docker.",
+		"*.docker.":        `\041` + strings.Repeat(`\255`, 62) + ".docker.",
+		"host1-db.docker.": "host1-da" + strings.Repeat(`\255`, 55) + ".docker.",
+	}
+
+	for name, want := range table {
+		if got := predecessor(name, "docker."); got != want {
+			t.Fatalf("predecessor of %q was %q, not %q", name, got, want)
+		}
+	}
+}
@@ -2,38 +2,201 @@ package dnsserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math/rand"
 	"net"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/erikh/dnsserver/db"
 	"github.com/miekg/dns"
 )
 
+// maxTXTChunk is the longest character-string a TXT record may carry.
+const maxTXTChunk = 255
+
+// errCNAMELoop is returned internally when a CNAME chain points back at the
+// name being queried.
+var errCNAMELoop = errors.New("CNAME loop detected")
+
+// ErrNotIPv6 is returned when an address that is not IPv6 is supplied to
+// SetAAAA.
+var ErrNotIPv6 = errors.New("not an IPv6 address")
+
+// ErrNotFQDN is returned by SetSRVRaw when the target host is not a fully
+// qualified domain name.
+var ErrNotFQDN = errors.New("not a fully qualified domain name")
+
+// ErrUnknownNet is returned by Listen when WithListenNet was given a network
+// other than "udp", "tcp" or "both", and by ListenWithNet for a network other
+// than "udp", "udp4" or "udp6".
+var ErrUnknownNet = errors.New("unknown listen network")
+
+// ErrFamilyMismatch is returned by ListenWithNet when the address to bind is
+// not of the family the network requires.
+var ErrFamilyMismatch = errors.New("listen address does not match network family")
+
+// ErrServerClosed is returned when listening on a server which has been
+// closed.
+var ErrServerClosed = errors.New("server closed")
+
+// apexHost is the host under which records for the zone apex are stored.
+const apexHost = "@"
+
+// wildcardHost is the host under which the wildcard A record is stored.
+const wildcardHost = "*"
+
+// EDNS0Size is the UDP payload size this server advertises to EDNS0 clients.
+const EDNS0Size = 4096
+
+// DefaultTTL is the TTL emitted for records when no other TTL is configured.
+const DefaultTTL = 1
+
 // Server is the struct which describes the DNS server.
 type Server struct {
-	domain      string // using the constructor, this will always end in a '.', making it a FQDN.
-	db          db.DB
-	server      *dns.Server
-	configMutex sync.Mutex // mutex for server configuration operations
-	listenIP    net.IP
-	listenPort  uint
+	// 64-bit fields accessed atomically come first, to keep them aligned on
+	// 32-bit platforms.
+	maxAnswers   int64  // see SetMaxAnswers
+	queryTimeout int64  // time.Duration; see SetQueryTimeout
+	queries      uint64 // see Stats
+	listenedAt   int64  // UnixNano of the first Listen, or 0; see Stats
+
+	domain       string // using the constructor, this will always end in a '.', making it a FQDN.
+	db           db.DB
+	ttl          uint32      // default TTL for emitted records; accessed atomically
+	listenNet    string      // network Listen serves on; see WithListenNet
+	server       *dns.Server // UDP server
+	tcpServer    *dns.Server
+	tlsServer    *dns.Server // DNS-over-TLS server; see ListenTLS
+	configMutex  sync.Mutex  // mutex for server configuration operations
+	closed       bool
+	pending      int           // servers bound but not yet started
+	ready        bool          // readyCh is closed
+	readyCh      chan struct{} // closed once serving, or on Close
+	bound        bool          // boundCh is closed
+	boundCh      chan struct{} // closed once bound, or on Close
+	listenIP     net.IP
+	listenPort   uint
+	tcpIP        net.IP
+	tcpPort      uint
+	tlsIP        net.IP
+	tlsPort      uint
+	aclMutex     sync.RWMutex
+	queryACL     []net.IPNet
+	transferACL  []net.IPNet
+	updateACL    []net.IPNet
+	updateMutex  sync.Mutex // serializes dynamic updates
+	forwardMutex sync.RWMutex
+	forwarders   []string
+	cache        *cache
+	loggerMutex  sync.RWMutex
+	logger       *slog.Logger
+	queryLogger  QueryLogger
+	limiter      *limiter
+	soaMutex     sync.RWMutex
+	soaConfig    soaConfig
+	serial       uint32 // accessed atomically
+	watchers     watchers
+	tsigSecrets  map[string]string // see SetTSIGSecrets; guarded by configMutex
+	dnssecMutex  sync.RWMutex
+	dnssec       *dnssec // see EnableDNSSEC
+	viewMutex    sync.RWMutex
+	views        []*view // see AddView
+	chainMutex   sync.RWMutex
+	middleware   []Handler
+	chain        dns.Handler // middleware wrapped around serveDNS; see Use
+	unsupported  int32       // rcode for unsupported types; see SetUnsupportedTypeRcode
+	weightMutex  sync.RWMutex
+	weights      map[string]map[string]int // host -> address -> weight; see AddWeightedA
+	shuffleMutex sync.Mutex
+	shuffling    bool       // see SetShuffle
+	shuffleRand  *rand.Rand // not safe for concurrent use; guarded by shuffleMutex
+	drainMutex   sync.RWMutex
+	draining     bool           // see CloseGracefully
+	inflight     sync.WaitGroup // ServeDNS calls in progress
+	expiryMutex  sync.RWMutex
+	expiries     map[string]expiration // host -> expiry; see SetATemp
+	sweepStop    chan struct{}         // stops the sweeper; guarded by configMutex
+	sweepWake    chan struct{}
+	journal      journal  // zone changes for IXFR
+	qtypeCounts  sync.Map // uint16 -> *uint64; see Stats
+	rcodeCounts  sync.Map // int -> *uint64
+	slotMutex    sync.RWMutex
+	slots        chan struct{} // in-flight queries; see SetMaxInFlight
+	chaosMutex   sync.RWMutex
+	version      string   // see SetVersionString
+	chaosHost    bool     // see SetHostnameBind
+	reverse      ptrIndex // A records by address; see SetAutoPTR
+	boundsMutex  sync.RWMutex
+	minTTL       uint32 // see SetTTLBounds
+	maxTTL       uint32
+	defaultMutex sync.RWMutex
+	defaultA     net.IP // see SetDefaultA
+	maintenance  int32  // 1 while in maintenance; see SetMaintenanceMode
+	maintRcode   int32  // see SetMaintenanceRcode; 0 for SERVFAIL
 }
 
 // New creates a new DNS server. Domain is an unqualified domain that will be used
-// as the TLD.
-func New(domain string) *Server {
-	return NewWithDB(domain, db.NewMap())
+// as the TLD. Without options, records are kept in a db.Map and served over
+// UDP. The domain is not checked; use NewWithError to reject invalid ones.
+func New(domain string, opts ...Option) *Server {
+	ds := &Server{
+		domain:      strings.ToLower(domain) + ".",
+		db:          db.NewMap(),
+		ttl:         DefaultTTL,
+		listenNet:   "udp",
+		readyCh:     make(chan struct{}),
+		boundCh:     make(chan struct{}),
+		sweepWake:   make(chan struct{}, 1),
+		cache:       newCache(),
+		logger:      slog.New(discardHandler{}),
+		queryLogger: NopQueryLogger{},
+		limiter:     newLimiter(),
+		soaConfig: soaConfig{
+			refresh: DefaultSOARefresh,
+			retry:   DefaultSOARetry,
+			expire:  DefaultSOAExpire,
+		},
+		serial: 1,
+	}
+
+	for _, opt := range opts {
+		opt(ds)
+	}
+
+	return ds
 }
 
 // NewWithDB allows you to provide a custom DB implementation during
-// construction.
+// construction. It is equivalent to New(domain, WithDB(db)).
 func NewWithDB(domain string, db db.DB) *Server {
-	return &Server{
-		domain: domain + ".",
-		db:     db,
+	return New(domain, WithDB(db))
+}
+
+// SetTTL sets the default TTL used for all emitted records which do not carry
+// their own TTL. A TTL of 0 is raised to 1; 0 TTL results in UB for DNS
+// resolvers and generally causes problems.
+func (ds *Server) SetTTL(ttl uint32) {
+	if ttl == 0 {
+		ttl = 1
+	}
+
+	atomic.StoreUint32(&ds.ttl, ttl)
+}
+
+// ttlFor returns the TTL to emit for a record; the record's own TTL if it is
+// set, and the server default otherwise.
+func (ds *Server) ttlFor(recordTTL uint32) uint32 {
+	if recordTTL != 0 {
+		return recordTTL
 	}
+
+	return atomic.LoadUint32(&ds.ttl)
 }
 
 // Listening returns the ip:port of the listener.
@@ -43,179 +206,1525 @@ func (ds *Server) Listening() (net.IP, uint) {
 	return ds.listenIP, ds.listenPort
 }
 
+// ListeningTCP returns the ip:port of the TCP listener.
+func (ds *Server) ListeningTCP() (net.IP, uint) {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+	return ds.tcpIP, ds.tcpPort
+}
+
 // Listen for DNS requests. listenSpec is a dotted-quad + port, e.g.,
-// 127.0.0.1:53. This function blocks and only returns when the DNS service is
-// no longer functioning.
+// 127.0.0.1:53. Requests are served over UDP unless another network was chosen
+// with WithListenNet. This function blocks and only returns when the DNS
+// service is no longer functioning.
 func (ds *Server) Listen(listenSpec string) error {
+	serve, err := ds.bind(listenSpec)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	return serve()
+}
+
+// ListenContext is like Listen, but shuts the server down when ctx is done and
+// then returns ctx.Err().
+func (ds *Server) ListenContext(ctx context.Context, listenSpec string) error {
+	serve, err := ds.bind(listenSpec)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	errs := make(chan error, 1)
+	go func() { errs <- serve() }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	ds.Close()
+	<-errs
+
+	return ctx.Err()
+}
+
+// ListenAndReady binds listenSpec as Listen would, but serves it in the
+// background, returning once the server is ready to answer queries. Errors
+// binding the sockets, such as the port being in use, are returned at once.
+// The returned stop function closes the server and waits for it to stop
+// serving; it returns the error Close returned, or the one serving failed
+// with if it stopped by itself, and may be called more than once.
+func (ds *Server) ListenAndReady(listenSpec string) (stop func() error, err error) {
+	serve, err := ds.bind(listenSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.markBound()
+
+	errs := make(chan error, 1)
+	go func() { errs <- serve() }()
+
+	select {
+	case <-ds.readyCh:
+	case err := <-errs:
+		ds.Close()
+		return nil, err
+	}
+
+	var (
+		once    sync.Once
+		stopErr error
+	)
+
+	return func() error {
+		once.Do(func() {
+			select {
+			case stopErr = <-errs:
+				ds.Close()
+			default:
+				stopErr = ds.Close()
+				<-errs
+			}
+		})
+
+		return stopErr
+	}, nil
+}
+
+// ListenTCP is like Listen, but serves DNS requests over TCP.
+func (ds *Server) ListenTCP(listenSpec string) error {
+	srv, err := ds.bindTCP(listenSpec)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	return srv.ActivateAndServe()
+}
+
+// ListenBoth serves DNS requests over both UDP and TCP on listenSpec, so that
+// clients may retry truncated responses over TCP. If the port in listenSpec is
+// 0, the TCP listener binds the port chosen for UDP. This function blocks and
+// only returns when either service is no longer functioning; the other is shut
+// down with it.
+func (ds *Server) ListenBoth(listenSpec string) error {
+	serve, err := ds.bindBoth(listenSpec)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	return serve()
+}
+
+// bind binds the network chosen with WithListenNet, returning a function which
+// serves it.
+func (ds *Server) bind(listenSpec string) (func() error, error) {
+	switch ds.listenNet {
+	case "udp":
+		srv, err := ds.bindUDP(listenSpec)
+		if err != nil {
+			return nil, err
+		}
+		return srv.ActivateAndServe, nil
+	case "tcp":
+		srv, err := ds.bindTCP(listenSpec)
+		if err != nil {
+			return nil, err
+		}
+		return srv.ActivateAndServe, nil
+	case "both":
+		return ds.bindBoth(listenSpec)
+	default:
+		return nil, ErrUnknownNet
+	}
+}
+
+func (ds *Server) bindBoth(listenSpec string) (func() error, error) {
+	udp, err := ds.bindUDP(listenSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, port := ds.Listening()
+
+	tcp, err := ds.bindTCP(net.JoinHostPort(ip.String(), fmt.Sprintf("%d", port)))
+	if err != nil {
+		udp.PacketConn.Close()
+		return nil, err
+	}
+
+	return func() error {
+		errs := make(chan error, 2)
+		go func() { errs <- udp.ActivateAndServe() }()
+		go func() { errs <- tcp.ActivateAndServe() }()
+
+		err := <-errs
+		ds.Close()
+		if err2 := <-errs; err == nil {
+			err = err2
+		}
+
+		return err
+	}, nil
+}
+
+// ListenWithNet is like Listen, but binds UDP on the given network: "udp4"
+// or "udp6" to force an address family, or "udp" for either. An IP address in
+// listenSpec must belong to the family chosen; ErrFamilyMismatch is returned
+// otherwise.
+func (ds *Server) ListenWithNet(listenSpec, network string) error {
+	srv, err := ds.bindUDPNet(network, listenSpec)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	return srv.ActivateAndServe()
+}
+
+// checkFamily reports whether the host in listenSpec can be bound on network.
+func checkFamily(network, listenSpec string) error {
+	switch network {
+	case "udp", "udp4", "udp6":
+	default:
+		return ErrUnknownNet
+	}
+
+	host, _, err := net.SplitHostPort(listenSpec)
+	if err != nil {
+		return err
+	}
+
+	if i := strings.LastIndex(host, "%"); i >= 0 {
+		host = host[:i]
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+
+	if (network == "udp4" && ip.To4() == nil) || (network == "udp6" && ip.To4() != nil) {
+		return ErrFamilyMismatch
+	}
+
+	return nil
+}
+
+func (ds *Server) bindUDP(listenSpec string) (*dns.Server, error) {
+	return ds.bindUDPNet("udp", listenSpec)
+}
+
+func (ds *Server) bindUDPNet(network, listenSpec string) (*dns.Server, error) {
+	if err := checkFamily(network, listenSpec); err != nil {
+		return nil, err
+	}
+
 	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if ds.closed {
+		return nil, ErrServerClosed
+	}
+
 	var lc net.ListenConfig
-	conn, err := lc.ListenPacket(context.Background(), "udp", listenSpec)
+	conn, err := lc.ListenPacket(context.Background(), network, listenSpec)
 	if err != nil {
-		ds.configMutex.Unlock()
-		return err
+		return nil, err
 	}
-	ds.server = &dns.Server{PacketConn: conn, Addr: listenSpec, Net: "udp", Handler: ds}
+	return ds.usePacketConn(conn, network, listenSpec), nil
+}
+
+// usePacketConn makes the UDP server serving conn, which must be bound to a
+// UDP address. The caller must hold configMutex.
+func (ds *Server) usePacketConn(conn net.PacketConn, network, listenSpec string) *dns.Server {
+	ds.server = &dns.Server{PacketConn: conn, Addr: listenSpec, Net: network, Handler: ds, MsgAcceptFunc: acceptMsg, NotifyStartedFunc: ds.started, TsigSecret: ds.tsigSecrets}
+	ds.pending++
 	u := conn.LocalAddr().(*net.UDPAddr)
 	ds.listenIP, ds.listenPort = u.IP, uint(u.Port)
-	ds.configMutex.Unlock()
-	return ds.server.ActivateAndServe()
+	return ds.server
+}
+
+func (ds *Server) bindTCP(listenSpec string) (*dns.Server, error) {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if ds.closed {
+		return nil, ErrServerClosed
+	}
+
+	var lc net.ListenConfig
+	l, err := lc.Listen(context.Background(), "tcp", listenSpec)
+	if err != nil {
+		return nil, err
+	}
+	return ds.useListener(l, listenSpec), nil
+}
+
+// useListener makes the TCP server serving l, which must be bound to a TCP
+// address. The caller must hold configMutex.
+func (ds *Server) useListener(l net.Listener, listenSpec string) *dns.Server {
+	ds.tcpServer = &dns.Server{Listener: l, Addr: listenSpec, Net: "tcp", Handler: ds, MsgAcceptFunc: acceptMsg, NotifyStartedFunc: ds.started, TsigSecret: ds.tsigSecrets}
+	ds.pending++
+	t := l.Addr().(*net.TCPAddr)
+	ds.tcpIP, ds.tcpPort = t.IP, uint(t.Port)
+	return ds.tcpServer
+}
+
+// Started returns a channel which is closed once Listen, or any of its
+// variants, has bound its sockets. Listening and ListeningTCP report the bound
+// addresses by then, so a caller may listen on port 0 and read back the port
+// chosen. The channel is also closed by Close, after which the addresses are
+// zero.
+func (ds *Server) Started() <-chan struct{} {
+	return ds.boundCh
+}
+
+// markBound closes the channel returned by Started.
+func (ds *Server) markBound() {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if !ds.bound {
+		ds.bound = true
+		close(ds.boundCh)
+		ds.startSweeper()
+		atomic.StoreInt64(&ds.listenedAt, time.Now().UnixNano())
+	}
+}
+
+// started is called by each dns.Server once it is serving. The server is
+// ready when every bound server has started.
+func (ds *Server) started() {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	ds.pending--
+	if ds.pending == 0 && !ds.ready {
+		ds.ready = true
+		close(ds.readyCh)
+	}
+}
+
+// Ready reports whether the server is serving queries: every listener which
+// has been bound has started, and the server has not been closed.
+func (ds *Server) Ready() bool {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	return ds.ready && !ds.closed
+}
+
+// WaitReady blocks until the server is ready, as reported by Ready, or ctx is
+// done, in which case ctx.Err() is returned. If the server is closed first,
+// ErrServerClosed is returned.
+func (ds *Server) WaitReady(ctx context.Context) error {
+	select {
+	case <-ds.readyCh:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if ds.closed {
+		return ErrServerClosed
+	}
+
+	return nil
 }
 
-// Close closes the DNS server. If it is not started, nil is returned.
+// Close closes the DNS server, including any TCP listener. It may be called
+// more than once, and from several goroutines; only the first call does any
+// work, and if the server was not started, nil is returned. A Listen which is
+// still binding or starting is interrupted. Once closed, the server cannot
+// listen again; Listen returns ErrServerClosed.
 func (ds *Server) Close() error {
+	return ds.closeContext(context.Background())
+}
+
+// closeContext closes the server, waiting until ctx is done for the listeners
+// to finish any queries they are answering.
+func (ds *Server) closeContext(ctx context.Context) error {
 	ds.configMutex.Lock()
 	defer ds.configMutex.Unlock()
 
+	if ds.closed {
+		return nil
+	}
+	ds.closed = true
+	ds.stopSweeper()
+	atomic.StoreInt64(&ds.listenedAt, 0)
+
+	var err error
+
 	if ds.server != nil {
-		return ds.server.Shutdown()
+		if e := ds.server.ShutdownContext(ctx); e != nil && err == nil {
+			err = e
+		}
+		// stops a server which had not started yet
+		ds.server.PacketConn.Close()
 	}
 
-	return nil
+	if ds.tcpServer != nil {
+		if e := ds.tcpServer.ShutdownContext(ctx); e != nil && err == nil {
+			err = e
+		}
+		ds.tcpServer.Listener.Close()
+	}
+
+	if ds.tlsServer != nil {
+		if e := ds.tlsServer.ShutdownContext(ctx); e != nil && err == nil {
+			err = e
+		}
+		ds.tlsServer.Listener.Close()
+	}
+
+	ds.server, ds.tcpServer, ds.tlsServer = nil, nil, nil
+	ds.listenIP, ds.listenPort = nil, 0
+	ds.tcpIP, ds.tcpPort = nil, 0
+	ds.tlsIP, ds.tlsPort = nil, 0
+
+	// release anyone waiting on Started or in WaitReady
+	if !ds.bound {
+		ds.bound = true
+		close(ds.boundCh)
+	}
+
+	if !ds.ready {
+		ds.ready = true
+		close(ds.readyCh)
+	}
+
+	return err
 }
 
 // Convenience function to ensure the fqdn is well-formed, and keeps the
-// set/delete interface easy.
+// set/delete interface easy. The apex host qualifies to the domain itself.
+// The name is normalized as query names are, see normalizeName, so that hosts
+// stored in a non-canonical form still make names replies can carry. Names
+// which are validated after qualifying, such as record targets, use joinHost
+// instead, so that "a..b" is not quietly accepted as "a.b".
 func (ds *Server) qualifyHost(host string) string {
-	return host + "." + ds.domain
+	fqdn := ds.joinHost(host)
+	if name, ok := normalizeName(fqdn); ok {
+		return name
+	}
+
+	return fqdn
+}
+
+// joinHost qualifies host with the managed domain as it is given.
+func (ds *Server) joinHost(host string) string {
+	if host == apexHost {
+		return ds.domain
+	}
+
+	return strings.ToLower(host) + "." + ds.domain
 }
 
 // Convenience function to ensure that SRV names are well-formed.
 func (ds *Server) qualifySrv(service, protocol string) string {
-	return fmt.Sprintf("_%s._%s", service, protocol)
+	return strings.ToLower(db.SRVSpec{Service: service, Protocol: protocol}.String())
 }
 
 // rewrites supplied host entries to use the domain this dns server manages.
-// Returns and modifies the pointer.
+// Returns a modified copy; the supplied record is left untouched. Hosts which
+// are already qualified are not qualified again.
 func (ds *Server) qualifySrvHost(srv *db.SRVRecord) *db.SRVRecord {
-	srv.Host = ds.qualifyHost(srv.Host)
-	return srv
+	t := *srv
+
+	switch {
+	case dns.IsFqdn(t.Host):
+	case ds.inDomain(t.Host + "."):
+		t.Host += "."
+	default:
+		t.Host = ds.joinHost(t.Host)
+	}
+
+	return &t
+}
+
+// qualifyTarget qualifies a record target with the managed domain, unless it is
+// already a FQDN.
+func (ds *Server) qualifyTarget(target string) string {
+	if dns.IsFqdn(target) {
+		return target
+	}
+
+	return ds.joinHost(target)
+}
+
+// inDomain reports whether the FQDN is managed by this server.
+func (ds *Server) inDomain(name string) bool {
+	return strings.HasSuffix(strings.ToLower(name), "."+ds.domain)
 }
 
 func (ds *Server) subdomain(name string) string {
+	if strings.EqualFold(name, ds.domain) {
+		return apexHost
+	}
+
 	// this is probably the worst idea ever.
-	return strings.TrimSuffix(name, "."+ds.domain)
+	return strings.TrimSuffix(strings.ToLower(name), "."+ds.domain)
 }
 
-// GetA receives a FQDN; looks up and supplies the A record.
+// GetA receives a FQDN; looks up and supplies the A records. One record is
+// returned for each address registered to the host. Names in the domain with
+// no A records of their own are answered from the wildcard, if one is set. The
+// domain itself is answered from the apex address; see SetApexA.
+// Errors are logged and yield no records; use LookupA to tell them apart.
 func (ds *Server) GetA(name string) []*dns.A {
+	records, err := ds.LookupA(name)
+	ds.logLookup(name, dns.TypeA, err)
+	return records
+}
+
+// LookupA is like GetA, but returns any error from the DB, including
+// db.ErrNotFound when the name has no A records.
+func (ds *Server) LookupA(name string) ([]*dns.A, error) {
 	sub := ds.subdomain(name)
-	val, err := ds.db.GetA(sub)
+	vals, err := ds.getA(sub)
+	if err == db.ErrNotFound && ds.inDomain(name) {
+		sub = wildcardHost
+		vals, err = ds.getA(sub)
+	}
+
 	if err != nil {
-		if err != db.ErrNotFound {
-			fmt.Println(err)
-		}
+		return nil, err
+	}
+
+	ttl, err := ds.db.GetATTL(sub)
+	if err != nil && err != db.ErrNotFound {
+		return nil, err
+	}
+
+	records := []*dns.A{}
+
+	for _, val := range ds.weigh(sub, vals) {
+		records = append(records, &dns.A{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypeA,
+				Class:  dns.ClassINET,
+				Ttl:    ds.ttlFor(ttl),
+			},
+			A: val,
+		})
+	}
+
+	return records, nil
+}
+
+// SetWildcardA sets the address served for any name in the domain that has no
+// A records of its own. The wildcard is stored as the host "*", so it can be
+// removed with DeleteA("*") and appears as such in ListA.
+func (ds *Server) SetWildcardA(ip net.IP) error {
+	return ds.changed(ds.db.SetA(wildcardHost, ip))
+}
+
+// SetDefaultA sets the address served for any name in the domain that has
+// neither A records of its own nor a wildcard to answer it, such as a
+// placeholder page for every name not yet in use. Unlike the wildcard, it is
+// kept by the server rather than the DB, and is not listed by ListA or sent
+// in zone transfers. It only answers A queries: GetA does not return it, and
+// the names it answers are not taken to exist by update prerequisites or
+// used as glue. A nil address removes it; ErrInvalidIP is returned for an
+// unspecified or non-IPv4 one.
+func (ds *Server) SetDefaultA(ip net.IP) error {
+	if ip != nil && (ip.IsUnspecified() || ip.To4() == nil) {
+		return fmt.Errorf("%w: %v", ErrInvalidIP, ip)
+	}
+
+	if ip != nil {
+		ip = append(net.IP{}, ip.To4()...)
+	}
+
+	ds.defaultMutex.Lock()
+	ds.defaultA = ip
+	ds.defaultMutex.Unlock()
+
+	return ds.changed(nil)
+}
+
+// defaultAddr returns the address set with SetDefaultA, or nil.
+func (ds *Server) defaultAddr() net.IP {
+	ds.defaultMutex.RLock()
+	defer ds.defaultMutex.RUnlock()
+	return ds.defaultA
+}
+
+// lookupDefaultA answers an A query for name from the default address, if one
+// is set and name is in the domain. It is only consulted by serve, once the
+// name's own records and the wildcard have missed, so that names answered
+// this way do not appear to exist to updates, glue or GetA.
+func (ds *Server) lookupDefaultA(name string) []dns.RR {
+	ip := ds.defaultAddr()
+	if ip == nil || !ds.inDomain(name) {
 		return nil
 	}
 
-	return []*dns.A{&dns.A{
+	return []dns.RR{&dns.A{
 		Hdr: dns.RR_Header{
 			Name:   name,
 			Rrtype: dns.TypeA,
 			Class:  dns.ClassINET,
-			Ttl:    1,
+			Ttl:    ds.ttlFor(0),
 		},
-		A: val,
+		A: ip,
 	}}
 }
 
-// SetA sets a host to an IP. Note that this is not the FQDN, but a hostname.
+// SetApexA sets the address served for the domain itself, so that e.g. a query
+// for "docker." resolves, replacing any already set. The address is stored as
+// the host "@", so it can be removed with DeleteA("@") and appears as such in
+// ListA.
+func (ds *Server) SetApexA(ip net.IP) error {
+	return ds.SetA(apexHost, ip)
+}
+
+// SetA sets a host to an IP, replacing any addresses already registered. Note
+// that this is not the FQDN, but a hostname. ErrInvalidName is returned for a
+// host which cannot be qualified into a valid name, and ErrInvalidIP for a nil,
+// unspecified or non-IPv4 address.
 func (ds *Server) SetA(host string, ip net.IP) error {
-	return ds.db.SetA(host, ip)
+	if err := ds.checkA(host, ip); err != nil {
+		return err
+	}
+
+	ds.forgetWeights(host)
+	ds.forgetExpiry(host)
+	return ds.announce(ds.changed(ds.db.SetA(host, ip)), ds.aEvents(EventSet, host, ip)...)
 }
 
-// DeleteA deletes a host. Note that this is not the FQDN, but a hostname.
-func (ds *Server) DeleteA(host string) error {
-	return ds.db.DeleteA(host)
+// SetATTL sets the TTL emitted for a host's A records, overriding the server
+// default. A TTL of 0 reverts to the default. db.ErrNotFound is returned if the
+// host has no A records.
+func (ds *Server) SetATTL(host string, ttl uint32) error {
+	return ds.changed(ds.db.SetATTL(host, ttl))
 }
 
-// ListA lists all A records.
-func (ds *Server) ListA() (map[string]net.IP, error) {
-	return ds.db.ListA()
+// AddA adds an IP to a host, keeping any addresses already registered so the
+// host can be served round-robin. Note that this is not the FQDN, but a
+// hostname.
+func (ds *Server) AddA(host string, ip net.IP) error {
+	if err := ds.checkA(host, ip); err != nil {
+		return err
+	}
+
+	ds.forgetWeights(host, ip)
+	return ds.announce(ds.changed(ds.db.AddA(host, ip)), ds.aEvents(EventAdd, host, ip)...)
 }
 
-// ListSRV lists all SRV records.
-func (ds *Server) ListSRV() (map[string]*db.SRVRecord, error) {
-	return ds.db.ListSRV()
+// DeleteA deletes a host. Note that this is not the FQDN, but a hostname. If
+// any ips are provided, only those addresses are removed from the host.
+func (ds *Server) DeleteA(host string, ips ...net.IP) error {
+	ds.forgetWeights(host, ips...)
+
+	err := ds.db.DeleteA(host, ips...)
+	if err == nil {
+		ds.forgetExpiry(host, ips...)
+	}
+
+	return ds.announce(ds.changed(err), ds.aEvents(EventDelete, host, ips...)...)
 }
 
-// GetSRV given a service spec, looks up and returns an array of *dns.SRV objects.
-// These must be massaged into the []dns.RR after the fact.
-func (ds *Server) GetSRV(spec string) []*dns.SRV {
-	sub := ds.subdomain(spec)
-	srv, err := ds.db.GetSRV(sub)
+// DeleteByIP removes ip from every host's A and AAAA records, returning the
+// number of records removed; hosts left with no addresses are deleted. This is
+// for tearing down a container whose hostnames are not all known. As with
+// DeleteA, the address loses its weight, and hosts left with none lose their
+// expiry. Watchers see the address deleted from each host which had it.
+func (ds *Server) DeleteByIP(ip net.IP) (int, error) {
+	hosts, err := ds.hostsWithA(ip)
 	if err != nil {
-		if err != db.ErrNotFound {
-			fmt.Println(err)
-		}
-		return nil
+		return 0, err
 	}
 
-	srvRecord := &dns.SRV{
-		Hdr: dns.RR_Header{
-			Name:   spec,
-			Rrtype: dns.TypeSRV,
-			Class:  dns.ClassINET,
-			// 0 TTL results in UB for DNS resolvers and generally causes problems.
-			Ttl: 1,
-		},
-		Priority: 0,
-		Weight:   0,
-		Port:     srv.Port,
-		Target:   ds.qualifyHost(srv.Host),
+	for _, host := range hosts {
+		ds.forgetWeights(host, ip)
 	}
 
-	return []*dns.SRV{srvRecord}
-}
+	n, err := ds.db.DeleteByIP(ip)
+	if n == 0 {
+		return n, err
+	}
 
-// SetSRV sets a SRV with a service and protocol. See SRVRecord for more information
-// on what that requires.
-func (ds *Server) SetSRV(service, protocol string, srv *db.SRVRecord) error {
-	return ds.db.SetSRV(ds.qualifySrv(service, protocol), srv)
-}
+	var events []Event
+	for _, host := range hosts {
+		ds.forgetExpiry(host, ip)
+		events = append(events, ds.aEvents(EventDelete, host, ip)...)
+	}
 
-// DeleteSRV deletes a SRV record based on the service and protocol.
-func (ds *Server) DeleteSRV(service, protocol string) error {
-	return ds.db.DeleteSRV(ds.qualifySrv(service, protocol))
+	return n, ds.announce(ds.changed(err), events...)
 }
 
-// ServeDNS is the main callback for miekg/dns. Collects information about the
-// query, constructs a response, and returns it to the connector.
-func (ds *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+// hostsWithA lists the hosts which have ip among their A records.
+func (ds *Server) hostsWithA(ip net.IP) ([]string, error) {
+	as, err := ds.db.ListA()
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []string
+
+	for host, ips := range as {
+		for _, addr := range ips {
+			if addr.Equal(ip) {
+				hosts = append(hosts, host)
+				break
+			}
+		}
+	}
+
+	return hosts, nil
+}
+
+// HostA returns the addresses stored for host, as ListA lists them. Unlike
+// LookupA, it takes a hostname rather than a FQDN and does not fall back to
+// the wildcard. db.ErrNotFound is returned if the host has no A records.
+func (ds *Server) HostA(host string) ([]net.IP, error) {
+	return ds.db.GetA(host)
+}
+
+// ListA lists all A records.
+func (ds *Server) ListA() (db.ARecords, error) {
+	return ds.db.ListA()
+}
+
+// ForEachA calls fn with the host and address of each A record, as ListA
+// keys them, until fn returns false. Unlike ListA, the records are not copied
+// first, which matters for large zones; see the DB's ForEachA for what fn may
+// do.
+func (ds *Server) ForEachA(fn func(host string, ip net.IP) bool) error {
+	return ds.db.ForEachA(fn)
+}
+
+// CountA returns the number of hosts with A records, without listing them.
+func (ds *Server) CountA() (int, error) {
+	return ds.db.CountA()
+}
+
+// GetAAAA receives a FQDN; looks up and supplies the AAAA record.
+func (ds *Server) GetAAAA(name string) []*dns.AAAA {
+	sub := ds.subdomain(name)
+	val, err := ds.db.GetAAAA(sub)
+	if err != nil {
+		ds.logLookup(name, dns.TypeAAAA, err)
+		return nil
+	}
+
+	return []*dns.AAAA{&dns.AAAA{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeAAAA,
+			Class:  dns.ClassINET,
+			Ttl:    ds.ttlFor(0),
+		},
+		AAAA: val,
+	}}
+}
+
+// SetAAAA sets a host to an IPv6 address. Note that this is not the FQDN, but
+// a hostname. IPv4 addresses are rejected with ErrNotIPv6.
+func (ds *Server) SetAAAA(host string, ip net.IP) error {
+	if ip.To4() != nil || ip.To16() == nil {
+		return ErrNotIPv6
+	}
+
+	return ds.changed(ds.db.SetAAAA(host, ip))
+}
+
+// DeleteAAAA deletes a host's AAAA record. Note that this is not the FQDN, but
+// a hostname.
+func (ds *Server) DeleteAAAA(host string) error {
+	return ds.changed(ds.db.DeleteAAAA(host))
+}
+
+// ListAAAA lists all AAAA records.
+func (ds *Server) ListAAAA() (map[string]net.IP, error) {
+	return ds.db.ListAAAA()
+}
+
+// GetCNAME receives a FQDN; looks up and supplies the CNAME record.
+func (ds *Server) GetCNAME(name string) []*dns.CNAME {
+	sub := ds.subdomain(name)
+	target, err := ds.db.GetCNAME(sub)
+	if err != nil {
+		ds.logLookup(name, dns.TypeCNAME, err)
+		return nil
+	}
+
+	return []*dns.CNAME{&dns.CNAME{
+		Hdr: dns.RR_Header{
+			Name:   name,
+			Rrtype: dns.TypeCNAME,
+			Class:  dns.ClassINET,
+			Ttl:    ds.ttlFor(0),
+		},
+		Target: target,
+	}}
+}
+
+// SetCNAME aliases a host to a target. The alias is a hostname, not the FQDN.
+// The target is qualified with the server's domain unless it is already a FQDN.
+func (ds *Server) SetCNAME(alias, target string) error {
+	return ds.changed(ds.db.SetCNAME(alias, ds.qualifyTarget(target)))
+}
+
+// DeleteCNAME deletes an alias. Note that this is not the FQDN, but a hostname.
+func (ds *Server) DeleteCNAME(alias string) error {
+	return ds.changed(ds.db.DeleteCNAME(alias))
+}
+
+// GetTXT receives a FQDN; looks up and supplies the TXT records. One record is
+// returned for each value registered to the host.
+func (ds *Server) GetTXT(name string) []*dns.TXT {
+	sub := ds.subdomain(name)
+	values, err := ds.db.GetTXT(sub)
+	if err != nil {
+		ds.logLookup(name, dns.TypeTXT, err)
+		return nil
+	}
+
+	records := []*dns.TXT{}
+
+	for _, value := range values {
+		records = append(records, &dns.TXT{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypeTXT,
+				Class:  dns.ClassINET,
+				Ttl:    ds.ttlFor(0),
+			},
+			Txt: splitTXT(value),
+		})
+	}
+
+	return records
+}
+
+// SetTXT sets the TXT records for a host, replacing any already registered.
+// Each value is served as its own TXT record. Note that this is not the FQDN,
+// but a hostname.
+func (ds *Server) SetTXT(host string, values []string) error {
+	return ds.changed(ds.db.SetTXT(host, values))
+}
+
+// DeleteTXT deletes a host's TXT records. Note that this is not the FQDN, but
+// a hostname.
+func (ds *Server) DeleteTXT(host string) error {
+	return ds.changed(ds.db.DeleteTXT(host))
+}
+
+// GetMX receives a FQDN; looks up and supplies the MX records, sorted by
+// preference.
+func (ds *Server) GetMX(name string) []*dns.MX {
+	sub := ds.subdomain(name)
+	recs, err := ds.db.GetMX(sub)
+	if err != nil {
+		ds.logLookup(name, dns.TypeMX, err)
+		return nil
+	}
+
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].Preference < recs[j].Preference })
+
+	records := []*dns.MX{}
+
+	for _, rec := range recs {
+		records = append(records, &dns.MX{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypeMX,
+				Class:  dns.ClassINET,
+				Ttl:    ds.ttlFor(0),
+			},
+			Preference: rec.Preference,
+			Mx:         rec.Mail,
+		})
+	}
+
+	return records
+}
+
+// SetMX adds a mail exchange for a host. Note that this is not the FQDN, but a
+// hostname. The mail exchange is qualified with the server's domain unless it
+// is already a FQDN. Setting an already registered mail exchange updates its
+// preference.
+func (ds *Server) SetMX(host string, preference uint16, mail string) error {
+	return ds.changed(ds.db.SetMX(host, &db.MXRecord{Preference: preference, Mail: ds.qualifyTarget(mail)}))
+}
+
+// DeleteMX deletes all of a host's MX records. Note that this is not the FQDN,
+// but a hostname.
+func (ds *Server) DeleteMX(host string) error {
+	return ds.changed(ds.db.DeleteMX(host))
+}
+
+// GetNS receives the apex FQDN and supplies its NS records. Until SetNS is
+// called, a single record for ns.<domain> is served. Names other than the
+// apex have no NS records.
+func (ds *Server) GetNS(name string) []*dns.NS {
+	if !strings.EqualFold(name, ds.domain) {
+		return nil
+	}
+
+	nameservers, err := ds.db.GetNS(apexHost)
+	if err == db.ErrNotFound {
+		nameservers = []string{"ns." + ds.domain}
+	} else if err != nil {
+		ds.logLookup(name, dns.TypeNS, err)
+		return nil
+	}
+
+	records := []*dns.NS{}
+
+	for _, nameserver := range nameservers {
+		records = append(records, &dns.NS{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypeNS,
+				Class:  dns.ClassINET,
+				Ttl:    ds.ttlFor(0),
+			},
+			Ns: nameserver,
+		})
+	}
+
+	return records
+}
+
+// SetNS sets the nameservers for the zone apex, replacing any already set.
+// Unqualified names are qualified with the server's domain. An empty list
+// reverts to the default.
+func (ds *Server) SetNS(nameservers []string) error {
+	if len(nameservers) == 0 {
+		return ds.changed(ds.db.DeleteNS(apexHost))
+	}
+
+	qualified := make([]string, 0, len(nameservers))
+	for _, nameserver := range nameservers {
+		qualified = append(qualified, ds.qualifyTarget(nameserver))
+	}
+
+	return ds.changed(ds.db.SetNS(apexHost, qualified))
+}
+
+// authority returns the apex NS records as dns.RRs.
+func (ds *Server) authority() []dns.RR {
+	rrs := []dns.RR{}
+	for _, record := range ds.GetNS(ds.domain) {
+		rrs = append(rrs, record)
+	}
+
+	return rrs
+}
+
+// splitTXT breaks a TXT value into the 255-byte character-strings the wire
+// format requires.
+func splitTXT(value string) []string {
+	chunks := []string{}
+
+	for len(value) > maxTXTChunk {
+		chunks = append(chunks, value[:maxTXTChunk])
+		value = value[maxTXTChunk:]
+	}
+
+	return append(chunks, value)
+}
+
+// chaseCNAME resolves name through its CNAME, if any, returning the CNAME RR
+// followed by whatever lookup yields for the target. Only one level is chased,
+// to avoid loops; if the target is the name itself or points straight back at
+// it, errCNAMELoop is returned. A nil result means name has no CNAME.
+func (ds *Server) chaseCNAME(name string, lookup func(string) []dns.RR) ([]dns.RR, error) {
+	cnames := ds.GetCNAME(name)
+	if len(cnames) == 0 {
+		return nil, nil
+	}
+
+	cname := cnames[0]
+	if strings.EqualFold(cname.Target, name) {
+		return nil, errCNAMELoop
+	}
+
+	answers := []dns.RR{cname}
+
+	if !ds.inDomain(cname.Target) {
+		return answers, nil
+	}
+
+	if next := ds.GetCNAME(cname.Target); len(next) > 0 {
+		if strings.EqualFold(next[0].Target, name) {
+			return nil, errCNAMELoop
+		}
+
+		return answers, nil
+	}
+
+	return append(answers, lookup(cname.Target)...), nil
+}
+
+func (ds *Server) lookupA(name string) []dns.RR {
+	answers := []dns.RR{}
+	for _, record := range ds.GetA(name) {
+		answers = append(answers, record)
+	}
+
+	return answers
+}
+
+func (ds *Server) lookupAAAA(name string) []dns.RR {
+	answers := []dns.RR{}
+	for _, record := range ds.GetAAAA(name) {
+		answers = append(answers, record)
+	}
+
+	return answers
+}
+
+// ListSRV lists all SRV records, keyed by the String form of their spec.
+func (ds *Server) ListSRV() (db.SRVRecords, error) {
+	return ds.db.ListSRV()
+}
+
+// ServiceSRV returns the targets stored for a service, as ListSRV lists them,
+// with their TTLs as set rather than as served. db.ErrNotFound is returned if
+// the service has no SRV records.
+func (ds *Server) ServiceSRV(service, protocol string) ([]*db.SRVRecord, error) {
+	return ds.db.GetSRV(ds.qualifySrv(service, protocol))
+}
+
+// ListSRVSpecs is like ListSRV, but keys the records by their parsed spec.
+// Records stored under a name which is not a valid spec are left out.
+func (ds *Server) ListSRVSpecs() (map[db.SRVSpec][]*db.SRVRecord, error) {
+	records, err := ds.db.ListSRV()
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make(map[db.SRVSpec][]*db.SRVRecord, len(records))
+	for name, srvs := range records {
+		if spec, err := db.ParseSRVSpec(name, ""); err == nil {
+			specs[spec] = srvs
+		}
+	}
+
+	return specs, nil
+}
+
+// GetSRV given a service spec, looks up and returns an array of *dns.SRV objects,
+// one for each target registered to the service. These must be massaged into
+// the []dns.RR after the fact. Errors are logged and yield no records; use
+// LookupSRV to tell them apart.
+func (ds *Server) GetSRV(spec string) []*dns.SRV {
+	records, err := ds.LookupSRV(spec)
+	ds.logLookup(spec, dns.TypeSRV, err)
+	return records
+}
+
+// LookupSRV is like GetSRV, but returns any error from the DB, including
+// db.ErrNotFound when the service has no targets.
+func (ds *Server) LookupSRV(spec string) ([]*dns.SRV, error) {
+	srvs, err := ds.db.GetSRV(ds.subdomain(spec))
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*dns.SRV{}
+
+	for _, srv := range srvs {
+		records = append(records, &dns.SRV{
+			Hdr: dns.RR_Header{
+				Name:   spec,
+				Rrtype: dns.TypeSRV,
+				Class:  dns.ClassINET,
+				// 0 TTL results in UB for DNS resolvers and generally causes problems.
+				// ttlFor never yields 0; see SetTTL.
+				Ttl: ds.ttlFor(srv.TTL),
+			},
+			Priority: srv.Priority,
+			Weight:   srv.Weight,
+			Port:     srv.Port,
+			Target:   ds.qualifySrvHost(srv).Host,
+		})
+	}
+
+	return records, nil
+}
+
+// SetSRV sets a SRV with a service and protocol, replacing any targets already
+// registered. The target host is qualified with the server's domain before it
+// is stored. See SRVRecord for more information on what that requires.
+// ErrInvalidName is returned if the service, protocol or target cannot form a
+// valid name.
+func (ds *Server) SetSRV(service, protocol string, srv *db.SRVRecord) error {
+	spec, srv := ds.qualifySrv(service, protocol), ds.qualifySrvHost(srv)
+	if err := ds.checkSRV(spec, srv); err != nil {
+		return err
+	}
+
+	return ds.announce(ds.changed(ds.db.SetSRV(spec, srv)), ds.srvEvent(EventSet, spec, srv))
+}
+
+// SetSRVRaw is like SetSRV, but stores the target host verbatim rather than
+// qualifying it with the managed domain, so that SRV records may point at
+// external hosts. The host must be a FQDN ending in '.'; ErrNotFQDN is
+// returned otherwise.
+func (ds *Server) SetSRVRaw(service, protocol string, srv *db.SRVRecord) error {
+	if _, ok := dns.IsDomainName(srv.Host); !ok || !dns.IsFqdn(srv.Host) {
+		return fmt.Errorf("%w: %q", ErrNotFQDN, srv.Host)
+	}
+
+	spec, t := ds.qualifySrv(service, protocol), *srv
+	if err := ds.checkSRV(spec, &t); err != nil {
+		return err
+	}
+
+	return ds.announce(ds.changed(ds.db.SetSRV(spec, &t)), ds.srvEvent(EventSet, spec, &t))
+}
+
+// AddSRV adds a target to a SRV with a service and protocol, keeping any
+// targets already registered. See SRVRecord for more information on what that
+// requires.
+func (ds *Server) AddSRV(service, protocol string, srv *db.SRVRecord) error {
+	spec, srv := ds.qualifySrv(service, protocol), ds.qualifySrvHost(srv)
+	if err := ds.checkSRV(spec, srv); err != nil {
+		return err
+	}
+
+	return ds.announce(ds.changed(ds.db.AddSRV(spec, srv)), ds.srvEvent(EventAdd, spec, srv))
+}
+
+// DeleteSRV deletes a SRV record based on the service and protocol.
+func (ds *Server) DeleteSRV(service, protocol string) error {
+	spec := ds.qualifySrv(service, protocol)
+	return ds.announce(ds.changed(ds.db.DeleteSRV(spec)), ds.srvEvent(EventDelete, spec, nil))
+}
+
+// CountSRV returns the number of services with SRV records, without listing
+// them.
+func (ds *Server) CountSRV() (int, error) {
+	return ds.db.CountSRV()
+}
+
+// ServeDNS is the main callback for miekg/dns. Collects information about the
+// query, constructs a response, and returns it to the connector. The query
+// logger is called once the reply is written. Middleware registered with Use
+// runs around all of this.
+func (ds *Server) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	w = ds.countQuery(w, r)
+
+	if !ds.track() {
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeRefused)
+		w.WriteMsg(m)
+		return
+	}
+
+	slots, ok := ds.acquire()
+	if !ok {
+		ds.turnAway(w, r)
+		ds.inflight.Done()
+		return
+	}
+
+	hw := &heldWriter{ResponseWriter: w, ds: ds, slots: slots, refs: 1}
+	defer hw.release()
+	w = hw
+
+	ds.chainMutex.RLock()
+	chain := ds.chain
+	ds.chainMutex.RUnlock()
+
+	if chain == nil {
+		ds.serveDNS(w, r)
+		return
+	}
+
+	chain.ServeDNS(w, r)
+}
+
+// serveDNS is the terminal handler of the middleware chain.
+func (ds *Server) serveDNS(w dns.ResponseWriter, r *dns.Msg) {
+	start := time.Now()
+	rw := &recordingWriter{ResponseWriter: w}
+
+	ds.serveTimeout(rw, r)
+
+	if len(r.Question) != 0 {
+		ds.loggerMutex.RLock()
+		logger := ds.queryLogger
+		ds.loggerMutex.RUnlock()
+
+		logger.LogQuery(w.RemoteAddr(), r.Question[0], rw.rcode, rw.answers, time.Since(start))
+	}
+}
+
+// serve answers a query.
+func (ds *Server) serve(w dns.ResponseWriter, r *dns.Msg) {
+	if rcode, ok := ds.inMaintenance(); ok {
+		m := &dns.Msg{}
+		m.SetRcode(r, rcode)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	if !ds.queryAllowed(w.RemoteAddr()) {
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeRefused)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	if ok, action := ds.limiter.allow(w.RemoteAddr()); !ok {
+		ds.throttle(w, r, action)
+		return
+	}
+
+	if r.Opcode == dns.OpcodeUpdate {
+		ds.serveUpdate(w, r)
+		return
+	}
+
+	if len(r.Question) == 1 && r.Question[0].Qclass == dns.ClassCHAOS {
+		ds.serveChaos(w, r)
+		return
+	}
+
+	if len(r.Question) == 1 && r.Question[0].Qtype == dns.TypeAXFR {
+		ds.serveAXFR(w, r)
+		return
+	}
+
+	if len(r.Question) == 1 && r.Question[0].Qtype == dns.TypeIXFR {
+		ds.serveIXFR(w, r)
+		return
+	}
+
+	// Names are looked up normalized, and answered as such; see normalizeReply.
+	questions, ok := normalizeQuestions(r.Question)
+	if !ok {
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeFormatError)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	q := *r
+	q.Question = questions
+
+	if ds.shouldForward(&q) {
+		ds.forward(w, &q)
+		return
+	}
+
 	m := &dns.Msg{}
 	m.SetReply(r)
+	m.Authoritative = ds.namesInZone(questions)
+	m.RecursionAvailable = ds.forwarding()
 
 	answers := []dns.RR{}
+	view := ds.viewFor(w.RemoteAddr())
 
-	for _, question := range r.Question {
+	for _, question := range questions {
 		// nil records == not found
 		switch question.Qtype {
-		case dns.TypeA:
-			for _, record := range ds.GetA(question.Name) {
+		case dns.TypeA, dns.TypeAAAA:
+			lookup := ds.viewLookupA(view)
+			if question.Qtype == dns.TypeAAAA {
+				lookup = ds.lookupAAAA
+			}
+
+			records, err := ds.chaseDNAME(question.Name, lookup)
+			if err == nil && records == nil {
+				records, err = ds.chaseCNAME(question.Name, lookup)
+			}
+
+			if err != nil {
+				m.SetRcode(r, dns.RcodeServerFailure)
+				ds.writeMsg(w, r, m)
+				return
+			}
+
+			if records == nil {
+				records = lookup(question.Name)
+			}
+
+			if len(records) == 0 && question.Qtype == dns.TypeA {
+				records = ds.lookupDefaultA(question.Name)
+			}
+
+			answers = append(answers, records...)
+		case dns.TypeCNAME:
+			for _, record := range ds.GetCNAME(question.Name) {
+				answers = append(answers, record)
+			}
+		case dns.TypeTXT:
+			for _, record := range ds.GetTXT(question.Name) {
+				answers = append(answers, record)
+			}
+		case dns.TypeMX:
+			for _, record := range ds.GetMX(question.Name) {
+				answers = append(answers, record)
+			}
+		case dns.TypeCAA:
+			for _, record := range ds.GetCAA(question.Name) {
+				answers = append(answers, record)
+			}
+		case dns.TypePTR:
+			for _, record := range ds.GetPTR(question.Name) {
 				answers = append(answers, record)
 			}
 		case dns.TypeSRV:
 			for _, record := range ds.GetSRV(question.Name) {
 				answers = append(answers, record)
 			}
+		case dns.TypeSOA:
+			if strings.EqualFold(question.Name, ds.domain) {
+				answers = append(answers, ds.soa())
+			}
+		case dns.TypeNS:
+			for _, record := range ds.GetNS(question.Name) {
+				answers = append(answers, record)
+			}
+		case dns.TypeDNSKEY, dns.TypeDS:
+			answers = append(answers, ds.lookupDNSSEC(question.Name, question.Qtype)...)
+		case dns.TypeDNAME:
+			for _, record := range ds.GetDNAME(question.Name) {
+				answers = append(answers, record)
+			}
+		case dns.TypeANY:
+			records, err := ds.chaseDNAME(question.Name, ds.anyRecords)
+			if err != nil {
+				m.SetRcode(r, dns.RcodeServerFailure)
+				ds.writeMsg(w, r, m)
+				return
+			}
+
+			if records == nil {
+				records = ds.anyRecords(question.Name)
+			}
+
+			answers = append(answers, records...)
+		default:
+			answers = append(answers, ds.GetRaw(question.Name, question.Qtype)...)
 		}
 	}
 
 	// If we have no answers, that means we found nothing or didn't get a query
-	// we can reply to. Reply with no answers so we ensure the query moves on to
-	// the next server.
+	// we can reply to. If the name has records of other types, that is NODATA:
+	// an empty success with our SOA so resolvers can cache the absence.
+	// Otherwise reply NXDOMAIN so we ensure the query moves on to the next
+	// server, with the SOA if the name is one we are authoritative for.
+	// Reverse names may hold our PTR records, so misses there are NXDOMAIN
+	// too; any other name is not ours to answer, and is refused. Names which
+	// exist but were asked for a type we do not serve at all may be answered
+	// otherwise; see SetUnsupportedTypeRcode. If the DB failed to look the
+	// names up, none of this can be known, and the reply is SERVFAIL.
 	if len(answers) == 0 {
-		m.SetRcode(r, dns.RcodeNameError)
-		w.WriteMsg(m)
+		if err := ds.lookupError(questions); err != nil {
+			m.SetRcode(r, dns.RcodeServerFailure)
+			ds.writeMsg(w, r, m)
+			return
+		}
+
+		rcode, unsupported := ds.unsupportedRcode(questions)
+
+		switch {
+		case unsupported && ds.namesExist(questions):
+			m.SetRcode(r, rcode)
+		case ds.namesExist(questions):
+			m.Authoritative = true
+			m.Ns = []dns.RR{ds.negativeSOA()}
+			m.SetRcode(r, dns.RcodeSuccess)
+		case ds.namesInDomain(questions):
+			m.Ns = []dns.RR{ds.negativeSOA()}
+			m.SetRcode(r, dns.RcodeNameError)
+		case namesReverse(questions):
+			m.SetRcode(r, dns.RcodeNameError)
+		default:
+			m.SetRcode(r, dns.RcodeRefused)
+		}
+
+		ds.writeMsg(w, r, m)
 		return
 	}
 
-	// Without these the glibc resolver gets very angry.
+	ds.shuffle(answers)
+
+	// Without this the glibc resolver gets very angry.
 	m.Authoritative = true
-	m.RecursionAvailable = false
 	m.Answer = answers
+	m.Ns = ds.authority()
+	m.Extra = ds.glue(answers)
 
 	m.SetRcode(r, dns.RcodeSuccess)
+	ds.writeMsg(w, r, m)
+}
+
+// namesExist reports whether any of the questions names something we hold
+// records for, including the apex.
+func (ds *Server) namesExist(questions []dns.Question) bool {
+	for _, question := range questions {
+		if strings.EqualFold(question.Name, ds.domain) || ds.nameInUse(question.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// namesInZone reports whether all of the questions are for the apex or names
+// in our domain, which we answer authoritatively whatever the rcode.
+func (ds *Server) namesInZone(questions []dns.Question) bool {
+	for _, question := range questions {
+		if !strings.EqualFold(question.Name, ds.domain) && !ds.inDomain(question.Name) {
+			return false
+		}
+	}
+
+	return len(questions) != 0
+}
+
+// namesReverse reports whether all of the questions are for reverse lookup
+// names, under in-addr.arpa or ip6.arpa.
+func namesReverse(questions []dns.Question) bool {
+	for _, question := range questions {
+		name := strings.ToLower(question.Name)
+		if !strings.HasSuffix(name, ipv4ArpaSuffix) && !strings.HasSuffix(name, ipv6ArpaSuffix) {
+			return false
+		}
+	}
+
+	return len(questions) != 0
+}
+
+// namesInDomain reports whether all of the questions are for names in our
+// domain.
+func (ds *Server) namesInDomain(questions []dns.Question) bool {
+	for _, question := range questions {
+		if !ds.inDomain(question.Name) {
+			return false
+		}
+	}
+
+	return len(questions) != 0
+}
+
+// glue returns the address records of the in-domain SRV and MX targets among
+// answers, for the additional section, so that resolvers need not look them up
+// separately.
+func (ds *Server) glue(answers []dns.RR) []dns.RR {
+	var extra []dns.RR
+
+	seen := map[string]bool{}
+
+	for _, rr := range answers {
+		var target string
+
+		switch rr := rr.(type) {
+		case *dns.SRV:
+			target = rr.Target
+		case *dns.MX:
+			target = rr.Mx
+		default:
+			continue
+		}
+
+		target = strings.ToLower(target)
+		if seen[target] || !ds.inDomain(target) {
+			continue
+		}
+		seen[target] = true
+
+		extra = append(extra, ds.lookupA(target)...)
+		extra = append(extra, ds.lookupAAAA(target)...)
+	}
+
+	return extra
+}
+
+// anyRecords gathers the records of every supported type held for name, to
+// answer an ANY query.
+func (ds *Server) anyRecords(name string) []dns.RR {
+	rrs := []dns.RR{}
+
+	if strings.EqualFold(name, ds.domain) {
+		rrs = append(rrs, ds.soa())
+		for _, record := range ds.GetNS(name) {
+			rrs = append(rrs, record)
+		}
+	}
+
+	for _, rrtype := range []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeCNAME, dns.TypeDNAME, dns.TypeTXT, dns.TypeMX, dns.TypeCAA, dns.TypePTR, dns.TypeSRV} {
+		rrs = append(rrs, ds.rrset(name, rrtype)...)
+	}
+
+	return append(rrs, ds.GetRaw(name, dns.TypeANY)...)
+}
+
+// writeMsg finishes the reply m to the request r and writes it to the client.
+func (ds *Server) writeMsg(w dns.ResponseWriter, r *dns.Msg, m *dns.Msg) {
+	normalizeReply(m)
+
+	// signatures cover the TTLs and the records themselves, so they are
+	// clamped and capped first; capping after would cut the RRSIGs off
+	ds.clampTTLs(m)
+	ds.capAnswers(m)
+	ds.secure(r, m)
+
+	if opt := r.IsEdns0(); opt != nil {
+		// The DO bit is echoed back; see EnableDNSSEC for what it signs.
+		m.SetEdns0(EDNS0Size, opt.Do())
+	}
+
+	// UDP replies must fit in the client's buffer. Truncate trims the answers
+	// and sets the TC bit so compliant resolvers retry over TCP. Its size
+	// math accounts for name compression, which it turns on when the reply
+	// would not fit without.
+	if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		truncated, answers, ns := m.Truncated, len(m.Answer), len(m.Ns)
+		m.Truncate(udpSize(r))
+
+		// glue left out of the additional section is not worth a retry over
+		// TCP (RFC 2181 section 9)
+		if !truncated && len(m.Answer) == answers && len(m.Ns) == ns {
+			m.Truncated = false
+		}
+	}
+
+	// replies such as many SRV targets sharing the domain are much smaller
+	// compressed, whether or not they had to be trimmed
+	m.Compress = true
+
+	ds.signReply(w, r, m)
+
 	if err := w.WriteMsg(m); err != nil {
-		fmt.Println(err)
+		ds.log().Warn("writing reply failed", append(queryAttrs(w, r), "err", err)...)
+	}
+}
+
+// udpSize returns the largest UDP reply the client will accept: the size it
+// advertised with EDNS0, capped at EDNS0Size, or 512 bytes without it.
+func udpSize(r *dns.Msg) int {
+	if opt := r.IsEdns0(); opt != nil {
+		if size := int(opt.UDPSize()); size < EDNS0Size {
+			return size
+		}
+
+		return EDNS0Size
 	}
+
+	return dns.MinMsgSize
 }
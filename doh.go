@@ -0,0 +1,104 @@
+package dnsserver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/miekg/dns"
+)
+
+// dohMediaType is the content type of DNS messages carried over HTTPS.
+const dohMediaType = "application/dns-message"
+
+// DoHHandler returns an http.Handler serving DNS-over-HTTPS (RFC 8484). It
+// accepts GET requests carrying a base64url encoded query in the dns
+// parameter, and POST requests with an application/dns-message body. Queries
+// are answered by ResolveContext with the request's context, so zone
+// transfers, which need more than one reply, are refused. The Cache-Control
+// max-age of a reply is the lowest TTL of the records in it.
+//
+// The handler does not terminate TLS itself; serve it with an https server.
+func (ds *Server) DoHHandler() http.Handler {
+	return http.HandlerFunc(ds.serveDoH)
+}
+
+func (ds *Server) serveDoH(w http.ResponseWriter, req *http.Request) {
+	var (
+		buf []byte
+		err error
+	)
+
+	switch req.Method {
+	case http.MethodGet:
+		buf, err = base64.RawURLEncoding.DecodeString(req.URL.Query().Get("dns"))
+		if err != nil || len(buf) == 0 {
+			http.Error(w, "missing or malformed dns parameter", http.StatusBadRequest)
+			return
+		}
+	case http.MethodPost:
+		if req.Header.Get("Content-Type") != dohMediaType {
+			http.Error(w, "unsupported content type", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		buf, err = io.ReadAll(io.LimitReader(req.Body, dns.MaxMsgSize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(buf) > dns.MaxMsgSize {
+			http.Error(w, "query too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r := new(dns.Msg)
+	if err := r.Unpack(buf); err != nil {
+		http.Error(w, "malformed DNS message", http.StatusBadRequest)
+		return
+	}
+
+	rw := &msgWriter{remote: dohRemoteAddr(req)}
+
+	reply := ds.ResolveContext(req.Context(), r, rw.remote)
+	if reply == nil {
+		http.Error(w, "no reply", http.StatusInternalServerError)
+		return
+	}
+
+	out, err := reply.Pack()
+	if err != nil {
+		ds.log().Warn("packing DoH reply failed", append(queryAttrs(rw, r), "err", err)...)
+		http.Error(w, "packing reply failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", dohMediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(out)))
+	if ttl, ok := minTTL(reply); ok {
+		w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", ttl))
+	}
+
+	w.Write(out)
+}
+
+// dohRemoteAddr returns the client's address as a TCP address, so that ACLs
+// and rate limits apply to DoH clients as they do to others.
+func dohRemoteAddr(req *http.Request) net.Addr {
+	host, port, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return &net.TCPAddr{}
+	}
+
+	p, _ := strconv.Atoi(port)
+	return &net.TCPAddr{IP: net.ParseIP(host), Port: p}
+}
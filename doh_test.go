@@ -0,0 +1,92 @@
+package dnsserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestDoH(t *testing.T) {
+	ds := New("docker", WithTTL(60))
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+	ds.SetATTL("test", 30)
+
+	srv := httptest.NewServer(ds.DoHHandler())
+	defer srv.Close()
+
+	q := new(dns.Msg)
+	q.SetQuestion("test.docker.", dns.TypeA)
+	q.Id = 0
+
+	buf, err := q.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func() (*http.Response, error) {
+		return http.Post(srv.URL, dohMediaType, bytes.NewReader(buf))
+	}
+
+	get := func() (*http.Response, error) {
+		return http.Get(srv.URL + "?dns=" + base64.RawURLEncoding.EncodeToString(buf))
+	}
+
+	for name, do := range map[string]func() (*http.Response, error){"POST": post, "GET": get} {
+		resp, err := do()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != dohMediaType {
+			t.Fatalf("%s: reply was %s with type %q", name, resp.Status, resp.Header.Get("Content-Type"))
+		}
+
+		if cc := resp.Header.Get("Cache-Control"); cc != "max-age=30" {
+			t.Fatalf("%s: Cache-Control was %q", name, cc)
+		}
+
+		msg := new(dns.Msg)
+		if err := msg.Unpack(body); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("127.0.0.2")) {
+			t.Fatalf("%s: DoH answer was %v", name, msg.Answer)
+		}
+	}
+
+	for _, tc := range []struct {
+		name   string
+		do     func() (*http.Response, error)
+		status int
+	}{
+		{"bad parameter", func() (*http.Response, error) { return http.Get(srv.URL + "?dns=!!") }, http.StatusBadRequest},
+		{"bad content type", func() (*http.Response, error) { return http.Post(srv.URL, "text/plain", bytes.NewReader(buf)) }, http.StatusUnsupportedMediaType},
+		{"bad method", func() (*http.Response, error) {
+			req, _ := http.NewRequest(http.MethodPut, srv.URL, bytes.NewReader(buf))
+			return http.DefaultClient.Do(req)
+		}, http.StatusMethodNotAllowed},
+	} {
+		resp, err := tc.do()
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.status {
+			t.Fatalf("%s: status was %s, expected %d", tc.name, resp.Status, tc.status)
+		}
+	}
+}
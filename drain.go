@@ -0,0 +1,44 @@
+package dnsserver
+
+import "context"
+
+// CloseGracefully is like Close, but lets queries already being answered
+// finish first. New queries are refused from the moment it is called, so that
+// clients move on to another server; once every query in progress has been
+// answered, or ctx is done, the server is closed. If ctx ended the wait, the
+// server is closed anyway and ctx.Err() is returned.
+func (ds *Server) CloseGracefully(ctx context.Context) error {
+	ds.drainMutex.Lock()
+	ds.draining = true
+	ds.drainMutex.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		ds.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		ds.closeContext(ctx)
+		return ctx.Err()
+	}
+
+	return ds.closeContext(ctx)
+}
+
+// track counts a query as in progress, unless the server is draining, in
+// which case it returns false and the query must be refused. Queries counted
+// must call ds.inflight.Done when answered.
+func (ds *Server) track() bool {
+	ds.drainMutex.RLock()
+	defer ds.drainMutex.RUnlock()
+
+	if ds.draining {
+		return false
+	}
+
+	ds.inflight.Add(1)
+	return true
+}
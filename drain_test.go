@@ -0,0 +1,106 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// blockingDB holds every A lookup until release is closed, signalling on
+// started as each begins.
+type blockingDB struct {
+	db.DB
+	started chan struct{}
+	release chan struct{}
+}
+
+func newBlockingDB() *blockingDB {
+	return &blockingDB{DB: db.NewMap(), started: make(chan struct{}, 10), release: make(chan struct{})}
+}
+
+func (d *blockingDB) GetA(name string) ([]net.IP, error) {
+	d.started <- struct{}{}
+	<-d.release
+	return d.DB.GetA(name)
+}
+
+func TestCloseGracefully(t *testing.T) {
+	bdb := newBlockingDB()
+	ds := NewWithDB("docker", bdb)
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	replies := make(chan *dns.Msg, 1)
+	go func() {
+		msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+		if err != nil {
+			t.Error(err)
+		}
+		replies <- msg
+	}()
+
+	<-bdb.started
+
+	closed := make(chan error, 1)
+	go func() { closed <- ds.CloseGracefully(context.Background()) }()
+
+	select {
+	case err := <-closed:
+		t.Fatalf("CloseGracefully returned with a query in progress: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if msg := queryFrom(ds, "127.0.0.1", "test.docker."); msg.Rcode != dns.RcodeRefused {
+		t.Fatalf("query while draining was answered with %v", msg)
+	}
+
+	close(bdb.release)
+
+	msg := <-replies
+	if msg == nil || len(msg.Answer) != 1 {
+		t.Fatalf("query in progress was answered with %v", msg)
+	}
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CloseGracefully did not return once the query was answered")
+	}
+
+	if _, port := ds.Listening(); port != 0 {
+		t.Fatalf("drained server still reports port %d", port)
+	}
+}
+
+func TestCloseGracefullyDeadline(t *testing.T) {
+	bdb := newBlockingDB()
+	defer close(bdb.release)
+
+	ds := NewWithDB("docker", bdb)
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	addr := startServer(t, ds)
+
+	go msgClientAddr(addr, "test.docker.", dns.TypeA)
+	<-bdb.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := ds.CloseGracefully(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("CloseGracefully past its deadline returned %v", err)
+	}
+
+	if _, port := ds.Listening(); port != 0 {
+		t.Fatalf("server was not closed at the deadline, on port %d", port)
+	}
+}
@@ -0,0 +1,232 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// ErrInvalidExpiry is returned by SetATemp for expiries which are not
+// positive.
+var ErrInvalidExpiry = errors.New("expiry must be positive")
+
+// expiration is when a temporary host set with SetATemp expires, and how long
+// RefreshA extends it for.
+type expiration struct {
+	at       time.Time
+	lifetime time.Duration
+}
+
+// SetATemp is like SetA, but the host expires unless it is refreshed with
+// RefreshA within expiry of being set or last refreshed. Expired hosts are
+// answered as if they did not exist, and are deleted by a sweeper which runs
+// from the first Listen until Close. Addresses added with AddA expire with the
+// host; SetA makes it permanent again.
+//
+// Expiries are kept by the server rather than the DB, so they do not survive a
+// restart.
+func (ds *Server) SetATemp(host string, ip net.IP, expiry time.Duration) error {
+	if err := ds.checkA(host, ip); err != nil {
+		return err
+	}
+
+	if expiry <= 0 {
+		return ErrInvalidExpiry
+	}
+
+	ds.forgetWeights(host)
+
+	// held across the write, so that the sweeper cannot delete the host on
+	// behalf of an older expiry
+	ds.expiryMutex.Lock()
+	err := ds.db.SetA(host, ip)
+	if err == nil {
+		if ds.expiries == nil {
+			ds.expiries = map[string]expiration{}
+		}
+
+		ds.expiries[strings.ToLower(host)] = newExpiry(expiry)
+	}
+	ds.expiryMutex.Unlock()
+
+	if err == nil {
+		ds.wakeSweeper()
+	}
+
+	return ds.announce(ds.changed(err), ds.aEvents(EventSet, host, ip)...)
+}
+
+// RefreshA extends the expiry of a host set with SetATemp by the expiry it was
+// set with, counting from now. db.ErrNotFound is returned if the host is not
+// temporary, or has already expired.
+func (ds *Server) RefreshA(host string) error {
+	ds.expiryMutex.Lock()
+	defer ds.expiryMutex.Unlock()
+
+	host = strings.ToLower(host)
+
+	e, ok := ds.expiries[host]
+	if !ok || !time.Now().Before(e.at) {
+		return db.ErrNotFound
+	}
+
+	ds.expiries[host] = newExpiry(e.lifetime)
+	return nil
+}
+
+func newExpiry(lifetime time.Duration) expiration {
+	return expiration{at: time.Now().Add(lifetime), lifetime: lifetime}
+}
+
+// getA is the DB's GetA, but yields db.ErrNotFound for expired hosts.
+func (ds *Server) getA(host string) ([]net.IP, error) {
+	ds.expiryMutex.RLock()
+	e, ok := ds.expiries[host]
+	ds.expiryMutex.RUnlock()
+
+	if ok && !time.Now().Before(e.at) {
+		return nil, db.ErrNotFound
+	}
+
+	return ds.db.GetA(host)
+}
+
+// forgetExpiry makes host permanent. If ips are given, it is only forgotten
+// once the host has no addresses left.
+func (ds *Server) forgetExpiry(host string, ips ...net.IP) {
+	ds.expiryMutex.Lock()
+	defer ds.expiryMutex.Unlock()
+
+	host = strings.ToLower(host)
+	if _, ok := ds.expiries[host]; !ok {
+		return
+	}
+
+	if len(ips) != 0 {
+		if _, err := ds.db.GetA(host); err != db.ErrNotFound {
+			return
+		}
+	}
+
+	delete(ds.expiries, host)
+}
+
+// moveExpiry gives newHost the expiry of oldHost, if it has one, in place of
+// its own. The caller holds expiryMutex.
+func (ds *Server) moveExpiry(oldHost, newHost string) {
+	oldHost, newHost = strings.ToLower(oldHost), strings.ToLower(newHost)
+
+	e, ok := ds.expiries[oldHost]
+	delete(ds.expiries, oldHost)
+	delete(ds.expiries, newHost)
+
+	if ok {
+		ds.expiries[newHost] = e
+	}
+}
+
+// startSweeper starts the goroutine deleting expired hosts, which runs until
+// stopSweeper. The caller holds configMutex.
+func (ds *Server) startSweeper() {
+	if ds.sweepStop != nil {
+		return
+	}
+
+	ds.sweepStop = make(chan struct{})
+	go ds.sweep(ds.sweepStop)
+}
+
+// stopSweeper stops the sweeper, if it was started. The caller holds
+// configMutex.
+func (ds *Server) stopSweeper() {
+	if ds.sweepStop != nil {
+		close(ds.sweepStop)
+	}
+}
+
+// wakeSweeper has the sweeper reconsider when the next host expires.
+func (ds *Server) wakeSweeper() {
+	select {
+	case ds.sweepWake <- struct{}{}:
+	default:
+	}
+}
+
+// sweep deletes hosts as they expire until stop is closed, sleeping until the
+// next one is due.
+func (ds *Server) sweep(stop <-chan struct{}) {
+	for {
+		var (
+			timer *time.Timer
+			due   <-chan time.Time
+		)
+
+		if next, ok := ds.sweepExpired(); ok {
+			timer = time.NewTimer(time.Until(next))
+			due = timer.C
+		}
+
+		select {
+		case <-stop:
+		case <-ds.sweepWake:
+		case <-due:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		select {
+		case <-stop:
+			return
+		default:
+		}
+	}
+}
+
+// sweepExpired deletes the hosts which have expired, and returns when the next
+// one expires, if any remain.
+func (ds *Server) sweepExpired() (time.Time, bool) {
+	var (
+		next   time.Time
+		events []Event
+	)
+
+	ds.expiryMutex.Lock()
+
+	now := time.Now()
+
+	for host, e := range ds.expiries {
+		if now.Before(e.at) {
+			if next.IsZero() || e.at.Before(next) {
+				next = e.at
+			}
+
+			continue
+		}
+
+		delete(ds.expiries, host)
+
+		if err := ds.db.DeleteA(host); err != nil {
+			if err != db.ErrNotFound {
+				ds.log().Error("deleting expired host failed", "host", host, "err", err)
+			}
+
+			continue
+		}
+
+		ds.forgetWeights(host)
+		events = append(events, ds.aEvents(EventDelete, host)...)
+	}
+
+	ds.expiryMutex.Unlock()
+
+	if len(events) != 0 {
+		ds.announce(ds.changed(nil), events...)
+	}
+
+	return next, !next.IsZero()
+}
@@ -0,0 +1,117 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+func TestSetATemp(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetATemp("test", net.ParseIP("127.0.0.2"), 0); err != ErrInvalidExpiry {
+		t.Fatalf("zero expiry was %v", err)
+	}
+
+	if err := ds.SetATemp("test", net.ParseIP("127.0.0.2"), 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetATemp("kept", net.ParseIP("127.0.0.3"), 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	// SetA makes the host permanent
+	if err := ds.SetA("kept", net.ParseIP("127.0.0.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	if a := ds.GetA("test.docker."); len(a) != 1 {
+		t.Fatalf("temporary host was %v before expiring", a)
+	}
+
+	if err := ds.RefreshA("kept"); err != db.ErrNotFound {
+		t.Fatalf("refreshing a permanent host was %v", err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if a := ds.GetA("test.docker."); len(a) != 0 {
+		t.Fatalf("expired host was answered with %v", a)
+	}
+
+	if a := ds.GetA("kept.docker."); len(a) != 1 {
+		t.Fatalf("permanent host was %v", a)
+	}
+
+	if err := ds.RefreshA("test"); err != db.ErrNotFound {
+		t.Fatalf("refreshing an expired host was %v", err)
+	}
+
+	// nothing sweeps until the server listens
+	if records, _ := ds.ListA(); len(records["test"]) != 1 {
+		t.Fatalf("expired host was swept before listening: %v", records)
+	}
+
+	startServer(t, ds)
+	defer ds.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		records, err := ds.ListA()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if records["test"] == nil {
+			if len(records["kept"]) != 1 {
+				t.Fatalf("sweeper deleted the permanent host: %v", records)
+			}
+
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("expired host was not swept")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRefreshA(t *testing.T) {
+	ds := New("docker")
+	startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetATemp("test", net.ParseIP("127.0.0.2"), 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 4; i++ {
+		time.Sleep(100 * time.Millisecond)
+
+		if err := ds.RefreshA("TEST"); err != nil {
+			t.Fatalf("refresh %d: %v", i, err)
+		}
+	}
+
+	if a := ds.GetA("test.docker."); len(a) != 1 {
+		t.Fatalf("refreshed host was %v", a)
+	}
+
+	ds.Close()
+
+	// once closed, the host expires but is not swept
+	time.Sleep(300 * time.Millisecond)
+
+	if a := ds.GetA("test.docker."); len(a) != 0 {
+		t.Fatalf("expired host was answered with %v", a)
+	}
+
+	if records, _ := ds.ListA(); len(records["test"]) != 1 {
+		t.Fatalf("host was swept after Close: %v", records)
+	}
+}
@@ -0,0 +1,154 @@
+package dnsserver
+
+import "github.com/erikh/dnsserver/db"
+
+// FlushA removes every A record, along with the weights and expiries of their
+// hosts. DBs which implement db.Flusher do so at once; otherwise each listed
+// host is deleted in turn. Watchers see each host deleted.
+func (ds *Server) FlushA() error {
+	events, err := ds.flushEvents(true, false)
+	if err != nil {
+		return err
+	}
+
+	return ds.announce(ds.changed(ds.flushA()), events...)
+}
+
+// FlushSRV removes every SRV record, as FlushA does for A records.
+func (ds *Server) FlushSRV() error {
+	events, err := ds.flushEvents(false, true)
+	if err != nil {
+		return err
+	}
+
+	return ds.announce(ds.changed(ds.flushSRV()), events...)
+}
+
+// FlushAll removes every record the DB holds. DBs which do not implement
+// db.Flusher can only enumerate A, AAAA and SRV records, so only those are
+// removed from them. Watchers see each A host and SRV service deleted.
+func (ds *Server) FlushAll() error {
+	events, err := ds.flushEvents(true, true)
+	if err != nil {
+		return err
+	}
+
+	if f, ok := ds.db.(db.Flusher); ok {
+		if err := f.FlushAll(); err != nil {
+			return err
+		}
+
+		ds.forgetAll()
+		return ds.announce(ds.changed(nil), events...)
+	}
+
+	if err := ds.flushA(); err != nil {
+		return err
+	}
+
+	aaaas, err := ds.db.ListAAAA()
+	if err != nil {
+		return err
+	}
+
+	for host := range aaaas {
+		if err := ds.db.DeleteAAAA(host); err != nil {
+			return err
+		}
+	}
+
+	return ds.announce(ds.changed(ds.flushSRV()), events...)
+}
+
+// flushEvents describes the A and SRV records about to be flushed as deletes.
+// The records are only listed when there are watchers to tell.
+func (ds *Server) flushEvents(a, srv bool) ([]Event, error) {
+	if !ds.watching() {
+		return nil, nil
+	}
+
+	var events []Event
+
+	if a {
+		as, err := ds.db.ListA()
+		if err != nil {
+			return nil, err
+		}
+
+		for host := range as {
+			events = append(events, ds.aEvents(EventDelete, host)...)
+		}
+	}
+
+	if srv {
+		srvs, err := ds.db.ListSRV()
+		if err != nil {
+			return nil, err
+		}
+
+		for spec := range srvs {
+			events = append(events, ds.srvEvent(EventDelete, spec, nil))
+		}
+	}
+
+	return events, nil
+}
+
+func (ds *Server) flushA() error {
+	if f, ok := ds.db.(db.Flusher); ok {
+		if err := f.FlushA(); err != nil {
+			return err
+		}
+
+		ds.forgetAll()
+		return nil
+	}
+
+	as, err := ds.db.ListA()
+	if err != nil {
+		return err
+	}
+
+	for host := range as {
+		ds.forgetWeights(host)
+
+		if err := ds.db.DeleteA(host); err != nil {
+			return err
+		}
+
+		ds.forgetExpiry(host)
+	}
+
+	return nil
+}
+
+// forgetAll drops the weights and expiries of every host, once a flush has
+// removed their A records.
+func (ds *Server) forgetAll() {
+	ds.weightMutex.Lock()
+	ds.weights = nil
+	ds.weightMutex.Unlock()
+
+	ds.expiryMutex.Lock()
+	ds.expiries = nil
+	ds.expiryMutex.Unlock()
+}
+
+func (ds *Server) flushSRV() error {
+	if f, ok := ds.db.(db.Flusher); ok {
+		return f.FlushSRV()
+	}
+
+	srvs, err := ds.db.ListSRV()
+	if err != nil {
+		return err
+	}
+
+	for spec := range srvs {
+		if err := ds.db.DeleteSRV(spec); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+func TestFlush(t *testing.T) {
+	for _, ds := range []*Server{New("docker"), NewWithDB("docker", setOnlyDB{db.NewMap()})} {
+		populate := func() {
+			ds.SetA("one", net.ParseIP("127.0.0.2"))
+			ds.SetA("two", net.ParseIP("127.0.0.3"))
+			ds.SetSRV("test", "tcp", &db.SRVRecord{Port: 80, Host: "one"})
+			if err := ds.SetAAAA("six", net.ParseIP("::1")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		populate()
+
+		if err := ds.FlushA(); err != nil {
+			t.Fatal(err)
+		}
+
+		if as, err := ds.db.ListA(); err != nil || len(as) != 0 {
+			t.Fatalf("%T: A records remained after FlushA: %v (%v)", ds.db, as, err)
+		}
+
+		if srvs, err := ds.db.ListSRV(); err != nil || len(srvs) != 1 {
+			t.Fatalf("%T: FlushA removed SRV records: %v (%v)", ds.db, srvs, err)
+		}
+
+		if err := ds.FlushSRV(); err != nil {
+			t.Fatal(err)
+		}
+
+		if srvs, err := ds.db.ListSRV(); err != nil || len(srvs) != 0 {
+			t.Fatalf("%T: SRV records remained after FlushSRV: %v (%v)", ds.db, srvs, err)
+		}
+
+		populate()
+
+		serial := ds.Serial()
+
+		if err := ds.FlushAll(); err != nil {
+			t.Fatal(err)
+		}
+
+		if ds.Serial() == serial {
+			t.Fatalf("%T: FlushAll did not change the serial", ds.db)
+		}
+
+		as, _ := ds.db.ListA()
+		aaaas, _ := ds.db.ListAAAA()
+		srvs, _ := ds.db.ListSRV()
+
+		if len(as) != 0 || len(aaaas) != 0 || len(srvs) != 0 {
+			t.Fatalf("%T: records remained after FlushAll: %v %v %v", ds.db, as, aaaas, srvs)
+		}
+	}
+}
+
+func TestFlushAExpiry(t *testing.T) {
+	for _, ds := range []*Server{New("docker"), NewWithDB("docker", setOnlyDB{db.NewMap()})} {
+		if err := ds.SetATemp("temp", net.ParseIP("127.0.0.2"), 100*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ds.AddWeightedA("temp", net.ParseIP("127.0.0.3"), 5); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ds.FlushA(); err != nil {
+			t.Fatal(err)
+		}
+
+		// a host set again after the flush starts afresh
+		if err := ds.AddA("temp", net.ParseIP("127.0.0.3")); err != nil {
+			t.Fatal(err)
+		}
+
+		if ds.weighted("temp.docker.") {
+			t.Fatalf("%T: weights survived FlushA", ds.db)
+		}
+
+		time.Sleep(150 * time.Millisecond)
+
+		if a := ds.GetA("temp.docker."); len(a) != 1 {
+			t.Fatalf("%T: expiry survived FlushA: %v", ds.db, a)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ForwardTimeout is how long each forwarder is given to answer before the
+// next one is tried.
+const ForwardTimeout = 2 * time.Second
+
+// SetForwarders sets the upstream servers that queries for names outside the
+// server's domain are proxied to, in the order they are tried. Addresses
+// without a port use port 53. With no forwarders, such queries are refused.
+func (ds *Server) SetForwarders(forwarders []string) {
+	addrs := make([]string, 0, len(forwarders))
+
+	for _, forwarder := range forwarders {
+		if _, _, err := net.SplitHostPort(forwarder); err != nil {
+			forwarder = net.JoinHostPort(forwarder, "53")
+		}
+		addrs = append(addrs, forwarder)
+	}
+
+	ds.forwardMutex.Lock()
+	ds.forwarders = addrs
+	ds.forwardMutex.Unlock()
+}
+
+// shouldForward reports whether a query is for a name this server does not
+// serve and there is somewhere to send it.
+func (ds *Server) shouldForward(r *dns.Msg) bool {
+	if len(r.Question) != 1 {
+		return false
+	}
+
+	question := r.Question[0]
+
+	if strings.EqualFold(question.Name, ds.domain) || ds.inDomain(question.Name) {
+		return false
+	}
+
+	// reverse names are not under our domain but may still be ours
+	if question.Qtype == dns.TypePTR && len(ds.GetPTR(question.Name)) != 0 {
+		return false
+	}
+
+	return ds.forwarding()
+}
+
+// forwarding reports whether any forwarders are set, so that recursion is
+// available to clients.
+func (ds *Server) forwarding() bool {
+	ds.forwardMutex.RLock()
+	defer ds.forwardMutex.RUnlock()
+
+	return len(ds.forwarders) != 0
+}
+
+// forward proxies a query to each forwarder in turn, relaying the first
+// answer. If none answers, the client gets SERVFAIL. Answers are served from
+// the cache when possible.
+func (ds *Server) forward(w dns.ResponseWriter, r *dns.Msg) {
+	_, udp := w.RemoteAddr().(*net.UDPAddr)
+
+	if resp := ds.cache.get(r.Question[0]); resp != nil {
+		resp.Id = r.Id
+		ds.relay(w, r, resp, udp)
+		return
+	}
+
+	ds.forwardMutex.RLock()
+	forwarders := ds.forwarders
+	ds.forwardMutex.RUnlock()
+
+	client := &dns.Client{Net: "udp", Timeout: ForwardTimeout}
+	if !udp {
+		client.Net = "tcp"
+	}
+
+	for _, forwarder := range forwarders {
+		resp, _, err := client.Exchange(r, forwarder)
+		if err != nil {
+			ds.log().Warn("forwarding failed", append(queryAttrs(w, r), "forwarder", forwarder, "err", err)...)
+			continue
+		}
+
+		ds.cache.put(r.Question[0], resp)
+		ds.relay(w, r, resp, udp)
+		return
+	}
+
+	m := &dns.Msg{}
+	m.SetRcode(r, dns.RcodeServerFailure)
+	ds.writeMsg(w, r, m)
+}
+
+// relay writes an upstream response to the client, truncating it to fit if
+// the client is on UDP, and to the limit set with SetMaxAnswers. TTLs are
+// clamped as SetTTLBounds sets.
+func (ds *Server) relay(w dns.ResponseWriter, r *dns.Msg, resp *dns.Msg, udp bool) {
+	ds.clampTTLs(resp)
+
+	if udp {
+		resp.Truncate(udpSize(r))
+	}
+
+	ds.capAnswers(resp)
+
+	if err := w.WriteMsg(resp); err != nil {
+		ds.log().Warn("writing reply failed", append(queryAttrs(w, r), "err", err)...)
+	}
+}
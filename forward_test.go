@@ -0,0 +1,71 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestForwarders(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		rr, _ := dns.NewRR(r.Question[0].Name + " 30 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, rr)
+		w.WriteMsg(m)
+	})}
+	go upstream.ActivateAndServe()
+	defer upstream.Shutdown()
+
+	// a port nothing is listening on, so the first forwarder fails
+	dead, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.LocalAddr().String()
+	dead.Close()
+
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if msg, err := msgClientAddr(addr, "example.com.", dns.TypeA); err != nil || msg.Rcode != dns.RcodeRefused || msg.RecursionAvailable {
+		t.Fatalf("external name without forwarders was not refused: %v (%v)", msg, err)
+	}
+
+	ds.SetForwarders([]string{deadAddr, conn.LocalAddr().String()})
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	msg, err := msgClientAddr(addr, "example.com.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("192.0.2.1")) {
+		t.Fatalf("external name was not forwarded: %v", msg)
+	}
+
+	msg, err = msgClientAddr(addr, "test.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("local name was not answered locally: %v", msg)
+	}
+
+	msg, err = msgClientAddr(addr, "missing.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeNameError {
+		t.Fatalf("missing local name was forwarded: %v", msg)
+	}
+}
@@ -0,0 +1,73 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// ImportA sets the A record for each host in records, replacing any addresses
+// already registered, as SetA does, and dropping their weights and expiries.
+// Every record is validated before any is stored. DBs which implement
+// db.Importer store them in one pass.
+func (ds *Server) ImportA(records map[string]net.IP) error {
+	for host, ip := range records {
+		if err := ds.checkA(host, ip); err != nil {
+			return err
+		}
+	}
+
+	for host := range records {
+		ds.forgetWeights(host)
+		ds.forgetExpiry(host)
+	}
+
+	events := make([]Event, 0, len(records))
+	for host, ip := range records {
+		events = append(events, ds.aEvents(EventSet, host, ip)...)
+	}
+
+	if imp, ok := ds.db.(db.Importer); ok {
+		return ds.announce(ds.changed(imp.ImportA(records)), events...)
+	}
+
+	for host, ip := range records {
+		if err := ds.db.SetA(host, ip); err != nil {
+			return err
+		}
+	}
+
+	return ds.announce(ds.changed(nil), events...)
+}
+
+// ImportSRV sets the SRV record for each spec in records, e.g. "_http._tcp",
+// replacing any targets already registered, as SetSRV does. Target hosts are
+// qualified with the server's domain. DBs which implement db.Importer store
+// them in one pass.
+func (ds *Server) ImportSRV(records map[string]*db.SRVRecord) error {
+	qualified := make(map[string]*db.SRVRecord, len(records))
+	for spec, srv := range records {
+		qualified[spec] = ds.qualifySrvHost(srv)
+		if err := ds.checkSRV(spec, qualified[spec]); err != nil {
+			return err
+		}
+	}
+
+	events := make([]Event, 0, len(qualified))
+	for spec, srv := range qualified {
+		events = append(events, ds.srvEvent(EventSet, strings.ToLower(spec), srv))
+	}
+
+	if imp, ok := ds.db.(db.Importer); ok {
+		return ds.announce(ds.changed(imp.ImportSRV(qualified)), events...)
+	}
+
+	for spec, srv := range qualified {
+		if err := ds.db.SetSRV(spec, srv); err != nil {
+			return err
+		}
+	}
+
+	return ds.announce(ds.changed(nil), events...)
+}
@@ -0,0 +1,100 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// setOnlyDB hides the optional interfaces, such as db.Importer and
+// db.Flusher, of the DB it wraps.
+type setOnlyDB struct {
+	db.DB
+}
+
+func TestImport(t *testing.T) {
+	for _, ds := range []*Server{New("docker"), NewWithDB("docker", setOnlyDB{db.NewMap()})} {
+		if err := ds.ImportA(map[string]net.IP{
+			"one": net.ParseIP("127.0.0.2"),
+			"two": net.ParseIP("127.0.0.3"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ds.ImportSRV(map[string]*db.SRVRecord{
+			"_web._tcp": {Port: 80, Host: "one"},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if records := ds.GetA("two.docker."); len(records) != 1 || !records[0].A.Equal(net.ParseIP("127.0.0.3")) {
+			t.Fatalf("imported A record was %v", records)
+		}
+
+		if records := ds.GetSRV("_web._tcp.docker."); len(records) != 1 || records[0].Target != "one.docker." {
+			t.Fatalf("imported SRV record was %v", records)
+		}
+	}
+}
+
+func TestImportAExpiry(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetATemp("temp", net.ParseIP("127.0.0.2"), 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.AddWeightedA("temp", net.ParseIP("127.0.0.3"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.ImportA(map[string]net.IP{"temp": net.ParseIP("127.0.0.4")}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.weighted("temp.docker.") {
+		t.Fatal("imported host kept its weights")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if a := ds.GetA("temp.docker."); len(a) != 1 {
+		t.Fatalf("imported host kept its expiry: %v", a)
+	}
+}
+
+func importRecords(n int) map[string]net.IP {
+	records := make(map[string]net.IP, n)
+	for i := 0; i < n; i++ {
+		records[fmt.Sprintf("host%d", i)] = net.IPv4(10, 0, byte(i>>8), byte(i))
+	}
+
+	return records
+}
+
+func BenchmarkImportA(b *testing.B) {
+	records := importRecords(1000)
+	ds := New("docker")
+
+	for i := 0; i < b.N; i++ {
+		if err := ds.ImportA(records); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSetAEach(b *testing.B) {
+	records := importRecords(1000)
+	ds := New("docker")
+
+	for i := 0; i < b.N; i++ {
+		for host, ip := range records {
+			if err := ds.SetA(host, ip); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package dnsserver
+
+import (
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultJournalSize is the number of zone deltas kept for IXFR unless set
+// otherwise with SetJournalSize.
+const DefaultJournalSize = 64
+
+// journal holds the changes made to the zone between the serials it was
+// transferred at, so that IXFR can send secondaries only what changed since
+// their copy. Changes are found by comparing the zone at each transfer with
+// the one before; changes made between two transfers are sent as one delta.
+type journal struct {
+	mutex  sync.Mutex
+	size   int // see SetJournalSize; 0 for DefaultJournalSize
+	serial uint32
+	zone   []dns.RR // as transferred at serial; nil before the first transfer
+	deltas []zoneDelta
+}
+
+// zoneDelta holds the records removed and added to go from serial from to to.
+type zoneDelta struct {
+	from, to       uint32
+	removed, added []dns.RR
+}
+
+// SetJournalSize sets the number of zone deltas kept for IXFR; the oldest are
+// evicted beyond it, and secondaries whose serial is older than the journal
+// receive the whole zone. A size of 0 reverts to DefaultJournalSize.
+func (ds *Server) SetJournalSize(size int) {
+	ds.journal.mutex.Lock()
+	defer ds.journal.mutex.Unlock()
+
+	ds.journal.size = size
+	ds.journal.evict()
+}
+
+// note records the zone as transferred at serial, adding a delta from the last
+// one noted if the serial has changed since.
+func (j *journal) note(serial uint32, zone []dns.RR) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.zone != nil {
+		if serial == j.serial {
+			return
+		}
+
+		removed, added := diffZone(j.zone, zone)
+		j.deltas = append(j.deltas, zoneDelta{from: j.serial, to: serial, removed: removed, added: added})
+		j.evict()
+	}
+
+	j.serial, j.zone = serial, zone
+}
+
+// since returns the deltas from serial from to serial to, or false if either
+// is not in the journal.
+func (j *journal) since(from, to uint32) ([]zoneDelta, bool) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for i, delta := range j.deltas {
+		if delta.from != from {
+			continue
+		}
+
+		for k := i; k < len(j.deltas); k++ {
+			if j.deltas[k].to == to {
+				return append([]zoneDelta{}, j.deltas[i:k+1]...), true
+			}
+		}
+
+		break
+	}
+
+	return nil, false
+}
+
+// evict drops the oldest deltas beyond the journal's size. The caller holds
+// the mutex.
+func (j *journal) evict() {
+	size := j.size
+	if size <= 0 {
+		size = DefaultJournalSize
+	}
+
+	if len(j.deltas) > size {
+		j.deltas = append([]zoneDelta{}, j.deltas[len(j.deltas)-size:]...)
+	}
+}
+
+// diffZone returns the records of old missing from zone, and those of zone
+// missing from old.
+func diffZone(old, zone []dns.RR) (removed, added []dns.RR) {
+	keys := func(rrs []dns.RR) map[string]bool {
+		set := make(map[string]bool, len(rrs))
+		for _, rr := range rrs {
+			set[rr.String()] = true
+		}
+		return set
+	}
+
+	had, has := keys(old), keys(zone)
+
+	for _, rr := range old {
+		if !has[rr.String()] {
+			removed = append(removed, rr)
+		}
+	}
+
+	for _, rr := range zone {
+		if !had[rr.String()] {
+			added = append(added, rr)
+		}
+	}
+
+	return removed, added
+}
+
+// serveIXFR answers an IXFR query (RFC 1995) with the changes made since the
+// serial in the query's authority section, each framed by the SOA records of
+// the serials it goes between. Secondaries which are up to date receive the
+// current SOA alone, and those whose serial is not in the journal receive the
+// whole zone as AXFR would send it. The same queries are refused as for AXFR.
+func (ds *Server) serveIXFR(w dns.ResponseWriter, r *dns.Msg) {
+	if !ds.transferChecked(w, r) {
+		return
+	}
+
+	var have *dns.SOA
+	for _, rr := range r.Ns {
+		if soa, ok := rr.(*dns.SOA); ok {
+			have = soa
+			break
+		}
+	}
+
+	if have == nil {
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeFormatError)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	soa, records, ok := ds.transferZone(w, r)
+	if !ok {
+		return
+	}
+
+	if have.Serial == soa.Serial {
+		ds.sendTransfer(w, r, []dns.RR{soa})
+		return
+	}
+
+	deltas, ok := ds.journal.since(have.Serial, soa.Serial)
+	if !ok {
+		ds.sendTransfer(w, r, append(append([]dns.RR{soa}, records...), soa))
+		return
+	}
+
+	rrs := []dns.RR{soa}
+	for _, delta := range deltas {
+		rrs = append(rrs, soaAt(soa, delta.from))
+		rrs = append(rrs, delta.removed...)
+		rrs = append(rrs, soaAt(soa, delta.to))
+		rrs = append(rrs, delta.added...)
+	}
+
+	ds.sendTransfer(w, r, append(rrs, soa))
+}
+
+// soaAt returns a copy of soa with its serial set to serial.
+func soaAt(soa *dns.SOA, serial uint32) *dns.SOA {
+	s := *soa
+	s.Serial = serial
+	return &s
+}
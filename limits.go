@@ -0,0 +1,254 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// errQueryTimedOut is returned when a reply is written after the query
+// timeout has already been answered.
+var errQueryTimedOut = errors.New("query timed out")
+
+// SetMaxAnswers caps the number of records in the answer section of any
+// reply. Replies with more are trimmed to n and have the TC bit set. 0, the
+// default, disables the cap.
+func (ds *Server) SetMaxAnswers(n int) {
+	if n < 0 {
+		n = 0
+	}
+
+	atomic.StoreInt64(&ds.maxAnswers, int64(n))
+}
+
+// SetQueryTimeout sets how long a query may take to answer, for instance when
+// the DB is slow, before the client is sent SERVFAIL instead. The lookup
+// itself is not interrupted; its eventual reply is discarded, but until then
+// it still holds a slot against SetMaxInFlight and is waited for by
+// CloseGracefully. 0, the default, disables the timeout.
+func (ds *Server) SetQueryTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+
+	atomic.StoreInt64(&ds.queryTimeout, int64(d))
+}
+
+// inFlightWait is how long a query waits for one of the slots set with
+// SetMaxInFlight to come free before it is turned away.
+const inFlightWait = 50 * time.Millisecond
+
+// SetMaxInFlight limits the number of queries answered at once, so that a
+// storm of queries against a slow DB cannot pile up without bound. A query
+// arriving when n are in flight waits briefly for one to finish; if none does,
+// it is sent an empty reply with TC set over UDP, so the client retries over
+// TCP, and dropped over TCP. Queries already in flight when the limit is
+// changed do not count against the new one. 0, the default, disables the
+// limit.
+func (ds *Server) SetMaxInFlight(n int) {
+	var slots chan struct{}
+	if n > 0 {
+		slots = make(chan struct{}, n)
+	}
+
+	ds.slotMutex.Lock()
+	ds.slots = slots
+	ds.slotMutex.Unlock()
+}
+
+// acquire takes an in-flight slot, returning the channel to release it to
+// once the query is answered, or false if none came free in time. The channel
+// is nil if there is no limit.
+func (ds *Server) acquire() (chan struct{}, bool) {
+	ds.slotMutex.RLock()
+	slots := ds.slots
+	ds.slotMutex.RUnlock()
+
+	if slots == nil {
+		return nil, true
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return slots, true
+	default:
+	}
+
+	timer := time.NewTimer(inFlightWait)
+	defer timer.Stop()
+
+	select {
+	case slots <- struct{}{}:
+		return slots, true
+	case <-timer.C:
+		return nil, false
+	}
+}
+
+// release gives back a slot taken by acquire.
+func release(slots chan struct{}) {
+	if slots != nil {
+		<-slots
+	}
+}
+
+// heldWriter carries the in-flight slot and drain count a query took in
+// ServeDNS. They are given back once every holder has released them, so that
+// a lookup serveTimeout stops waiting for still counts against SetMaxInFlight
+// and CloseGracefully until it finishes.
+type heldWriter struct {
+	dns.ResponseWriter
+	ds    *Server
+	slots chan struct{}
+	refs  int32
+}
+
+// retain adds a holder, which must call release.
+func (w *heldWriter) retain() {
+	atomic.AddInt32(&w.refs, 1)
+}
+
+func (w *heldWriter) release() {
+	if atomic.AddInt32(&w.refs, -1) == 0 {
+		release(w.slots)
+		w.ds.inflight.Done()
+	}
+}
+
+// Unwrap returns the writer w wraps; see QueryContext.
+func (w *heldWriter) Unwrap() dns.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// heldBy returns the heldWriter w wraps, looking through writers as
+// QueryContext does, or nil if there is none.
+func heldBy(w dns.ResponseWriter) *heldWriter {
+	for w != nil {
+		switch t := w.(type) {
+		case *heldWriter:
+			return t
+		case interface{ Unwrap() dns.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// turnAway answers a query for which no in-flight slot came free.
+func (ds *Server) turnAway(w dns.ResponseWriter, r *dns.Msg) {
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); !udp {
+		return
+	}
+
+	m := &dns.Msg{}
+	m.SetReply(r)
+	m.Truncated = true
+	w.WriteMsg(m)
+}
+
+// capAnswers trims m to the limit set with SetMaxAnswers.
+func (ds *Server) capAnswers(m *dns.Msg) {
+	if max := int(atomic.LoadInt64(&ds.maxAnswers)); max > 0 && len(m.Answer) > max {
+		m.Answer = m.Answer[:max]
+		m.Truncated = true
+	}
+}
+
+// serveTimeout serves r, replying SERVFAIL if the timeout set with
+// SetQueryTimeout passes first. The lookup keeps the query's in-flight slot
+// until it finishes, even once the client has been answered.
+func (ds *Server) serveTimeout(w dns.ResponseWriter, r *dns.Msg) {
+	timeout := time.Duration(atomic.LoadInt64(&ds.queryTimeout))
+	if timeout == 0 {
+		ds.serve(w, r)
+		return
+	}
+
+	tw := &timeoutWriter{ResponseWriter: w}
+	done := make(chan struct{})
+
+	held := heldBy(w)
+	if held != nil {
+		held.retain()
+	}
+
+	go func() {
+		defer close(done)
+		if held != nil {
+			defer held.release()
+		}
+
+		ds.serve(tw, r)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	if tw.expire() {
+		ds.log().Warn("query timed out", append(queryAttrs(w, r), "timeout", timeout)...)
+
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeServerFailure)
+		ds.writeMsg(w, r, m)
+	}
+}
+
+// timeoutWriter discards replies written after the query has timed out.
+type timeoutWriter struct {
+	dns.ResponseWriter
+	mutex   sync.Mutex
+	written bool
+	expired bool
+}
+
+func (w *timeoutWriter) WriteMsg(m *dns.Msg) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.expired {
+		return errQueryTimedOut
+	}
+
+	w.written = true
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+func (w *timeoutWriter) Write(buf []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.expired {
+		return 0, errQueryTimedOut
+	}
+
+	w.written = true
+	return w.ResponseWriter.Write(buf)
+}
+
+// Unwrap returns the writer w wraps; see QueryContext.
+func (w *timeoutWriter) Unwrap() dns.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// expire stops further writes, and reports whether nothing was written before
+// it, so the caller should reply instead.
+func (w *timeoutWriter) expire() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.expired = true
+	return !w.written
+}
@@ -0,0 +1,195 @@
+package dnsserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestMaxAnswers(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	for i := 2; i < 12; i++ {
+		ds.AddA("many", net.IPv4(127, 0, 0, byte(i)))
+	}
+
+	ds.SetMaxAnswers(4)
+
+	msg, err := msgClientAddr(addr, "many.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 4 || !msg.Truncated {
+		t.Fatalf("capped reply had %d answers, truncated %v", len(msg.Answer), msg.Truncated)
+	}
+
+	ds.SetMaxAnswers(0)
+
+	msg, err = msgClientAddr(addr, "many.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 10 || msg.Truncated {
+		t.Fatalf("uncapped reply had %d answers, truncated %v", len(msg.Answer), msg.Truncated)
+	}
+}
+
+// slowDB delays every A lookup.
+type slowDB struct {
+	db.DB
+	delay time.Duration
+}
+
+func (d slowDB) GetA(name string) ([]net.IP, error) {
+	time.Sleep(d.delay)
+	return d.DB.GetA(name)
+}
+
+func TestQueryTimeout(t *testing.T) {
+	ds := NewWithDB("docker", slowDB{DB: db.NewMap(), delay: 500 * time.Millisecond})
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	ds.SetQueryTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+
+	msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("slow query was not SERVFAIL: %v", msg)
+	}
+
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Fatalf("timeout took %v", elapsed)
+	}
+
+	ds.SetQueryTimeout(time.Second)
+
+	msg, err = msgClientAddr(addr, "test.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 {
+		t.Fatalf("query within the timeout was not answered: %v", msg)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	backend := newBlockingDB()
+	ds := NewWithDB("docker", backend)
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+	ds.SetMaxInFlight(2)
+
+	query := func(remote net.Addr) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion("test.docker.", dns.TypeA)
+		return ds.Resolve(r, remote)
+	}
+
+	udp := &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53}
+
+	replies := make(chan *dns.Msg, 2)
+	for i := 0; i < 2; i++ {
+		go func() { replies <- query(udp) }()
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-backend.started:
+		case <-time.After(time.Second):
+			t.Fatal("queries within the limit were not served")
+		}
+	}
+
+	if m := query(udp); m == nil || !m.Truncated || len(m.Answer) != 0 {
+		t.Fatalf("UDP query over the limit was answered with %v", m)
+	}
+
+	if m := query(&net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53}); m != nil {
+		t.Fatalf("TCP query over the limit was answered with %v", m)
+	}
+
+	close(backend.release)
+
+	for i := 0; i < 2; i++ {
+		if m := <-replies; m == nil || len(m.Answer) != 1 {
+			t.Fatalf("query within the limit was answered with %v", m)
+		}
+	}
+
+	if m := query(udp); m == nil || m.Truncated || len(m.Answer) != 1 {
+		t.Fatalf("query after the slots came free was answered with %v", m)
+	}
+
+	ds.SetMaxInFlight(0)
+
+	if m := query(udp); m == nil || len(m.Answer) != 1 {
+		t.Fatalf("query without a limit was answered with %v", m)
+	}
+}
+
+func TestQueryTimeoutHoldsSlot(t *testing.T) {
+	backend := newBlockingDB()
+	ds := NewWithDB("docker", backend)
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+	ds.SetMaxInFlight(1)
+	ds.SetQueryTimeout(20 * time.Millisecond)
+
+	query := func() *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion("test.docker.", dns.TypeA)
+		return ds.Resolve(r, &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 53})
+	}
+
+	if m := query(); m == nil || m.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("slow query was answered with %v", m)
+	}
+
+	if m := query(); m == nil || !m.Truncated || len(m.Answer) != 0 {
+		t.Fatalf("query while a timed out lookup held the slot was answered with %v", m)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	drained := make(chan error, 1)
+	go func() { drained <- ds.CloseGracefully(ctx) }()
+
+	select {
+	case err := <-drained:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("CloseGracefully did not wait for the timed out lookup: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CloseGracefully did not return")
+	}
+
+	close(backend.release)
+
+	released := make(chan struct{})
+	go func() {
+		ds.inflight.Wait()
+		close(released)
+	}()
+
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("the timed out lookup did not give back its slot once it finished")
+	}
+}
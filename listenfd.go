@@ -0,0 +1,99 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"github.com/miekg/dns"
+)
+
+// ErrUnsupportedSocket is returned when serving an already open socket which
+// is not a UDP or TCP socket.
+var ErrUnsupportedSocket = errors.New("socket is not a UDP or TCP socket")
+
+// ListenFD is like Listen, but serves a socket which is already bound, such as
+// one passed in by systemd socket activation, rather than binding one itself.
+// fd may be a UDP or a TCP socket; which one is served is decided by its type.
+// The descriptor is duplicated, so the caller may close fd once ListenFD has
+// returned. Listening or ListeningTCP report the address of the socket.
+func (ds *Server) ListenFD(fd uintptr) error {
+	f := os.NewFile(fd, "dnsserver")
+	if f == nil {
+		return ErrUnsupportedSocket
+	}
+
+	conn, err := net.FilePacketConn(f)
+	if err == nil {
+		f.Close()
+		return ds.ListenPacketConn(conn)
+	}
+
+	l, err := net.FileListener(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	return ds.ListenListener(l)
+}
+
+// ListenPacketConn is like Listen, but serves conn, which must be a bound UDP
+// socket, rather than binding one itself. conn is closed by Close.
+func (ds *Server) ListenPacketConn(conn net.PacketConn) error {
+	srv, err := ds.bindPacketConn(conn)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	return srv.ActivateAndServe()
+}
+
+// ListenListener is like ListenTCP, but serves l, which must be a bound TCP
+// listener, rather than binding one itself. l is closed by Close.
+func (ds *Server) ListenListener(l net.Listener) error {
+	srv, err := ds.bindListener(l)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	return srv.ActivateAndServe()
+}
+
+func (ds *Server) bindPacketConn(conn net.PacketConn) (*dns.Server, error) {
+	if _, ok := conn.LocalAddr().(*net.UDPAddr); !ok {
+		conn.Close()
+		return nil, ErrUnsupportedSocket
+	}
+
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if ds.closed {
+		conn.Close()
+		return nil, ErrServerClosed
+	}
+
+	return ds.usePacketConn(conn, "udp", conn.LocalAddr().String()), nil
+}
+
+func (ds *Server) bindListener(l net.Listener) (*dns.Server, error) {
+	if _, ok := l.Addr().(*net.TCPAddr); !ok {
+		l.Close()
+		return nil, ErrUnsupportedSocket
+	}
+
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if ds.closed {
+		l.Close()
+		return nil, ErrServerClosed
+	}
+
+	return ds.useListener(l, l.Addr().String()), nil
+}
@@ -0,0 +1,92 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestListenFD(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := conn.(*net.UDPConn).File()
+	conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ds := New("docker")
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 1)
+	go func() { errs <- ds.ListenFD(f.Fd()) }()
+
+	addr := waitListening(t, ds.Listening)
+	if addr != conn.LocalAddr().String() {
+		t.Fatalf("listening on %s, not the socket passed in at %s", addr, conn.LocalAddr())
+	}
+
+	msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("query over the passed socket was answered with %v", msg)
+	}
+
+	ds.Close()
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListenFDTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := l.(*net.TCPListener).File()
+	l.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ds := New("docker")
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	go ds.ListenFD(f.Fd())
+
+	addr := waitListening(t, ds.ListeningTCP)
+
+	m := new(dns.Msg)
+	m.SetQuestion("test.docker.", dns.TypeA)
+
+	msg, _, err := (&dns.Client{Net: "tcp"}).Exchange(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 {
+		t.Fatalf("query over the passed listener was answered with %v", msg)
+	}
+
+	if ip, _ := ds.Listening(); ip != nil {
+		t.Fatalf("a TCP socket was served as UDP on %v", ip)
+	}
+}
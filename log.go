@@ -0,0 +1,57 @@
+package dnsserver
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// discardHandler is a slog.Handler which drops every record.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// SetLogger sets the logger that errors and diagnostics are written to. By
+// default, and if logger is nil, they are discarded.
+func (ds *Server) SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		logger = slog.New(discardHandler{})
+	}
+
+	ds.loggerMutex.Lock()
+	ds.logger = logger
+	ds.loggerMutex.Unlock()
+}
+
+func (ds *Server) log() *slog.Logger {
+	ds.loggerMutex.RLock()
+	defer ds.loggerMutex.RUnlock()
+
+	return ds.logger
+}
+
+// logLookup logs a failed DB lookup for name. Missing records are not errors
+// and are not logged.
+func (ds *Server) logLookup(name string, qtype uint16, err error) {
+	if err == nil || err == db.ErrNotFound {
+		return
+	}
+
+	ds.log().Error("record lookup failed", "name", name, "qtype", dns.TypeToString[qtype], "err", err)
+}
+
+// queryAttrs describes a query for logging.
+func queryAttrs(w dns.ResponseWriter, r *dns.Msg) []interface{} {
+	attrs := []interface{}{"remote", w.RemoteAddr().String()}
+
+	if len(r.Question) != 0 {
+		attrs = append(attrs, "name", r.Question[0].Name, "qtype", dns.TypeToString[r.Question[0].Qtype])
+	}
+
+	return attrs
+}
@@ -0,0 +1,66 @@
+package dnsserver
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+type capturingHandler struct {
+	mutex   sync.Mutex
+	records []slog.Record
+}
+
+func (h *capturingHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *capturingHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *capturingHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *capturingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.records = append(h.records, r)
+	return nil
+}
+
+func TestLogger(t *testing.T) {
+	ds := NewWithDB("docker", failingDB{db.NewMap()})
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	handler := &capturingHandler{}
+	ds.SetLogger(slog.New(handler))
+
+	if _, err := msgClientAddr(addr, "test.docker.", dns.TypeA); err != nil {
+		t.Fatal(err)
+	}
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	var found bool
+
+	for _, record := range handler.records {
+		if record.Level != slog.LevelError {
+			continue
+		}
+
+		attrs := map[string]string{}
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value.String()
+			return true
+		})
+
+		if attrs["name"] == "test.docker." && attrs["qtype"] == "A" && attrs["err"] == errBackendDown.Error() {
+			found = true
+		}
+	}
+
+	if !found {
+		t.Fatalf("backend error was not logged: %v", handler.records)
+	}
+}
@@ -0,0 +1,43 @@
+package dnsserver
+
+import (
+	"sync/atomic"
+
+	"github.com/miekg/dns"
+)
+
+// SetMaintenanceMode sets whether the server is in maintenance: while it is,
+// every query is answered with the rcode set by SetMaintenanceRcode, SERVFAIL
+// by default, so that clients retry elsewhere, and the listeners stay up. It
+// may be toggled while serving; queries already being answered are not
+// affected.
+func (ds *Server) SetMaintenanceMode(enabled bool) {
+	var flag int32
+	if enabled {
+		flag = 1
+	}
+
+	atomic.StoreInt32(&ds.maintenance, flag)
+}
+
+// SetMaintenanceRcode sets the rcode queries are answered with in maintenance,
+// such as dns.RcodeRefused. An rcode of dns.RcodeSuccess reverts to the
+// default, dns.RcodeServerFailure.
+func (ds *Server) SetMaintenanceRcode(rcode int) {
+	atomic.StoreInt32(&ds.maintRcode, int32(rcode))
+}
+
+// inMaintenance returns the rcode to answer every query with, and whether the
+// server is in maintenance at all.
+func (ds *Server) inMaintenance() (int, bool) {
+	if atomic.LoadInt32(&ds.maintenance) == 0 {
+		return 0, false
+	}
+
+	rcode := int(atomic.LoadInt32(&ds.maintRcode))
+	if rcode == dns.RcodeSuccess {
+		rcode = dns.RcodeServerFailure
+	}
+
+	return rcode, true
+}
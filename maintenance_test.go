@@ -0,0 +1,53 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(rcode int) {
+		t.Helper()
+
+		msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Rcode != rcode {
+			t.Fatalf("expected rcode %s, got %v", dns.RcodeToString[rcode], msg)
+		}
+
+		if rcode != dns.RcodeSuccess && len(msg.Answer) != 0 {
+			t.Fatalf("reply in maintenance had answers: %v", msg)
+		}
+	}
+
+	expect(dns.RcodeSuccess)
+
+	ds.SetMaintenanceMode(true)
+	expect(dns.RcodeServerFailure)
+
+	ds.SetMaintenanceRcode(dns.RcodeRefused)
+	expect(dns.RcodeRefused)
+
+	ds.SetMaintenanceMode(false)
+	expect(dns.RcodeSuccess)
+
+	ds.SetMaintenanceRcode(dns.RcodeSuccess)
+	ds.SetMaintenanceMode(true)
+	expect(dns.RcodeServerFailure)
+
+	ds.SetMaintenanceMode(false)
+	expect(dns.RcodeSuccess)
+}
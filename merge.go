@@ -0,0 +1,148 @@
+package dnsserver
+
+import (
+	"net"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// MergeFrom copies the A and SRV records of other into the server, for
+// consolidating two instances. A host or service the server already holds is
+// a conflict: with overwrite, its records are replaced by other's, and
+// otherwise other's are skipped. A record TTLs set in other come along with
+// the addresses. The records are validated as SetA and SetSRV would; an
+// invalid one stops the merge, leaving those before it merged. The number of
+// records merged, counting each address and SRV target, is returned.
+func (ds *Server) MergeFrom(other db.DB, overwrite bool) (int, error) {
+	hosts, err := other.ListA()
+	if err != nil {
+		return 0, err
+	}
+
+	services, err := other.ListSRV()
+	if err != nil {
+		return 0, err
+	}
+
+	ds.updateMutex.Lock()
+	defer ds.updateMutex.Unlock()
+
+	var (
+		n      int
+		events []Event
+	)
+
+	// announce whatever was merged, even if a later record failed
+	defer func() {
+		if n != 0 {
+			ds.announce(ds.changed(nil), events...)
+		}
+	}()
+
+	for host, ips := range hosts {
+		if len(ips) == 0 {
+			continue
+		}
+
+		held, err := ds.mergeConflict(ds.db.GetA(host))
+		if err != nil {
+			return n, err
+		}
+
+		if held && !overwrite {
+			continue
+		}
+
+		for _, ip := range ips {
+			if err := ds.checkA(host, ip); err != nil {
+				return n, err
+			}
+		}
+
+		if err := ds.mergeA(other, host, ips); err != nil {
+			return n, err
+		}
+
+		n += len(ips)
+		events = append(events, ds.aSetEvents(host, ips)...)
+	}
+
+	for spec, srvs := range services {
+		if len(srvs) == 0 {
+			continue
+		}
+
+		held, err := ds.mergeConflict(ds.db.GetSRV(spec))
+		if err != nil {
+			return n, err
+		}
+
+		if held && !overwrite {
+			continue
+		}
+
+		qualified := make([]*db.SRVRecord, 0, len(srvs))
+		for _, srv := range srvs {
+			srv = ds.qualifySrvHost(srv)
+			if err := ds.checkSRV(spec, srv); err != nil {
+				return n, err
+			}
+			qualified = append(qualified, srv)
+		}
+
+		for i, srv := range qualified {
+			set, op := ds.db.AddSRV, EventAdd
+			if i == 0 {
+				set, op = ds.db.SetSRV, EventSet
+			}
+
+			if err := set(spec, srv); err != nil {
+				return n, err
+			}
+
+			n++
+			events = append(events, ds.srvEvent(op, spec, srv))
+		}
+	}
+
+	return n, nil
+}
+
+// mergeConflict reports whether a lookup made by MergeFrom found records the
+// server already holds, returning any error other than db.ErrNotFound.
+func (ds *Server) mergeConflict(_ interface{}, err error) (bool, error) {
+	if err == db.ErrNotFound {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// mergeA replaces host's addresses with ips, and its TTL with the one other
+// holds for it, if any.
+func (ds *Server) mergeA(other db.DB, host string, ips []net.IP) error {
+	ds.forgetWeights(host)
+	ds.forgetExpiry(host)
+
+	for i, ip := range ips {
+		set := ds.db.AddA
+		if i == 0 {
+			set = ds.db.SetA
+		}
+
+		if err := set(host, ip); err != nil {
+			return err
+		}
+	}
+
+	ttl, err := other.GetATTL(host)
+	if err == db.ErrNotFound || ttl == 0 {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return ds.db.SetATTL(host, ttl)
+}
@@ -0,0 +1,126 @@
+package dnsserver
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestMergeFrom(t *testing.T) {
+	other := db.NewMap()
+	other.SetA("one", net.ParseIP("127.0.0.2"))
+	other.AddA("one", net.ParseIP("127.0.0.3"))
+	other.SetATTL("one", 60)
+	other.SetA("shared", net.ParseIP("127.0.0.4"))
+	other.SetSRV("_http._tcp", &db.SRVRecord{Port: 80, Host: "one.docker."})
+	other.SetSRV("_shared._tcp", &db.SRVRecord{Port: 81, Host: "shared.docker."})
+
+	for _, overwrite := range []bool{false, true} {
+		ds := New("docker")
+		ds.SetA("shared", net.ParseIP("127.0.0.10"))
+		ds.SetA("mine", net.ParseIP("127.0.0.11"))
+		ds.SetSRV("shared", "tcp", &db.SRVRecord{Port: 82, Host: "mine"})
+
+		n, err := ds.MergeFrom(other, overwrite)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// two addresses for one and an SRV target for _http, plus the
+		// conflicting host and service when overwriting
+		expected := 3
+		if overwrite {
+			expected = 5
+		}
+
+		if n != expected {
+			t.Fatalf("overwrite %v merged %d records, expected %d", overwrite, n, expected)
+		}
+
+		if records := ds.GetA("one.docker."); len(records) != 2 || records[0].Hdr.Ttl != 60 {
+			t.Fatalf("overwrite %v: one was merged as %v", overwrite, records)
+		}
+
+		if records := ds.GetA("mine.docker."); len(records) != 1 {
+			t.Fatalf("overwrite %v: mine was lost: %v", overwrite, records)
+		}
+
+		if records := ds.GetSRV("_http._tcp.docker."); len(records) != 1 || records[0].Port != 80 {
+			t.Fatalf("overwrite %v: _http was merged as %v", overwrite, records)
+		}
+
+		want, port := net.ParseIP("127.0.0.10"), uint16(82)
+		if overwrite {
+			want, port = net.ParseIP("127.0.0.4"), 81
+		}
+
+		if records := ds.GetA("shared.docker."); len(records) != 1 || !records[0].A.Equal(want) {
+			t.Fatalf("overwrite %v: shared host was %v, expected %v", overwrite, records, want)
+		}
+
+		if records := ds.GetSRV("_shared._tcp.docker."); len(records) != 1 || records[0].Port != port {
+			t.Fatalf("overwrite %v: shared service was %v, expected port %d", overwrite, records, port)
+		}
+	}
+}
+
+func TestMergeFromEvents(t *testing.T) {
+	other := db.NewMap()
+	other.SetA("multi", net.ParseIP("127.0.0.2"))
+	other.AddA("multi", net.ParseIP("127.0.0.3"))
+	other.SetA("shared", net.ParseIP("127.0.0.4"))
+	other.AddA("shared", net.ParseIP("127.0.0.5"))
+	other.SetSRV("_multi._tcp", &db.SRVRecord{Port: 80, Host: "multi.docker."})
+	other.AddSRV("_multi._tcp", &db.SRVRecord{Port: 81, Host: "multi.docker."})
+
+	for _, overwrite := range []bool{false, true} {
+		ds := New("docker")
+		ds.SetA("shared", net.ParseIP("127.0.0.10"))
+
+		events, cancel := ds.Watch()
+
+		if _, err := ds.MergeFrom(other, overwrite); err != nil {
+			t.Fatal(err)
+		}
+
+		// names come in no particular order, but the values of each are set
+		// and then added, so that a subscriber ends up with all of them
+		want := map[string][]Event{
+			"multi.docker.": {
+				{Op: EventSet, Type: dns.TypeA, Name: "multi.docker.", Value: "127.0.0.2"},
+				{Op: EventAdd, Type: dns.TypeA, Name: "multi.docker.", Value: "127.0.0.3"},
+			},
+			"_multi._tcp.docker.": {
+				{Op: EventSet, Type: dns.TypeSRV, Name: "_multi._tcp.docker.", Value: "0 0 80 multi.docker."},
+				{Op: EventAdd, Type: dns.TypeSRV, Name: "_multi._tcp.docker.", Value: "0 0 81 multi.docker."},
+			},
+		}
+
+		if overwrite {
+			want["shared.docker."] = []Event{
+				{Op: EventSet, Type: dns.TypeA, Name: "shared.docker.", Value: "127.0.0.4"},
+				{Op: EventAdd, Type: dns.TypeA, Name: "shared.docker.", Value: "127.0.0.5"},
+			}
+		}
+
+		got := map[string][]Event{}
+		for i := 0; i < 2*len(want); i++ {
+			select {
+			case event := <-events:
+				got[event.Name] = append(got[event.Name], event)
+			case <-time.After(time.Second):
+				t.Fatalf("overwrite %v: only got events %v", overwrite, got)
+			}
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("overwrite %v: events were %v, expected %v", overwrite, got, want)
+		}
+
+		cancel()
+	}
+}
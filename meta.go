@@ -0,0 +1,96 @@
+package dnsserver
+
+import (
+	"net"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// SetAWithMeta sets a host's A record as SetA does, tagging it with meta for
+// bookkeeping, such as the ID of the container which owns it. The metadata is
+// never served; it is kept until the host is next set or deleted, and can be
+// used to find the host again with ListAWithMeta or DeleteByMeta.
+func (ds *Server) SetAWithMeta(host string, ip net.IP, meta map[string]string) error {
+	if err := ds.checkA(host, ip); err != nil {
+		return err
+	}
+
+	ds.forgetWeights(host)
+	ds.forgetExpiry(host)
+
+	if err := ds.changed(ds.db.SetA(host, ip)); err != nil {
+		return err
+	}
+
+	return ds.announce(ds.db.SetAMeta(host, meta), ds.aEvents(EventSet, host, ip)...)
+}
+
+// ListAWithMeta lists all A records along with their metadata.
+func (ds *Server) ListAWithMeta() (map[string]db.AHost, error) {
+	records, err := ds.db.ListA()
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := make(map[string]db.AHost, len(records))
+
+	for host, ips := range records {
+		meta, err := ds.db.GetAMeta(host)
+		switch err {
+		case nil:
+		case db.ErrNotFound:
+			// deleted since it was listed
+			continue
+		default:
+			return nil, err
+		}
+
+		hosts[host] = db.AHost{IPs: ips, Meta: meta}
+	}
+
+	return hosts, nil
+}
+
+// DeleteByMeta deletes every host whose A records are tagged with key set to
+// value, returning the number of hosts deleted. Dynamic updates are held off
+// while the hosts are found and deleted.
+func (ds *Server) DeleteByMeta(key, value string) (int, error) {
+	ds.updateMutex.Lock()
+	defer ds.updateMutex.Unlock()
+
+	hosts, err := ds.ListAWithMeta()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		n      int
+		events []Event
+	)
+
+	// announce whatever was deleted, even if a later delete failed
+	defer func() {
+		if n != 0 {
+			ds.announce(ds.changed(nil), events...)
+		}
+	}()
+
+	for host, record := range hosts {
+		if val, ok := record.Meta[key]; !ok || val != value {
+			continue
+		}
+
+		ds.forgetWeights(host)
+
+		if err := ds.db.DeleteA(host); err != nil && err != db.ErrNotFound {
+			return n, err
+		}
+
+		ds.forgetExpiry(host)
+
+		n++
+		events = append(events, ds.aEvents(EventDelete, host)...)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,81 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDeleteByMeta(t *testing.T) {
+	ds := New("docker")
+
+	tags := map[string]string{"web": "abc", "db": "abc", "cache": "def"}
+	for host, owner := range tags {
+		if err := ds.SetAWithMeta(host, net.ParseIP("127.0.0.2"), map[string]string{"container": owner}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ds.SetA("untagged", net.ParseIP("127.0.0.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetAWithMeta("bad", net.ParseIP("::1"), map[string]string{"container": "abc"}); err == nil {
+		t.Fatal("invalid address was accepted")
+	}
+
+	hosts, err := ds.ListAWithMeta()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hosts) != 4 || hosts["untagged"].Meta != nil || len(hosts["untagged"].IPs) != 1 {
+		t.Fatalf("listed hosts were %v", hosts)
+	}
+
+	for host, owner := range tags {
+		if got := hosts[host].Meta["container"]; got != owner {
+			t.Fatalf("%s was tagged %q, not %q", host, got, owner)
+		}
+	}
+
+	serial := ds.Serial()
+
+	n, err := ds.DeleteByMeta("container", "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Fatalf("deleted %d hosts", n)
+	}
+
+	if ds.Serial() != serial+1 {
+		t.Fatalf("serial went from %d to %d", serial, ds.Serial())
+	}
+
+	records, err := ds.ListA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 || records["cache"] == nil || records["untagged"] == nil {
+		t.Fatalf("DeleteByMeta left %v", records)
+	}
+
+	if n, err := ds.DeleteByMeta("container", "abc"); err != nil || n != 0 {
+		t.Fatalf("second delete removed %d hosts (%v)", n, err)
+	}
+
+	if ds.Serial() != serial+1 {
+		t.Fatal("delete without matches bumped the serial")
+	}
+
+	// setting the host again drops its tags
+	if err := ds.SetA("cache", net.ParseIP("127.0.0.4")); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := ds.DeleteByMeta("container", "def"); err != nil || n != 0 {
+		t.Fatalf("retagged host was deleted: %d (%v)", n, err)
+	}
+}
@@ -0,0 +1,88 @@
+// Package metrics exports dnsserver query metrics to Prometheus. It lives in
+// its own package so that dnsserver itself does not depend on Prometheus.
+//
+// A Collector is both a prometheus.Collector and a dnsserver.QueryLogger:
+//
+//	c := metrics.New()
+//	prometheus.MustRegister(c)
+//	ds.SetQueryLogger(c)
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes every metric name.
+const Namespace = "dnsserver"
+
+// Collector counts queries by type and responses by rcode, and records how
+// long each query took to answer.
+type Collector struct {
+	queries   *prometheus.CounterVec
+	responses *prometheus.CounterVec
+	latency   prometheus.Histogram
+}
+
+// New creates a Collector.
+func New() *Collector {
+	return &Collector{
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "queries_total",
+			Help:      "Queries received, by query type.",
+		}, []string{"qtype"}),
+		responses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Name:      "responses_total",
+			Help:      "Responses sent, by rcode.",
+		}, []string{"rcode"}),
+		latency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Name:      "query_duration_seconds",
+			Help:      "Time taken to answer a query.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.queries.Describe(ch)
+	c.responses.Describe(ch)
+	c.latency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.queries.Collect(ch)
+	c.responses.Collect(ch)
+	c.latency.Collect(ch)
+}
+
+// LogQuery implements dnsserver.QueryLogger.
+func (c *Collector) LogQuery(remote net.Addr, q dns.Question, rcode int, answers int, dur time.Duration) {
+	c.queries.WithLabelValues(qtypeLabel(q.Qtype)).Inc()
+	c.responses.WithLabelValues(rcodeLabel(rcode)).Inc()
+	c.latency.Observe(dur.Seconds())
+}
+
+func qtypeLabel(qtype uint16) string {
+	if name, ok := dns.TypeToString[qtype]; ok {
+		return name
+	}
+
+	return strconv.Itoa(int(qtype))
+}
+
+func rcodeLabel(rcode int) string {
+	if name, ok := dns.RcodeToString[rcode]; ok {
+		return name
+	}
+
+	return strconv.Itoa(rcode)
+}
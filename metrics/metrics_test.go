@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver"
+	"github.com/miekg/dns"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	ds := dnsserver.New("docker")
+	go ds.Listen("127.0.0.1:0")
+	defer ds.Close()
+
+	c := New()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	ds.SetQueryLogger(c)
+
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	var port uint
+	for i := 0; i < 100 && port == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+		_, port = ds.Listening()
+	}
+	addr := (&net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: int(port)}).String()
+
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+	}{
+		{"test.docker.", dns.TypeA},
+		{"test.docker.", dns.TypeA},
+		{"missing.docker.", dns.TypeSRV},
+	} {
+		m := new(dns.Msg)
+		m.SetQuestion(q.name, q.qtype)
+		if _, err := dns.Exchange(m, addr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the logger runs after the reply is written; wait for the last query
+	for i := 0; i < 100 && testutil.ToFloat64(c.responses.WithLabelValues("NXDOMAIN")) == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := testutil.ToFloat64(c.queries.WithLabelValues("A")); n != 2 {
+		t.Fatalf("expected 2 A queries, got %v", n)
+	}
+
+	if n := testutil.ToFloat64(c.queries.WithLabelValues("SRV")); n != 1 {
+		t.Fatalf("expected 1 SRV query, got %v", n)
+	}
+
+	if n := testutil.ToFloat64(c.responses.WithLabelValues("NOERROR")); n != 2 {
+		t.Fatalf("expected 2 NOERROR responses, got %v", n)
+	}
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found bool
+	for _, family := range families {
+		if family.GetName() == "dnsserver_query_duration_seconds" {
+			found = family.GetMetric()[0].GetHistogram().GetSampleCount() == 3
+		}
+	}
+
+	if !found {
+		t.Fatal("latency histogram did not record every query")
+	}
+}
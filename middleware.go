@@ -0,0 +1,51 @@
+package dnsserver
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Handler is middleware for the server. It is given the next handler in the
+// chain and returns a handler wrapping it, which may inspect or rewrite the
+// query and reply, or answer without calling next at all.
+type Handler func(next dns.Handler) dns.Handler
+
+// Use appends middleware to the chain run for every query. Middleware runs in
+// the order it was registered, the first outermost, with the server's own
+// query handling as the last handler in the chain.
+func (ds *Server) Use(mw ...Handler) {
+	ds.chainMutex.Lock()
+	defer ds.chainMutex.Unlock()
+
+	ds.middleware = append(ds.middleware, mw...)
+
+	var chain dns.Handler = dns.HandlerFunc(ds.serveDNS)
+	for i := len(ds.middleware) - 1; i >= 0; i-- {
+		chain = ds.middleware[i](chain)
+	}
+
+	ds.chain = chain
+}
+
+// RequestIDLogger returns middleware which gives each query a sequential ID
+// and logs it to logger at debug level, once when it is received and again
+// with the rcode once it is answered.
+func RequestIDLogger(logger *slog.Logger) Handler {
+	var id uint64
+
+	return func(next dns.Handler) dns.Handler {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			start := time.Now()
+			attrs := append([]interface{}{"id", atomic.AddUint64(&id, 1)}, queryAttrs(w, r)...)
+			logger.Debug("query received", attrs...)
+
+			rw := &recordingWriter{ResponseWriter: w}
+			next.ServeDNS(rw, r)
+
+			logger.Debug("query answered", append(attrs, "rcode", dns.RcodeToString[rw.rcode], "dur", time.Since(start))...)
+		})
+	}
+}
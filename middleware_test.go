@@ -0,0 +1,118 @@
+package dnsserver
+
+import (
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// rewritingWriter adds a TXT record to every reply written through it.
+type rewritingWriter struct {
+	dns.ResponseWriter
+}
+
+func (w rewritingWriter) WriteMsg(m *dns.Msg) error {
+	m.Extra = append(m.Extra, &dns.TXT{
+		Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: dns.TypeTXT, Class: dns.ClassINET},
+		Txt: []string{"rewritten"},
+	})
+
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+func TestUse(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mutex sync.Mutex
+		order []string
+	)
+
+	record := func(name string) Handler {
+		return func(next dns.Handler) dns.Handler {
+			return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+				mutex.Lock()
+				order = append(order, name)
+				mutex.Unlock()
+
+				next.ServeDNS(w, r)
+			})
+		}
+	}
+
+	rewrite := func(next dns.Handler) dns.Handler {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			next.ServeDNS(rewritingWriter{w}, r)
+		})
+	}
+
+	ds.Use(record("first"), rewrite)
+	ds.Use(record("second"))
+
+	msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("answer was not served through the middleware: %v", msg.Answer)
+	}
+
+	if len(msg.Extra) != 1 || msg.Extra[0].(*dns.TXT).Txt[0] != "rewritten" {
+		t.Fatalf("middleware did not rewrite the reply: %v", msg.Extra)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Fatalf("middleware ran in the wrong order: %v", order)
+	}
+}
+
+func TestRequestIDLogger(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	handler := &capturingHandler{}
+	ds.Use(RequestIDLogger(slog.New(handler)))
+
+	for i := 0; i < 2; i++ {
+		if _, err := msgClientAddr(addr, "missing.docker.", dns.TypeA); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	handler.mutex.Lock()
+	defer handler.mutex.Unlock()
+
+	if len(handler.records) != 4 {
+		t.Fatalf("expected a received and answered record for each query, got %d", len(handler.records))
+	}
+
+	for i, record := range handler.records {
+		attrs := map[string]string{}
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value.String()
+			return true
+		})
+
+		if want := []string{"1", "2"}[i/2]; attrs["id"] != want {
+			t.Fatalf("record %d had id %q, not %q", i, attrs["id"], want)
+		}
+
+		if i%2 == 1 && attrs["rcode"] != "NXDOMAIN" {
+			t.Fatalf("answered record had rcode %q", attrs["rcode"])
+		}
+	}
+}
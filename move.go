@@ -0,0 +1,117 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// RenameA moves the A records of oldHost, and any TTL, expiry or weights set
+// for them, to newHost, replacing any addresses newHost already has. db.ErrNotFound is
+// returned if oldHost has no A records. DBs which implement db.Mover rename it
+// in one step; otherwise newHost is populated before oldHost is deleted, so
+// that neither name goes missing while both exist.
+func (ds *Server) RenameA(oldHost, newHost string) error {
+	if err := ds.checkHost(newHost); err != nil {
+		return err
+	}
+
+	ips, err := ds.db.GetA(oldHost)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(oldHost, newHost) {
+		return nil
+	}
+
+	events := append(ds.aEvents(EventDelete, oldHost), ds.aSetEvents(newHost, ips)...)
+
+	// held across the write, so that the sweeper cannot expire either name
+	// before the expiry has moved with the records
+	ds.expiryMutex.Lock()
+	if m, ok := ds.db.(db.Mover); ok {
+		err = m.RenameA(oldHost, newHost)
+	} else {
+		err = ds.renameA(oldHost, newHost, ips)
+	}
+
+	if err == nil {
+		ds.moveExpiry(oldHost, newHost)
+	}
+	ds.expiryMutex.Unlock()
+
+	if err == nil {
+		ds.moveWeights(oldHost, newHost)
+	}
+
+	return ds.announce(ds.changed(err), events...)
+}
+
+// MoveA replaces the addresses of an existing host with ip, as SetA does, but
+// keeps any TTL set for them. Like SetA, it drops the host's weights and makes
+// it permanent. db.ErrNotFound is returned if the host has no A records.
+func (ds *Server) MoveA(host string, ip net.IP) error {
+	if err := ds.checkA(host, ip); err != nil {
+		return err
+	}
+
+	if _, err := ds.db.GetA(host); err != nil {
+		return err
+	}
+
+	ds.forgetWeights(host)
+	ds.forgetExpiry(host)
+
+	if m, ok := ds.db.(db.Mover); ok {
+		return ds.announce(ds.changed(m.MoveA(host, ip)), ds.aEvents(EventSet, host, ip)...)
+	}
+
+	ttl, err := ds.db.GetATTL(host)
+	if err != nil {
+		return err
+	}
+
+	if err := ds.db.SetA(host, ip); err != nil {
+		return err
+	}
+
+	return ds.announce(ds.changed(ds.restoreATTL(host, ttl)), ds.aEvents(EventSet, host, ip)...)
+}
+
+// renameA renames a host one record at a time, for DBs which are not a
+// db.Mover.
+func (ds *Server) renameA(oldHost, newHost string, ips []net.IP) error {
+	ttl, err := ds.db.GetATTL(oldHost)
+	if err != nil {
+		return err
+	}
+
+	for i, ip := range ips {
+		set := ds.db.AddA
+		if i == 0 {
+			set = ds.db.SetA
+		}
+
+		if err := set(newHost, ip); err != nil {
+			return err
+		}
+	}
+
+	if err := ds.restoreATTL(newHost, ttl); err != nil {
+		return err
+	}
+
+	return ds.db.DeleteA(oldHost)
+}
+
+// restoreATTL sets a TTL read with GetATTL back on host, once SetA has
+// cleared it.
+func (ds *Server) restoreATTL(host string, ttl uint32) error {
+	if ttl == 0 {
+		return nil
+	}
+
+	return ds.db.SetATTL(host, ttl)
+}
@@ -0,0 +1,194 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestRenameA(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.RenameA("missing", "test"); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("renaming a missing host was %v", err)
+	}
+
+	if err := ds.MoveA("missing", net.ParseIP("127.0.0.2")); !errors.Is(err, db.ErrNotFound) {
+		t.Fatalf("moving a missing host was %v", err)
+	}
+
+	if err := ds.SetA("blue", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.AddA("blue", net.ParseIP("127.0.0.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetATTL("blue", 30); err != nil {
+		t.Fatal(err)
+	}
+
+	events, cancel := ds.Watch()
+	defer cancel()
+
+	if err := ds.RenameA("blue", "green"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a subscriber applying these in turn is left with both addresses
+	for i, want := range []Event{
+		{Op: EventDelete, Type: dns.TypeA, Name: "blue.docker."},
+		{Op: EventSet, Type: dns.TypeA, Name: "green.docker.", Value: "127.0.0.2"},
+		{Op: EventAdd, Type: dns.TypeA, Name: "green.docker.", Value: "127.0.0.3"},
+	} {
+		select {
+		case got := <-events:
+			if got != want {
+				t.Fatalf("rename event %d was %+v, expected %+v", i, got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("rename event %d was not delivered", i)
+		}
+	}
+
+	if records := ds.GetA("blue.docker."); len(records) != 0 {
+		t.Fatalf("old host was left with %v", records)
+	}
+
+	records := ds.GetA("green.docker.")
+	if len(records) != 2 || records[0].Hdr.Ttl != 30 {
+		t.Fatalf("renamed host was %v", records)
+	}
+
+	if err := ds.MoveA("green", net.ParseIP("127.0.0.4")); err != nil {
+		t.Fatal(err)
+	}
+
+	records = ds.GetA("green.docker.")
+	if len(records) != 1 || !records[0].A.Equal(net.ParseIP("127.0.0.4")) || records[0].Hdr.Ttl != 30 {
+		t.Fatalf("moved host was %v", records)
+	}
+
+	if err := ds.RenameA("green", "a..b"); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("renaming to an invalid name was %v", err)
+	}
+}
+
+func TestRenameAExpiry(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetATemp("a", net.ParseIP("127.0.0.2"), 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.AddWeightedA("a", net.ParseIP("127.0.0.3"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.RenameA("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the expiry and weights moved to b, so a new host a is permanent
+	if err := ds.AddA("a", net.ParseIP("127.0.0.4")); err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.weighted("a.docker.") || !ds.weighted("b.docker.") {
+		t.Fatal("weights did not move with the renamed host")
+	}
+
+	if err := ds.SetATemp("moved", net.ParseIP("127.0.0.5"), 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.MoveA("moved", net.ParseIP("127.0.0.6")); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if records := ds.GetA("a.docker."); len(records) != 1 {
+		t.Fatalf("new host took the expiry of the renamed one: %v", records)
+	}
+
+	if records := ds.GetA("b.docker."); len(records) != 0 {
+		t.Fatalf("renamed host did not expire: %v", records)
+	}
+
+	if records := ds.GetA("moved.docker."); len(records) != 1 {
+		t.Fatalf("moved host expired: %v", records)
+	}
+}
+
+// TestMoveAConcurrent queries a host while it is moved between addresses and
+// renamed back and forth; run it with -race. A host being moved must always
+// resolve, to one of its addresses.
+func TestMoveAConcurrent(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	ips := []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")}
+
+	for _, host := range []string{"moving", "blue"} {
+		if err := ds.SetA(host, ips[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		names := []string{"blue", "green"}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if err := ds.MoveA("moving", ips[i%2]); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if err := ds.RenameA(names[i%2], names[(i+1)%2]); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		msg, err := msgClientAddr(addr, "moving.docker.", dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(msg.Answer) != 1 {
+			t.Fatalf("moving host did not resolve: %v", msg)
+		}
+
+		if a := msg.Answer[0].(*dns.A).A; !a.Equal(ips[0]) && !a.Equal(ips[1]) {
+			t.Fatalf("moving host resolved to %v", a)
+		}
+
+		if records := ds.GetA("green.docker."); len(records) > 1 {
+			t.Fatalf("renamed host was %v", records)
+		}
+	}
+
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,70 @@
+package dnsserver
+
+import (
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// normalizeName returns name as a FQDN with any empty labels dropped, so that
+// "test.docker" and "test..docker." are both looked up as "test.docker.". It
+// returns false if the name is still not one DNS can carry, such as one with a
+// label over 63 octets.
+func normalizeName(name string) (string, bool) {
+	name = dns.Fqdn(name)
+
+	for strings.Contains(name, "..") {
+		name = strings.Replace(name, "..", ".", -1)
+	}
+
+	if name != "." {
+		name = strings.TrimPrefix(name, ".")
+	}
+
+	if _, ok := dns.IsDomainName(name); !ok {
+		return "", false
+	}
+
+	return name, true
+}
+
+// normalizeQuestions returns questions with their names normalized, or false
+// if any cannot be. questions is returned as is if no name changed.
+func normalizeQuestions(questions []dns.Question) ([]dns.Question, bool) {
+	var normalized []dns.Question
+
+	for i, question := range questions {
+		name, ok := normalizeName(question.Name)
+		if !ok {
+			return nil, false
+		}
+
+		if name == question.Name {
+			continue
+		}
+
+		if normalized == nil {
+			normalized = append([]dns.Question{}, questions...)
+		}
+
+		normalized[i].Name = name
+	}
+
+	if normalized == nil {
+		return questions, true
+	}
+
+	return normalized, true
+}
+
+// normalizeReply normalizes the question names of a reply, as its answers
+// already are. Only names asked without the trailing dot or with empty labels
+// change, and a reply echoing those as asked could not be packed. Names which
+// cannot be normalized are left alone.
+func normalizeReply(m *dns.Msg) {
+	for i, question := range m.Question {
+		if name, ok := normalizeName(question.Name); ok {
+			m.Question[i].Name = name
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package dnsserver
+
+import "github.com/erikh/dnsserver/db"
+
+// Option configures a Server at construction; see New.
+type Option func(*Server)
+
+// WithDB stores records in the given DB instead of a new db.Map.
+func WithDB(d db.DB) Option {
+	return func(ds *Server) {
+		ds.db = d
+	}
+}
+
+// WithTTL sets the default TTL; see SetTTL.
+func WithTTL(ttl uint32) Option {
+	return func(ds *Server) {
+		ds.SetTTL(ttl)
+	}
+}
+
+// WithForwarders sets the upstream servers; see SetForwarders.
+func WithForwarders(forwarders []string) Option {
+	return func(ds *Server) {
+		ds.SetForwarders(forwarders)
+	}
+}
+
+// WithListenNet chooses the network Listen serves on: "udp" (the default),
+// "tcp", or "both".
+func WithListenNet(network string) Option {
+	return func(ds *Server) {
+		ds.listenNet = network
+	}
+}
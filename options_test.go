@@ -0,0 +1,70 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestNewDefaults(t *testing.T) {
+	ds := New("Docker")
+
+	if ds.domain != "docker." {
+		t.Fatalf("domain was %q", ds.domain)
+	}
+
+	if _, ok := ds.db.(*db.Map); !ok {
+		t.Fatalf("default DB was %T", ds.db)
+	}
+
+	if ds.ttlFor(0) != DefaultTTL || ds.listenNet != "udp" || len(ds.forwarders) != 0 {
+		t.Fatal("zero-option server was not configured with the defaults")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	m := db.NewMap()
+
+	ds := New("docker",
+		WithDB(m),
+		WithTTL(30),
+		WithForwarders([]string{"192.0.2.1"}),
+		WithListenNet("tcp"),
+	)
+
+	if ds.db != m {
+		t.Fatal("WithDB was not applied")
+	}
+
+	if ds.ttlFor(0) != 30 {
+		t.Fatal("WithTTL was not applied")
+	}
+
+	if len(ds.forwarders) != 1 || ds.forwarders[0] != "192.0.2.1:53" {
+		t.Fatalf("WithForwarders was not applied: %v", ds.forwarders)
+	}
+
+	go ds.Listen("127.0.0.1:0")
+	addr := waitListening(t, ds.ListeningTCP)
+	defer ds.Close()
+
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	m2 := new(dns.Msg)
+	m2.SetQuestion("test.docker.", dns.TypeA)
+
+	msg, _, err := (&dns.Client{Net: "tcp"}).Exchange(m2, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 || msg.Answer[0].Header().Ttl != 30 {
+		t.Fatalf("server configured with options answered %v", msg.Answer)
+	}
+
+	if err := New("docker", WithListenNet("sctp")).Listen("127.0.0.1:0"); err != ErrUnknownNet {
+		t.Fatalf("unknown listen network yielded %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package dnsserver
+
+import (
+	"strings"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// ListAByPrefix is like ListA, but only lists the hosts at or below prefix, a
+// name in the domain: "svc", "svc.docker." and "*.svc.docker." all list the
+// host "svc" and every host ending in ".svc". The domain itself lists every
+// host, and names outside it none. DBs implementing db.PrefixLister filter the
+// records themselves; for others, every record is listed and then filtered.
+func (ds *Server) ListAByPrefix(prefix string) (db.ARecords, error) {
+	sub, ok := ds.prefixName(prefix)
+	if !ok {
+		return db.ARecords{}, nil
+	}
+
+	if pl, ok := ds.db.(db.PrefixLister); ok {
+		return pl.ListAByPrefix(sub)
+	}
+
+	records, err := ds.db.ListA()
+	if err != nil {
+		return nil, err
+	}
+
+	for host := range records {
+		if !db.Under(host, sub) {
+			delete(records, host)
+		}
+	}
+
+	return records, nil
+}
+
+// ListSRVByPrefix is like ListSRV, but only lists the services at or below
+// prefix, as ListAByPrefix does for hosts; "_tcp" lists every TCP service.
+func (ds *Server) ListSRVByPrefix(prefix string) (db.SRVRecords, error) {
+	sub, ok := ds.prefixName(prefix)
+	if !ok {
+		return db.SRVRecords{}, nil
+	}
+
+	if pl, ok := ds.db.(db.PrefixLister); ok {
+		return pl.ListSRVByPrefix(sub)
+	}
+
+	records, err := ds.db.ListSRV()
+	if err != nil {
+		return nil, err
+	}
+
+	for spec := range records {
+		if !db.Under(spec, sub) {
+			delete(records, spec)
+		}
+	}
+
+	return records, nil
+}
+
+// prefixName returns prefix relative to the domain, as the DB keys names, or
+// false if it is a FQDN outside the domain. A leading wildcard label is
+// dropped.
+func (ds *Server) prefixName(prefix string) (string, bool) {
+	name := strings.ToLower(strings.TrimPrefix(prefix, "*."))
+
+	if !dns.IsFqdn(name) {
+		return name, true
+	}
+
+	if name == ds.domain {
+		return "", true
+	}
+
+	if !ds.inDomain(name) {
+		return "", false
+	}
+
+	return ds.subdomain(name), true
+}
@@ -0,0 +1,52 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+func TestListByPrefix(t *testing.T) {
+	for _, ds := range []*Server{New("docker"), NewWithDB("docker", setOnlyDB{db.NewMap()})} {
+		for _, host := range []string{"svc", "web.svc", "db.web.svc", "notsvc", "svc.other"} {
+			if err := ds.SetA(host, net.ParseIP("127.0.0.2")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, spec := range [][2]string{{"http", "tcp"}, {"dns", "udp"}} {
+			if err := ds.SetSRV(spec[0], spec[1], &db.SRVRecord{Port: 80, Host: "web.svc"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, prefix := range []string{"svc", "SVC.docker.", "*.svc.docker."} {
+			records, err := ds.ListAByPrefix(prefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(records) != 3 || records["svc"] == nil || records["web.svc"] == nil || records["db.web.svc"] == nil {
+				t.Fatalf("%T: records under %q were %v", ds.db, prefix, records)
+			}
+		}
+
+		if records, err := ds.ListAByPrefix("docker."); err != nil || len(records) != 5 {
+			t.Fatalf("%T: records under the domain were %v (%v)", ds.db, records, err)
+		}
+
+		if records, err := ds.ListAByPrefix("svc.example."); err != nil || len(records) != 0 {
+			t.Fatalf("%T: records outside the domain were %v (%v)", ds.db, records, err)
+		}
+
+		srvs, err := ds.ListSRVByPrefix("_tcp.docker.")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(srvs) != 1 || len(srvs["_http._tcp"]) != 1 {
+			t.Fatalf("%T: services under _tcp were %v", ds.db, srvs)
+		}
+	}
+}
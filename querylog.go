@@ -0,0 +1,60 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// QueryLogger is called once for every query the server answers. rcode and
+// answers describe the reply that was written; dur is measured from when the
+// query was received.
+type QueryLogger interface {
+	LogQuery(remote net.Addr, q dns.Question, rcode int, answers int, dur time.Duration)
+}
+
+// NopQueryLogger discards all queries. It is the default.
+type NopQueryLogger struct{}
+
+// LogQuery does nothing.
+func (NopQueryLogger) LogQuery(net.Addr, dns.Question, int, int, time.Duration) {}
+
+// StdoutQueryLogger prints a line for each query to standard output.
+type StdoutQueryLogger struct{}
+
+// LogQuery prints the query and a summary of its reply.
+func (StdoutQueryLogger) LogQuery(remote net.Addr, q dns.Question, rcode int, answers int, dur time.Duration) {
+	fmt.Printf("%s %s %s %s %d answers %s\n", remote, q.Name, dns.TypeToString[q.Qtype], dns.RcodeToString[rcode], answers, dur)
+}
+
+// SetQueryLogger sets the logger called for every query. A nil logger
+// restores the default, which discards them.
+func (ds *Server) SetQueryLogger(logger QueryLogger) {
+	if logger == nil {
+		logger = NopQueryLogger{}
+	}
+
+	ds.loggerMutex.Lock()
+	ds.queryLogger = logger
+	ds.loggerMutex.Unlock()
+}
+
+// recordingWriter notes the replies written through it so they can be logged.
+type recordingWriter struct {
+	dns.ResponseWriter
+	rcode   int
+	answers int
+}
+
+func (w *recordingWriter) WriteMsg(m *dns.Msg) error {
+	w.rcode = m.Rcode
+	w.answers += len(m.Answer)
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// Unwrap returns the writer w wraps; see QueryContext.
+func (w *recordingWriter) Unwrap() dns.ResponseWriter {
+	return w.ResponseWriter
+}
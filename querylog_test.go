@@ -0,0 +1,76 @@
+package dnsserver
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+type loggedQuery struct {
+	name    string
+	rcode   int
+	answers int
+}
+
+type capturingLogger struct {
+	mutex   sync.Mutex
+	queries []loggedQuery
+}
+
+func (l *capturingLogger) LogQuery(remote net.Addr, q dns.Question, rcode int, answers int, dur time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.queries = append(l.queries, loggedQuery{name: q.Name, rcode: rcode, answers: answers})
+}
+
+func TestQueryLogger(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	logger := &capturingLogger{}
+	ds.SetQueryLogger(logger)
+
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+	ds.AddA("test", net.ParseIP("127.0.0.3"))
+
+	for _, name := range []string{"test.docker.", "missing.docker."} {
+		if _, err := msgClientAddr(addr, name, dns.TypeA); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the logger runs after the reply is written, so it may lag the client
+	for i := 0; i < 100; i++ {
+		logger.mutex.Lock()
+		n := len(logger.queries)
+		logger.mutex.Unlock()
+
+		if n >= 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+
+	expected := []loggedQuery{
+		{name: "test.docker.", rcode: dns.RcodeSuccess, answers: 2},
+		{name: "missing.docker.", rcode: dns.RcodeNameError, answers: 0},
+	}
+
+	if len(logger.queries) != len(expected) {
+		t.Fatalf("expected %d logged queries, got %v", len(expected), logger.queries)
+	}
+
+	for i, query := range expected {
+		if logger.queries[i] != query {
+			t.Fatalf("logged query was %+v instead of %+v", logger.queries[i], query)
+		}
+	}
+}
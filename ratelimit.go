@@ -0,0 +1,136 @@
+package dnsserver
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RateLimitAction is what the server does with a query from a client over its
+// rate limit.
+type RateLimitAction int
+
+const (
+	// RateLimitRefuse answers throttled queries with REFUSED. It is the
+	// default.
+	RateLimitRefuse RateLimitAction = iota
+	// RateLimitTruncate answers throttled UDP queries with an empty, truncated
+	// reply so well-behaved clients retry over TCP. Throttled TCP queries are
+	// refused.
+	RateLimitTruncate
+)
+
+// rateLimitGCInterval is how often idle client buckets are discarded.
+const rateLimitGCInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter is a token bucket rate limiter keyed by client IP.
+type limiter struct {
+	mutex   sync.Mutex
+	qps     float64
+	burst   float64
+	action  RateLimitAction
+	buckets map[string]*bucket
+	lastGC  time.Time
+	now     func() time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{
+		buckets: map[string]*bucket{},
+		now:     time.Now,
+	}
+}
+
+// SetRateLimit limits each client IP to qps queries per second, allowing
+// bursts of up to burst queries. A qps of 0 or less, the default, disables
+// rate limiting.
+func (ds *Server) SetRateLimit(qps int, burst int) {
+	if burst < 1 {
+		burst = 1
+	}
+
+	ds.limiter.mutex.Lock()
+	defer ds.limiter.mutex.Unlock()
+
+	ds.limiter.qps = float64(qps)
+	ds.limiter.burst = float64(burst)
+	ds.limiter.buckets = map[string]*bucket{}
+}
+
+// SetRateLimitAction sets how queries over the rate limit are answered.
+func (ds *Server) SetRateLimitAction(action RateLimitAction) {
+	ds.limiter.mutex.Lock()
+	ds.limiter.action = action
+	ds.limiter.mutex.Unlock()
+}
+
+// allow takes a token from the client's bucket, reporting false if it is
+// empty, along with the action to take.
+func (l *limiter) allow(addr net.Addr) (bool, RateLimitAction) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	ip := addrIP(addr)
+	if l.qps <= 0 || ip == nil {
+		return true, l.action
+	}
+
+	now := l.now()
+
+	if now.Sub(l.lastGC) >= rateLimitGCInterval {
+		l.gc(now)
+	}
+
+	key := ip.String()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.qps
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, l.action
+	}
+
+	b.tokens--
+	return true, l.action
+}
+
+// gc drops buckets that would have refilled by now; a new bucket starts full,
+// so forgetting them changes nothing. The mutex must be held.
+func (l *limiter) gc(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.qps >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+
+	l.lastGC = now
+}
+
+// throttle answers a query from a client over its rate limit.
+func (ds *Server) throttle(w dns.ResponseWriter, r *dns.Msg, action RateLimitAction) {
+	m := &dns.Msg{}
+
+	if _, udp := w.RemoteAddr().(*net.UDPAddr); udp && action == RateLimitTruncate {
+		m.SetReply(r)
+		m.Truncated = true
+	} else {
+		m.SetRcode(r, dns.RcodeRefused)
+	}
+
+	ds.writeMsg(w, r, m)
+}
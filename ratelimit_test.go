@@ -0,0 +1,79 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestRateLimit(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+	ds.SetRateLimit(1, 3)
+
+	var answered, refused int
+
+	for i := 0; i < 10; i++ {
+		msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch msg.Rcode {
+		case dns.RcodeSuccess:
+			answered++
+		case dns.RcodeRefused:
+			refused++
+		}
+	}
+
+	// the burst, plus perhaps one token refilled while querying
+	if answered < 3 || answered > 4 || answered+refused != 10 {
+		t.Fatalf("%d queries answered and %d refused", answered, refused)
+	}
+
+	ds.SetRateLimitAction(RateLimitTruncate)
+
+	msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !msg.Truncated || len(msg.Answer) != 0 {
+		t.Fatalf("throttled query was not truncated: %v", msg)
+	}
+
+	ds.SetRateLimit(0, 0)
+
+	if msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA); err != nil || len(msg.Answer) != 1 {
+		t.Fatalf("query was throttled with rate limiting disabled: %v (%v)", msg, err)
+	}
+}
+
+func TestRateLimitGC(t *testing.T) {
+	now := time.Now()
+
+	l := newLimiter()
+	l.now = func() time.Time { return now }
+	l.qps, l.burst = 1, 2
+
+	for i := 0; i < 10; i++ {
+		l.allow(&net.UDPAddr{IP: net.IPv4(127, 0, 0, byte(i+1))})
+	}
+
+	if len(l.buckets) != 10 {
+		t.Fatalf("expected 10 buckets, got %d", len(l.buckets))
+	}
+
+	now = now.Add(2 * rateLimitGCInterval)
+	l.allow(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	if len(l.buckets) != 1 {
+		t.Fatalf("idle buckets were not collected; %d remain", len(l.buckets))
+	}
+}
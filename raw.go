@@ -0,0 +1,79 @@
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// ErrTypedRecord is returned by SetRaw for records of a type the server has
+// its own records for, such as A or MX, and for meta types such as OPT, which
+// are never served from records.
+var ErrTypedRecord = errors.New("record type cannot be stored raw")
+
+// GetRaw receives a FQDN; looks up and supplies the raw records of a type
+// stored for it with SetRaw. dns.TypeANY supplies those of every type.
+func (ds *Server) GetRaw(name string, qtype uint16) []dns.RR {
+	rrs, err := ds.db.GetRaw(ds.subdomain(name), qtype)
+	if err != nil {
+		ds.logLookup(name, qtype, err)
+		return nil
+	}
+
+	for i, rr := range rrs {
+		rr = dns.Copy(rr)
+		rr.Header().Name = name
+		rr.Header().Ttl = ds.ttlFor(rr.Header().Ttl)
+		rrs[i] = rr
+	}
+
+	return rrs
+}
+
+// SetRaw stores a record of a type the server does not otherwise model, such
+// as HINFO or LOC, to be served as it is to queries for its type. The record's
+// owner name is replaced with the host's FQDN, and a TTL of 0 is served as the
+// default TTL. A host may hold several records of a type; one which differs
+// only in its TTL from a record already held replaces it. Types the server
+// has its own records for return ErrTypedRecord; set those with their own
+// setters. Raw records are not included in zone transfers. Note that this is
+// not the FQDN, but a hostname.
+func (ds *Server) SetRaw(host string, rr dns.RR) error {
+	if rr == nil {
+		return fmt.Errorf("%w: no record", ErrTypedRecord)
+	}
+
+	if rrtype := rr.Header().Rrtype; !rawType(rrtype) {
+		return fmt.Errorf("%w: %s", ErrTypedRecord, dns.TypeToString[rrtype])
+	}
+
+	if err := ds.checkHost(host); err != nil {
+		return err
+	}
+
+	rr = dns.Copy(rr)
+	rr.Header().Name = ds.qualifyHost(host)
+	if rr.Header().Class == 0 {
+		rr.Header().Class = dns.ClassINET
+	}
+
+	return ds.changed(ds.db.SetRaw(host, rr))
+}
+
+// DeleteRaw deletes a host's raw records of a type; dns.TypeANY deletes those
+// of every type. Note that this is not the FQDN, but a hostname.
+func (ds *Server) DeleteRaw(host string, qtype uint16) error {
+	return ds.changed(ds.db.DeleteRaw(host, qtype))
+}
+
+// rawType reports whether records of rrtype may be stored with SetRaw: those
+// of types not answered from the server's own records, other than the meta
+// types of RFC 6895.
+func rawType(rrtype uint16) bool {
+	if supportedTypes[rrtype] || rrtype == dns.TypeNone || rrtype == dns.TypeOPT {
+		return false
+	}
+
+	return rrtype < dns.TypeTKEY || rrtype > dns.TypeANY
+}
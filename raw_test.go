@@ -0,0 +1,77 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestRaw(t *testing.T) {
+	ds := New("docker")
+
+	hinfo := &dns.HINFO{
+		Hdr: dns.RR_Header{Rrtype: dns.TypeHINFO},
+		Cpu: "amd64",
+		Os:  "linux",
+	}
+
+	if err := ds.SetRaw("box", hinfo); err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+
+		m := ds.Resolve(r, nil)
+		if m == nil {
+			t.Fatalf("no reply to %s %s", name, dns.TypeToString[qtype])
+		}
+
+		return m
+	}
+
+	m := resolve("Box.docker.", dns.TypeHINFO)
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+		t.Fatalf("HINFO reply was %v", m)
+	}
+
+	got, ok := m.Answer[0].(*dns.HINFO)
+	if !ok || got.Cpu != "amd64" || got.Os != "linux" {
+		t.Fatalf("answer was not the HINFO record: %v", m.Answer[0])
+	}
+
+	if got.Hdr.Name != "Box.docker." || got.Hdr.Class != dns.ClassINET || got.Hdr.Ttl != ds.ttlFor(0) {
+		t.Fatalf("HINFO header was %v", got.Hdr)
+	}
+
+	// the record set is a copy
+	if hinfo.Hdr.Name != "" {
+		t.Fatalf("SetRaw changed the record it was given: %v", hinfo)
+	}
+
+	// the name exists, so other types are NODATA
+	if m := resolve("box.docker.", dns.TypeLOC); m.Rcode != dns.RcodeSuccess || len(m.Answer) != 0 || len(m.Ns) != 1 {
+		t.Fatalf("LOC reply at a raw name was %v", m)
+	}
+
+	if m := resolve("box.docker.", dns.TypeANY); len(m.Answer) != 1 {
+		t.Fatalf("ANY reply at a raw name was %v", m)
+	}
+
+	if err := ds.SetRaw("box", &dns.A{Hdr: dns.RR_Header{Rrtype: dns.TypeA}, A: net.ParseIP("127.0.0.2")}); !errors.Is(err, ErrTypedRecord) {
+		t.Fatalf("SetRaw of an A record returned %v", err)
+	}
+
+	if err := ds.DeleteRaw("box", dns.TypeHINFO); err != nil {
+		t.Fatal(err)
+	}
+
+	if m := resolve("box.docker.", dns.TypeHINFO); m.Rcode != dns.RcodeNameError {
+		t.Fatalf("HINFO reply after deletion was %v", m)
+	}
+}
@@ -0,0 +1,82 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+)
+
+// ReconcileA makes the A records match desired, which maps each host to its
+// one address, and returns how many hosts were added, given a new address and
+// removed. Hosts which already have exactly their desired address are left
+// alone, keeping any TTL, expiry or weights set for them; the others lose
+// theirs, as with SetA and DeleteA. Every entry is validated before
+// anything is changed, and dynamic updates are held off while the changes are
+// applied. If applying a change fails, the counts of those already made are
+// returned with the error.
+func (ds *Server) ReconcileA(desired map[string]net.IP) (added, updated, removed int, err error) {
+	want := make(map[string]net.IP, len(desired))
+	for host, ip := range desired {
+		if err := ds.checkA(host, ip); err != nil {
+			return 0, 0, 0, err
+		}
+
+		want[strings.ToLower(host)] = ip
+	}
+
+	ds.updateMutex.Lock()
+	defer ds.updateMutex.Unlock()
+
+	current, err := ds.db.ListA()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var events []Event
+
+	// announce whatever was applied, even if a later change failed
+	defer func() {
+		if added+updated+removed != 0 {
+			ds.announce(ds.changed(nil), events...)
+		}
+	}()
+
+	for host, ip := range want {
+		ips, ok := current[host]
+		if ok && len(ips) == 1 && ips[0].Equal(ip) {
+			continue
+		}
+
+		ds.forgetWeights(host)
+		ds.forgetExpiry(host)
+
+		if err := ds.db.SetA(host, ip); err != nil {
+			return added, updated, removed, err
+		}
+
+		if ok {
+			updated++
+		} else {
+			added++
+		}
+
+		events = append(events, ds.aEvents(EventSet, host, ip)...)
+	}
+
+	for host := range current {
+		if _, ok := want[host]; ok {
+			continue
+		}
+
+		ds.forgetWeights(host)
+
+		if err := ds.db.DeleteA(host); err != nil {
+			return added, updated, removed, err
+		}
+
+		ds.forgetExpiry(host)
+		removed++
+		events = append(events, ds.aEvents(EventDelete, host)...)
+	}
+
+	return added, updated, removed, nil
+}
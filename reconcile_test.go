@@ -0,0 +1,111 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReconcileA(t *testing.T) {
+	ds := New("docker")
+
+	for host, ip := range map[string]string{"same": "127.0.0.2", "moved": "127.0.0.3", "gone": "127.0.0.4"} {
+		if err := ds.SetA(host, net.ParseIP(ip)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ds.SetATTL("same", 30); err != nil {
+		t.Fatal(err)
+	}
+
+	serial := ds.Serial()
+
+	desired := map[string]net.IP{
+		"same":  net.ParseIP("127.0.0.2"),
+		"Moved": net.ParseIP("127.0.0.5"),
+		"new":   net.ParseIP("127.0.0.6"),
+	}
+
+	added, updated, removed, err := ds.ReconcileA(desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if added != 1 || updated != 1 || removed != 1 {
+		t.Fatalf("reconcile added %d, updated %d and removed %d", added, updated, removed)
+	}
+
+	if ds.Serial() != serial+1 {
+		t.Fatalf("serial went from %d to %d", serial, ds.Serial())
+	}
+
+	records, err := ds.ListA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != len(desired) {
+		t.Fatalf("reconciled records were %v", records)
+	}
+
+	for host, ip := range map[string]string{"same": "127.0.0.2", "moved": "127.0.0.5", "new": "127.0.0.6"} {
+		if ips := records[host]; len(ips) != 1 || !ips[0].Equal(net.ParseIP(ip)) {
+			t.Fatalf("%s was %v, not %s", host, ips, ip)
+		}
+	}
+
+	if a := ds.GetA("same.docker."); len(a) != 1 || a[0].Hdr.Ttl != 30 {
+		t.Fatalf("unchanged host lost its TTL: %v", a)
+	}
+
+	if added, updated, removed, err = ds.ReconcileA(desired); err != nil || added+updated+removed != 0 {
+		t.Fatalf("second reconcile made %d/%d/%d changes: %v", added, updated, removed, err)
+	}
+
+	if ds.Serial() != serial+1 {
+		t.Fatal("reconcile without changes bumped the serial")
+	}
+
+	if _, _, _, err := ds.ReconcileA(map[string]net.IP{"bad": net.ParseIP("::1")}); err == nil {
+		t.Fatal("invalid address was accepted")
+	}
+
+	if records, _ := ds.ListA(); len(records) != len(desired) {
+		t.Fatalf("rejected reconcile changed the records: %v", records)
+	}
+}
+
+func TestReconcileAExpiry(t *testing.T) {
+	ds := New("docker")
+
+	for _, host := range []string{"same", "moved", "gone"} {
+		if err := ds.SetATemp(host, net.ParseIP("127.0.0.2"), 100*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, _, _, err := ds.ReconcileA(map[string]net.IP{
+		"same":  net.ParseIP("127.0.0.2"),
+		"moved": net.ParseIP("127.0.0.3"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a host removed by the reconcile and then set again is permanent
+	if err := ds.AddA("gone", net.ParseIP("127.0.0.4")); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if a := ds.GetA("same.docker."); len(a) != 0 {
+		t.Fatalf("unchanged host lost its expiry: %v", a)
+	}
+
+	for _, name := range []string{"moved.docker.", "gone.docker."} {
+		if a := ds.GetA(name); len(a) != 1 {
+			t.Fatalf("%s kept an expiry the reconcile dropped: %v", name, a)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// errReplied is returned when a second reply is written to a query answered
+// by Resolve, which keeps only one.
+var errReplied = errors.New("reply already written")
+
+// Resolve answers r as ServeDNS would for a client at remote, and returns the
+// reply, without a socket. Middleware, ACLs, rate limits and the query logger
+// all apply. remote decides how the query is treated: a *net.UDPAddr is served
+// as a query over UDP, which may be truncated; anything else as one over TCP.
+// A nil remote is a local client over TCP. Zone transfers, which need more
+// than one reply, are refused. nil is returned if the server chose not to
+// reply at all, as it may for a client over its rate limit.
+func (ds *Server) Resolve(r *dns.Msg, remote net.Addr) *dns.Msg {
+	return ds.ResolveContext(context.Background(), r, remote)
+}
+
+// ResolveContext is like Resolve, but carries ctx to the middleware serving
+// the query, which can retrieve it with QueryContext; a tracing middleware,
+// for one, can record the query's span as a child of the span in ctx. The
+// server does not stop answering when ctx is done.
+func (ds *Server) ResolveContext(ctx context.Context, r *dns.Msg, remote net.Addr) *dns.Msg {
+	if remote == nil {
+		remote = &net.TCPAddr{IP: net.IPv6loopback}
+	}
+
+	w := &msgWriter{ctx: ctx, remote: remote}
+
+	if len(r.Question) == 1 && (r.Question[0].Qtype == dns.TypeAXFR || r.Question[0].Qtype == dns.TypeIXFR) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		return m
+	}
+
+	ds.ServeDNS(w, r)
+	return w.reply
+}
+
+// QueryContext returns the context a query served through w was resolved
+// with by ResolveContext, looking through writers which wrap it with an
+// Unwrap() dns.ResponseWriter method. Queries which came in over a socket have
+// none, and context.Background is returned.
+func QueryContext(w dns.ResponseWriter) context.Context {
+	for w != nil {
+		switch t := w.(type) {
+		case interface{ Context() context.Context }:
+			return t.Context()
+		case interface{ Unwrap() dns.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			w = nil
+		}
+	}
+
+	return context.Background()
+}
+
+// msgWriter is the dns.ResponseWriter a query answered by Resolve is served
+// through. It keeps the reply for the caller.
+type msgWriter struct {
+	ctx    context.Context // see ResolveContext; nil for Background
+	remote net.Addr
+	reply  *dns.Msg
+}
+
+func (w *msgWriter) Context() context.Context {
+	if w.ctx == nil {
+		return context.Background()
+	}
+
+	return w.ctx
+}
+
+func (w *msgWriter) LocalAddr() net.Addr {
+	if _, ok := w.remote.(*net.UDPAddr); ok {
+		return &net.UDPAddr{}
+	}
+
+	return &net.TCPAddr{}
+}
+
+func (w *msgWriter) RemoteAddr() net.Addr { return w.remote }
+
+func (w *msgWriter) WriteMsg(m *dns.Msg) error {
+	if w.reply != nil {
+		return errReplied
+	}
+
+	w.reply = m
+	return nil
+}
+
+func (w *msgWriter) Write(buf []byte) (int, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(buf); err != nil {
+		return 0, err
+	}
+
+	return len(buf), w.WriteMsg(m)
+}
+
+func (w *msgWriter) Close() error        { return nil }
+func (w *msgWriter) TsigStatus() error   { return dns.ErrSecret } // there is no TSIG to verify
+func (w *msgWriter) TsigTimersOnly(bool) {}
+func (w *msgWriter) Hijack()             {}
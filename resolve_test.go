@@ -0,0 +1,178 @@
+package dnsserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestResolve(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetSRV("http", "tcp", &db.SRVRecord{Port: 80, Host: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+
+		m := ds.Resolve(r, nil)
+		if m == nil {
+			t.Fatalf("no reply to %s %s", name, dns.TypeToString[qtype])
+		}
+
+		if m.Id != r.Id {
+			t.Fatalf("reply id %d does not match query id %d", m.Id, r.Id)
+		}
+
+		return m
+	}
+
+	m := resolve("test.docker.", dns.TypeA)
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 || !m.Answer[0].(*dns.A).A.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("A reply was %v", m)
+	}
+
+	m = resolve("_http._tcp.docker.", dns.TypeSRV)
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+		t.Fatalf("SRV reply was %v", m)
+	}
+
+	if srv := m.Answer[0].(*dns.SRV); srv.Port != 80 || srv.Target != "test.docker." {
+		t.Fatalf("SRV answer was %v", srv)
+	}
+
+	m = resolve("missing.docker.", dns.TypeA)
+	if m.Rcode != dns.RcodeNameError || len(m.Answer) != 0 || len(m.Ns) != 1 {
+		t.Fatalf("NXDOMAIN reply was %v", m)
+	}
+
+	if m = resolve("docker.", dns.TypeAXFR); m.Rcode != dns.RcodeRefused {
+		t.Fatalf("zone transfer was %v", m)
+	}
+}
+
+func TestResolveACL(t *testing.T) {
+	ds := New("docker")
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	_, allowed, _ := net.ParseCIDR("10.0.0.0/8")
+	ds.SetQueryACL([]net.IPNet{*allowed})
+
+	r := new(dns.Msg)
+	r.SetQuestion("test.docker.", dns.TypeA)
+
+	if m := ds.Resolve(r, &net.UDPAddr{IP: net.ParseIP("192.168.0.1"), Port: 53}); m == nil || m.Rcode != dns.RcodeRefused {
+		t.Fatalf("query from outside the ACL was answered with %v", m)
+	}
+
+	if m := ds.Resolve(r, &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 53}); m == nil || len(m.Answer) != 1 {
+		t.Fatalf("query from inside the ACL was answered with %v", m)
+	}
+}
+
+func TestResolveNormalizesNames(t *testing.T) {
+	ds := New("docker")
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	for _, name := range []string{"test.docker", "test..docker.", "TEST.docker"} {
+		r := new(dns.Msg)
+		r.Question = []dns.Question{{Name: name, Qtype: dns.TypeA, Qclass: dns.ClassINET}}
+
+		m := ds.Resolve(r, nil)
+		if m == nil || m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+			t.Fatalf("reply to %q was %v", name, m)
+		}
+
+		// the reply carries the name as looked up, so that it can be packed
+		want, _ := normalizeName(name)
+		if m.Question[0].Name != want || m.Answer[0].Header().Name != want {
+			t.Fatalf("reply to %q did not carry %q: %v", name, want, m)
+		}
+
+		if _, err := m.Pack(); err != nil {
+			t.Fatalf("reply to %q could not be packed: %v", name, err)
+		}
+	}
+
+	r := new(dns.Msg)
+	r.Question = []dns.Question{{Name: strings.Repeat("a", 64) + ".docker.", Qtype: dns.TypeA, Qclass: dns.ClassINET}}
+
+	if m := ds.Resolve(r, nil); m == nil || m.Rcode != dns.RcodeFormatError {
+		t.Fatalf("reply to a name with a long label was %v", m)
+	}
+}
+
+func TestResolveContext(t *testing.T) {
+	type key struct{}
+
+	ds := New("docker")
+
+	var got interface{}
+	ds.Use(func(next dns.Handler) dns.Handler {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			got = QueryContext(w).Value(key{})
+			next.ServeDNS(w, r)
+		})
+	})
+
+	r := new(dns.Msg)
+	r.SetQuestion("test.docker.", dns.TypeA)
+
+	if m := ds.ResolveContext(context.WithValue(context.Background(), key{}, "traced"), r, nil); m == nil {
+		t.Fatal("no reply")
+	}
+
+	if got != "traced" {
+		t.Fatalf("middleware saw context value %v", got)
+	}
+
+	if ds.Resolve(r, nil); got != nil {
+		t.Fatalf("middleware saw context value %v without a context", got)
+	}
+}
+
+func TestQualifyHostNormalizes(t *testing.T) {
+	ds := New("docker")
+
+	for host, want := range map[string]string{"test": "test.docker.", "Test.": "test.docker.", "@": "docker."} {
+		if got := ds.qualifyHost(host); got != want {
+			t.Fatalf("%q qualified to %q, not %q", host, got, want)
+		}
+	}
+
+	// hosts stored in a non-canonical form are still listed with valid names
+	if err := ds.db.SetA("odd.", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ds.ListASorted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 || entries[0].Name != "odd.docker." {
+		t.Fatalf("non-canonical host was listed as %v", entries)
+	}
+
+	// but hosts and targets are validated as given
+	if err := ds.SetA("a..b", net.ParseIP("127.0.0.2")); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("SetA of a host with an empty label returned %v", err)
+	}
+
+	if err := ds.SetSRV("http", "tcp", &db.SRVRecord{Port: 80, Host: "a..b"}); !errors.Is(err, ErrInvalidName) {
+		t.Fatalf("SetSRV with a target with an empty label returned %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	ipv4ArpaSuffix = ".in-addr.arpa."
+	ipv6ArpaSuffix = ".ip6.arpa."
+)
+
+// ErrMalformedArpa is returned when a reverse lookup name cannot be converted
+// back to an IP address.
+var ErrMalformedArpa = errors.New("malformed arpa name")
+
+// ReverseName converts an IP to its in-addr.arpa (IPv4) or ip6.arpa (IPv6)
+// reverse lookup name, e.g., 1.0.0.127.in-addr.arpa. for 127.0.0.1.
+func ReverseName(ip net.IP) (string, error) {
+	if ip.To16() == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+
+	return dns.ReverseAddr(ip.String())
+}
+
+// ParseReverseName converts an in-addr.arpa or ip6.arpa name back into the IP
+// address it represents. ErrMalformedArpa is returned if the name is not a
+// complete reverse lookup name.
+func ParseReverseName(name string) (net.IP, error) {
+	name = strings.ToLower(dns.Fqdn(name))
+
+	switch {
+	case strings.HasSuffix(name, ipv4ArpaSuffix):
+		labels := strings.Split(strings.TrimSuffix(name, ipv4ArpaSuffix), ".")
+		if len(labels) != net.IPv4len {
+			return nil, ErrMalformedArpa
+		}
+
+		ip := make(net.IP, net.IPv4len)
+		for i, label := range labels {
+			octet, err := strconv.ParseUint(label, 10, 8)
+			if err != nil {
+				return nil, ErrMalformedArpa
+			}
+			ip[net.IPv4len-1-i] = byte(octet)
+		}
+
+		return ip.To16(), nil
+	case strings.HasSuffix(name, ipv6ArpaSuffix):
+		labels := strings.Split(strings.TrimSuffix(name, ipv6ArpaSuffix), ".")
+		if len(labels) != net.IPv6len*2 {
+			return nil, ErrMalformedArpa
+		}
+
+		ip := make(net.IP, net.IPv6len)
+		for i, label := range labels {
+			nibble, err := strconv.ParseUint(label, 16, 4)
+			if err != nil || len(label) != 1 {
+				return nil, ErrMalformedArpa
+			}
+
+			// labels run from the least significant nibble to the most.
+			pos := len(labels) - 1 - i
+			if pos%2 == 0 {
+				ip[pos/2] |= byte(nibble) << 4
+			} else {
+				ip[pos/2] |= byte(nibble)
+			}
+		}
+
+		return ip, nil
+	}
+
+	return nil, ErrMalformedArpa
+}
+
+// GetPTR receives a reverse lookup FQDN; looks up and supplies the PTR record,
+// followed by those derived from A records if SetAutoPTR is on. Malformed arpa
+// names yield no records.
+func (ds *Server) GetPTR(name string) []*dns.PTR {
+	ip, err := ParseReverseName(name)
+	if err != nil {
+		return nil
+	}
+
+	arpa, err := ReverseName(ip)
+	if err != nil {
+		return nil
+	}
+
+	var hosts []string
+
+	host, err := ds.db.GetPTR(arpa)
+	if err == nil {
+		hosts = append(hosts, host)
+	} else {
+		ds.logLookup(name, dns.TypePTR, err)
+	}
+
+	derived, err := ds.autoPTRs(ip)
+	if err != nil {
+		ds.logLookup(name, dns.TypePTR, err)
+	}
+
+	for _, h := range derived {
+		if !strings.EqualFold(h, host) {
+			hosts = append(hosts, h)
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	records := make([]*dns.PTR, 0, len(hosts))
+	for _, h := range hosts {
+		records = append(records, &dns.PTR{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypePTR,
+				Class:  dns.ClassINET,
+				Ttl:    ds.ttlFor(0),
+			},
+			Ptr: h,
+		})
+	}
+
+	return records
+}
+
+// SetPTR points the reverse lookup name for an IP at a host. The host is
+// qualified with the server's domain unless it is already a FQDN.
+func (ds *Server) SetPTR(ip net.IP, host string) error {
+	arpa, err := ReverseName(ip)
+	if err != nil {
+		return err
+	}
+
+	return ds.changed(ds.db.SetPTR(arpa, ds.qualifyTarget(host)))
+}
+
+// DeletePTR deletes the reverse lookup record for an IP.
+func (ds *Server) DeletePTR(ip net.IP) error {
+	arpa, err := ReverseName(ip)
+	if err != nil {
+		return err
+	}
+
+	return ds.changed(ds.db.DeletePTR(arpa))
+}
@@ -0,0 +1,194 @@
+package dnsserver
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestReverseNameRoundTrip(t *testing.T) {
+	table := map[string]string{
+		"127.0.0.1":              "1.0.0.127.in-addr.arpa.",
+		"10.20.30.40":            "40.30.20.10.in-addr.arpa.",
+		"fe80::1":                "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.e.f.ip6.arpa.",
+		"2001:db8::ff00:42:8329": "9.2.3.8.2.4.0.0.0.0.f.f.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	}
+
+	for addr, arpa := range table {
+		ip := net.ParseIP(addr)
+
+		name, err := ReverseName(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if name != arpa {
+			t.Fatalf("reverse name for %q was %q, expected %q", addr, name, arpa)
+		}
+
+		parsed, err := ParseReverseName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !parsed.Equal(ip) {
+			t.Fatalf("parsed %q back to %q, expected %q", name, parsed, ip)
+		}
+	}
+}
+
+func TestParseReverseNameMalformed(t *testing.T) {
+	for _, name := range []string{
+		"1.0.127.in-addr.arpa.",
+		"256.0.0.127.in-addr.arpa.",
+		"a.0.0.127.in-addr.arpa.",
+		"1.0.0.0.8.e.f.ip6.arpa.",
+		"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.e.g.ip6.arpa.",
+		"test.docker.",
+	} {
+		if _, err := ParseReverseName(name); err != ErrMalformedArpa {
+			t.Fatalf("%q did not yield ErrMalformedArpa: %v", name, err)
+		}
+	}
+}
+
+func TestPTRRecord(t *testing.T) {
+	table := map[string]string{
+		"127.0.0.30": "ptr.docker.",
+		"fe80::30":   "ptr6.docker.",
+	}
+
+	server.SetPTR(net.ParseIP("127.0.0.30"), "ptr")
+	server.SetPTR(net.ParseIP("fe80::30"), "ptr6.docker.")
+
+	for addr, host := range table {
+		arpa, err := ReverseName(net.ParseIP(addr))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		msg, err := msgClient(arpa, dns.TypePTR)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(msg.Answer) != 1 {
+			t.Fatalf("Server did not reply with a valid answer.")
+		}
+
+		ptr, ok := msg.Answer[0].(*dns.PTR)
+		if !ok {
+			t.Fatalf("Expected PTR record, got a record of type %d instead.", msg.Answer[0].Header().Rrtype)
+		}
+
+		if ptr.Hdr.Name != arpa || ptr.Ptr != host {
+			t.Fatalf("PTR record was %q -> %q, expected %q -> %q", ptr.Hdr.Name, ptr.Ptr, arpa, host)
+		}
+	}
+
+	msg, err := msgClient("1.0.127.in-addr.arpa.", dns.TypePTR)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeNameError {
+		t.Fatalf("Expected NXDOMAIN for malformed arpa name, got rcode %d", msg.Rcode)
+	}
+
+	for addr := range table {
+		if err := server.DeletePTR(net.ParseIP(addr)); err != nil {
+			t.Fatal(err)
+		}
+
+		arpa, _ := ReverseName(net.ParseIP(addr))
+		msg, err := msgClient(arpa, dns.TypePTR)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(msg.Answer) != 0 {
+			t.Fatal("Server gave a reply after record has been deleted")
+		}
+	}
+}
+
+func TestAutoPTR(t *testing.T) {
+	ds := New("docker")
+	ds.SetAutoPTR(true)
+
+	ip := net.ParseIP("127.0.0.40")
+	arpa, err := ReverseName(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func() []string {
+		t.Helper()
+
+		r := new(dns.Msg)
+		r.SetQuestion(arpa, dns.TypePTR)
+
+		m := ds.Resolve(r, nil)
+		if m == nil {
+			t.Fatal("no reply to the reverse lookup")
+		}
+
+		hosts := []string{}
+		for _, rr := range m.Answer {
+			ptr, ok := rr.(*dns.PTR)
+			if !ok || ptr.Hdr.Name != arpa {
+				t.Fatalf("answer was not a PTR record for %q: %v", arpa, rr)
+			}
+
+			hosts = append(hosts, ptr.Ptr)
+		}
+
+		return hosts
+	}
+
+	if err := ds.SetA("web", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if hosts := resolve(); !reflect.DeepEqual(hosts, []string{"web.docker."}) {
+		t.Fatalf("reverse lookup answered %v", hosts)
+	}
+
+	// every host at the address is answered
+	if err := ds.SetA("api", ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if hosts := resolve(); !reflect.DeepEqual(hosts, []string{"api.docker.", "web.docker."}) {
+		t.Fatalf("reverse lookup of a shared address answered %v", hosts)
+	}
+
+	// records set with SetPTR come first, and are not repeated
+	if err := ds.SetPTR(ip, "web"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hosts := resolve(); !reflect.DeepEqual(hosts, []string{"web.docker.", "api.docker."}) {
+		t.Fatalf("reverse lookup with a PTR record answered %v", hosts)
+	}
+
+	if err := ds.DeletePTR(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.DeleteA("web"); err != nil {
+		t.Fatal(err)
+	}
+
+	if hosts := resolve(); !reflect.DeepEqual(hosts, []string{"api.docker."}) {
+		t.Fatalf("reverse lookup after DeleteA answered %v", hosts)
+	}
+
+	ds.SetAutoPTR(false)
+
+	if hosts := resolve(); len(hosts) != 0 {
+		t.Fatalf("reverse lookup with SetAutoPTR off answered %v", hosts)
+	}
+}
@@ -0,0 +1,1595 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.1
+// 	protoc        (unknown)
+// source: dnsserver.proto
+
+package rpc
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Event_Op int32
+
+const (
+	Event_OP_UNSPECIFIED Event_Op = 0
+	Event_OP_SET         Event_Op = 1
+	Event_OP_ADD         Event_Op = 2
+	Event_OP_DELETE      Event_Op = 3
+)
+
+// Enum value maps for Event_Op.
+var (
+	Event_Op_name = map[int32]string{
+		0: "OP_UNSPECIFIED",
+		1: "OP_SET",
+		2: "OP_ADD",
+		3: "OP_DELETE",
+	}
+	Event_Op_value = map[string]int32{
+		"OP_UNSPECIFIED": 0,
+		"OP_SET":         1,
+		"OP_ADD":         2,
+		"OP_DELETE":      3,
+	}
+)
+
+func (x Event_Op) Enum() *Event_Op {
+	p := new(Event_Op)
+	*p = x
+	return p
+}
+
+func (x Event_Op) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Event_Op) Descriptor() protoreflect.EnumDescriptor {
+	return file_dnsserver_proto_enumTypes[0].Descriptor()
+}
+
+func (Event_Op) Type() protoreflect.EnumType {
+	return &file_dnsserver_proto_enumTypes[0]
+}
+
+func (x Event_Op) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Event_Op.Descriptor instead.
+func (Event_Op) EnumDescriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{20, 0}
+}
+
+type SetARequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Host string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Ip   string `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+}
+
+func (x *SetARequest) Reset() {
+	*x = SetARequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetARequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetARequest) ProtoMessage() {}
+
+func (x *SetARequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetARequest.ProtoReflect.Descriptor instead.
+func (*SetARequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SetARequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *SetARequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+type SetAResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetAResponse) Reset() {
+	*x = SetAResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetAResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAResponse) ProtoMessage() {}
+
+func (x *SetAResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAResponse.ProtoReflect.Descriptor instead.
+func (*SetAResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{1}
+}
+
+type AddARequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Host string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Ip   string `protobuf:"bytes,2,opt,name=ip,proto3" json:"ip,omitempty"`
+}
+
+func (x *AddARequest) Reset() {
+	*x = AddARequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddARequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddARequest) ProtoMessage() {}
+
+func (x *AddARequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddARequest.ProtoReflect.Descriptor instead.
+func (*AddARequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *AddARequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *AddARequest) GetIp() string {
+	if x != nil {
+		return x.Ip
+	}
+	return ""
+}
+
+type AddAResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *AddAResponse) Reset() {
+	*x = AddAResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddAResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddAResponse) ProtoMessage() {}
+
+func (x *AddAResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddAResponse.ProtoReflect.Descriptor instead.
+func (*AddAResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{3}
+}
+
+type DeleteARequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Host string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	// If any are given, only these addresses are removed from the host.
+	Ips []string `protobuf:"bytes,2,rep,name=ips,proto3" json:"ips,omitempty"`
+}
+
+func (x *DeleteARequest) Reset() {
+	*x = DeleteARequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteARequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteARequest) ProtoMessage() {}
+
+func (x *DeleteARequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteARequest.ProtoReflect.Descriptor instead.
+func (*DeleteARequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DeleteARequest) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *DeleteARequest) GetIps() []string {
+	if x != nil {
+		return x.Ips
+	}
+	return nil
+}
+
+type DeleteAResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DeleteAResponse) Reset() {
+	*x = DeleteAResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteAResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteAResponse) ProtoMessage() {}
+
+func (x *DeleteAResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteAResponse.ProtoReflect.Descriptor instead.
+func (*DeleteAResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{5}
+}
+
+type ListARequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListARequest) Reset() {
+	*x = ListARequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListARequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListARequest) ProtoMessage() {}
+
+func (x *ListARequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListARequest.ProtoReflect.Descriptor instead.
+func (*ListARequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{6}
+}
+
+type ARecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Host string   `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Ips  []string `protobuf:"bytes,2,rep,name=ips,proto3" json:"ips,omitempty"`
+}
+
+func (x *ARecord) Reset() {
+	*x = ARecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ARecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ARecord) ProtoMessage() {}
+
+func (x *ARecord) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ARecord.ProtoReflect.Descriptor instead.
+func (*ARecord) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ARecord) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *ARecord) GetIps() []string {
+	if x != nil {
+		return x.Ips
+	}
+	return nil
+}
+
+type ListAResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Records []*ARecord `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+}
+
+func (x *ListAResponse) Reset() {
+	*x = ListAResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListAResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListAResponse) ProtoMessage() {}
+
+func (x *ListAResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListAResponse.ProtoReflect.Descriptor instead.
+func (*ListAResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListAResponse) GetRecords() []*ARecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type SRVTarget struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Priority uint32 `protobuf:"varint,1,opt,name=priority,proto3" json:"priority,omitempty"`
+	Weight   uint32 `protobuf:"varint,2,opt,name=weight,proto3" json:"weight,omitempty"`
+	Port     uint32 `protobuf:"varint,3,opt,name=port,proto3" json:"port,omitempty"`
+	Host     string `protobuf:"bytes,4,opt,name=host,proto3" json:"host,omitempty"`
+	Ttl      uint32 `protobuf:"varint,5,opt,name=ttl,proto3" json:"ttl,omitempty"`
+}
+
+func (x *SRVTarget) Reset() {
+	*x = SRVTarget{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SRVTarget) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SRVTarget) ProtoMessage() {}
+
+func (x *SRVTarget) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SRVTarget.ProtoReflect.Descriptor instead.
+func (*SRVTarget) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SRVTarget) GetPriority() uint32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *SRVTarget) GetWeight() uint32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+func (x *SRVTarget) GetPort() uint32 {
+	if x != nil {
+		return x.Port
+	}
+	return 0
+}
+
+func (x *SRVTarget) GetHost() string {
+	if x != nil {
+		return x.Host
+	}
+	return ""
+}
+
+func (x *SRVTarget) GetTtl() uint32 {
+	if x != nil {
+		return x.Ttl
+	}
+	return 0
+}
+
+type SetSRVRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Service  string     `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Protocol string     `protobuf:"bytes,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Target   *SRVTarget `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+}
+
+func (x *SetSRVRequest) Reset() {
+	*x = SetSRVRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetSRVRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetSRVRequest) ProtoMessage() {}
+
+func (x *SetSRVRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetSRVRequest.ProtoReflect.Descriptor instead.
+func (*SetSRVRequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SetSRVRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *SetSRVRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *SetSRVRequest) GetTarget() *SRVTarget {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+type SetSRVResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *SetSRVResponse) Reset() {
+	*x = SetSRVResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetSRVResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetSRVResponse) ProtoMessage() {}
+
+func (x *SetSRVResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetSRVResponse.ProtoReflect.Descriptor instead.
+func (*SetSRVResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{11}
+}
+
+type AddSRVRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Service  string     `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Protocol string     `protobuf:"bytes,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	Target   *SRVTarget `protobuf:"bytes,3,opt,name=target,proto3" json:"target,omitempty"`
+}
+
+func (x *AddSRVRequest) Reset() {
+	*x = AddSRVRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddSRVRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddSRVRequest) ProtoMessage() {}
+
+func (x *AddSRVRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddSRVRequest.ProtoReflect.Descriptor instead.
+func (*AddSRVRequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AddSRVRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *AddSRVRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *AddSRVRequest) GetTarget() *SRVTarget {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+type AddSRVResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *AddSRVResponse) Reset() {
+	*x = AddSRVResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddSRVResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddSRVResponse) ProtoMessage() {}
+
+func (x *AddSRVResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddSRVResponse.ProtoReflect.Descriptor instead.
+func (*AddSRVResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{13}
+}
+
+type DeleteSRVRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Service  string `protobuf:"bytes,1,opt,name=service,proto3" json:"service,omitempty"`
+	Protocol string `protobuf:"bytes,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+}
+
+func (x *DeleteSRVRequest) Reset() {
+	*x = DeleteSRVRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteSRVRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSRVRequest) ProtoMessage() {}
+
+func (x *DeleteSRVRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSRVRequest.ProtoReflect.Descriptor instead.
+func (*DeleteSRVRequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *DeleteSRVRequest) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *DeleteSRVRequest) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+type DeleteSRVResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DeleteSRVResponse) Reset() {
+	*x = DeleteSRVResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteSRVResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteSRVResponse) ProtoMessage() {}
+
+func (x *DeleteSRVResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteSRVResponse.ProtoReflect.Descriptor instead.
+func (*DeleteSRVResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{15}
+}
+
+type ListSRVRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListSRVRequest) Reset() {
+	*x = ListSRVRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListSRVRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSRVRequest) ProtoMessage() {}
+
+func (x *ListSRVRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSRVRequest.ProtoReflect.Descriptor instead.
+func (*ListSRVRequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{16}
+}
+
+type SRVRecord struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The service and protocol, as in "_http._tcp".
+	Spec    string       `protobuf:"bytes,1,opt,name=spec,proto3" json:"spec,omitempty"`
+	Targets []*SRVTarget `protobuf:"bytes,2,rep,name=targets,proto3" json:"targets,omitempty"`
+}
+
+func (x *SRVRecord) Reset() {
+	*x = SRVRecord{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SRVRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SRVRecord) ProtoMessage() {}
+
+func (x *SRVRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SRVRecord.ProtoReflect.Descriptor instead.
+func (*SRVRecord) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SRVRecord) GetSpec() string {
+	if x != nil {
+		return x.Spec
+	}
+	return ""
+}
+
+func (x *SRVRecord) GetTargets() []*SRVTarget {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+type ListSRVResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Records []*SRVRecord `protobuf:"bytes,1,rep,name=records,proto3" json:"records,omitempty"`
+}
+
+func (x *ListSRVResponse) Reset() {
+	*x = ListSRVResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListSRVResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListSRVResponse) ProtoMessage() {}
+
+func (x *ListSRVResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListSRVResponse.ProtoReflect.Descriptor instead.
+func (*ListSRVResponse) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListSRVResponse) GetRecords() []*SRVRecord {
+	if x != nil {
+		return x.Records
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{19}
+}
+
+// Event describes a record change; see dnsserver.Event.
+type Event struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Op Event_Op `protobuf:"varint,1,opt,name=op,proto3,enum=dnsserver.rpc.Event_Op" json:"op,omitempty"`
+	// The DNS record type, e.g. 1 for A.
+	Type  uint32 `protobuf:"varint,2,opt,name=type,proto3" json:"type,omitempty"`
+	Name  string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Value string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_dnsserver_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_dnsserver_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_dnsserver_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *Event) GetOp() Event_Op {
+	if x != nil {
+		return x.Op
+	}
+	return Event_OP_UNSPECIFIED
+}
+
+func (x *Event) GetType() uint32 {
+	if x != nil {
+		return x.Type
+	}
+	return 0
+}
+
+func (x *Event) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Event) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+var File_dnsserver_proto protoreflect.FileDescriptor
+
+var file_dnsserver_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x0d, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63,
+	0x22, 0x31, 0x0a, 0x0b, 0x53, 0x65, 0x74, 0x41, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68,
+	0x6f, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x02, 0x69, 0x70, 0x22, 0x0e, 0x0a, 0x0c, 0x53, 0x65, 0x74, 0x41, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x31, 0x0a, 0x0b, 0x41, 0x64, 0x64, 0x41, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x70, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x70, 0x22, 0x0e, 0x0a, 0x0c, 0x41, 0x64, 0x64, 0x41, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x36, 0x0a, 0x0e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x41, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f, 0x73, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03,
+	0x69, 0x70, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x70, 0x73, 0x22, 0x11,
+	0x0a, 0x0f, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x41, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x22, 0x0e, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x22, 0x2f, 0x0a, 0x07, 0x41, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x12, 0x12, 0x0a, 0x04,
+	0x68, 0x6f, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74,
+	0x12, 0x10, 0x0a, 0x03, 0x69, 0x70, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69,
+	0x70, 0x73, 0x22, 0x41, 0x0a, 0x0d, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x30, 0x0a, 0x07, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72,
+	0x2e, 0x72, 0x70, 0x63, 0x2e, 0x41, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x52, 0x07, 0x72, 0x65,
+	0x63, 0x6f, 0x72, 0x64, 0x73, 0x22, 0x79, 0x0a, 0x09, 0x53, 0x52, 0x56, 0x54, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x16,
+	0x0a, 0x06, 0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06,
+	0x77, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x68, 0x6f,
+	0x73, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x68, 0x6f, 0x73, 0x74, 0x12, 0x10,
+	0x0a, 0x03, 0x74, 0x74, 0x6c, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x03, 0x74, 0x74, 0x6c,
+	0x22, 0x77, 0x0a, 0x0d, 0x53, 0x65, 0x74, 0x53, 0x52, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x12, 0x30, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x52, 0x56, 0x54, 0x61, 0x72, 0x67, 0x65,
+	0x74, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x22, 0x10, 0x0a, 0x0e, 0x53, 0x65, 0x74,
+	0x53, 0x52, 0x56, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x77, 0x0a, 0x0d, 0x41,
+	0x64, 0x64, 0x53, 0x52, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07,
+	0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63,
+	0x6f, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63,
+	0x6f, 0x6c, 0x12, 0x30, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x18, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x53, 0x52, 0x56, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x52, 0x06, 0x74, 0x61,
+	0x72, 0x67, 0x65, 0x74, 0x22, 0x10, 0x0a, 0x0e, 0x41, 0x64, 0x64, 0x53, 0x52, 0x56, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x48, 0x0a, 0x10, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65,
+	0x53, 0x52, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x72,
+	0x76, 0x69, 0x63, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c,
+	0x22, 0x13, 0x0a, 0x11, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x52, 0x56, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x10, 0x0a, 0x0e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x52, 0x56,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x53, 0x0a, 0x09, 0x53, 0x52, 0x56, 0x52, 0x65,
+	0x63, 0x6f, 0x72, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x70, 0x65, 0x63, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x73, 0x70, 0x65, 0x63, 0x12, 0x32, 0x0a, 0x07, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x64, 0x6e, 0x73, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x52, 0x56, 0x54, 0x61, 0x72,
+	0x67, 0x65, 0x74, 0x52, 0x07, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x73, 0x22, 0x45, 0x0a, 0x0f,
+	0x4c, 0x69, 0x73, 0x74, 0x53, 0x52, 0x56, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x32, 0x0a, 0x07, 0x72, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x18, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x53, 0x52, 0x56, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64, 0x52, 0x07, 0x72, 0x65, 0x63, 0x6f,
+	0x72, 0x64, 0x73, 0x22, 0x0e, 0x0a, 0x0c, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x22, 0xaf, 0x01, 0x0a, 0x05, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x27, 0x0a,
+	0x02, 0x6f, 0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x64, 0x6e, 0x73, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e,
+	0x4f, 0x70, 0x52, 0x02, 0x6f, 0x70, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x22, 0x3f, 0x0a, 0x02, 0x4f, 0x70, 0x12, 0x12, 0x0a, 0x0e, 0x4f, 0x50,
+	0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0a,
+	0x0a, 0x06, 0x4f, 0x50, 0x5f, 0x53, 0x45, 0x54, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x4f, 0x50,
+	0x5f, 0x41, 0x44, 0x44, 0x10, 0x02, 0x12, 0x0d, 0x0a, 0x09, 0x4f, 0x50, 0x5f, 0x44, 0x45, 0x4c,
+	0x45, 0x54, 0x45, 0x10, 0x03, 0x32, 0xff, 0x04, 0x0a, 0x07, 0x52, 0x65, 0x63, 0x6f, 0x72, 0x64,
+	0x73, 0x12, 0x3f, 0x0a, 0x04, 0x53, 0x65, 0x74, 0x41, 0x12, 0x1a, 0x2e, 0x64, 0x6e, 0x73, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x65, 0x74, 0x41, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x65, 0x74, 0x41, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x3f, 0x0a, 0x04, 0x41, 0x64, 0x64, 0x41, 0x12, 0x1a, 0x2e, 0x64, 0x6e, 0x73,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x41, 0x64, 0x64, 0x41, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x41, 0x64, 0x64, 0x41, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x48, 0x0a, 0x07, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x41, 0x12, 0x1d,
+	0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x44,
+	0x65, 0x6c, 0x65, 0x74, 0x65, 0x41, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e,
+	0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x44, 0x65,
+	0x6c, 0x65, 0x74, 0x65, 0x41, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x42, 0x0a,
+	0x05, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x12, 0x1b, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76,
+	0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e,
+	0x72, 0x70, 0x63, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x41, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x45, 0x0a, 0x06, 0x53, 0x65, 0x74, 0x53, 0x52, 0x56, 0x12, 0x1c, 0x2e, 0x64, 0x6e,
+	0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x65, 0x74, 0x53,
+	0x52, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x64, 0x6e, 0x73, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x53, 0x65, 0x74, 0x53, 0x52, 0x56,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x45, 0x0a, 0x06, 0x41, 0x64, 0x64, 0x53,
+	0x52, 0x56, 0x12, 0x1c, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x41, 0x64, 0x64, 0x53, 0x52, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x1d, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63,
+	0x2e, 0x41, 0x64, 0x64, 0x53, 0x52, 0x56, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x4e, 0x0a, 0x09, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x53, 0x52, 0x56, 0x12, 0x1f, 0x2e, 0x64,
+	0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x44, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x53, 0x52, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e,
+	0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x44, 0x65,
+	0x6c, 0x65, 0x74, 0x65, 0x53, 0x52, 0x56, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x48, 0x0a, 0x07, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x52, 0x56, 0x12, 0x1d, 0x2e, 0x64, 0x6e, 0x73,
+	0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53,
+	0x52, 0x56, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x64, 0x6e, 0x73, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x53, 0x52,
+	0x56, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3c, 0x0a, 0x05, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x12, 0x1b, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72,
+	0x70, 0x63, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x14, 0x2e, 0x64, 0x6e, 0x73, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x2e, 0x72, 0x70, 0x63, 0x2e,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x20, 0x5a, 0x1e, 0x67, 0x69, 0x74, 0x68, 0x75,
+	0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x65, 0x72, 0x69, 0x6b, 0x68, 0x2f, 0x64, 0x6e, 0x73, 0x73,
+	0x65, 0x72, 0x76, 0x65, 0x72, 0x2f, 0x72, 0x70, 0x63, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x33,
+}
+
+var (
+	file_dnsserver_proto_rawDescOnce sync.Once
+	file_dnsserver_proto_rawDescData = file_dnsserver_proto_rawDesc
+)
+
+func file_dnsserver_proto_rawDescGZIP() []byte {
+	file_dnsserver_proto_rawDescOnce.Do(func() {
+		file_dnsserver_proto_rawDescData = protoimpl.X.CompressGZIP(file_dnsserver_proto_rawDescData)
+	})
+	return file_dnsserver_proto_rawDescData
+}
+
+var file_dnsserver_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_dnsserver_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_dnsserver_proto_goTypes = []interface{}{
+	(Event_Op)(0),             // 0: dnsserver.rpc.Event.Op
+	(*SetARequest)(nil),       // 1: dnsserver.rpc.SetARequest
+	(*SetAResponse)(nil),      // 2: dnsserver.rpc.SetAResponse
+	(*AddARequest)(nil),       // 3: dnsserver.rpc.AddARequest
+	(*AddAResponse)(nil),      // 4: dnsserver.rpc.AddAResponse
+	(*DeleteARequest)(nil),    // 5: dnsserver.rpc.DeleteARequest
+	(*DeleteAResponse)(nil),   // 6: dnsserver.rpc.DeleteAResponse
+	(*ListARequest)(nil),      // 7: dnsserver.rpc.ListARequest
+	(*ARecord)(nil),           // 8: dnsserver.rpc.ARecord
+	(*ListAResponse)(nil),     // 9: dnsserver.rpc.ListAResponse
+	(*SRVTarget)(nil),         // 10: dnsserver.rpc.SRVTarget
+	(*SetSRVRequest)(nil),     // 11: dnsserver.rpc.SetSRVRequest
+	(*SetSRVResponse)(nil),    // 12: dnsserver.rpc.SetSRVResponse
+	(*AddSRVRequest)(nil),     // 13: dnsserver.rpc.AddSRVRequest
+	(*AddSRVResponse)(nil),    // 14: dnsserver.rpc.AddSRVResponse
+	(*DeleteSRVRequest)(nil),  // 15: dnsserver.rpc.DeleteSRVRequest
+	(*DeleteSRVResponse)(nil), // 16: dnsserver.rpc.DeleteSRVResponse
+	(*ListSRVRequest)(nil),    // 17: dnsserver.rpc.ListSRVRequest
+	(*SRVRecord)(nil),         // 18: dnsserver.rpc.SRVRecord
+	(*ListSRVResponse)(nil),   // 19: dnsserver.rpc.ListSRVResponse
+	(*WatchRequest)(nil),      // 20: dnsserver.rpc.WatchRequest
+	(*Event)(nil),             // 21: dnsserver.rpc.Event
+}
+var file_dnsserver_proto_depIdxs = []int32{
+	8,  // 0: dnsserver.rpc.ListAResponse.records:type_name -> dnsserver.rpc.ARecord
+	10, // 1: dnsserver.rpc.SetSRVRequest.target:type_name -> dnsserver.rpc.SRVTarget
+	10, // 2: dnsserver.rpc.AddSRVRequest.target:type_name -> dnsserver.rpc.SRVTarget
+	10, // 3: dnsserver.rpc.SRVRecord.targets:type_name -> dnsserver.rpc.SRVTarget
+	18, // 4: dnsserver.rpc.ListSRVResponse.records:type_name -> dnsserver.rpc.SRVRecord
+	0,  // 5: dnsserver.rpc.Event.op:type_name -> dnsserver.rpc.Event.Op
+	1,  // 6: dnsserver.rpc.Records.SetA:input_type -> dnsserver.rpc.SetARequest
+	3,  // 7: dnsserver.rpc.Records.AddA:input_type -> dnsserver.rpc.AddARequest
+	5,  // 8: dnsserver.rpc.Records.DeleteA:input_type -> dnsserver.rpc.DeleteARequest
+	7,  // 9: dnsserver.rpc.Records.ListA:input_type -> dnsserver.rpc.ListARequest
+	11, // 10: dnsserver.rpc.Records.SetSRV:input_type -> dnsserver.rpc.SetSRVRequest
+	13, // 11: dnsserver.rpc.Records.AddSRV:input_type -> dnsserver.rpc.AddSRVRequest
+	15, // 12: dnsserver.rpc.Records.DeleteSRV:input_type -> dnsserver.rpc.DeleteSRVRequest
+	17, // 13: dnsserver.rpc.Records.ListSRV:input_type -> dnsserver.rpc.ListSRVRequest
+	20, // 14: dnsserver.rpc.Records.Watch:input_type -> dnsserver.rpc.WatchRequest
+	2,  // 15: dnsserver.rpc.Records.SetA:output_type -> dnsserver.rpc.SetAResponse
+	4,  // 16: dnsserver.rpc.Records.AddA:output_type -> dnsserver.rpc.AddAResponse
+	6,  // 17: dnsserver.rpc.Records.DeleteA:output_type -> dnsserver.rpc.DeleteAResponse
+	9,  // 18: dnsserver.rpc.Records.ListA:output_type -> dnsserver.rpc.ListAResponse
+	12, // 19: dnsserver.rpc.Records.SetSRV:output_type -> dnsserver.rpc.SetSRVResponse
+	14, // 20: dnsserver.rpc.Records.AddSRV:output_type -> dnsserver.rpc.AddSRVResponse
+	16, // 21: dnsserver.rpc.Records.DeleteSRV:output_type -> dnsserver.rpc.DeleteSRVResponse
+	19, // 22: dnsserver.rpc.Records.ListSRV:output_type -> dnsserver.rpc.ListSRVResponse
+	21, // 23: dnsserver.rpc.Records.Watch:output_type -> dnsserver.rpc.Event
+	15, // [15:24] is the sub-list for method output_type
+	6,  // [6:15] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_dnsserver_proto_init() }
+func file_dnsserver_proto_init() {
+	if File_dnsserver_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_dnsserver_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetARequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetAResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddARequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddAResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteARequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteAResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListARequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ARecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListAResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SRVTarget); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetSRVRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetSRVResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddSRVRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AddSRVResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteSRVRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteSRVResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListSRVRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SRVRecord); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListSRVResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_dnsserver_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Event); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_dnsserver_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   21,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_dnsserver_proto_goTypes,
+		DependencyIndexes: file_dnsserver_proto_depIdxs,
+		EnumInfos:         file_dnsserver_proto_enumTypes,
+		MessageInfos:      file_dnsserver_proto_msgTypes,
+	}.Build()
+	File_dnsserver_proto = out.File
+	file_dnsserver_proto_rawDesc = nil
+	file_dnsserver_proto_goTypes = nil
+	file_dnsserver_proto_depIdxs = nil
+}
@@ -0,0 +1,466 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: dnsserver.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Records_SetA_FullMethodName      = "/dnsserver.rpc.Records/SetA"
+	Records_AddA_FullMethodName      = "/dnsserver.rpc.Records/AddA"
+	Records_DeleteA_FullMethodName   = "/dnsserver.rpc.Records/DeleteA"
+	Records_ListA_FullMethodName     = "/dnsserver.rpc.Records/ListA"
+	Records_SetSRV_FullMethodName    = "/dnsserver.rpc.Records/SetSRV"
+	Records_AddSRV_FullMethodName    = "/dnsserver.rpc.Records/AddSRV"
+	Records_DeleteSRV_FullMethodName = "/dnsserver.rpc.Records/DeleteSRV"
+	Records_ListSRV_FullMethodName   = "/dnsserver.rpc.Records/ListSRV"
+	Records_Watch_FullMethodName     = "/dnsserver.rpc.Records/Watch"
+)
+
+// RecordsClient is the client API for Records service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Records manages the A and SRV records of a dnsserver.
+type RecordsClient interface {
+	// SetA sets a host to an address, replacing any already registered.
+	SetA(ctx context.Context, in *SetARequest, opts ...grpc.CallOption) (*SetAResponse, error)
+	// AddA adds an address to a host.
+	AddA(ctx context.Context, in *AddARequest, opts ...grpc.CallOption) (*AddAResponse, error)
+	// DeleteA deletes a host, or only the given addresses of it.
+	DeleteA(ctx context.Context, in *DeleteARequest, opts ...grpc.CallOption) (*DeleteAResponse, error)
+	// ListA lists all A records.
+	ListA(ctx context.Context, in *ListARequest, opts ...grpc.CallOption) (*ListAResponse, error)
+	// SetSRV sets a service to a target, replacing any already registered.
+	SetSRV(ctx context.Context, in *SetSRVRequest, opts ...grpc.CallOption) (*SetSRVResponse, error)
+	// AddSRV adds a target to a service.
+	AddSRV(ctx context.Context, in *AddSRVRequest, opts ...grpc.CallOption) (*AddSRVResponse, error)
+	// DeleteSRV deletes a service.
+	DeleteSRV(ctx context.Context, in *DeleteSRVRequest, opts ...grpc.CallOption) (*DeleteSRVResponse, error)
+	// ListSRV lists all SRV records.
+	ListSRV(ctx context.Context, in *ListSRVRequest, opts ...grpc.CallOption) (*ListSRVResponse, error)
+	// Watch streams A and SRV record changes as they are made, until the
+	// client cancels.
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Records_WatchClient, error)
+}
+
+type recordsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRecordsClient(cc grpc.ClientConnInterface) RecordsClient {
+	return &recordsClient{cc}
+}
+
+func (c *recordsClient) SetA(ctx context.Context, in *SetARequest, opts ...grpc.CallOption) (*SetAResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetAResponse)
+	err := c.cc.Invoke(ctx, Records_SetA_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) AddA(ctx context.Context, in *AddARequest, opts ...grpc.CallOption) (*AddAResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddAResponse)
+	err := c.cc.Invoke(ctx, Records_AddA_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) DeleteA(ctx context.Context, in *DeleteARequest, opts ...grpc.CallOption) (*DeleteAResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteAResponse)
+	err := c.cc.Invoke(ctx, Records_DeleteA_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) ListA(ctx context.Context, in *ListARequest, opts ...grpc.CallOption) (*ListAResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAResponse)
+	err := c.cc.Invoke(ctx, Records_ListA_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) SetSRV(ctx context.Context, in *SetSRVRequest, opts ...grpc.CallOption) (*SetSRVResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetSRVResponse)
+	err := c.cc.Invoke(ctx, Records_SetSRV_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) AddSRV(ctx context.Context, in *AddSRVRequest, opts ...grpc.CallOption) (*AddSRVResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddSRVResponse)
+	err := c.cc.Invoke(ctx, Records_AddSRV_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) DeleteSRV(ctx context.Context, in *DeleteSRVRequest, opts ...grpc.CallOption) (*DeleteSRVResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteSRVResponse)
+	err := c.cc.Invoke(ctx, Records_DeleteSRV_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) ListSRV(ctx context.Context, in *ListSRVRequest, opts ...grpc.CallOption) (*ListSRVResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListSRVResponse)
+	err := c.cc.Invoke(ctx, Records_ListSRV_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *recordsClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (Records_WatchClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Records_ServiceDesc.Streams[0], Records_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &recordsWatchClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Records_WatchClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type recordsWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *recordsWatchClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RecordsServer is the server API for Records service.
+// All implementations must embed UnimplementedRecordsServer
+// for forward compatibility
+//
+// Records manages the A and SRV records of a dnsserver.
+type RecordsServer interface {
+	// SetA sets a host to an address, replacing any already registered.
+	SetA(context.Context, *SetARequest) (*SetAResponse, error)
+	// AddA adds an address to a host.
+	AddA(context.Context, *AddARequest) (*AddAResponse, error)
+	// DeleteA deletes a host, or only the given addresses of it.
+	DeleteA(context.Context, *DeleteARequest) (*DeleteAResponse, error)
+	// ListA lists all A records.
+	ListA(context.Context, *ListARequest) (*ListAResponse, error)
+	// SetSRV sets a service to a target, replacing any already registered.
+	SetSRV(context.Context, *SetSRVRequest) (*SetSRVResponse, error)
+	// AddSRV adds a target to a service.
+	AddSRV(context.Context, *AddSRVRequest) (*AddSRVResponse, error)
+	// DeleteSRV deletes a service.
+	DeleteSRV(context.Context, *DeleteSRVRequest) (*DeleteSRVResponse, error)
+	// ListSRV lists all SRV records.
+	ListSRV(context.Context, *ListSRVRequest) (*ListSRVResponse, error)
+	// Watch streams A and SRV record changes as they are made, until the
+	// client cancels.
+	Watch(*WatchRequest, Records_WatchServer) error
+	mustEmbedUnimplementedRecordsServer()
+}
+
+// UnimplementedRecordsServer must be embedded to have forward compatible implementations.
+type UnimplementedRecordsServer struct {
+}
+
+func (UnimplementedRecordsServer) SetA(context.Context, *SetARequest) (*SetAResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetA not implemented")
+}
+func (UnimplementedRecordsServer) AddA(context.Context, *AddARequest) (*AddAResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddA not implemented")
+}
+func (UnimplementedRecordsServer) DeleteA(context.Context, *DeleteARequest) (*DeleteAResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteA not implemented")
+}
+func (UnimplementedRecordsServer) ListA(context.Context, *ListARequest) (*ListAResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListA not implemented")
+}
+func (UnimplementedRecordsServer) SetSRV(context.Context, *SetSRVRequest) (*SetSRVResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSRV not implemented")
+}
+func (UnimplementedRecordsServer) AddSRV(context.Context, *AddSRVRequest) (*AddSRVResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddSRV not implemented")
+}
+func (UnimplementedRecordsServer) DeleteSRV(context.Context, *DeleteSRVRequest) (*DeleteSRVResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteSRV not implemented")
+}
+func (UnimplementedRecordsServer) ListSRV(context.Context, *ListSRVRequest) (*ListSRVResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSRV not implemented")
+}
+func (UnimplementedRecordsServer) Watch(*WatchRequest, Records_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedRecordsServer) mustEmbedUnimplementedRecordsServer() {}
+
+// UnsafeRecordsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RecordsServer will
+// result in compilation errors.
+type UnsafeRecordsServer interface {
+	mustEmbedUnimplementedRecordsServer()
+}
+
+func RegisterRecordsServer(s grpc.ServiceRegistrar, srv RecordsServer) {
+	s.RegisterService(&Records_ServiceDesc, srv)
+}
+
+func _Records_SetA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).SetA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_SetA_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).SetA(ctx, req.(*SetARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_AddA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).AddA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_AddA_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).AddA(ctx, req.(*AddARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_DeleteA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).DeleteA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_DeleteA_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).DeleteA(ctx, req.(*DeleteARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_ListA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).ListA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_ListA_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).ListA(ctx, req.(*ListARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_SetSRV_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetSRVRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).SetSRV(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_SetSRV_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).SetSRV(ctx, req.(*SetSRVRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_AddSRV_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddSRVRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).AddSRV(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_AddSRV_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).AddSRV(ctx, req.(*AddSRVRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_DeleteSRV_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteSRVRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).DeleteSRV(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_DeleteSRV_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).DeleteSRV(ctx, req.(*DeleteSRVRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_ListSRV_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListSRVRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RecordsServer).ListSRV(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Records_ListSRV_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RecordsServer).ListSRV(ctx, req.(*ListSRVRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Records_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RecordsServer).Watch(m, &recordsWatchServer{ServerStream: stream})
+}
+
+type Records_WatchServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type recordsWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *recordsWatchServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// Records_ServiceDesc is the grpc.ServiceDesc for Records service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Records_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "dnsserver.rpc.Records",
+	HandlerType: (*RecordsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetA",
+			Handler:    _Records_SetA_Handler,
+		},
+		{
+			MethodName: "AddA",
+			Handler:    _Records_AddA_Handler,
+		},
+		{
+			MethodName: "DeleteA",
+			Handler:    _Records_DeleteA_Handler,
+		},
+		{
+			MethodName: "ListA",
+			Handler:    _Records_ListA_Handler,
+		},
+		{
+			MethodName: "SetSRV",
+			Handler:    _Records_SetSRV_Handler,
+		},
+		{
+			MethodName: "AddSRV",
+			Handler:    _Records_AddSRV_Handler,
+		},
+		{
+			MethodName: "DeleteSRV",
+			Handler:    _Records_DeleteSRV_Handler,
+		},
+		{
+			MethodName: "ListSRV",
+			Handler:    _Records_ListSRV_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Records_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "dnsserver.proto",
+}
@@ -0,0 +1,219 @@
+// Package rpc provides a gRPC service for managing a dnsserver's A and SRV
+// records remotely, including a stream of record changes. It lives in its own
+// package so that dnsserver itself does not depend on gRPC or protobuf.
+//
+//	s := rpc.NewGRPCServer(ds)
+//	s.Serve(listener)
+//
+// The service is defined in dnsserver.proto; the *.pb.go files are generated
+// from it.
+package rpc
+
+//go:generate buf generate
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+
+	"github.com/erikh/dnsserver"
+	"github.com/erikh/dnsserver/db"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPCServer returns a gRPC server with the Records service registered for
+// ds. Further options, such as credentials, are passed on to grpc.NewServer.
+func NewGRPCServer(ds *dnsserver.Server, opts ...grpc.ServerOption) *grpc.Server {
+	s := grpc.NewServer(opts...)
+	RegisterRecordsServer(s, &recordsServer{ds: ds})
+	return s
+}
+
+type recordsServer struct {
+	UnimplementedRecordsServer
+	ds *dnsserver.Server
+}
+
+func (s *recordsServer) SetA(_ context.Context, req *SetARequest) (*SetAResponse, error) {
+	ip, err := parseIP(req.Ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetAResponse{}, toStatus(s.ds.SetA(req.Host, ip))
+}
+
+func (s *recordsServer) AddA(_ context.Context, req *AddARequest) (*AddAResponse, error) {
+	ip, err := parseIP(req.Ip)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddAResponse{}, toStatus(s.ds.AddA(req.Host, ip))
+}
+
+func (s *recordsServer) DeleteA(_ context.Context, req *DeleteARequest) (*DeleteAResponse, error) {
+	ips := make([]net.IP, 0, len(req.Ips))
+
+	for _, val := range req.Ips {
+		ip, err := parseIP(val)
+		if err != nil {
+			return nil, err
+		}
+
+		ips = append(ips, ip)
+	}
+
+	return &DeleteAResponse{}, toStatus(s.ds.DeleteA(req.Host, ips...))
+}
+
+func (s *recordsServer) ListA(context.Context, *ListARequest) (*ListAResponse, error) {
+	records, err := s.ds.ListA()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ListAResponse{}
+
+	for host, ips := range records {
+		record := &ARecord{Host: host}
+		for _, ip := range ips {
+			record.Ips = append(record.Ips, ip.String())
+		}
+
+		resp.Records = append(resp.Records, record)
+	}
+
+	return resp, nil
+}
+
+func (s *recordsServer) SetSRV(_ context.Context, req *SetSRVRequest) (*SetSRVResponse, error) {
+	srv, err := srvRecord(req.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SetSRVResponse{}, toStatus(s.ds.SetSRV(req.Service, req.Protocol, srv))
+}
+
+func (s *recordsServer) AddSRV(_ context.Context, req *AddSRVRequest) (*AddSRVResponse, error) {
+	srv, err := srvRecord(req.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AddSRVResponse{}, toStatus(s.ds.AddSRV(req.Service, req.Protocol, srv))
+}
+
+func (s *recordsServer) DeleteSRV(_ context.Context, req *DeleteSRVRequest) (*DeleteSRVResponse, error) {
+	return &DeleteSRVResponse{}, toStatus(s.ds.DeleteSRV(req.Service, req.Protocol))
+}
+
+func (s *recordsServer) ListSRV(context.Context, *ListSRVRequest) (*ListSRVResponse, error) {
+	records, err := s.ds.ListSRVSpecs()
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &ListSRVResponse{}
+
+	for spec, srvs := range records {
+		record := &SRVRecord{Spec: spec.String()}
+		for _, srv := range srvs {
+			record.Targets = append(record.Targets, &SRVTarget{
+				Priority: uint32(srv.Priority),
+				Weight:   uint32(srv.Weight),
+				Port:     uint32(srv.Port),
+				Host:     srv.Host,
+				Ttl:      srv.TTL,
+			})
+		}
+
+		resp.Records = append(resp.Records, record)
+	}
+
+	return resp, nil
+}
+
+func (s *recordsServer) Watch(_ *WatchRequest, stream Records_WatchServer) error {
+	events, cancel := s.ds.Watch()
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			err := stream.Send(&Event{
+				Op:    toOp(event.Op),
+				Type:  uint32(event.Type),
+				Name:  event.Name,
+				Value: event.Value,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toOp(op dnsserver.EventOp) Event_Op {
+	switch op {
+	case dnsserver.EventSet:
+		return Event_OP_SET
+	case dnsserver.EventAdd:
+		return Event_OP_ADD
+	case dnsserver.EventDelete:
+		return Event_OP_DELETE
+	default:
+		return Event_OP_UNSPECIFIED
+	}
+}
+
+func parseIP(val string) (net.IP, error) {
+	ip := net.ParseIP(val)
+	if ip == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid address %q", val)
+	}
+
+	return ip, nil
+}
+
+// srvRecord converts a target from a request, whose fields are wider than
+// those of a SRV record.
+func srvRecord(target *SRVTarget) (*db.SRVRecord, error) {
+	if target == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing target")
+	}
+
+	for _, val := range []uint32{target.Priority, target.Weight, target.Port} {
+		if val > math.MaxUint16 {
+			return nil, status.Errorf(codes.InvalidArgument, "%d is out of range for a SRV field", val)
+		}
+	}
+
+	return &db.SRVRecord{
+		Priority: uint16(target.Priority),
+		Weight:   uint16(target.Weight),
+		Port:     uint16(target.Port),
+		Host:     target.Host,
+		TTL:      target.Ttl,
+	}, nil
+}
+
+// toStatus converts an error from the server into a gRPC status error.
+func toStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, db.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, dnsserver.ErrInvalidName), errors.Is(err, dnsserver.ErrInvalidIP), errors.Is(err, dnsserver.ErrNotFQDN):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,177 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver"
+	"github.com/miekg/dns"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial serves ds over an in-process connection and returns a client for it.
+func dial(t *testing.T, ds *dnsserver.Server) RecordsClient {
+	t.Helper()
+
+	l := bufconn.Listen(1 << 20)
+	s := NewGRPCServer(ds)
+	go s.Serve(l)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return l.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return NewRecordsClient(conn)
+}
+
+func TestA(t *testing.T) {
+	ds := dnsserver.New("docker")
+	client := dial(t, ds)
+	ctx := context.Background()
+
+	if _, err := client.SetA(ctx, &SetARequest{Host: "test", Ip: "127.0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.AddA(ctx, &AddARequest{Host: "test", Ip: "127.0.0.3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if records := ds.GetA("test.docker."); len(records) != 2 {
+		t.Fatalf("expected two addresses, got %v", records)
+	}
+
+	resp, err := client.ListA(ctx, &ListARequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Records) != 1 || resp.Records[0].Host != "test" || len(resp.Records[0].Ips) != 2 {
+		t.Fatalf("ListA returned %v", resp.Records)
+	}
+
+	if _, err := client.DeleteA(ctx, &DeleteARequest{Host: "test", Ips: []string{"127.0.0.2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if records := ds.GetA("test.docker."); len(records) != 1 || !records[0].A.Equal(net.ParseIP("127.0.0.3")) {
+		t.Fatalf("DeleteA left %v", records)
+	}
+
+	for _, req := range []*SetARequest{{Host: "test", Ip: "bogus"}, {Host: "test", Ip: "::1"}, {Host: "", Ip: "127.0.0.2"}} {
+		if _, err := client.SetA(ctx, req); status.Code(err) != codes.InvalidArgument {
+			t.Fatalf("SetA %v was %v", req, err)
+		}
+	}
+}
+
+func TestSRV(t *testing.T) {
+	ds := dnsserver.New("docker")
+	client := dial(t, ds)
+	ctx := context.Background()
+
+	if _, err := client.SetSRV(ctx, &SetSRVRequest{Service: "http", Protocol: "tcp", Target: &SRVTarget{Port: 80, Host: "web"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.AddSRV(ctx, &AddSRVRequest{Service: "http", Protocol: "tcp", Target: &SRVTarget{Priority: 10, Port: 8080, Host: "web2", Ttl: 30}}); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.ListSRV(ctx, &ListSRVRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resp.Records) != 1 || resp.Records[0].Spec != "_http._tcp" || len(resp.Records[0].Targets) != 2 {
+		t.Fatalf("ListSRV returned %v", resp.Records)
+	}
+
+	if target := resp.Records[0].Targets[1]; target.Priority != 10 || target.Port != 8080 || target.Host != "web2.docker." || target.Ttl != 30 {
+		t.Fatalf("second target was %v", target)
+	}
+
+	if _, err := client.SetSRV(ctx, &SetSRVRequest{Service: "http", Protocol: "tcp", Target: &SRVTarget{Port: 1 << 16, Host: "web"}}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("out of range port was %v", err)
+	}
+
+	if _, err := client.SetSRV(ctx, &SetSRVRequest{Service: "http", Protocol: "tcp"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("missing target was %v", err)
+	}
+
+	if _, err := client.DeleteSRV(ctx, &DeleteSRVRequest{Service: "http", Protocol: "tcp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if records := ds.GetSRV("_http._tcp.docker."); len(records) != 0 {
+		t.Fatalf("DeleteSRV left %v", records)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ds := dnsserver.New("docker")
+	client := dial(t, ds)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &WatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the subscription is made once the server has the stream; keep making
+	// changes until one is seen.
+	events := make(chan *Event)
+	go func() {
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				close(events)
+				return
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("stream ended without an event")
+			}
+
+			if event.Op != Event_OP_SET || event.Type != uint32(dns.TypeA) || event.Name != "test.docker." || event.Value != "127.0.0.2" {
+				t.Fatalf("unexpected event %v", event)
+			}
+
+			return
+		case <-tick.C:
+			if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+				t.Fatal(err)
+			}
+		case <-ctx.Done():
+			t.Fatal("no event was received")
+		}
+	}
+}
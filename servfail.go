@@ -0,0 +1,72 @@
+package dnsserver
+
+import (
+	"strings"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// lookupError returns the error the DB fails with when asked for the records
+// of questions which serve found nothing for, or nil if the DB is answering.
+// Lookups log such errors and yield no records, which is indistinguishable
+// from the name not existing; answering NXDOMAIN or NODATA then would have
+// clients cache a wrong negative, where SERVFAIL has them retry.
+func (ds *Server) lookupError(questions []dns.Question) error {
+	for _, question := range questions {
+		if err := ds.questionError(question); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// questionError asks the DB for the records of question, returning any error
+// other than db.ErrNotFound. Names which are not ours are not asked for.
+func (ds *Server) questionError(question dns.Question) error {
+	var err error
+
+	if question.Qtype == dns.TypePTR {
+		ip, perr := ParseReverseName(question.Name)
+		if perr != nil {
+			return nil
+		}
+
+		arpa, perr := ReverseName(ip)
+		if perr != nil {
+			return nil
+		}
+
+		_, err = ds.db.GetPTR(arpa)
+	} else {
+		if !strings.EqualFold(question.Name, ds.domain) && !ds.inDomain(question.Name) {
+			return nil
+		}
+
+		sub := ds.subdomain(question.Name)
+
+		switch question.Qtype {
+		case dns.TypeAAAA:
+			_, err = ds.db.GetAAAA(sub)
+		case dns.TypeCNAME:
+			_, err = ds.db.GetCNAME(sub)
+		case dns.TypeTXT:
+			_, err = ds.db.GetTXT(sub)
+		case dns.TypeMX:
+			_, err = ds.db.GetMX(sub)
+		case dns.TypeCAA:
+			_, err = ds.db.GetCAA(sub)
+		case dns.TypeSRV:
+			_, err = ds.db.GetSRV(sub)
+		default:
+			_, err = ds.db.GetA(sub)
+		}
+	}
+
+	if err == db.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
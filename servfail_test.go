@@ -0,0 +1,128 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// downDB fails every lookup while down is set, as a DB whose backend is
+// unreachable would.
+type downDB struct {
+	db.DB
+	down bool
+}
+
+func (d *downDB) fail() error {
+	if d.down {
+		return errBackendDown
+	}
+
+	return nil
+}
+
+func (d *downDB) GetA(fqdn string) ([]net.IP, error) {
+	if err := d.fail(); err != nil {
+		return nil, err
+	}
+
+	return d.DB.GetA(fqdn)
+}
+
+func (d *downDB) GetAAAA(fqdn string) (net.IP, error) {
+	if err := d.fail(); err != nil {
+		return nil, err
+	}
+
+	return d.DB.GetAAAA(fqdn)
+}
+
+func (d *downDB) GetCNAME(alias string) (string, error) {
+	if err := d.fail(); err != nil {
+		return "", err
+	}
+
+	return d.DB.GetCNAME(alias)
+}
+
+func (d *downDB) GetTXT(fqdn string) ([]string, error) {
+	if err := d.fail(); err != nil {
+		return nil, err
+	}
+
+	return d.DB.GetTXT(fqdn)
+}
+
+func (d *downDB) GetPTR(arpa string) (string, error) {
+	if err := d.fail(); err != nil {
+		return "", err
+	}
+
+	return d.DB.GetPTR(arpa)
+}
+
+func (d *downDB) GetSRV(spec string) ([]*db.SRVRecord, error) {
+	if err := d.fail(); err != nil {
+		return nil, err
+	}
+
+	return d.DB.GetSRV(spec)
+}
+
+func TestServFailOnBackendError(t *testing.T) {
+	backend := &downDB{DB: db.NewMap()}
+	ds := NewWithDB("docker", backend)
+
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+	ds.SetTXT("test", []string{"text"})
+
+	resolve := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+
+		m := ds.Resolve(r, nil)
+		if m == nil {
+			t.Fatalf("no reply to %s %s", name, dns.TypeToString[qtype])
+		}
+
+		return m
+	}
+
+	backend.down = true
+
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+	}{
+		{"test.docker.", dns.TypeA},
+		{"missing.docker.", dns.TypeA},
+		{"test.docker.", dns.TypeTXT},
+		{"_http._tcp.docker.", dns.TypeSRV},
+		{"2.0.0.127.in-addr.arpa.", dns.TypePTR},
+	} {
+		if m := resolve(q.name, q.qtype); m.Rcode != dns.RcodeServerFailure || len(m.Ns) != 0 {
+			t.Fatalf("%s %s with the backend down was answered with %v", q.name, dns.TypeToString[q.qtype], m)
+		}
+	}
+
+	backend.down = false
+
+	if m := resolve("test.docker.", dns.TypeA); m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+		t.Fatalf("A reply after the backend recovered was %v", m)
+	}
+
+	if m := resolve("missing.docker.", dns.TypeA); m.Rcode != dns.RcodeNameError {
+		t.Fatalf("missing name after the backend recovered was answered with %v", m)
+	}
+
+	// names which are not ours are refused without asking the DB
+	backend.down = true
+
+	if m := resolve("example.com.", dns.TypeA); m.Rcode != dns.RcodeRefused {
+		t.Fatalf("out of zone name with the backend down was answered with %v", m)
+	}
+}
@@ -0,0 +1,62 @@
+package dnsserver
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// SetShuffle turns shuffling of A and AAAA answers on or off. When on, each
+// reply lists a name's addresses in a fresh random order, so that clients
+// which always use the first address spread their load across all of them.
+// Hosts with weighted addresses (see AddWeightedA) keep their weighted order.
+// Shuffling is off by default; the randomness may be seeded with
+// WithShuffleSource.
+func (ds *Server) SetShuffle(enabled bool) {
+	ds.shuffleMutex.Lock()
+	defer ds.shuffleMutex.Unlock()
+
+	ds.shuffling = enabled
+}
+
+// WithShuffleSource sets the source of randomness for SetShuffle, so that the
+// order of answers can be reproduced, e.g. in tests.
+func WithShuffleSource(src rand.Source) Option {
+	return func(ds *Server) {
+		ds.shuffleRand = rand.New(src)
+	}
+}
+
+// shuffle reorders each run of A or AAAA records for the same name in
+// answers, if shuffling is on. Other records, such as the CNAMEs leading to
+// the addresses, stay where they are.
+func (ds *Server) shuffle(answers []dns.RR) {
+	ds.shuffleMutex.Lock()
+	defer ds.shuffleMutex.Unlock()
+
+	if !ds.shuffling {
+		return
+	}
+
+	if ds.shuffleRand == nil {
+		ds.shuffleRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	for start := 0; start < len(answers); {
+		h := answers[start].Header()
+
+		end := start + 1
+		for end < len(answers) && answers[end].Header().Rrtype == h.Rrtype && strings.EqualFold(answers[end].Header().Name, h.Name) {
+			end++
+		}
+
+		if (h.Rrtype == dns.TypeA || h.Rrtype == dns.TypeAAAA) && !ds.weighted(h.Name) {
+			run := answers[start:end]
+			ds.shuffleRand.Shuffle(len(run), func(i, j int) { run[i], run[j] = run[j], run[i] })
+		}
+
+		start = end
+	}
+}
@@ -0,0 +1,52 @@
+package dnsserver
+
+import (
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestShuffle(t *testing.T) {
+	ds := New("docker", WithShuffleSource(rand.NewSource(1)))
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	for _, ip := range []string{"127.0.0.2", "127.0.0.3", "127.0.0.4"} {
+		if err := ds.AddA("test", net.ParseIP(ip)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	firsts := func() map[string]int {
+		t.Helper()
+
+		seen := map[string]int{}
+
+		for i := 0; i < 30; i++ {
+			msg, err := msgClientAddr(addr, "test.docker.", dns.TypeA)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(msg.Answer) != 3 {
+				t.Fatalf("expected three answers, got %v", msg.Answer)
+			}
+
+			seen[msg.Answer[0].(*dns.A).A.String()]++
+		}
+
+		return seen
+	}
+
+	if seen := firsts(); len(seen) != 1 || seen["127.0.0.2"] != 30 {
+		t.Fatalf("answers were reordered with shuffling off: %v", seen)
+	}
+
+	ds.SetShuffle(true)
+
+	if seen := firsts(); len(seen) != 3 {
+		t.Fatalf("first answers with shuffling on were %v", seen)
+	}
+}
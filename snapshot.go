@@ -0,0 +1,223 @@
+package dnsserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// SnapshotVersion is the format version written by Snapshot.
+const SnapshotVersion = 1
+
+// ErrSnapshotVersion is returned by Restore for snapshots in a format it does
+// not understand.
+var ErrSnapshotVersion = errors.New("unknown snapshot version")
+
+type snapshot struct {
+	Version int                     `json:"version"`
+	A       map[string]snapshotHost `json:"a"`
+	SRV     db.SRVRecords           `json:"srv"`
+}
+
+type snapshotHost struct {
+	IPs []net.IP `json:"ips"`
+	TTL uint32   `json:"ttl,omitempty"`
+}
+
+// Snapshot serializes all A and SRV records, with their TTLs, to a versioned
+// JSON blob which Restore can load.
+func (ds *Server) Snapshot() ([]byte, error) {
+	ds.updateMutex.Lock()
+	defer ds.updateMutex.Unlock()
+
+	snap, err := ds.snapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(snap)
+}
+
+// snapshot collects the A and SRV records; the caller holds updateMutex.
+func (ds *Server) snapshot() (snapshot, error) {
+	as, err := ds.db.ListA()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	srvs, err := ds.db.ListSRV()
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	snap := snapshot{
+		Version: SnapshotVersion,
+		A:       make(map[string]snapshotHost, len(as)),
+		SRV:     srvs,
+	}
+
+	for host, ips := range as {
+		ttl, err := ds.db.GetATTL(host)
+		if err != nil && err != db.ErrNotFound {
+			return snapshot{}, err
+		}
+
+		snap.A[host] = snapshotHost{IPs: ips, TTL: ttl}
+	}
+
+	return snap, nil
+}
+
+// Restore replaces all A and SRV records with those in a blob produced by
+// Snapshot. The blob is decoded and every record validated before anything is
+// changed, and dynamic updates are held off while it is applied. DBs which
+// implement db.Replacer swap the records in one step; otherwise they are
+// replaced one at a time, so queries may see the restore part-way through,
+// and the previous records are put back if it fails. Expiries and weights of
+// the replaced hosts are dropped, and watchers see the old records deleted
+// and the new ones set.
+func (ds *Server) Restore(content []byte) error {
+	var snap snapshot
+	if err := json.Unmarshal(content, &snap); err != nil {
+		return err
+	}
+
+	if snap.Version != SnapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, snap.Version)
+	}
+
+	for host, entry := range snap.A {
+		if len(entry.IPs) == 0 {
+			return fmt.Errorf("snapshot host %q has no addresses", host)
+		}
+
+		for _, ip := range entry.IPs {
+			if err := ds.checkA(host, ip); err != nil {
+				return err
+			}
+		}
+	}
+
+	for spec, targets := range snap.SRV {
+		if len(targets) == 0 {
+			return fmt.Errorf("snapshot service %q has no targets", spec)
+		}
+
+		for _, srv := range targets {
+			if err := ds.checkSRV(spec, srv); err != nil {
+				return err
+			}
+		}
+	}
+
+	ds.updateMutex.Lock()
+	defer ds.updateMutex.Unlock()
+
+	old, err := ds.snapshot()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range []snapshot{old, snap} {
+		for host := range s.A {
+			ds.forgetWeights(host)
+			ds.forgetExpiry(host)
+		}
+	}
+
+	if err := ds.replace(snap); err != nil {
+		// best effort: the error restoring is the one worth reporting
+		ds.replace(old)
+		return err
+	}
+
+	return ds.announce(ds.changed(nil), ds.restoreEvents(old, snap)...)
+}
+
+// replace swaps the A and SRV records for those in snap; the caller holds
+// updateMutex.
+func (ds *Server) replace(snap snapshot) error {
+	if r, ok := ds.db.(db.Replacer); ok {
+		as := make(db.ARecords, len(snap.A))
+		ttls := map[string]uint32{}
+
+		for host, entry := range snap.A {
+			as[host] = entry.IPs
+			if entry.TTL != 0 {
+				ttls[host] = entry.TTL
+			}
+		}
+
+		return r.Replace(as, ttls, snap.SRV)
+	}
+
+	if err := ds.flushA(); err != nil {
+		return err
+	}
+
+	if err := ds.flushSRV(); err != nil {
+		return err
+	}
+
+	for host, entry := range snap.A {
+		if err := ds.db.SetA(host, entry.IPs[0]); err != nil {
+			return err
+		}
+
+		for _, ip := range entry.IPs[1:] {
+			if err := ds.db.AddA(host, ip); err != nil {
+				return err
+			}
+		}
+
+		if entry.TTL != 0 {
+			if err := ds.db.SetATTL(host, entry.TTL); err != nil {
+				return err
+			}
+		}
+	}
+
+	for spec, targets := range snap.SRV {
+		for _, srv := range targets {
+			if err := ds.db.AddSRV(spec, srv); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// restoreEvents describes a restore from old to snap: every old host and
+// service deleted, then every new one set.
+func (ds *Server) restoreEvents(old, snap snapshot) []Event {
+	events := []Event{}
+
+	for host := range old.A {
+		events = append(events, ds.aEvents(EventDelete, host)...)
+	}
+
+	for spec := range old.SRV {
+		events = append(events, ds.srvEvent(EventDelete, spec, nil))
+	}
+
+	for host, entry := range snap.A {
+		events = append(events, ds.aSetEvents(host, entry.IPs)...)
+	}
+
+	for spec, targets := range snap.SRV {
+		for i, srv := range targets {
+			op := EventAdd
+			if i == 0 {
+				op = EventSet
+			}
+
+			events = append(events, ds.srvEvent(op, spec, srv))
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,183 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ds := New("docker")
+
+	ds.SetA("one", net.ParseIP("127.0.0.2"))
+	ds.AddA("one", net.ParseIP("127.0.0.3"))
+	ds.SetATTL("one", 60)
+	ds.SetA("two", net.ParseIP("127.0.0.4"))
+	ds.SetSRV("web", "tcp", &db.SRVRecord{Port: 80, Host: "one", TTL: 30})
+	ds.AddSRV("web", "tcp", &db.SRVRecord{Port: 81, Host: "two"})
+
+	content, err := ds.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds2 := New("docker")
+	ds2.SetA("stale", net.ParseIP("127.0.0.9"))
+	ds2.SetSRV("stale", "udp", &db.SRVRecord{Port: 53, Host: "stale"})
+
+	if err := ds2.Restore(content); err != nil {
+		t.Fatal(err)
+	}
+
+	as, _ := ds.ListA()
+	as2, _ := ds2.ListA()
+	if !reflect.DeepEqual(as, as2) {
+		t.Fatalf("A records differ after restore: %v vs %v", as, as2)
+	}
+
+	if ttl, err := ds2.db.GetATTL("one"); err != nil || ttl != 60 {
+		t.Fatalf("A TTL after restore was %d (%v)", ttl, err)
+	}
+
+	srvs, _ := ds.ListSRV()
+	srvs2, _ := ds2.ListSRV()
+	if !reflect.DeepEqual(srvs, srvs2) {
+		t.Fatalf("SRV records differ after restore: %v vs %v", srvs, srvs2)
+	}
+
+	if err := ds2.Restore([]byte(`{"version": 99}`)); !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("restoring an unknown version yielded %v", err)
+	}
+
+	if err := ds2.Restore([]byte(`{"version": 1, "a": {"broken": {"ips": []}}}`)); err == nil {
+		t.Fatal("restoring a host without addresses succeeded")
+	}
+
+	// failed restores leave the records alone
+	if as3, _ := ds2.ListA(); !reflect.DeepEqual(as, as3) {
+		t.Fatalf("failed restore modified A records: %v", as3)
+	}
+}
+
+// failSRVDB fails to store SRV records at fail, and hides the optional
+// interfaces of the DB it wraps.
+type failSRVDB struct {
+	db.DB
+	fail string
+}
+
+func (f failSRVDB) AddSRV(spec string, srv *db.SRVRecord) error {
+	if spec == f.fail {
+		return errors.New("AddSRV failed")
+	}
+
+	return f.DB.AddSRV(spec, srv)
+}
+
+func TestRestoreValidation(t *testing.T) {
+	ds := New("docker")
+	ds.SetA("kept", net.ParseIP("127.0.0.2"))
+
+	for _, content := range []string{
+		`{"version": 1, "a": {"one": {"ips": ["127.0.0.3"]}, "two": {"ips": ["::1"]}}}`,
+		`{"version": 1, "a": {"a..b": {"ips": ["127.0.0.3"]}}}`,
+		`{"version": 1, "srv": {"_web._tcp": [{"Host": "a..b.", "Port": 80}]}}`,
+		`{"version": 1, "srv": {"_web._tcp": []}}`,
+	} {
+		if err := ds.Restore([]byte(content)); err == nil {
+			t.Fatalf("restoring %s succeeded", content)
+		}
+
+		if as, _ := ds.ListA(); len(as) != 1 || as["kept"] == nil {
+			t.Fatalf("failed restore of %s modified A records: %v", content, as)
+		}
+	}
+}
+
+func TestRestoreRollback(t *testing.T) {
+	ds := NewWithDB("docker", failSRVDB{DB: db.NewMap(), fail: "_bad._tcp"})
+
+	ds.SetA("kept", net.ParseIP("127.0.0.2"))
+	ds.SetATTL("kept", 60)
+	ds.SetSRV("web", "tcp", &db.SRVRecord{Port: 80, Host: "kept"})
+
+	as, _ := ds.ListA()
+	srvs, _ := ds.ListSRV()
+
+	content := `{"version": 1, "a": {"new": {"ips": ["127.0.0.3"]}}, "srv": {"_bad._tcp": [{"Host": "new.docker.", "Port": 80}]}}`
+	if err := ds.Restore([]byte(content)); err == nil {
+		t.Fatal("restore succeeded despite the DB failing")
+	}
+
+	if as2, _ := ds.ListA(); !reflect.DeepEqual(as, as2) {
+		t.Fatalf("failed restore left A records %v instead of %v", as2, as)
+	}
+
+	if ttl, err := ds.db.GetATTL("kept"); err != nil || ttl != 60 {
+		t.Fatalf("failed restore left A TTL %d (%v)", ttl, err)
+	}
+
+	if srvs2, _ := ds.ListSRV(); !reflect.DeepEqual(srvs, srvs2) {
+		t.Fatalf("failed restore left SRV records %v instead of %v", srvs2, srvs)
+	}
+}
+
+func TestRestoreExpiryAndEvents(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetATemp("temp", net.ParseIP("127.0.0.2"), 100*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.AddWeightedA("weighted", net.ParseIP("127.0.0.3"), 5); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := ds.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, stop := ds.Watch()
+	defer stop()
+
+	if err := ds.Restore(content); err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.weighted("weighted.docker.") {
+		t.Fatal("weights survived restore")
+	}
+
+	// restored hosts are permanent
+	time.Sleep(150 * time.Millisecond)
+
+	if a := ds.GetA("temp.docker."); len(a) != 1 {
+		t.Fatalf("restored host expired: %v", a)
+	}
+
+	want := map[Event]bool{
+		{Op: EventDelete, Type: dns.TypeA, Name: "temp.docker."}:                      true,
+		{Op: EventDelete, Type: dns.TypeA, Name: "weighted.docker."}:                  true,
+		{Op: EventSet, Type: dns.TypeA, Name: "temp.docker.", Value: "127.0.0.2"}:     true,
+		{Op: EventSet, Type: dns.TypeA, Name: "weighted.docker.", Value: "127.0.0.3"}: true,
+	}
+
+	for len(want) != 0 {
+		select {
+		case event := <-events:
+			if !want[event] {
+				t.Fatalf("unexpected event %+v", event)
+			}
+
+			delete(want, event)
+		case <-time.After(time.Second):
+			t.Fatalf("missing events %v", want)
+		}
+	}
+}
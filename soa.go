@@ -0,0 +1,117 @@
+package dnsserver
+
+import (
+	"sync/atomic"
+
+	"github.com/miekg/dns"
+)
+
+// Defaults for the zone's SOA record.
+const (
+	DefaultSOARefresh = 3600
+	DefaultSOARetry   = 600
+	DefaultSOAExpire  = 86400
+)
+
+// soaConfig holds the configurable fields of the zone's SOA record.
+type soaConfig struct {
+	primary string
+	mbox    string
+	refresh uint32
+	retry   uint32
+	expire  uint32
+	minTTL  uint32
+}
+
+// SetSOA configures the SOA record served for the apex and in negative
+// replies. Unqualified names are qualified with the server's domain; an empty
+// primary or mbox reverts to ns.<domain> or hostmaster.<domain>. A minTTL of
+// 0 follows the server's default TTL. The serial is not configurable: it
+// starts at 1 and is incremented whenever a record changes.
+func (ds *Server) SetSOA(primary, mbox string, refresh, retry, expire, minTTL uint32) {
+	if primary != "" {
+		primary = ds.qualifyTarget(primary)
+	}
+
+	if mbox != "" {
+		mbox = ds.qualifyTarget(mbox)
+	}
+
+	ds.soaMutex.Lock()
+	ds.soaConfig = soaConfig{
+		primary: primary,
+		mbox:    mbox,
+		refresh: refresh,
+		retry:   retry,
+		expire:  expire,
+		minTTL:  minTTL,
+	}
+	ds.soaMutex.Unlock()
+}
+
+// SetNegativeTTL sets how long resolvers may cache NXDOMAIN and NODATA
+// replies, by setting the SOA minimum those replies carry (RFC 2308). A ttl of
+// 0 follows the server's default TTL. It overrides the minTTL given to SetSOA.
+func (ds *Server) SetNegativeTTL(ttl uint32) {
+	ds.soaMutex.Lock()
+	ds.soaConfig.minTTL = ttl
+	ds.soaMutex.Unlock()
+}
+
+// Serial returns the zone's current SOA serial.
+func (ds *Server) Serial() uint32 {
+	return atomic.LoadUint32(&ds.serial)
+}
+
+// changed bumps the serial if err is nil, and returns err. Every method that
+// modifies records funnels its result through here.
+func (ds *Server) changed(err error) error {
+	if err == nil {
+		atomic.AddUint32(&ds.serial, 1)
+	}
+
+	return err
+}
+
+// soa generates the SOA record for the apex.
+func (ds *Server) soa() *dns.SOA {
+	ds.soaMutex.RLock()
+	config := ds.soaConfig
+	ds.soaMutex.RUnlock()
+
+	if config.primary == "" {
+		config.primary = "ns." + ds.domain
+	}
+
+	if config.mbox == "" {
+		config.mbox = "hostmaster." + ds.domain
+	}
+
+	return &dns.SOA{
+		Hdr: dns.RR_Header{
+			Name:   ds.domain,
+			Rrtype: dns.TypeSOA,
+			Class:  dns.ClassINET,
+			Ttl:    ds.ttlFor(0),
+		},
+		Ns:      config.primary,
+		Mbox:    config.mbox,
+		Serial:  ds.Serial(),
+		Refresh: config.refresh,
+		Retry:   config.retry,
+		Expire:  config.expire,
+		Minttl:  ds.ttlFor(config.minTTL),
+	}
+}
+
+// negativeSOA returns the SOA for the authority section of a negative reply.
+// Its TTL is capped at the SOA minimum, which resolvers take as the time to
+// cache the reply for (RFC 2308 section 3).
+func (ds *Server) negativeSOA() *dns.SOA {
+	soa := ds.soa()
+	if soa.Minttl < soa.Hdr.Ttl {
+		soa.Hdr.Ttl = soa.Minttl
+	}
+
+	return soa
+}
@@ -0,0 +1,117 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestSOA(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	query := func() *dns.SOA {
+		t.Helper()
+
+		msg, err := msgClientAddr(addr, "docker.", dns.TypeSOA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(msg.Answer) != 1 || !msg.Authoritative {
+			t.Fatalf("apex SOA query was not answered: %v", msg)
+		}
+
+		return msg.Answer[0].(*dns.SOA)
+	}
+
+	soa := query()
+	if soa.Ns != "ns.docker." || soa.Mbox != "hostmaster.docker." || soa.Refresh != DefaultSOARefresh || soa.Serial != 1 {
+		t.Fatalf("default SOA was %v", soa)
+	}
+
+	ds.SetSOA("ns1", "admin.example.com.", 100, 200, 300, 60)
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetSRV("test", "tcp", &db.SRVRecord{Port: 80, Host: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	soa = query()
+	if soa.Ns != "ns1.docker." || soa.Mbox != "admin.example.com." || soa.Refresh != 100 || soa.Retry != 200 || soa.Expire != 300 || soa.Minttl != 60 {
+		t.Fatalf("configured SOA was %v", soa)
+	}
+
+	if soa.Serial != 3 {
+		t.Fatalf("serial was not incremented on change: %d", soa.Serial)
+	}
+
+	msg, err := msgClientAddr(addr, "test.docker.", dns.TypeSOA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 0 {
+		t.Fatalf("SOA was served below the apex: %v", msg.Answer)
+	}
+
+	msg, err = msgClientAddr(addr, "missing.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeNameError || len(msg.Ns) != 1 || msg.Ns[0].(*dns.SOA).Serial != 3 {
+		t.Fatalf("NXDOMAIN reply did not carry the SOA: %v", msg)
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	ds.SetTTL(60)
+	ds.SetNegativeTTL(5)
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		qtype uint16
+		rcode int
+	}{
+		{"missing.docker.", dns.TypeA, dns.RcodeNameError},
+		{"test.docker.", dns.TypeMX, dns.RcodeSuccess},
+	} {
+		msg, err := msgClientAddr(addr, tc.name, tc.qtype)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Rcode != tc.rcode || len(msg.Ns) != 1 {
+			t.Fatalf("%s %s reply was %v", tc.name, dns.TypeToString[tc.qtype], msg)
+		}
+
+		soa, ok := msg.Ns[0].(*dns.SOA)
+		if !ok || soa.Minttl != 5 || soa.Hdr.Ttl != 5 {
+			t.Fatalf("%s %s authority was %v", tc.name, dns.TypeToString[tc.qtype], msg.Ns[0])
+		}
+	}
+
+	msg, err := msgClientAddr(addr, "docker.", dns.TypeSOA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if soa := msg.Answer[0].(*dns.SOA); soa.Minttl != 5 || soa.Hdr.Ttl != 60 {
+		t.Fatalf("apex SOA was %v", soa)
+	}
+}
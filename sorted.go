@@ -0,0 +1,84 @@
+package dnsserver
+
+import (
+	"bytes"
+	"net"
+	"sort"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+// ARecordEntry is a host's A records, as listed by ListASorted.
+type ARecordEntry struct {
+	Name string // the host's FQDN
+	IPs  []net.IP
+}
+
+// SRVRecordEntry is a service's SRV records, as listed by ListSRVSorted.
+type SRVRecordEntry struct {
+	Name    string // the service's FQDN, e.g. "_http._tcp.docker."
+	Targets []*db.SRVRecord
+}
+
+// ListASorted is like ListA, but returns the hosts sorted by FQDN, each with
+// its addresses in byte order, so that the output is the same from one call
+// to the next for diffing or display. The entries are copies the caller may
+// modify.
+func (ds *Server) ListASorted() ([]ARecordEntry, error) {
+	records, err := ds.db.ListA()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]ARecordEntry, 0, len(records))
+
+	for host, ips := range records {
+		copied := make([]net.IP, 0, len(ips))
+		for _, ip := range ips {
+			copied = append(copied, append(net.IP(nil), ip...))
+		}
+
+		sort.Slice(copied, func(i, j int) bool { return bytes.Compare(copied[i].To16(), copied[j].To16()) < 0 })
+		entries = append(entries, ARecordEntry{Name: ds.qualifyHost(host), IPs: copied})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// ListSRVSorted is like ListSRV, but returns the services sorted by FQDN, each
+// with its targets sorted by priority, then host, then port. The entries are
+// copies the caller may modify.
+func (ds *Server) ListSRVSorted() ([]SRVRecordEntry, error) {
+	records, err := ds.db.ListSRV()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]SRVRecordEntry, 0, len(records))
+
+	for spec, srvs := range records {
+		copied := make([]*db.SRVRecord, 0, len(srvs))
+		for _, srv := range srvs {
+			t := *srv
+			copied = append(copied, &t)
+		}
+
+		sort.Slice(copied, func(i, j int) bool {
+			a, b := copied[i], copied[j]
+			switch {
+			case a.Priority != b.Priority:
+				return a.Priority < b.Priority
+			case a.Host != b.Host:
+				return a.Host < b.Host
+			default:
+				return a.Port < b.Port
+			}
+		})
+
+		entries = append(entries, SRVRecordEntry{Name: ds.qualifyHost(spec), Targets: copied})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
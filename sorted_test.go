@@ -0,0 +1,80 @@
+package dnsserver
+
+import (
+	"net"
+	"sort"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+func TestListSorted(t *testing.T) {
+	ds := New("docker")
+
+	for _, host := range []string{"zulu", "alpha", "mike", "bravo"} {
+		ds.SetA(host, net.ParseIP("127.0.0.9"))
+		ds.AddA(host, net.ParseIP("127.0.0.2"))
+	}
+
+	for _, service := range []string{"web", "db", "cache"} {
+		ds.SetSRV(service, "tcp", &db.SRVRecord{Priority: 10, Port: 80, Host: "zulu"})
+		ds.AddSRV(service, "tcp", &db.SRVRecord{Priority: 0, Port: 80, Host: "mike"})
+		ds.AddSRV(service, "tcp", &db.SRVRecord{Priority: 0, Port: 80, Host: "alpha"})
+	}
+
+	as, err := ds.ListASorted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(as) != 4 || !sort.SliceIsSorted(as, func(i, j int) bool { return as[i].Name < as[j].Name }) {
+		t.Fatalf("A entries were not sorted: %v", as)
+	}
+
+	if as[0].Name != "alpha.docker." {
+		t.Fatalf("first A entry was %q", as[0].Name)
+	}
+
+	for _, entry := range as {
+		if len(entry.IPs) != 2 || !entry.IPs[0].Equal(net.ParseIP("127.0.0.2")) {
+			t.Fatalf("addresses of %s were not sorted: %v", entry.Name, entry.IPs)
+		}
+	}
+
+	// the entries are copies
+	as[0].IPs[0][15] = 99
+
+	if records := ds.GetA("alpha.docker."); records[0].A.Equal(as[0].IPs[0]) || records[1].A.Equal(as[0].IPs[0]) {
+		t.Fatal("modifying an A entry changed the server's records")
+	}
+
+	srvs, err := ds.ListSRVSorted()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srvs) != 3 || !sort.SliceIsSorted(srvs, func(i, j int) bool { return srvs[i].Name < srvs[j].Name }) {
+		t.Fatalf("SRV entries were not sorted: %v", srvs)
+	}
+
+	if srvs[0].Name != "_cache._tcp.docker." {
+		t.Fatalf("first SRV entry was %q", srvs[0].Name)
+	}
+
+	for _, entry := range srvs {
+		hosts := []string{}
+		for _, target := range entry.Targets {
+			hosts = append(hosts, target.Host)
+		}
+
+		if len(hosts) != 3 || hosts[0] != "alpha.docker." || hosts[1] != "mike.docker." || hosts[2] != "zulu.docker." {
+			t.Fatalf("targets of %s were not sorted: %v", entry.Name, hosts)
+		}
+	}
+
+	srvs[0].Targets[0].Port = 9999
+
+	if records := ds.GetSRV("_cache._tcp.docker."); records[0].Port == 9999 || records[1].Port == 9999 || records[2].Port == 9999 {
+		t.Fatal("modifying an SRV entry changed the server's records")
+	}
+}
@@ -0,0 +1,103 @@
+package dnsserver
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ServerStats is a snapshot of a server's counters, for polling without a
+// metrics system; see Stats.
+type ServerStats struct {
+	// Queries is the number of queries received, including those refused.
+	Queries uint64
+	// QueriesByType counts queries by the type of their first question.
+	QueriesByType map[uint16]uint64
+	// ResponsesByRcode counts replies written by rcode. A zone transfer
+	// written in several messages counts once for each.
+	ResponsesByRcode map[int]uint64
+	// ARecords and SRVRecords are the number of hosts with A records and
+	// services with SRV records.
+	ARecords   int
+	SRVRecords int
+	// Uptime is how long the server has been listening, or 0 if it is not.
+	Uptime time.Duration
+}
+
+// Stats returns a snapshot of the server's counters. They are kept with
+// atomics as queries are served, so taking a snapshot does not slow them down.
+// The record counts come from the DB; if it fails to count them, the error is
+// logged and the count left at 0.
+func (ds *Server) Stats() ServerStats {
+	stats := ServerStats{
+		Queries:          atomic.LoadUint64(&ds.queries),
+		QueriesByType:    map[uint16]uint64{},
+		ResponsesByRcode: map[int]uint64{},
+	}
+
+	ds.qtypeCounts.Range(func(key, val interface{}) bool {
+		stats.QueriesByType[key.(uint16)] = atomic.LoadUint64(val.(*uint64))
+		return true
+	})
+
+	ds.rcodeCounts.Range(func(key, val interface{}) bool {
+		stats.ResponsesByRcode[key.(int)] = atomic.LoadUint64(val.(*uint64))
+		return true
+	})
+
+	var err error
+
+	if stats.ARecords, err = ds.db.CountA(); err != nil {
+		ds.log().Error("counting A records failed", "err", err)
+	}
+
+	if stats.SRVRecords, err = ds.db.CountSRV(); err != nil {
+		ds.log().Error("counting SRV records failed", "err", err)
+	}
+
+	if since := atomic.LoadInt64(&ds.listenedAt); since != 0 {
+		stats.Uptime = time.Since(time.Unix(0, since))
+	}
+
+	return stats
+}
+
+// countQuery counts r, and returns w wrapped to count the replies written to
+// it.
+func (ds *Server) countQuery(w dns.ResponseWriter, r *dns.Msg) dns.ResponseWriter {
+	atomic.AddUint64(&ds.queries, 1)
+
+	if len(r.Question) != 0 {
+		increment(&ds.qtypeCounts, r.Question[0].Qtype)
+	}
+
+	return &statsWriter{ResponseWriter: w, ds: ds}
+}
+
+// increment adds one to the counter for key in counters, making it if need be.
+func increment(counters *sync.Map, key interface{}) {
+	val, ok := counters.Load(key)
+	if !ok {
+		val, _ = counters.LoadOrStore(key, new(uint64))
+	}
+
+	atomic.AddUint64(val.(*uint64), 1)
+}
+
+// statsWriter counts the replies written through it by rcode.
+type statsWriter struct {
+	dns.ResponseWriter
+	ds *Server
+}
+
+func (w *statsWriter) WriteMsg(m *dns.Msg) error {
+	increment(&w.ds.rcodeCounts, m.Rcode)
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// Unwrap returns the writer w wraps; see QueryContext.
+func (w *statsWriter) Unwrap() dns.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,85 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestStats(t *testing.T) {
+	ds := New("docker")
+
+	if stats := ds.Stats(); stats.Queries != 0 || stats.Uptime != 0 {
+		t.Fatalf("new server had stats %+v", stats)
+	}
+
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetSRV("http", "tcp", &db.SRVRecord{Port: 80, Host: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+		rcode int
+	}{
+		{"test.docker.", dns.TypeA, dns.RcodeSuccess},
+		{"test.docker.", dns.TypeA, dns.RcodeSuccess},
+		{"_http._tcp.docker.", dns.TypeSRV, dns.RcodeSuccess},
+		{"missing.docker.", dns.TypeA, dns.RcodeNameError},
+	} {
+		msg, err := msgClientAddr(addr, q.name, q.qtype)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Rcode != q.rcode {
+			t.Fatalf("%s: rcode was %s", q.name, dns.RcodeToString[msg.Rcode])
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+
+	stats := ds.Stats()
+
+	if stats.Queries != 4 {
+		t.Fatalf("counted %d queries", stats.Queries)
+	}
+
+	if stats.QueriesByType[dns.TypeA] != 3 || stats.QueriesByType[dns.TypeSRV] != 1 {
+		t.Fatalf("queries by type were %v", stats.QueriesByType)
+	}
+
+	if stats.ResponsesByRcode[dns.RcodeSuccess] != 3 || stats.ResponsesByRcode[dns.RcodeNameError] != 1 {
+		t.Fatalf("responses by rcode were %v", stats.ResponsesByRcode)
+	}
+
+	if stats.ARecords != 1 || stats.SRVRecords != 1 {
+		t.Fatalf("counted %d hosts and %d services", stats.ARecords, stats.SRVRecords)
+	}
+
+	if stats.Uptime < 10*time.Millisecond {
+		t.Fatalf("uptime was %v", stats.Uptime)
+	}
+
+	// the snapshot is a copy
+	stats.QueriesByType[dns.TypeA] = 100
+	if ds.Stats().QueriesByType[dns.TypeA] != 3 {
+		t.Fatal("modifying a snapshot changed the counters")
+	}
+
+	ds.Close()
+
+	if stats := ds.Stats(); stats.Uptime != 0 || stats.Queries != 4 {
+		t.Fatalf("closed server had stats %+v", stats)
+	}
+}
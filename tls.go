@@ -0,0 +1,60 @@
+package dnsserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// ErrNoCertificate is returned by ListenTLS when the tls.Config has no
+// certificate to serve.
+var ErrNoCertificate = errors.New("TLS config has no certificate")
+
+// ListeningTLS returns the ip:port of the DNS-over-TLS listener.
+func (ds *Server) ListeningTLS() (net.IP, uint) {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+	return ds.tlsIP, ds.tlsPort
+}
+
+// ListenTLS is like Listen, but serves DNS-over-TLS (RFC 7858) on listenSpec,
+// conventionally port 853. tlsConfig must carry at least one certificate, or a
+// GetCertificate callback; ErrNoCertificate is returned otherwise. The
+// listener is shut down by Close along with any others.
+func (ds *Server) ListenTLS(listenSpec string, tlsConfig *tls.Config) error {
+	srv, err := ds.bindTLS(listenSpec, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	ds.markBound()
+
+	return srv.ActivateAndServe()
+}
+
+func (ds *Server) bindTLS(listenSpec string, tlsConfig *tls.Config) (*dns.Server, error) {
+	if tlsConfig == nil || (len(tlsConfig.Certificates) == 0 && tlsConfig.GetCertificate == nil) {
+		return nil, ErrNoCertificate
+	}
+
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if ds.closed {
+		return nil, ErrServerClosed
+	}
+
+	var lc net.ListenConfig
+	l, err := lc.Listen(context.Background(), "tcp", listenSpec)
+	if err != nil {
+		return nil, err
+	}
+	ds.tlsServer = &dns.Server{Listener: tls.NewListener(l, tlsConfig), Addr: listenSpec, Net: "tcp-tls", TLSConfig: tlsConfig, Handler: ds, MsgAcceptFunc: acceptMsg, NotifyStartedFunc: ds.started, TsigSecret: ds.tsigSecrets}
+	ds.pending++
+	t := l.Addr().(*net.TCPAddr)
+	ds.tlsIP, ds.tlsPort = t.IP, uint(t.Port)
+	return ds.tlsServer, nil
+}
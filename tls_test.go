@@ -0,0 +1,91 @@
+package dnsserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// selfSigned makes a certificate for 127.0.0.1 and a pool trusting it.
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dnsserver test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestListenTLS(t *testing.T) {
+	ds := New("docker")
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+
+	if err := ds.ListenTLS("127.0.0.1:0", &tls.Config{}); err != ErrNoCertificate {
+		t.Fatalf("ListenTLS without a certificate returned %v", err)
+	}
+
+	cert, pool := selfSigned(t)
+
+	go ds.ListenTLS("127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer ds.Close()
+
+	addr := waitListening(t, ds.ListeningTLS)
+
+	m := new(dns.Msg)
+	m.SetQuestion("test.docker.", dns.TypeA)
+
+	c := &dns.Client{Net: "tcp-tls", TLSConfig: &tls.Config{RootCAs: pool}}
+	msg, _, err := c.Exchange(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("DoT answer was %v", msg.Answer)
+	}
+
+	ds.Close()
+
+	if _, port := ds.ListeningTLS(); port != 0 {
+		t.Fatal("Close did not shut down the TLS listener")
+	}
+
+	if _, _, err := c.Exchange(m, addr); err == nil {
+		t.Fatal("TLS listener still answered after Close")
+	}
+}
@@ -0,0 +1,172 @@
+// Package tracing traces dnsserver queries with OpenTelemetry. It lives in
+// its own package so that dnsserver itself does not depend on OpenTelemetry.
+//
+// Middleware records a span for each query the server answers, whether it
+// came in over a socket or through Resolve:
+//
+//	ds.Use(tracing.Middleware())
+//
+// Queries answered by ResolveContext are traced as children of the span in
+// its context. To link DoH queries to the traces of the clients sending them,
+// wrap the DoH handler with HTTPHandler, which takes the trace propagated in
+// the request's headers:
+//
+//	http.Handle("/dns-query", tracing.HTTPHandler(ds.DoHHandler()))
+package tracing
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/erikh/dnsserver"
+	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope the tracer is obtained under.
+const ScopeName = "github.com/erikh/dnsserver/tracing"
+
+// SpanName is the name of the span recorded for each query.
+const SpanName = "dns.query"
+
+// Attribute keys set on each query's span.
+const (
+	// NameKey is the name asked for by the query's first question.
+	NameKey = attribute.Key("dns.question.name")
+	// TypeKey is the type asked for by it, such as "A".
+	TypeKey = attribute.Key("dns.question.type")
+	// RcodeKey is the rcode of the reply, such as "NOERROR". It is not set
+	// when the server chose not to reply.
+	RcodeKey = attribute.Key("dns.response.code")
+	// LatencyKey is the time the server took to answer, looking up its
+	// records or asking its forwarders, in milliseconds.
+	LatencyKey = attribute.Key("dns.backend.latency_ms")
+)
+
+// Option configures Middleware and HTTPHandler.
+type Option func(*config)
+
+type config struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider sets the provider spans are recorded with, instead of
+// the global one.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithPropagator sets how HTTPHandler reads the trace from request headers,
+// instead of with the global propagator.
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		c.propagator = propagator
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Middleware returns dnsserver middleware recording a span for each query,
+// named SpanName and carrying the attributes above. Replies with SERVFAIL set
+// the span's status to an error. The span is a child of the one in the
+// query's context, if any; see dnsserver.QueryContext.
+func Middleware(opts ...Option) dnsserver.Handler {
+	tracer := newConfig(opts).provider.Tracer(ScopeName)
+
+	return func(next dns.Handler) dns.Handler {
+		return dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			_, span := tracer.Start(dnsserver.QueryContext(w), SpanName, trace.WithSpanKind(trace.SpanKindServer))
+			defer span.End()
+
+			if len(r.Question) != 0 {
+				span.SetAttributes(
+					NameKey.String(r.Question[0].Name),
+					TypeKey.String(typeString(r.Question[0].Qtype)),
+				)
+			}
+
+			rw := &rcodeWriter{ResponseWriter: w}
+			start := time.Now()
+
+			next.ServeDNS(rw, r)
+
+			span.SetAttributes(LatencyKey.Float64(float64(time.Since(start)) / float64(time.Millisecond)))
+
+			if !rw.replied {
+				return
+			}
+
+			span.SetAttributes(RcodeKey.String(rcodeString(rw.rcode)))
+			if rw.rcode == dns.RcodeServerFailure {
+				span.SetStatus(codes.Error, rcodeString(rw.rcode))
+			}
+		})
+	}
+}
+
+// HTTPHandler wraps a DoH handler, such as dnsserver's DoHHandler, so that
+// the trace propagated in a request's headers is carried in its context to
+// the queries it is answered with.
+func HTTPHandler(h http.Handler, opts ...Option) http.Handler {
+	propagator := newConfig(opts).propagator
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := propagator.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+		h.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
+// rcodeWriter notes the rcode of the first reply written through it.
+type rcodeWriter struct {
+	dns.ResponseWriter
+	rcode   int
+	replied bool
+}
+
+func (w *rcodeWriter) WriteMsg(m *dns.Msg) error {
+	if !w.replied {
+		w.rcode, w.replied = m.Rcode, true
+	}
+
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// Unwrap returns the writer w wraps; see dnsserver.QueryContext.
+func (w *rcodeWriter) Unwrap() dns.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func typeString(qtype uint16) string {
+	if name, ok := dns.TypeToString[qtype]; ok {
+		return name
+	}
+
+	return strconv.Itoa(int(qtype))
+}
+
+func rcodeString(rcode int) string {
+	if name, ok := dns.RcodeToString[rcode]; ok {
+		return name
+	}
+
+	return strconv.Itoa(rcode)
+}
@@ -0,0 +1,188 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/erikh/dnsserver"
+	"github.com/miekg/dns"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func attrs(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		m[kv.Key] = kv.Value
+	}
+
+	return m
+}
+
+func TestMiddleware(t *testing.T) {
+	provider, exporter := newProvider()
+
+	ds := dnsserver.New("docker")
+	ds.Use(Middleware(WithTracerProvider(provider)))
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []struct {
+		name  string
+		qtype uint16
+	}{
+		{"test.docker.", dns.TypeA},
+		{"missing.docker.", dns.TypeSRV},
+	} {
+		r := new(dns.Msg)
+		r.SetQuestion(q.name, q.qtype)
+
+		if m := ds.Resolve(r, nil); m == nil {
+			t.Fatalf("no reply to %s", q.name)
+		}
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected a span per query, got %d", len(spans))
+	}
+
+	for i, expected := range []map[attribute.Key]string{
+		{NameKey: "test.docker.", TypeKey: "A", RcodeKey: "NOERROR"},
+		{NameKey: "missing.docker.", TypeKey: "SRV", RcodeKey: "NXDOMAIN"},
+	} {
+		span := spans[i]
+		if span.Name != SpanName {
+			t.Fatalf("span was named %q", span.Name)
+		}
+
+		got := attrs(span)
+		for key, val := range expected {
+			if got[key].AsString() != val {
+				t.Fatalf("span %d had %s = %q, expected %q", i, key, got[key].AsString(), val)
+			}
+		}
+
+		if latency, ok := got[LatencyKey]; !ok || latency.AsFloat64() < 0 {
+			t.Fatalf("span %d had no latency: %v", i, got)
+		}
+
+		if span.Status.Code == codes.Error {
+			t.Fatalf("span %d had an error status", i)
+		}
+
+		if span.Parent.IsValid() {
+			t.Fatalf("span %d had a parent without one propagated", i)
+		}
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	provider, exporter := newProvider()
+	propagator := propagation.TraceContext{}
+
+	ds := dnsserver.New("docker")
+	ds.Use(Middleware(WithTracerProvider(provider)))
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(HTTPHandler(ds.DoHHandler(), WithPropagator(propagator)))
+	defer server.Close()
+
+	ctx, parent := provider.Tracer("client").Start(context.Background(), "lookup")
+
+	r := new(dns.Msg)
+	r.SetQuestion("test.docker.", dns.TypeA)
+
+	buf, err := r.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	propagator.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("DoH query returned %s", resp.Status)
+	}
+
+	parent.End()
+
+	var query *tracetest.SpanStub
+	spans := exporter.GetSpans()
+	for i := range spans {
+		if spans[i].Name == SpanName {
+			query = &spans[i]
+		}
+	}
+
+	if query == nil {
+		t.Fatalf("no query span among %d spans", len(spans))
+	}
+
+	if query.Parent.SpanID() != parent.SpanContext().SpanID() || query.SpanContext.TraceID() != parent.SpanContext().TraceID() {
+		t.Fatalf("query span was not a child of the propagated span: parent %v", query.Parent)
+	}
+}
+
+func TestMiddlewareBehindRequestIDLogger(t *testing.T) {
+	provider, exporter := newProvider()
+
+	ds := dnsserver.New("docker")
+	ds.Use(dnsserver.RequestIDLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	ds.Use(Middleware(WithTracerProvider(provider)))
+
+	ctx, parent := provider.Tracer("client").Start(context.Background(), "lookup")
+
+	r := new(dns.Msg)
+	r.SetQuestion("test.docker.", dns.TypeA)
+
+	if m := ds.ResolveContext(ctx, r, nil); m == nil {
+		t.Fatal("no reply")
+	}
+
+	parent.End()
+
+	spans := exporter.GetSpans()
+	for _, span := range spans {
+		if span.Name != SpanName {
+			continue
+		}
+
+		if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Fatalf("query span lost its parent behind RequestIDLogger: %v", span.Parent)
+		}
+
+		return
+	}
+
+	t.Fatalf("no query span among %d spans", len(spans))
+}
@@ -0,0 +1,142 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// axfrChunk is the number of records sent in each AXFR message.
+const axfrChunk = 100
+
+// SetTransferACL sets the networks allowed to request zone transfers. Transfers
+// are refused for everyone until this is called.
+func (ds *Server) SetTransferACL(acl []net.IPNet) {
+	ds.aclMutex.Lock()
+	defer ds.aclMutex.Unlock()
+
+	ds.transferACL = append([]net.IPNet{}, acl...)
+}
+
+// transferAllowed reports whether the client at addr may transfer the zone.
+// Transfers are only offered over TCP.
+func (ds *Server) transferAllowed(addr net.Addr) bool {
+	if _, ok := addr.(*net.TCPAddr); !ok {
+		return false
+	}
+
+	ds.aclMutex.RLock()
+	defer ds.aclMutex.RUnlock()
+
+	return aclAllows(ds.transferACL, addr)
+}
+
+// aclAllows reports whether the client at addr falls within one of the
+// networks in acl.
+func aclAllows(acl []net.IPNet, addr net.Addr) bool {
+	ip := addrIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range acl {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addrIP returns the IP of a UDP or TCP client address, or nil.
+func addrIP(addr net.Addr) net.IP {
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		return addr.IP
+	case *net.UDPAddr:
+		return addr.IP
+	}
+
+	return nil
+}
+
+// serveAXFR answers an AXFR query, streaming the zone between two SOA
+// records. Queries over UDP, for other zones, or from clients outside the
+// transfer ACL are refused.
+func (ds *Server) serveAXFR(w dns.ResponseWriter, r *dns.Msg) {
+	if !ds.transferChecked(w, r) {
+		return
+	}
+
+	soa, records, ok := ds.transferZone(w, r)
+	if !ok {
+		return
+	}
+
+	ds.sendTransfer(w, r, append(append([]dns.RR{soa}, records...), soa))
+}
+
+// transferChecked replies to transfer queries which are not allowed, and
+// reports whether r may go ahead.
+func (ds *Server) transferChecked(w dns.ResponseWriter, r *dns.Msg) bool {
+	if !ds.tsigVerified(w, r) {
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeNotAuth)
+		ds.writeMsg(w, r, m)
+		return false
+	}
+
+	if !strings.EqualFold(r.Question[0].Name, ds.domain) || !ds.transferAllowed(w.RemoteAddr()) {
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeRefused)
+		ds.writeMsg(w, r, m)
+		return false
+	}
+
+	return true
+}
+
+// transferZone returns the SOA and records of the zone to transfer, noting
+// them in the journal. If they cannot be listed, the query is answered with
+// SERVFAIL and ok is false.
+func (ds *Server) transferZone(w dns.ResponseWriter, r *dns.Msg) (soa *dns.SOA, records []dns.RR, ok bool) {
+	// the SOA is taken first, so the records are at least as new as its serial
+	soa = ds.soa()
+
+	records, err := ds.zoneRecords()
+	if err != nil {
+		ds.log().Error("listing records for transfer failed", append(queryAttrs(w, r), "err", err)...)
+		m := &dns.Msg{}
+		m.SetRcode(r, dns.RcodeServerFailure)
+		ds.writeMsg(w, r, m)
+		return nil, nil, false
+	}
+
+	ds.journal.note(soa.Serial, records)
+
+	return soa, records, true
+}
+
+// sendTransfer streams records in answer to a transfer query.
+func (ds *Server) sendTransfer(w dns.ResponseWriter, r *dns.Msg, records []dns.RR) {
+	ch := make(chan *dns.Envelope)
+	go func() {
+		defer close(ch)
+
+		for len(records) > axfrChunk {
+			ch <- &dns.Envelope{RR: records[:axfrChunk]}
+			records = records[axfrChunk:]
+		}
+
+		ch <- &dns.Envelope{RR: records}
+	}()
+
+	tr := &dns.Transfer{}
+	if err := tr.Out(w, r, ch); err != nil {
+		ds.log().Warn("zone transfer failed", append(queryAttrs(w, r), "err", err)...)
+		// drain so the sender can exit
+		for range ch {
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestAXFR(t *testing.T) {
+	ds := New("docker")
+	go ds.ListenTCP("127.0.0.1:0")
+	addr := waitListening(t, ds.ListeningTCP)
+	defer ds.Close()
+
+	for i := 0; i < 150; i++ {
+		if err := ds.SetA(fmt.Sprintf("host%d", i), net.ParseIP("127.0.0.2")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := ds.SetSRV("test", "tcp", &db.SRVRecord{Port: 80, Host: "host1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	transfer := func() ([]dns.RR, error) {
+		m := new(dns.Msg)
+		m.SetAxfr("docker.")
+
+		env, err := (&dns.Transfer{}).In(m, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		var rrs []dns.RR
+		for e := range env {
+			if e.Error != nil {
+				return nil, e.Error
+			}
+			rrs = append(rrs, e.RR...)
+		}
+
+		return rrs, nil
+	}
+
+	if _, err := transfer(); err == nil {
+		t.Fatal("transfer succeeded without an ACL")
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetTransferACL([]net.IPNet{*loopback})
+
+	rrs, err := transfer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// two SOA, 150 A and one SRV
+	if len(rrs) != 153 {
+		t.Fatalf("expected 153 records in transfer, got %d", len(rrs))
+	}
+
+	if rrs[0].Header().Rrtype != dns.TypeSOA || rrs[len(rrs)-1].Header().Rrtype != dns.TypeSOA {
+		t.Fatal("transfer was not framed by SOA records")
+	}
+
+	var srvs int
+	for _, rr := range rrs {
+		if srv, ok := rr.(*dns.SRV); ok && srv.Target == "host1.docker." {
+			srvs++
+		}
+	}
+
+	if srvs != 1 {
+		t.Fatalf("expected one SRV record in transfer, got %d", srvs)
+	}
+
+	m := new(dns.Msg)
+	m.SetAxfr("docker.")
+
+	msg, err := dns.Exchange(m, startServer(t, ds))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeRefused {
+		t.Fatalf("AXFR over UDP was not refused: %s", dns.RcodeToString[msg.Rcode])
+	}
+}
+
+func TestIXFR(t *testing.T) {
+	ds := New("docker")
+	go ds.ListenTCP("127.0.0.1:0")
+	addr := waitListening(t, ds.ListeningTCP)
+	defer ds.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetTransferACL([]net.IPNet{*loopback})
+
+	for i := 0; i < 10; i++ {
+		if err := ds.SetA(fmt.Sprintf("host%d", i), net.ParseIP("127.0.0.2")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transfer := func(m *dns.Msg) []dns.RR {
+		t.Helper()
+
+		env, err := (&dns.Transfer{}).In(m, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var rrs []dns.RR
+		for e := range env {
+			if e.Error != nil {
+				t.Fatal(e.Error)
+			}
+			rrs = append(rrs, e.RR...)
+		}
+
+		return rrs
+	}
+
+	ixfr := func(serial uint32) []dns.RR {
+		t.Helper()
+		return transfer(new(dns.Msg).SetIxfr("docker.", serial, "ns.docker.", "hostmaster.docker."))
+	}
+
+	// the soa records framing each delta, and the records between them
+	summarize := func(rrs []dns.RR) []string {
+		var out []string
+		for _, rr := range rrs {
+			switch rr := rr.(type) {
+			case *dns.SOA:
+				out = append(out, fmt.Sprintf("SOA %d", rr.Serial))
+			case *dns.A:
+				out = append(out, rr.Hdr.Name+" "+rr.A.String())
+			}
+		}
+		return out
+	}
+
+	// the initial copy, which the journal starts from
+	first := ds.Serial()
+	if rrs := transfer(new(dns.Msg).SetAxfr("docker.")); len(rrs) != 12 {
+		t.Fatalf("AXFR returned %d records", len(rrs))
+	}
+
+	if rrs := ixfr(first); len(rrs) != 1 || rrs[0].(*dns.SOA).Serial != first {
+		t.Fatalf("IXFR when up to date returned %v", rrs)
+	}
+
+	if err := ds.SetA("host1", net.ParseIP("127.0.0.3")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetA("new", net.ParseIP("127.0.0.4")); err != nil {
+		t.Fatal(err)
+	}
+
+	second := ds.Serial()
+
+	got := summarize(ixfr(first))
+	want := []string{
+		fmt.Sprintf("SOA %d", second),
+		fmt.Sprintf("SOA %d", first), "host1.docker. 127.0.0.2",
+		fmt.Sprintf("SOA %d", second), "host1.docker. 127.0.0.3", "new.docker. 127.0.0.4",
+		fmt.Sprintf("SOA %d", second),
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("first IXFR was %v, expected %v", got, want)
+	}
+
+	if err := ds.DeleteA("host2"); err != nil {
+		t.Fatal(err)
+	}
+
+	third := ds.Serial()
+
+	got = summarize(ixfr(second))
+	want = []string{
+		fmt.Sprintf("SOA %d", third),
+		fmt.Sprintf("SOA %d", second), "host2.docker. 127.0.0.2",
+		fmt.Sprintf("SOA %d", third),
+		fmt.Sprintf("SOA %d", third),
+	}
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("second IXFR was %v, expected %v", got, want)
+	}
+
+	// a secondary still at the first serial receives both deltas
+	if got := summarize(ixfr(first)); len(got) != 10 {
+		t.Fatalf("IXFR across two deltas was %v", got)
+	}
+
+	// once the first delta is evicted, that secondary receives the whole zone
+	ds.SetJournalSize(1)
+
+	if rrs := ixfr(first); len(rrs) != 12 || rrs[0].Header().Rrtype != dns.TypeSOA || rrs[len(rrs)-1].Header().Rrtype != dns.TypeSOA {
+		t.Fatalf("IXFR from an evicted serial returned %v", summarize(rrs))
+	}
+
+	if got := summarize(ixfr(second)); len(got) != 5 {
+		t.Fatalf("IXFR from a kept serial was %v", got)
+	}
+}
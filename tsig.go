@@ -0,0 +1,72 @@
+package dnsserver
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// ErrListening is returned when changing configuration which can only be set
+// before the server listens.
+var ErrListening = errors.New("server is already listening")
+
+// SetTSIGSecrets sets the TSIG keys (RFC 8945) that dynamic updates and zone
+// transfers must be signed with, mapping each key name to its base64 encoded
+// secret. Once keys are set, unsigned or badly signed requests are answered
+// with NOTAUTH, and replies to signed requests are signed with the same key;
+// the update and transfer ACLs still apply. An empty map turns the
+// requirement off.
+//
+// The keys are handed to the underlying listeners when they bind, so they
+// must be set before listening; ErrListening is returned otherwise.
+func (ds *Server) SetTSIGSecrets(secrets map[string]string) error {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	if ds.server != nil || ds.tcpServer != nil || ds.tlsServer != nil {
+		return ErrListening
+	}
+
+	if len(secrets) == 0 {
+		ds.tsigSecrets = nil
+		return nil
+	}
+
+	ds.tsigSecrets = map[string]string{}
+	for name, secret := range secrets {
+		ds.tsigSecrets[dns.Fqdn(strings.ToLower(name))] = secret
+	}
+
+	return nil
+}
+
+// tsigRequired reports whether TSIG keys have been set.
+func (ds *Server) tsigRequired() bool {
+	ds.configMutex.Lock()
+	defer ds.configMutex.Unlock()
+
+	return len(ds.tsigSecrets) != 0
+}
+
+// tsigVerified reports whether r may be acted on: either no keys are set, or
+// r carries a TSIG the listener verified against one of them.
+func (ds *Server) tsigVerified(w dns.ResponseWriter, r *dns.Msg) bool {
+	if !ds.tsigRequired() {
+		return true
+	}
+
+	return r.IsTsig() != nil && w.TsigStatus() == nil
+}
+
+// signReply signs m with the key r was signed with, if r was signed and
+// verified. The signature is made when m is written.
+func (ds *Server) signReply(w dns.ResponseWriter, r, m *dns.Msg) {
+	tsig := r.IsTsig()
+	if tsig == nil || w.TsigStatus() != nil || !ds.tsigRequired() {
+		return
+	}
+
+	m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, tsig.Fudge, time.Now().Unix())
+}
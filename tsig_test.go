@@ -0,0 +1,139 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	tsigKey    = "update.docker."
+	tsigSecret = "c2VjcmV0LXRoYXQtaXMtbG9uZy1lbm91Z2g="
+)
+
+func TestTSIGUpdate(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetTSIGSecrets(map[string]string{"Update.Docker": tsigSecret}); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetTSIGSecrets(nil); err != ErrListening {
+		t.Fatalf("secrets were changed while listening: %v", err)
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetUpdateACL([]net.IPNet{*loopback})
+
+	a, _ := dns.NewRR("test.docker. 60 IN A 127.0.0.2")
+
+	update := func(secret string, sign bool) (*dns.Msg, error) {
+		m := new(dns.Msg)
+		m.SetUpdate("docker.")
+		m.Insert([]dns.RR{a})
+
+		c := &dns.Client{}
+		if sign {
+			m.SetTsig(tsigKey, dns.HmacSHA256, 300, time.Now().Unix())
+			c.TsigSecret = map[string]string{tsigKey: secret}
+		}
+
+		msg, _, err := c.Exchange(m, addr)
+		return msg, err
+	}
+
+	msg, err := update("", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeNotAuth {
+		t.Fatalf("unsigned update was %s", dns.RcodeToString[msg.Rcode])
+	}
+
+	msg, err = update("d3Jvbmctc2VjcmV0", true)
+	if msg == nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeNotAuth || msg.IsTsig() != nil {
+		t.Fatalf("badly signed update was %s, signed %v", dns.RcodeToString[msg.Rcode], msg.IsTsig() != nil)
+	}
+
+	if records := ds.GetA("test.docker."); len(records) != 0 {
+		t.Fatalf("rejected updates added %v", records)
+	}
+
+	msg, err = update(tsigSecret, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeSuccess {
+		t.Fatalf("signed update was %s", dns.RcodeToString[msg.Rcode])
+	}
+
+	if msg.IsTsig() == nil || msg.IsTsig().Hdr.Name != tsigKey {
+		t.Fatalf("reply to a signed update was not signed with its key: %v", msg.Extra)
+	}
+
+	if records := ds.GetA("test.docker."); len(records) != 1 || !records[0].A.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("signed update was not applied: %v", records)
+	}
+}
+
+func TestTSIGTransfer(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetTSIGSecrets(map[string]string{tsigKey: tsigSecret}); err != nil {
+		t.Fatal(err)
+	}
+
+	go ds.ListenTCP("127.0.0.1:0")
+	addr := waitListening(t, ds.ListeningTCP)
+	defer ds.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetTransferACL([]net.IPNet{*loopback})
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(dns.Msg)
+	m.SetAxfr("docker.")
+
+	msg, _, err := (&dns.Client{Net: "tcp"}).Exchange(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Rcode != dns.RcodeNotAuth {
+		t.Fatalf("unsigned transfer was %s", dns.RcodeToString[msg.Rcode])
+	}
+
+	m.SetTsig(tsigKey, dns.HmacSHA256, 300, time.Now().Unix())
+
+	tr := &dns.Transfer{TsigSecret: map[string]string{tsigKey: tsigSecret}}
+	env, err := tr.In(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rrs []dns.RR
+	for e := range env {
+		if e.Error != nil {
+			t.Fatal(e.Error)
+		}
+		rrs = append(rrs, e.RR...)
+	}
+
+	if len(rrs) < 3 {
+		t.Fatalf("signed transfer returned %v", rrs)
+	}
+}
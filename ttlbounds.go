@@ -0,0 +1,58 @@
+package dnsserver
+
+import (
+	"github.com/miekg/dns"
+)
+
+// SetTTLBounds clamps the TTL of every record in replies to queries, local
+// and forwarded alike, to between min and max seconds, so that upstream
+// answers with a TTL of 0 or of days are not passed on as they are. A min of
+// 1 keeps resolvers from ever seeing a TTL of 0. A max of 0 sets no maximum,
+// and one below min is raised to it; SetTTLBounds(0, 0) removes the bounds.
+// Records sent in zone transfers are not clamped.
+func (ds *Server) SetTTLBounds(min, max uint32) {
+	if max != 0 && max < min {
+		max = min
+	}
+
+	ds.boundsMutex.Lock()
+	ds.minTTL, ds.maxTTL = min, max
+	ds.boundsMutex.Unlock()
+}
+
+// clampTTLs applies the bounds set with SetTTLBounds to the records of m.
+// Records which are changed are copied first, as they may be shared with the
+// cache or the DB.
+func (ds *Server) clampTTLs(m *dns.Msg) {
+	ds.boundsMutex.RLock()
+	min, max := ds.minTTL, ds.maxTTL
+	ds.boundsMutex.RUnlock()
+
+	if min == 0 && max == 0 {
+		return
+	}
+
+	for _, section := range [][]dns.RR{m.Answer, m.Ns, m.Extra} {
+		for i, rr := range section {
+			ttl := rr.Header().Ttl
+
+			switch rr.Header().Rrtype {
+			case dns.TypeOPT, dns.TypeTSIG:
+				// the TTL field holds flags, or must be 0
+				continue
+			}
+
+			switch {
+			case ttl < min:
+				ttl = min
+			case max != 0 && ttl > max:
+				ttl = max
+			default:
+				continue
+			}
+
+			section[i] = dns.Copy(rr)
+			section[i].Header().Ttl = ttl
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestTTLBounds(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upstream := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		for _, rr := range []string{" 0 IN A 192.0.2.1", " 604800 IN A 192.0.2.2", " 300 IN A 192.0.2.3"} {
+			a, _ := dns.NewRR(r.Question[0].Name + rr)
+			m.Answer = append(m.Answer, a)
+		}
+		w.WriteMsg(m)
+	})}
+	go upstream.ActivateAndServe()
+	defer upstream.Shutdown()
+
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	ds.SetForwarders([]string{conn.LocalAddr().String()})
+	ds.SetTTL(5)
+	ds.SetTTLBounds(60, 3600)
+
+	for host, ttl := range map[string]uint32{"short": 0, "long": 86400, "within": 300} {
+		if err := ds.SetA(host, net.ParseIP("127.0.0.2")); err != nil {
+			t.Fatal(err)
+		}
+
+		if ttl != 0 {
+			if err := ds.SetATTL(host, ttl); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	ttlOf := func(name string) []uint32 {
+		t.Helper()
+
+		msg, err := msgClientAddr(addr, name, dns.TypeA)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ttls := []uint32{}
+		for _, rr := range msg.Answer {
+			ttls = append(ttls, rr.Header().Ttl)
+		}
+
+		return ttls
+	}
+
+	for name, ttl := range map[string]uint32{"short.docker.": 60, "long.docker.": 3600, "within.docker.": 300} {
+		if ttls := ttlOf(name); len(ttls) != 1 || ttls[0] != ttl {
+			t.Fatalf("TTLs of %q were %v, expected %d", name, ttls, ttl)
+		}
+	}
+
+	// the cache is asked on the second lookup, and its answers are clamped too
+	for i := 0; i < 2; i++ {
+		ttls := ttlOf("example.com.")
+		if len(ttls) != 3 || ttls[0] != 60 || ttls[1] != 3600 || ttls[2] > 300 || ttls[2] < 60 {
+			t.Fatalf("forwarded TTLs were %v", ttls)
+		}
+	}
+
+	ds.SetTTLBounds(0, 0)
+
+	if ttls := ttlOf("long.docker."); len(ttls) != 1 || ttls[0] != 86400 {
+		t.Fatalf("TTLs without bounds were %v", ttls)
+	}
+}
@@ -0,0 +1,54 @@
+package dnsserver
+
+import (
+	"sync/atomic"
+
+	"github.com/miekg/dns"
+)
+
+// supportedTypes are the query types the server answers from its records.
+var supportedTypes = map[uint16]bool{
+	dns.TypeA:      true,
+	dns.TypeAAAA:   true,
+	dns.TypeCNAME:  true,
+	dns.TypeDNAME:  true,
+	dns.TypeTXT:    true,
+	dns.TypeMX:     true,
+	dns.TypeCAA:    true,
+	dns.TypePTR:    true,
+	dns.TypeSRV:    true,
+	dns.TypeSOA:    true,
+	dns.TypeNS:     true,
+	dns.TypeDNSKEY: true,
+	dns.TypeDS:     true,
+	dns.TypeANY:    true,
+}
+
+// SetUnsupportedTypeRcode sets the rcode of replies to queries for a type the
+// server has no records of its own for, such as HINFO or LOC, at a name which
+// exists and holds no raw records of the type; see SetRaw. The
+// default, dns.RcodeSuccess, answers NODATA, as for any other type the name
+// lacks; dns.RcodeNotImplemented or dns.RcodeRefused tell the client that the
+// type is not served at all. Names which do not exist are NXDOMAIN whatever
+// the type.
+func (ds *Server) SetUnsupportedTypeRcode(rcode int) {
+	atomic.StoreInt32(&ds.unsupported, int32(rcode))
+}
+
+// unsupportedRcode returns the rcode set with SetUnsupportedTypeRcode, and
+// whether it applies to questions: they must all ask for unsupported types,
+// and it must not be the NODATA default.
+func (ds *Server) unsupportedRcode(questions []dns.Question) (int, bool) {
+	rcode := int(atomic.LoadInt32(&ds.unsupported))
+	if rcode == dns.RcodeSuccess || len(questions) == 0 {
+		return rcode, false
+	}
+
+	for _, question := range questions {
+		if supportedTypes[question.Qtype] {
+			return rcode, false
+		}
+	}
+
+	return rcode, true
+}
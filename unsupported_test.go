@@ -0,0 +1,51 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestUnsupportedTypeRcode(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	if err := ds.SetA("test", net.ParseIP("127.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		t.Helper()
+
+		msg, err := msgClientAddr(addr, name, qtype)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return msg
+	}
+
+	if msg := query("test.docker.", dns.TypeHINFO); msg.Rcode != dns.RcodeSuccess || len(msg.Answer) != 0 || len(msg.Ns) != 1 {
+		t.Fatalf("unsupported type was not NODATA by default: %v", msg)
+	}
+
+	for _, rcode := range []int{dns.RcodeNotImplemented, dns.RcodeRefused} {
+		ds.SetUnsupportedTypeRcode(rcode)
+
+		for _, qtype := range []uint16{dns.TypeHINFO, dns.TypeLOC} {
+			if msg := query("test.docker.", qtype); msg.Rcode != rcode || len(msg.Answer) != 0 {
+				t.Fatalf("%s at an existing name was %v", dns.TypeToString[qtype], msg)
+			}
+		}
+
+		if msg := query("missing.docker.", dns.TypeHINFO); msg.Rcode != dns.RcodeNameError {
+			t.Fatalf("unsupported type at a missing name was %v", msg)
+		}
+
+		if msg := query("test.docker.", dns.TypeTXT); msg.Rcode != dns.RcodeSuccess || len(msg.Ns) != 1 {
+			t.Fatalf("supported type the name lacks was %v", msg)
+		}
+	}
+}
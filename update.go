@@ -0,0 +1,350 @@
+package dnsserver
+
+import (
+	"net"
+	"strings"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// SetUpdateACL sets the networks allowed to send RFC 2136 dynamic updates.
+// Updates are refused for everyone until this is called.
+func (ds *Server) SetUpdateACL(acl []net.IPNet) {
+	ds.aclMutex.Lock()
+	defer ds.aclMutex.Unlock()
+
+	ds.updateACL = append([]net.IPNet{}, acl...)
+}
+
+// acceptMsg extends dns.DefaultMsgAcceptFunc to let dynamic updates through;
+// they carry records in every section, which the default rejects.
+func acceptMsg(dh dns.Header) dns.MsgAcceptAction {
+	const qr = 1 << 15
+
+	if opcode := int(dh.Bits>>11) & 0xF; opcode == dns.OpcodeUpdate {
+		if dh.Bits&qr != 0 {
+			return dns.MsgIgnore
+		}
+
+		if dh.Qdcount != 1 {
+			return dns.MsgReject
+		}
+
+		return dns.MsgAccept
+	}
+
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
+// serveUpdate applies a dynamic update to A and SRV records. Prerequisites
+// are checked first; if any fails, nothing is changed.
+func (ds *Server) serveUpdate(w dns.ResponseWriter, r *dns.Msg) {
+	m := &dns.Msg{}
+
+	if !ds.tsigVerified(w, r) {
+		m.SetRcode(r, dns.RcodeNotAuth)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	ds.aclMutex.RLock()
+	allowed := aclAllows(ds.updateACL, w.RemoteAddr())
+	ds.aclMutex.RUnlock()
+
+	if !allowed {
+		m.SetRcode(r, dns.RcodeRefused)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	zone := r.Question[0]
+	if zone.Qtype != dns.TypeSOA || !strings.EqualFold(zone.Name, ds.domain) {
+		m.SetRcode(r, dns.RcodeNotAuth)
+		ds.writeMsg(w, r, m)
+		return
+	}
+
+	ds.updateMutex.Lock()
+	defer ds.updateMutex.Unlock()
+
+	rcode := ds.checkPrerequisites(r.Answer)
+	if rcode == dns.RcodeSuccess {
+		rcode = ds.checkUpdates(r.Ns)
+	}
+
+	if rcode == dns.RcodeSuccess {
+		for _, rr := range r.Ns {
+			if err := ds.applyUpdate(rr); err != nil {
+				ds.log().Error("applying update failed", append(queryAttrs(w, r), "err", err)...)
+				rcode = dns.RcodeServerFailure
+				break
+			}
+		}
+	}
+
+	m.SetRcode(r, rcode)
+	ds.writeMsg(w, r, m)
+}
+
+// checkPrerequisites evaluates the prerequisite section of an update (RFC
+// 2136 section 3.2) and returns the rcode to reply with on failure. Value
+// dependent prerequisites only require each listed record to exist.
+func (ds *Server) checkPrerequisites(prereqs []dns.RR) int {
+	for _, rr := range prereqs {
+		h := rr.Header()
+
+		if h.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+
+		if !ds.inDomain(h.Name) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rrtype == dns.TypeANY {
+				if !ds.nameInUse(h.Name) {
+					return dns.RcodeNameError
+				}
+			} else if len(ds.rrset(h.Name, h.Rrtype)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if h.Rrtype == dns.TypeANY {
+				if ds.nameInUse(h.Name) {
+					return dns.RcodeYXDomain
+				}
+			} else if len(ds.rrset(h.Name, h.Rrtype)) != 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			var found bool
+
+			for _, existing := range ds.rrset(h.Name, h.Rrtype) {
+				if dns.IsDuplicate(existing, rr) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return dns.RcodeNXRrset
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// checkUpdates prescans the update section (RFC 2136 section 3.4.1) so that
+// an invalid update is rejected before anything is applied. Only A and SRV
+// records may be changed, and records added must be ones AddA and AddSRV
+// accept, so that applying the section cannot stop partway through on one.
+func (ds *Server) checkUpdates(updates []dns.RR) int {
+	for _, rr := range updates {
+		h := rr.Header()
+
+		if !ds.inDomain(h.Name) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassINET:
+		case dns.ClassANY:
+			if h.Ttl != 0 {
+				return dns.RcodeFormatError
+			}
+
+			if h.Rrtype == dns.TypeANY {
+				continue
+			}
+		case dns.ClassNONE:
+			if h.Ttl != 0 {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+
+		switch h.Rrtype {
+		case dns.TypeA:
+			if a, ok := rr.(*dns.A); ok && h.Class == dns.ClassINET && ds.checkA(ds.subdomain(h.Name), a.A) != nil {
+				return dns.RcodeFormatError
+			}
+		case dns.TypeSRV:
+			spec, err := db.ParseSRVSpec(h.Name, ds.domain)
+			if err != nil {
+				return dns.RcodeFormatError
+			}
+
+			if srv, ok := rr.(*dns.SRV); ok && h.Class == dns.ClassINET && ds.ValidateSRV(spec.Service, spec.Protocol, &db.SRVRecord{Host: srv.Target}) != nil {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeNotImplemented
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// applyUpdate applies a single record from the update section.
+func (ds *Server) applyUpdate(rr dns.RR) error {
+	h := rr.Header()
+	host := ds.subdomain(h.Name)
+	spec, err := db.ParseSRVSpec(h.Name, ds.domain)
+	isSrv := err == nil
+
+	switch h.Class {
+	case dns.ClassINET:
+		switch rr := rr.(type) {
+		case *dns.A:
+			if err := ds.AddA(host, rr.A); err != nil {
+				return err
+			}
+
+			return ds.SetATTL(host, ds.zoneTTL(h.Ttl))
+		case *dns.SRV:
+			return ds.AddSRV(spec.Service, spec.Protocol, &db.SRVRecord{
+				Priority: rr.Priority,
+				Weight:   rr.Weight,
+				Port:     rr.Port,
+				Host:     rr.Target,
+				TTL:      ds.zoneTTL(h.Ttl),
+			})
+		}
+	case dns.ClassANY:
+		if h.Rrtype == dns.TypeA || h.Rrtype == dns.TypeANY {
+			if err := ds.DeleteA(host); err != nil {
+				return err
+			}
+		}
+
+		if isSrv && (h.Rrtype == dns.TypeSRV || h.Rrtype == dns.TypeANY) {
+			return ds.DeleteSRV(spec.Service, spec.Protocol)
+		}
+	case dns.ClassNONE:
+		switch rr := rr.(type) {
+		case *dns.A:
+			return ds.DeleteA(host, rr.A)
+		case *dns.SRV:
+			return ds.removeSRV(spec.Service, spec.Protocol, rr)
+		}
+	}
+
+	return nil
+}
+
+// removeSRV removes a single target from a SRV record set.
+func (ds *Server) removeSRV(service, protocol string, rr *dns.SRV) error {
+	spec := ds.qualifySrv(service, protocol)
+
+	srvs, err := ds.db.GetSRV(spec)
+	if err == db.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if err := ds.db.DeleteSRV(spec); err != nil {
+		return err
+	}
+
+	for _, srv := range srvs {
+		if srv.Port == rr.Port && srv.Priority == rr.Priority && srv.Weight == rr.Weight && strings.EqualFold(srv.Host, rr.Target) {
+			continue
+		}
+
+		if err := ds.db.AddSRV(spec, srv); err != nil {
+			return err
+		}
+	}
+
+	return ds.changed(nil)
+}
+
+// rrset returns the records of type rrtype at name.
+func (ds *Server) rrset(name string, rrtype uint16) []dns.RR {
+	rrs := []dns.RR{}
+
+	switch rrtype {
+	case dns.TypeA:
+		rrs = ds.storedA(name)
+	case dns.TypeAAAA:
+		rrs = ds.lookupAAAA(name)
+	case dns.TypeCNAME:
+		for _, rr := range ds.GetCNAME(name) {
+			rrs = append(rrs, rr)
+		}
+	case dns.TypeDNAME:
+		for _, rr := range ds.GetDNAME(name) {
+			rrs = append(rrs, rr)
+		}
+	case dns.TypeTXT:
+		for _, rr := range ds.GetTXT(name) {
+			rrs = append(rrs, rr)
+		}
+	case dns.TypeMX:
+		for _, rr := range ds.GetMX(name) {
+			rrs = append(rrs, rr)
+		}
+	case dns.TypeCAA:
+		for _, rr := range ds.GetCAA(name) {
+			rrs = append(rrs, rr)
+		}
+	case dns.TypePTR:
+		for _, rr := range ds.GetPTR(name) {
+			rrs = append(rrs, rr)
+		}
+	case dns.TypeSRV:
+		for _, rr := range ds.GetSRV(name) {
+			rrs = append(rrs, rr)
+		}
+	default:
+		rrs = append(rrs, ds.GetRaw(name, rrtype)...)
+	}
+
+	return rrs
+}
+
+// nameInUse reports whether any record exists at name.
+func (ds *Server) nameInUse(name string) bool {
+	for _, rrtype := range []uint16{dns.TypeA, dns.TypeAAAA, dns.TypeCNAME, dns.TypeDNAME, dns.TypeTXT, dns.TypeMX, dns.TypeCAA, dns.TypePTR, dns.TypeSRV} {
+		if len(ds.rrset(name, rrtype)) != 0 {
+			return true
+		}
+	}
+
+	return len(ds.GetRaw(name, dns.TypeANY)) != 0
+}
+
+// storedA returns the A records stored at name itself. Unlike lookupA, it does
+// not fall back to the wildcard: prerequisites are checked against what the
+// zone holds at a name (RFC 2136 section 3.2), not what a query for it would
+// be answered with.
+func (ds *Server) storedA(name string) []dns.RR {
+	rrs := []dns.RR{}
+
+	ips, err := ds.getA(ds.subdomain(name))
+	if err != nil {
+		ds.logLookup(name, dns.TypeA, err)
+		return rrs
+	}
+
+	for _, ip := range ips {
+		rrs = append(rrs, &dns.A{
+			Hdr: dns.RR_Header{
+				Name:   name,
+				Rrtype: dns.TypeA,
+				Class:  dns.ClassINET,
+			},
+			A: ip,
+		})
+	}
+
+	return rrs
+}
@@ -0,0 +1,245 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestDynamicUpdate(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	update := func(m *dns.Msg) int {
+		t.Helper()
+
+		msg, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return msg.Rcode
+	}
+
+	a, _ := dns.NewRR("test.docker. 60 IN A 127.0.0.2")
+	srv, _ := dns.NewRR("_web._tcp.docker. IN SRV 1 2 80 test.docker.")
+
+	m := new(dns.Msg)
+	m.SetUpdate("docker.")
+	m.Insert([]dns.RR{a, srv})
+
+	if rcode := update(m); rcode != dns.RcodeRefused {
+		t.Fatalf("update without an ACL was not refused: %s", dns.RcodeToString[rcode])
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetUpdateACL([]net.IPNet{*loopback})
+
+	if rcode := update(m); rcode != dns.RcodeSuccess {
+		t.Fatalf("update failed: %s", dns.RcodeToString[rcode])
+	}
+
+	if ips, err := ds.db.GetA("test"); err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("A record was not added: %v (%v)", ips, err)
+	}
+
+	if ttl, err := ds.db.GetATTL("test"); err != nil || ttl != 60 {
+		t.Fatalf("A TTL was %d (%v)", ttl, err)
+	}
+
+	if srvs, err := ds.db.GetSRV("_web._tcp"); err != nil || len(srvs) != 1 || srvs[0].Host != "test.docker." || srvs[0].Port != 80 {
+		t.Fatalf("SRV record was not added: %v (%v)", srvs, err)
+	}
+
+	// prerequisite: the name must not be in use
+	m = new(dns.Msg)
+	m.SetUpdate("docker.")
+	m.NameNotUsed([]dns.RR{a})
+	m.RemoveName([]dns.RR{a})
+
+	if rcode := update(m); rcode != dns.RcodeYXDomain {
+		t.Fatalf("failed prerequisite yielded %s", dns.RcodeToString[rcode])
+	}
+
+	if _, err := ds.db.GetA("test"); err != nil {
+		t.Fatalf("update was applied despite a failed prerequisite: %v", err)
+	}
+
+	m = new(dns.Msg)
+	m.SetUpdate("docker.")
+	m.NameUsed([]dns.RR{a})
+	m.RemoveName([]dns.RR{a, srv})
+
+	if rcode := update(m); rcode != dns.RcodeSuccess {
+		t.Fatalf("update failed: %s", dns.RcodeToString[rcode])
+	}
+
+	if _, err := ds.db.GetA("test"); err == nil {
+		t.Fatal("A record was not removed")
+	}
+
+	if _, err := ds.db.GetSRV("_web._tcp"); err == nil {
+		t.Fatal("SRV record was not removed")
+	}
+
+	outside, _ := dns.NewRR("test.example.com. IN A 127.0.0.2")
+
+	m = new(dns.Msg)
+	m.SetUpdate("docker.")
+	m.Insert([]dns.RR{outside})
+
+	if rcode := update(m); rcode != dns.RcodeNotZone {
+		t.Fatalf("update outside the zone yielded %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestDynamicUpdateDefaultA(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetUpdateACL([]net.IPNet{*loopback})
+
+	if err := ds.SetDefaultA(net.ParseIP("127.0.0.10")); err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(m *dns.Msg) int {
+		t.Helper()
+
+		msg, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return msg.Rcode
+	}
+
+	// names answered from the default address do not exist to prerequisites
+	a, _ := dns.NewRR("test.docker. 60 IN A 127.0.0.2")
+
+	m := new(dns.Msg)
+	m.SetUpdate("docker.")
+	m.NameUsed([]dns.RR{a})
+	m.Insert([]dns.RR{a})
+
+	if rcode := update(m); rcode != dns.RcodeNameError {
+		t.Fatalf("name-in-use prerequisite yielded %s", dns.RcodeToString[rcode])
+	}
+
+	m = new(dns.Msg)
+	m.SetUpdate("docker.")
+	m.RRsetNotUsed([]dns.RR{a})
+	m.Insert([]dns.RR{a})
+
+	if rcode := update(m); rcode != dns.RcodeSuccess {
+		t.Fatalf("RRset-not-in-use prerequisite yielded %s", dns.RcodeToString[rcode])
+	}
+
+	if err := ds.DeleteA("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	m = new(dns.Msg)
+	m.SetUpdate("docker.")
+	m.NameNotUsed([]dns.RR{a})
+	m.Insert([]dns.RR{a})
+
+	if rcode := update(m); rcode != dns.RcodeSuccess {
+		t.Fatalf("name-not-in-use prerequisite yielded %s", dns.RcodeToString[rcode])
+	}
+
+	if ips, err := ds.db.GetA("test"); err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("A record was not added: %v (%v)", ips, err)
+	}
+}
+
+func TestDynamicUpdateWildcard(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetUpdateACL([]net.IPNet{*loopback})
+
+	if err := ds.SetWildcardA(net.ParseIP("127.0.0.10")); err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(m *dns.Msg) int {
+		t.Helper()
+
+		msg, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return msg.Rcode
+	}
+
+	// names answered from the wildcard hold no records of their own
+	a, _ := dns.NewRR("test.docker. 60 IN A 127.0.0.2")
+	wild, _ := dns.NewRR("test.docker. 0 IN A 127.0.0.10")
+
+	for _, tc := range []struct {
+		name    string
+		prereq  func(m *dns.Msg)
+		rcode   int
+		applied bool
+	}{
+		{"name in use", func(m *dns.Msg) { m.NameUsed([]dns.RR{a}) }, dns.RcodeNameError, false},
+		{"RRset in use", func(m *dns.Msg) { m.RRsetUsed([]dns.RR{a}) }, dns.RcodeNXRrset, false},
+		{"RRset value", func(m *dns.Msg) { m.Used([]dns.RR{wild}) }, dns.RcodeNXRrset, false},
+		{"name not in use", func(m *dns.Msg) { m.NameNotUsed([]dns.RR{a}) }, dns.RcodeSuccess, true},
+	} {
+		m := new(dns.Msg)
+		m.SetUpdate("docker.")
+		tc.prereq(m)
+		m.Insert([]dns.RR{a})
+
+		if rcode := update(m); rcode != tc.rcode {
+			t.Fatalf("%s prerequisite yielded %s", tc.name, dns.RcodeToString[rcode])
+		}
+
+		if _, err := ds.db.GetA("test"); (err == nil) != tc.applied {
+			t.Fatalf("%s prerequisite left the update applied %v: %v", tc.name, !tc.applied, err)
+		}
+	}
+}
+
+func TestDynamicUpdateInvalid(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.SetUpdateACL([]net.IPNet{*loopback})
+
+	a, _ := dns.NewRR("test.docker. 60 IN A 127.0.0.2")
+	unspecified, _ := dns.NewRR("other.docker. 60 IN A 0.0.0.0")
+	root, _ := dns.NewRR("_web._tcp.docker. IN SRV 1 2 80 .")
+
+	// a record the setters reject fails the whole update, not just itself
+	for _, bad := range []dns.RR{unspecified, root} {
+		m := new(dns.Msg)
+		m.SetUpdate("docker.")
+		m.Insert([]dns.RR{a, bad})
+
+		msg, err := dns.Exchange(m, addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if msg.Rcode != dns.RcodeFormatError {
+			t.Fatalf("update adding %v yielded %s", bad, dns.RcodeToString[msg.Rcode])
+		}
+
+		if ips, err := ds.db.GetA("test"); err != db.ErrNotFound {
+			t.Fatalf("update adding %v was partly applied: %v (%v)", bad, ips, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+const (
+	// maxLabelLen is the longest a single label of a name may be.
+	maxLabelLen = 63
+	// maxNameLen is the longest a FQDN may be.
+	maxNameLen = 255
+)
+
+// ErrInvalidName is returned when a host or SRV target is empty or cannot
+// form a valid domain name.
+var ErrInvalidName = errors.New("invalid name")
+
+// ErrInvalidIP is returned when an A record is given an address that is nil,
+// unspecified or not IPv4.
+var ErrInvalidIP = errors.New("invalid IPv4 address")
+
+// ErrInvalidDomain is returned by NewWithError for a domain which is empty, is
+// already qualified or otherwise dotted at either end, or cannot form a valid
+// domain name.
+var ErrInvalidDomain = errors.New("invalid domain")
+
+// NewWithError is like New, but returns ErrInvalidDomain rather than a server
+// for a domain which would qualify hosts into invalid names. New does not
+// check, so with a domain of "" every host is rejected as "host..".
+func NewWithError(domain string, opts ...Option) (*Server, error) {
+	if err := checkDomain(domain); err != nil {
+		return nil, err
+	}
+
+	return New(domain, opts...), nil
+}
+
+// checkDomain validates an unqualified domain, as New takes it.
+func checkDomain(domain string) error {
+	switch {
+	case domain == "":
+		return fmt.Errorf("%w: empty domain", ErrInvalidDomain)
+	case strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, "."):
+		return fmt.Errorf("%w: %q begins or ends with a dot", ErrInvalidDomain, domain)
+	}
+
+	if err := checkName(domain + "."); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDomain, err)
+	}
+
+	return nil
+}
+
+// checkName validates a FQDN: it must be no more than maxNameLen octets, and
+// made of non-empty labels no longer than maxLabelLen.
+func checkName(fqdn string) error {
+	if len(fqdn) > maxNameLen {
+		return fmt.Errorf("%w: %q is longer than %d octets", ErrInvalidName, fqdn, maxNameLen)
+	}
+
+	for _, label := range strings.Split(strings.TrimSuffix(fqdn, "."), ".") {
+		switch {
+		case label == "":
+			return fmt.Errorf("%w: %q has an empty label", ErrInvalidName, fqdn)
+		case len(label) > maxLabelLen:
+			return fmt.Errorf("%w: %q has a label longer than %d octets", ErrInvalidName, fqdn, maxLabelLen)
+		}
+	}
+
+	return nil
+}
+
+// checkHost validates a hostname in the managed domain.
+func (ds *Server) checkHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("%w: empty host", ErrInvalidName)
+	}
+
+	// qualifyHost would drop empty labels, so "a..b" must be caught first
+	return checkName(ds.joinHost(host))
+}
+
+// checkA validates a host and the address to store for it.
+func (ds *Server) checkA(host string, ip net.IP) error {
+	if err := ds.checkHost(host); err != nil {
+		return err
+	}
+
+	if ip == nil || ip.IsUnspecified() || ip.To4() == nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIP, ip)
+	}
+
+	return nil
+}
+
+// checkSRV validates a SRV spec, as made by qualifySrv, and its target.
+func (ds *Server) checkSRV(spec string, srv *db.SRVRecord) error {
+	if strings.HasPrefix(spec, "_.") || strings.HasSuffix(spec, "._") {
+		return fmt.Errorf("%w: empty service or protocol in %q", ErrInvalidName, spec)
+	}
+
+	if err := checkName(spec + "." + ds.domain); err != nil {
+		return err
+	}
+
+	if srv.Host == "" {
+		return fmt.Errorf("%w: empty SRV target", ErrInvalidName)
+	}
+
+	return checkName(srv.Host)
+}
+
+// ValidateSRV reports whether SetSRV or AddSRV would accept srv for a service,
+// without storing it. It lets callers check a whole set of targets before
+// replacing the existing ones.
+func (ds *Server) ValidateSRV(service, protocol string, srv *db.SRVRecord) error {
+	return ds.checkSRV(ds.qualifySrv(service, protocol), ds.qualifySrvHost(srv))
+}
@@ -0,0 +1,111 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/erikh/dnsserver/db"
+)
+
+func TestSetAValidation(t *testing.T) {
+	ds := New("docker")
+
+	for _, tc := range []struct {
+		host string
+		ip   net.IP
+		err  error
+	}{
+		{"test", net.ParseIP("127.0.0.2"), nil},
+		{"sub.test", net.ParseIP("127.0.0.2"), nil},
+		{strings.Repeat("a", 63), net.ParseIP("127.0.0.2"), nil},
+		{"", net.ParseIP("127.0.0.2"), ErrInvalidName},
+		{"a..b", net.ParseIP("127.0.0.2"), ErrInvalidName},
+		{strings.Repeat("a", 64), net.ParseIP("127.0.0.2"), ErrInvalidName},
+		{strings.Repeat(strings.Repeat("a", 60)+".", 5), net.ParseIP("127.0.0.2"), ErrInvalidName},
+		{"test", nil, ErrInvalidIP},
+		{"test", net.IPv4zero, ErrInvalidIP},
+		{"test", net.ParseIP("::1"), ErrInvalidIP},
+	} {
+		if err := ds.SetA(tc.host, tc.ip); !errors.Is(err, tc.err) {
+			t.Fatalf("SetA(%q, %v) returned %v, expected %v", tc.host, tc.ip, err, tc.err)
+		}
+	}
+
+	if _, err := ds.db.GetA(""); err != db.ErrNotFound {
+		t.Fatalf("invalid host was stored: %v", err)
+	}
+}
+
+func TestSetSRVValidation(t *testing.T) {
+	ds := New("docker")
+
+	for _, tc := range []struct {
+		service, protocol string
+		host              string
+		err               error
+	}{
+		{"http", "tcp", "web", nil},
+		{"http", "tcp", "web.example.com.", nil},
+		{"", "tcp", "web", ErrInvalidName},
+		{"http", "", "web", ErrInvalidName},
+		{strings.Repeat("s", 63), "tcp", "web", ErrInvalidName},
+		{"http", "tcp", "", ErrInvalidName},
+		{"http", "tcp", strings.Repeat("a", 64), ErrInvalidName},
+	} {
+		err := ds.ValidateSRV(tc.service, tc.protocol, &db.SRVRecord{Port: 80, Host: tc.host})
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("ValidateSRV(%q, %q, %q) returned %v, expected %v", tc.service, tc.protocol, tc.host, err, tc.err)
+		}
+
+		err = ds.SetSRV(tc.service, tc.protocol, &db.SRVRecord{Port: 80, Host: tc.host})
+		if !errors.Is(err, tc.err) {
+			t.Fatalf("SetSRV(%q, %q, %q) returned %v, expected %v", tc.service, tc.protocol, tc.host, err, tc.err)
+		}
+	}
+
+	if err := ds.ImportA(map[string]net.IP{"good": net.ParseIP("127.0.0.2"), "bad": nil}); !errors.Is(err, ErrInvalidIP) {
+		t.Fatalf("ImportA accepted an invalid address: %v", err)
+	}
+
+	if _, err := ds.db.GetA("good"); err != db.ErrNotFound {
+		t.Fatalf("ImportA stored records from an invalid batch: %v", err)
+	}
+}
+
+func TestNewWithError(t *testing.T) {
+	for _, domain := range []string{"docker", "Example.COM", "a.b.c", strings.Repeat("a", 63)} {
+		ds, err := NewWithError(domain)
+		if err != nil {
+			t.Fatalf("NewWithError(%q) returned %v", domain, err)
+		}
+
+		if ds.domain != strings.ToLower(domain)+"." {
+			t.Fatalf("NewWithError(%q) serves %q", domain, ds.domain)
+		}
+	}
+
+	for _, domain := range []string{
+		"",
+		".",
+		"docker.",
+		".docker",
+		"a..b",
+		strings.Repeat("a", 64),
+		strings.Repeat(strings.Repeat("a", 60)+".", 5) + "com",
+	} {
+		if ds, err := NewWithError(domain); !errors.Is(err, ErrInvalidDomain) || ds != nil {
+			t.Fatalf("NewWithError(%q) returned %v, %v; expected ErrInvalidDomain", domain, ds, err)
+		}
+	}
+
+	ds, err := NewWithError("docker", WithTTL(60))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ds.ttlFor(0) != 60 {
+		t.Fatalf("options were not applied: TTL %d", ds.ttlFor(0))
+	}
+}
@@ -0,0 +1,132 @@
+package dnsserver
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// ErrUnknownView is returned when setting records in a view which has not been
+// added with AddView.
+var ErrUnknownView = errors.New("unknown view")
+
+// view is a set of A records served only to clients in particular networks.
+type view struct {
+	name  string
+	match []net.IPNet
+	db    db.DB
+}
+
+// AddView adds a view: A records which are served, in place of the server's
+// own, to clients within the match networks. A name with no A records in the
+// view is answered as usual, so a view need only hold the records that differ.
+// A client in several views sees the first added. Adding a view which exists
+// replaces its networks and keeps its records.
+//
+// View records are always held in memory, whichever DB the server uses.
+func (ds *Server) AddView(name string, match []net.IPNet) {
+	ds.viewMutex.Lock()
+	defer ds.viewMutex.Unlock()
+
+	match = append([]net.IPNet{}, match...)
+
+	for _, v := range ds.views {
+		if v.name == name {
+			v.match = match
+			return
+		}
+	}
+
+	ds.views = append(ds.views, &view{name: name, match: match, db: db.NewMap()})
+}
+
+// SetAInView sets a host to an IP within a view, replacing any addresses the
+// view already held for it. The host and address are validated as SetA does;
+// ErrUnknownView is returned if the view has not been added.
+func (ds *Server) SetAInView(name, host string, ip net.IP) error {
+	if err := ds.checkA(host, ip); err != nil {
+		return err
+	}
+
+	v, err := ds.view(name)
+	if err != nil {
+		return err
+	}
+
+	return ds.changed(v.db.SetA(host, ip))
+}
+
+// DeleteAInView deletes a host from a view, so that clients in the view see the
+// server's own records for it again.
+func (ds *Server) DeleteAInView(name, host string) error {
+	v, err := ds.view(name)
+	if err != nil {
+		return err
+	}
+
+	return ds.changed(v.db.DeleteA(host))
+}
+
+func (ds *Server) view(name string) (*view, error) {
+	ds.viewMutex.RLock()
+	defer ds.viewMutex.RUnlock()
+
+	for _, v := range ds.views {
+		if v.name == name {
+			return v, nil
+		}
+	}
+
+	return nil, ErrUnknownView
+}
+
+// viewFor returns the view serving the client at addr, or nil.
+func (ds *Server) viewFor(addr net.Addr) *view {
+	ds.viewMutex.RLock()
+	defer ds.viewMutex.RUnlock()
+
+	for _, v := range ds.views {
+		if aclAllows(v.match, addr) {
+			return v
+		}
+	}
+
+	return nil
+}
+
+// viewLookupA returns the A lookup for clients of v: its records, falling back
+// to the server's own. A nil view is the server's own lookup.
+func (ds *Server) viewLookupA(v *view) func(string) []dns.RR {
+	if v == nil {
+		return ds.lookupA
+	}
+
+	return func(name string) []dns.RR {
+		if !strings.EqualFold(name, ds.domain) && !ds.inDomain(name) {
+			return ds.lookupA(name)
+		}
+
+		ips, err := v.db.GetA(ds.subdomain(name))
+		if err != nil {
+			return ds.lookupA(name)
+		}
+
+		answers := []dns.RR{}
+		for _, ip := range ips {
+			answers = append(answers, &dns.A{
+				Hdr: dns.RR_Header{
+					Name:   name,
+					Rrtype: dns.TypeA,
+					Class:  dns.ClassINET,
+					Ttl:    ds.ttlFor(0),
+				},
+				A: ip,
+			})
+		}
+
+		return answers
+	}
+}
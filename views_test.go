@@ -0,0 +1,103 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// clientWriter captures the reply to a query from a chosen client address.
+type clientWriter struct {
+	remote net.Addr
+	reply  *dns.Msg
+}
+
+func (w *clientWriter) LocalAddr() net.Addr       { return &net.UDPAddr{} }
+func (w *clientWriter) RemoteAddr() net.Addr      { return w.remote }
+func (w *clientWriter) WriteMsg(m *dns.Msg) error { w.reply = m; return nil }
+func (w *clientWriter) Write([]byte) (int, error) { return 0, nil }
+func (w *clientWriter) Close() error              { return nil }
+func (w *clientWriter) TsigStatus() error         { return nil }
+func (w *clientWriter) TsigTimersOnly(bool)       {}
+func (w *clientWriter) Hijack()                   {}
+
+// queryFrom serves a query for name as if it came from ip.
+func queryFrom(ds *Server, ip, name string) *dns.Msg {
+	r := new(dns.Msg)
+	r.SetQuestion(name, dns.TypeA)
+
+	w := &clientWriter{remote: &net.UDPAddr{IP: net.ParseIP(ip), Port: 53}}
+	ds.ServeDNS(w, r)
+	return w.reply
+}
+
+func TestViews(t *testing.T) {
+	ds := New("docker")
+
+	if err := ds.SetAInView("internal", "web", net.ParseIP("10.0.0.2")); err != ErrUnknownView {
+		t.Fatalf("setting a record in a missing view was %v", err)
+	}
+
+	_, internal, _ := net.ParseCIDR("10.0.0.0/8")
+	_, office, _ := net.ParseCIDR("192.168.0.0/16")
+	ds.AddView("internal", []net.IPNet{*internal})
+	ds.AddView("office", []net.IPNet{*office})
+
+	for _, err := range []error{
+		ds.SetA("web", net.ParseIP("203.0.113.2")),
+		ds.SetA("mail", net.ParseIP("203.0.113.3")),
+		ds.SetAInView("internal", "web", net.ParseIP("10.0.0.2")),
+		ds.SetAInView("office", "web", net.ParseIP("192.168.0.2")),
+	} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	table := []struct {
+		client string
+		name   string
+		ip     string
+	}{
+		{"10.1.2.3", "web.docker.", "10.0.0.2"},
+		{"192.168.4.5", "web.docker.", "192.168.0.2"},
+		{"198.51.100.7", "web.docker.", "203.0.113.2"},
+		{"10.1.2.3", "mail.docker.", "203.0.113.3"},
+	}
+
+	for _, entry := range table {
+		msg := queryFrom(ds, entry.client, entry.name)
+		if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP(entry.ip)) {
+			t.Fatalf("%s from %s was answered with %v, not %s", entry.name, entry.client, msg.Answer, entry.ip)
+		}
+	}
+
+	if err := ds.DeleteAInView("internal", "web"); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg := queryFrom(ds, "10.1.2.3", "web.docker."); len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("203.0.113.2")) {
+		t.Fatalf("deleted view record was still served: %v", msg.Answer)
+	}
+
+	// the running server sees loopback clients
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	ds.AddView("internal", []net.IPNet{*loopback})
+
+	if err := ds.SetAInView("internal", "web", net.ParseIP("10.0.0.2")); err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	msg, err := msgClientAddr(addr, "web.docker.", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msg.Answer) != 1 || !msg.Answer[0].(*dns.A).A.Equal(net.ParseIP("10.0.0.2")) {
+		t.Fatalf("loopback client was answered with %v", msg.Answer)
+	}
+}
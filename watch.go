@@ -0,0 +1,158 @@
+package dnsserver
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// watchBuffer is how many events a subscriber may fall behind by before
+// further events are dropped for it.
+const watchBuffer = 64
+
+// EventOp is the kind of change an Event describes.
+type EventOp int
+
+const (
+	// EventSet replaces every value held for the name with Value.
+	EventSet EventOp = iota
+	// EventAdd adds Value to those held for the name.
+	EventAdd
+	// EventDelete removes Value from the name, or every value if Value is
+	// empty.
+	EventDelete
+)
+
+func (op EventOp) String() string {
+	switch op {
+	case EventSet:
+		return "set"
+	case EventAdd:
+		return "add"
+	case EventDelete:
+		return "delete"
+	}
+
+	return fmt.Sprintf("EventOp(%d)", int(op))
+}
+
+// Event describes a change to a record, as delivered by Watch. Type is the
+// record type, such as dns.TypeA, and Name its FQDN. Value is the record data
+// in zone file form: the address for A records, and "priority weight port
+// target" for SRV records.
+type Event struct {
+	Op    EventOp
+	Type  uint16
+	Name  string
+	Value string
+}
+
+// watchers tracks the subscribers created by Watch.
+type watchers struct {
+	mutex sync.Mutex
+	subs  map[chan Event]struct{}
+}
+
+// Watch subscribes to changes made through SetA, AddA, DeleteA, SetSRV,
+// SetSRVRaw, AddSRV and DeleteSRV, and the bulk ImportA, ImportSRV, Restore
+// and Flush methods, which send an event per host or service. Events are sent
+// without blocking: a subscriber which falls more than a few dozen events
+// behind, as a large bulk change easily makes it, misses those that follow
+// until it catches up. The returned func unsubscribes and closes the channel.
+func (ds *Server) Watch() (<-chan Event, func()) {
+	ch := make(chan Event, watchBuffer)
+
+	ds.watchers.mutex.Lock()
+	if ds.watchers.subs == nil {
+		ds.watchers.subs = map[chan Event]struct{}{}
+	}
+	ds.watchers.subs[ch] = struct{}{}
+	ds.watchers.mutex.Unlock()
+
+	var once sync.Once
+
+	return ch, func() {
+		once.Do(func() {
+			ds.watchers.mutex.Lock()
+			delete(ds.watchers.subs, ch)
+			ds.watchers.mutex.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// announce publishes events to every subscriber if err is nil, and returns
+// err.
+func (ds *Server) announce(err error, events ...Event) error {
+	if err != nil {
+		return err
+	}
+
+	ds.watchers.mutex.Lock()
+	defer ds.watchers.mutex.Unlock()
+
+	for ch := range ds.watchers.subs {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+			}
+		}
+	}
+
+	return nil
+}
+
+// watching reports whether there are any subscribers, so that changes which
+// are costly to describe need only be described for them.
+func (ds *Server) watching() bool {
+	ds.watchers.mutex.Lock()
+	defer ds.watchers.mutex.Unlock()
+
+	return len(ds.watchers.subs) != 0
+}
+
+// aEvents describes a change to host's A records, one event per address.
+func (ds *Server) aEvents(op EventOp, host string, ips ...net.IP) []Event {
+	name := ds.qualifyHost(host)
+
+	if len(ips) == 0 {
+		return []Event{{Op: op, Type: dns.TypeA, Name: name}}
+	}
+
+	events := make([]Event, 0, len(ips))
+	for _, ip := range ips {
+		events = append(events, Event{Op: op, Type: dns.TypeA, Name: name, Value: ip.String()})
+	}
+
+	return events
+}
+
+// aSetEvents describes replacing host's A records with ips: the first address
+// is set and the others added, so that a subscriber applying the events in
+// turn ends up with all of them.
+func (ds *Server) aSetEvents(host string, ips []net.IP) []Event {
+	if len(ips) == 0 {
+		return nil
+	}
+
+	events := ds.aEvents(EventSet, host, ips[0])
+	if len(ips) > 1 {
+		events = append(events, ds.aEvents(EventAdd, host, ips[1:]...)...)
+	}
+
+	return events
+}
+
+// srvEvent describes a change to the SRV records at spec.
+func (ds *Server) srvEvent(op EventOp, spec string, srv *db.SRVRecord) Event {
+	event := Event{Op: op, Type: dns.TypeSRV, Name: spec + "." + ds.domain}
+	if srv != nil {
+		event.Value = fmt.Sprintf("%d %d %d %s", srv.Priority, srv.Weight, srv.Port, srv.Host)
+	}
+
+	return event
+}
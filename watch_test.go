@@ -0,0 +1,182 @@
+package dnsserver
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+func TestWatch(t *testing.T) {
+	ds := New("docker")
+
+	events, cancel := ds.Watch()
+	defer cancel()
+
+	ds.SetA("test", net.ParseIP("127.0.0.2"))
+	ds.AddA("test", net.ParseIP("127.0.0.3"))
+	ds.DeleteA("test", net.ParseIP("127.0.0.2"))
+	ds.SetSRV("http", "tcp", &db.SRVRecord{Priority: 1, Weight: 2, Port: 80, Host: "test"})
+	ds.DeleteSRV("http", "tcp")
+	ds.DeleteA("test")
+
+	// failed changes are not reported
+	ds.SetA("", net.ParseIP("127.0.0.2"))
+
+	expected := []Event{
+		{Op: EventSet, Type: dns.TypeA, Name: "test.docker.", Value: "127.0.0.2"},
+		{Op: EventAdd, Type: dns.TypeA, Name: "test.docker.", Value: "127.0.0.3"},
+		{Op: EventDelete, Type: dns.TypeA, Name: "test.docker.", Value: "127.0.0.2"},
+		{Op: EventSet, Type: dns.TypeSRV, Name: "_http._tcp.docker.", Value: "1 2 80 test.docker."},
+		{Op: EventDelete, Type: dns.TypeSRV, Name: "_http._tcp.docker."},
+		{Op: EventDelete, Type: dns.TypeA, Name: "test.docker."},
+	}
+
+	for i, want := range expected {
+		select {
+		case got := <-events:
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("event %d was %+v, expected %+v", i, got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d was not delivered", i)
+		}
+	}
+
+	select {
+	case got := <-events:
+		t.Fatalf("unexpected event %+v", got)
+	default:
+	}
+
+	cancel()
+	cancel()
+
+	if _, ok := <-events; ok {
+		t.Fatal("channel was not closed by unsubscribing")
+	}
+
+	// publishing with no subscribers, or to a full one, must not block
+	_, slowCancel := ds.Watch()
+	defer slowCancel()
+
+	for i := 0; i < watchBuffer*2; i++ {
+		ds.SetA("test", net.ParseIP("127.0.0.2"))
+	}
+}
+
+func TestWatchBulk(t *testing.T) {
+	for _, ds := range []*Server{New("docker"), NewWithDB("docker", setOnlyDB{db.NewMap()})} {
+		events, cancel := ds.Watch()
+
+		// bulk events come in no particular order
+		expect := func(op string, want ...Event) {
+			t.Helper()
+
+			pending := map[Event]bool{}
+			for _, event := range want {
+				pending[event] = true
+			}
+
+			for len(pending) != 0 {
+				select {
+				case got := <-events:
+					if !pending[got] {
+						t.Fatalf("%T: %s sent unexpected event %+v", ds.db, op, got)
+					}
+
+					delete(pending, got)
+				case <-time.After(time.Second):
+					t.Fatalf("%T: %s did not send %v", ds.db, op, pending)
+				}
+			}
+		}
+
+		records := map[string]net.IP{"one": net.ParseIP("127.0.0.2"), "two": net.ParseIP("127.0.0.3")}
+		sets := []Event{
+			{Op: EventSet, Type: dns.TypeA, Name: "one.docker.", Value: "127.0.0.2"},
+			{Op: EventSet, Type: dns.TypeA, Name: "two.docker.", Value: "127.0.0.3"},
+		}
+		deletes := []Event{
+			{Op: EventDelete, Type: dns.TypeA, Name: "one.docker."},
+			{Op: EventDelete, Type: dns.TypeA, Name: "two.docker."},
+		}
+		srvSet := Event{Op: EventSet, Type: dns.TypeSRV, Name: "_web._tcp.docker.", Value: "0 0 80 one.docker."}
+		srvDelete := Event{Op: EventDelete, Type: dns.TypeSRV, Name: "_web._tcp.docker."}
+
+		if err := ds.ImportA(records); err != nil {
+			t.Fatal(err)
+		}
+		expect("ImportA", sets...)
+
+		if err := ds.ImportSRV(map[string]*db.SRVRecord{"_Web._tcp": {Port: 80, Host: "one"}}); err != nil {
+			t.Fatal(err)
+		}
+		expect("ImportSRV", srvSet)
+
+		if err := ds.FlushA(); err != nil {
+			t.Fatal(err)
+		}
+		expect("FlushA", deletes...)
+
+		if err := ds.FlushSRV(); err != nil {
+			t.Fatal(err)
+		}
+		expect("FlushSRV", srvDelete)
+
+		ds.ImportA(records)
+		ds.ImportSRV(map[string]*db.SRVRecord{"_web._tcp": {Port: 80, Host: "one"}})
+		expect("ImportA", append(sets, srvSet)...)
+
+		if err := ds.FlushAll(); err != nil {
+			t.Fatal(err)
+		}
+		expect("FlushAll", append(deletes, srvDelete)...)
+
+		cancel()
+	}
+}
+
+func TestWatchDeleteByIP(t *testing.T) {
+	ds := New("docker")
+	ip := net.ParseIP("127.0.0.2")
+
+	ds.SetA("one", ip)
+	ds.SetA("two", ip)
+	ds.AddA("two", net.ParseIP("127.0.0.3"))
+	ds.SetA("other", net.ParseIP("127.0.0.4"))
+
+	events, cancel := ds.Watch()
+	defer cancel()
+
+	if _, err := ds.DeleteByIP(ip); err != nil {
+		t.Fatal(err)
+	}
+
+	pending := map[Event]bool{
+		{Op: EventDelete, Type: dns.TypeA, Name: "one.docker.", Value: "127.0.0.2"}: true,
+		{Op: EventDelete, Type: dns.TypeA, Name: "two.docker.", Value: "127.0.0.2"}: true,
+	}
+
+	for len(pending) != 0 {
+		select {
+		case got := <-events:
+			if !pending[got] {
+				t.Fatalf("unexpected event %+v", got)
+			}
+
+			delete(pending, got)
+		case <-time.After(time.Second):
+			t.Fatalf("DeleteByIP did not send %v", pending)
+		}
+	}
+
+	select {
+	case got := <-events:
+		t.Fatalf("unexpected event %+v", got)
+	default:
+	}
+}
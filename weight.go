@@ -0,0 +1,142 @@
+package dnsserver
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidWeight is returned by AddWeightedA for weights below 1.
+var ErrInvalidWeight = errors.New("weight must be at least 1")
+
+// AddWeightedA is like AddA, but gives the address a weight, so that hosts
+// with several addresses answer with the heavier ones first more often: each
+// address comes first in proportion to its share of the host's total weight.
+// Addresses added with AddA weigh 1. Hosts with no weighted addresses are
+// answered in the order their addresses were added.
+//
+// Weights are kept by the server rather than the DB. They are forgotten when
+// SetA replaces the host's addresses, DeleteA removes the address, or AddA adds
+// it again.
+func (ds *Server) AddWeightedA(host string, ip net.IP, weight int) error {
+	if weight < 1 {
+		return ErrInvalidWeight
+	}
+
+	if err := ds.AddA(host, ip); err != nil {
+		return err
+	}
+
+	if weight == 1 {
+		return nil
+	}
+
+	ds.weightMutex.Lock()
+	defer ds.weightMutex.Unlock()
+
+	if ds.weights == nil {
+		ds.weights = map[string]map[string]int{}
+	}
+
+	host = strings.ToLower(host)
+	if ds.weights[host] == nil {
+		ds.weights[host] = map[string]int{}
+	}
+
+	ds.weights[host][ip.String()] = weight
+	return nil
+}
+
+// forgetWeights drops the weights of ips at host, or of all its addresses if
+// none are given.
+func (ds *Server) forgetWeights(host string, ips ...net.IP) {
+	ds.weightMutex.Lock()
+	defer ds.weightMutex.Unlock()
+
+	host = strings.ToLower(host)
+	if ds.weights[host] == nil {
+		return
+	}
+
+	if len(ips) == 0 {
+		delete(ds.weights, host)
+		return
+	}
+
+	for _, ip := range ips {
+		delete(ds.weights[host], ip.String())
+	}
+
+	if len(ds.weights[host]) == 0 {
+		delete(ds.weights, host)
+	}
+}
+
+// moveWeights gives newHost the weights of oldHost in place of its own.
+func (ds *Server) moveWeights(oldHost, newHost string) {
+	ds.weightMutex.Lock()
+	defer ds.weightMutex.Unlock()
+
+	oldHost, newHost = strings.ToLower(oldHost), strings.ToLower(newHost)
+
+	weights := ds.weights[oldHost]
+	delete(ds.weights, oldHost)
+	delete(ds.weights, newHost)
+
+	if weights != nil {
+		ds.weights[newHost] = weights
+	}
+}
+
+// weigh orders the addresses of host for an answer. If any are weighted, they
+// are put in a random order where each address comes first with probability
+// proportional to its weight (weighted sampling without replacement, with
+// keys u^(1/w)); otherwise ips is returned as it is.
+func (ds *Server) weigh(host string, ips []net.IP) []net.IP {
+	ds.weightMutex.RLock()
+	weights := ds.weights[strings.ToLower(host)]
+
+	if len(weights) == 0 || len(ips) < 2 {
+		ds.weightMutex.RUnlock()
+		return ips
+	}
+
+	keys := make([]float64, len(ips))
+	for i, ip := range ips {
+		weight, ok := weights[ip.String()]
+		if !ok {
+			weight = 1
+		}
+
+		keys[i] = math.Pow(rand.Float64(), 1/float64(weight))
+	}
+	ds.weightMutex.RUnlock()
+
+	sort.Sort(byKey{ips, keys})
+	return ips
+}
+
+// byKey sorts addresses by descending key.
+type byKey struct {
+	ips  []net.IP
+	keys []float64
+}
+
+func (b byKey) Len() int           { return len(b.ips) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] > b.keys[j] }
+
+func (b byKey) Swap(i, j int) {
+	b.ips[i], b.ips[j] = b.ips[j], b.ips[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
+
+// weighted reports whether the host name has weighted addresses.
+func (ds *Server) weighted(name string) bool {
+	ds.weightMutex.RLock()
+	defer ds.weightMutex.RUnlock()
+
+	return len(ds.weights[ds.subdomain(name)]) != 0
+}
@@ -0,0 +1,59 @@
+package dnsserver
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAddWeightedA(t *testing.T) {
+	ds := New("docker")
+
+	light, heavy := net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")
+
+	if err := ds.AddWeightedA("test", light, 0); err != ErrInvalidWeight {
+		t.Fatalf("zero weight was %v", err)
+	}
+
+	if err := ds.AddA("test", light); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.AddWeightedA("test", heavy, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// heavy should come first in 3 of every 4 answers
+	const n = 4000
+
+	var first int
+
+	for i := 0; i < n; i++ {
+		records := ds.GetA("test.docker.")
+		if len(records) != 2 {
+			t.Fatalf("weighted host returned %v", records)
+		}
+
+		if records[0].A.Equal(heavy) {
+			first++
+		}
+	}
+
+	if share := float64(first) / n; share < 0.70 || share > 0.80 {
+		t.Fatalf("heavier address came first in %.2f of answers, not about 0.75", share)
+	}
+
+	// replacing the addresses drops the weights, restoring insertion order
+	if err := ds.SetA("test", light); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.AddA("test", heavy); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if records := ds.GetA("test.docker."); !records[0].A.Equal(light) {
+			t.Fatalf("unweighted host was reordered: %v", records)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package dnsserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/erikh/dnsserver/db"
+	"github.com/miekg/dns"
+)
+
+// LoadZoneFile reads a BIND-style master file and adds its A, AAAA and SRV
+// records to the server. Relative names are taken to be under the server's
+// domain. Records outside the domain, and records of other types, are skipped;
+// the number skipped is returned. A and SRV records whose TTL differs from the
+// server default keep their TTL.
+func (ds *Server) LoadZoneFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var skipped int
+
+	zp := dns.NewZoneParser(f, ds.domain, path)
+
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if !ds.inDomain(rr.Header().Name) {
+			skipped++
+			continue
+		}
+
+		host := ds.subdomain(rr.Header().Name)
+
+		switch rr := rr.(type) {
+		case *dns.A:
+			if err := ds.AddA(host, rr.A); err != nil {
+				return skipped, err
+			}
+
+			if err := ds.SetATTL(host, ds.zoneTTL(rr.Hdr.Ttl)); err != nil {
+				return skipped, err
+			}
+		case *dns.AAAA:
+			if err := ds.SetAAAA(host, rr.AAAA); err != nil {
+				return skipped, err
+			}
+		case *dns.SRV:
+			spec, err := db.ParseSRVSpec(rr.Hdr.Name, ds.domain)
+			if err != nil {
+				skipped++
+				continue
+			}
+
+			srv := &db.SRVRecord{
+				Priority: rr.Priority,
+				Weight:   rr.Weight,
+				Port:     rr.Port,
+				Host:     rr.Target,
+				TTL:      ds.zoneTTL(rr.Hdr.Ttl),
+			}
+
+			if err := ds.AddSRV(spec.Service, spec.Protocol, srv); err != nil {
+				return skipped, err
+			}
+		default:
+			skipped++
+		}
+	}
+
+	return skipped, zp.Err()
+}
+
+// ErrOutOfZone is reported by ValidateZoneFile for a record whose name is not
+// in the server's domain.
+var ErrOutOfZone = errors.New("name is outside the zone")
+
+// ErrDuplicateRecord is reported by ValidateZoneFile for a record which
+// repeats one earlier in the file.
+var ErrDuplicateRecord = errors.New("duplicate record")
+
+// ErrCNAMEConflict is reported by ValidateZoneFile for a CNAME sharing its
+// name with another record, which RFC 1034 forbids.
+var ErrCNAMEConflict = errors.New("CNAME shares its name with other records")
+
+// ZoneError describes a problem with a single record found by
+// ValidateZoneFile.
+type ZoneError struct {
+	Name string
+	Type uint16
+	Err  error
+}
+
+func (e ZoneError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Name, dns.TypeToString[e.Type], e.Err)
+}
+
+func (e ZoneError) Unwrap() error {
+	return e.Err
+}
+
+// ValidateZoneFile reads a master file as LoadZoneFile does, but only checks
+// its records, leaving the server untouched. Every problem found is returned:
+// records outside the domain, A, AAAA and SRV records LoadZoneFile would
+// reject, duplicate records and CNAMEs which collide with other records. The
+// error is for failures to read or parse the file; parsing stops at the
+// first syntax error.
+func (ds *Server) ValidateZoneFile(path string) ([]ZoneError, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var problems []ZoneError
+
+	names := map[string][]dns.RR{}
+	zp := dns.NewZoneParser(f, ds.domain, path)
+
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		h := rr.Header()
+		report := func(err error) {
+			problems = append(problems, ZoneError{Name: h.Name, Type: h.Rrtype, Err: err})
+		}
+
+		if !strings.EqualFold(h.Name, ds.domain) && !ds.inDomain(h.Name) {
+			report(ErrOutOfZone)
+			continue
+		}
+
+		if err := ds.checkZoneRecord(rr); err != nil {
+			report(err)
+		}
+
+		name := strings.ToLower(h.Name)
+
+		for _, other := range names[name] {
+			if dns.IsDuplicate(other, rr) {
+				report(ErrDuplicateRecord)
+				break
+			}
+
+			if (h.Rrtype == dns.TypeCNAME) != (other.Header().Rrtype == dns.TypeCNAME) {
+				report(ErrCNAMEConflict)
+				break
+			}
+		}
+
+		names[name] = append(names[name], rr)
+	}
+
+	return problems, zp.Err()
+}
+
+// checkZoneRecord validates an in-domain record as the setter LoadZoneFile
+// stores it with would.
+func (ds *Server) checkZoneRecord(rr dns.RR) error {
+	host := ds.subdomain(rr.Header().Name)
+
+	switch rr := rr.(type) {
+	case *dns.A:
+		return ds.checkA(host, rr.A)
+	case *dns.AAAA:
+		return ds.checkHost(host)
+	case *dns.SRV:
+		spec, err := db.ParseSRVSpec(rr.Hdr.Name, ds.domain)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidName, err)
+		}
+
+		return ds.checkSRV(ds.qualifySrv(spec.Service, spec.Protocol), ds.qualifySrvHost(&db.SRVRecord{Host: rr.Target}))
+	}
+
+	return nil
+}
+
+// zoneTTL converts a TTL read from a zone file into a record TTL; the server
+// default is stored as 0 so the record follows it.
+func (ds *Server) zoneTTL(ttl uint32) uint32 {
+	if ttl == atomic.LoadUint32(&ds.ttl) {
+		return 0
+	}
+
+	return ttl
+}
+
+// ExportZone writes all A, AAAA and SRV records as a RFC 1035 master file,
+// headed by the SOA and NS records for the apex. The output can be read
+// back with LoadZoneFile.
+func (ds *Server) ExportZone(w io.Writer) error {
+	records, err := ds.zoneRecords()
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "$ORIGIN %s\n$TTL %d\n", ds.domain, atomic.LoadUint32(&ds.ttl)); err != nil {
+		return err
+	}
+
+	header := append([]dns.RR{ds.soa()}, ds.authority()...)
+
+	for _, rr := range append(header, records...) {
+		if _, err := fmt.Fprintln(w, rr.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// zoneRecords returns every A, AAAA and SRV record in the zone, sorted by
+// name.
+func (ds *Server) zoneRecords() ([]dns.RR, error) {
+	as, err := ds.ListASorted()
+	if err != nil {
+		return nil, err
+	}
+
+	aaaas, err := ds.ListAAAA()
+	if err != nil {
+		return nil, err
+	}
+
+	srvs, err := ds.ListSRVSorted()
+	if err != nil {
+		return nil, err
+	}
+
+	rrs := []dns.RR{}
+
+	for _, entry := range as {
+		for _, rr := range ds.GetA(entry.Name) {
+			rrs = append(rrs, rr)
+		}
+	}
+
+	hosts := make([]string, 0, len(aaaas))
+	for host := range aaaas {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		for _, rr := range ds.GetAAAA(ds.qualifyHost(host)) {
+			rrs = append(rrs, rr)
+		}
+	}
+
+	for _, entry := range srvs {
+		for _, rr := range ds.GetSRV(entry.Name) {
+			rrs = append(rrs, rr)
+		}
+	}
+
+	return rrs, nil
+}
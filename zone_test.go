@@ -0,0 +1,209 @@
+package dnsserver
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestLoadZoneFile(t *testing.T) {
+	ds := New("docker")
+	addr := startServer(t, ds)
+	defer ds.Close()
+
+	skipped, err := ds.LoadZoneFile("testdata/docker.zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SOA, NS and the out-of-domain A record
+	if skipped != 3 {
+		t.Fatalf("expected 3 skipped records, got %d", skipped)
+	}
+
+	table := []struct {
+		name    string
+		dnsType uint16
+		answers int
+		ttl     uint32
+	}{
+		{"zone1.docker.", dns.TypeA, 2, 60},
+		{"zone2.docker.", dns.TypeA, 1, 300},
+		{"zone1.docker.", dns.TypeAAAA, 1, 1},
+		{"_zone._tcp.docker.", dns.TypeSRV, 1, 60},
+		{"outside.example.com.", dns.TypeA, 0, 0},
+	}
+
+	for _, entry := range table {
+		msg, err := msgClientAddr(addr, entry.name, entry.dnsType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(msg.Answer) != entry.answers {
+			t.Fatalf("expected %d answers for %q, got %d", entry.answers, entry.name, len(msg.Answer))
+		}
+
+		for _, answer := range msg.Answer {
+			if answer.Header().Ttl != entry.ttl {
+				t.Fatalf("TTL for %q was %d instead of %d", entry.name, answer.Header().Ttl, entry.ttl)
+			}
+		}
+	}
+
+	msg, err := msgClientAddr(addr, "_zone._tcp.docker.", dns.TypeSRV)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := msg.Answer[0].(*dns.SRV)
+	if srv.Priority != 10 || srv.Weight != 20 || srv.Port != 8080 || srv.Target != "zone1.docker." {
+		t.Fatalf("SRV record was not loaded correctly: %v", srv)
+	}
+
+	ips, err := ds.ListA()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ips["zone1"][0].Equal(net.ParseIP("127.0.0.50")) || !ips["zone1"][1].Equal(net.ParseIP("127.0.0.51")) {
+		t.Fatalf("A records were not loaded in order: %v", ips["zone1"])
+	}
+}
+
+func TestLoadZoneFileMissing(t *testing.T) {
+	if _, err := New("docker").LoadZoneFile("testdata/missing.zone"); err == nil {
+		t.Fatal("loading a missing zone file did not fail")
+	}
+}
+
+func TestExportZone(t *testing.T) {
+	ds := New("docker")
+
+	if _, err := ds.LoadZoneFile("testdata/docker.zone"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.SetA("plain", net.ParseIP("127.0.0.60")); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "dnsserver-zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "export.zone")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ds.ExportZone(f); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ds2 := New("docker")
+
+	skipped, err := ds2.LoadZoneFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// generated SOA and NS
+	if skipped != 2 {
+		t.Fatalf("expected 2 skipped records, got %d", skipped)
+	}
+
+	as, _ := ds.ListA()
+	as2, _ := ds2.ListA()
+	if !reflect.DeepEqual(as, as2) {
+		t.Fatalf("A listings differ after round trip: %v vs %v", as, as2)
+	}
+
+	for host := range as {
+		ttl, _ := ds.db.GetATTL(host)
+		ttl2, _ := ds2.db.GetATTL(host)
+		if ttl != ttl2 {
+			t.Fatalf("A TTL for %q differs after round trip: %d vs %d", host, ttl, ttl2)
+		}
+	}
+
+	aaaas, _ := ds.ListAAAA()
+	aaaas2, _ := ds2.ListAAAA()
+	if !reflect.DeepEqual(aaaas, aaaas2) {
+		t.Fatalf("AAAA listings differ after round trip: %v vs %v", aaaas, aaaas2)
+	}
+
+	srvs, _ := ds.ListSRV()
+	srvs2, _ := ds2.ListSRV()
+	if !reflect.DeepEqual(srvs, srvs2) {
+		t.Fatalf("SRV listings differ after round trip: %v vs %v", srvs, srvs2)
+	}
+}
+
+func TestValidateZoneFile(t *testing.T) {
+	ds := New("docker")
+
+	problems, err := ds.ValidateZoneFile("testdata/docker.zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the out-of-domain A record
+	if len(problems) != 1 || problems[0].Name != "outside.example.com." || !errors.Is(problems[0], ErrOutOfZone) {
+		t.Fatalf("valid zone reported %v", problems)
+	}
+
+	problems, err = ds.ValidateZoneFile("testdata/invalid.zone")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		name  string
+		qtype uint16
+		err   error
+	}{
+		{"any.docker.", dns.TypeA, ErrInvalidIP},
+		{"good.docker.", dns.TypeA, ErrDuplicateRecord},
+		{"alias.docker.", dns.TypeA, ErrCNAMEConflict},
+		{"web.docker.", dns.TypeSRV, ErrInvalidName},
+		{"outside.example.com.", dns.TypeA, ErrOutOfZone},
+	}
+
+	if len(problems) != len(expected) {
+		t.Fatalf("expected %d problems, got %v", len(expected), problems)
+	}
+
+	for i, want := range expected {
+		if problems[i].Name != want.name || problems[i].Type != want.qtype || !errors.Is(problems[i], want.err) {
+			t.Fatalf("problem %d was %v, not %s %s: %v", i, problems[i], want.name, dns.TypeToString[want.qtype], want.err)
+		}
+	}
+
+	if records, err := ds.ListA(); err != nil || len(records) != 0 {
+		t.Fatalf("validation stored records: %v (%v)", records, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.zone")
+	if err := os.WriteFile(path, []byte("$ORIGIN docker.\nbogus IN A not-an-ip\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ds.ValidateZoneFile(path); err == nil {
+		t.Fatal("syntax error was not returned")
+	}
+}